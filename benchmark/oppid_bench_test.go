@@ -1,71 +1,90 @@
 package benchmark
 
 import (
+	"OPPID-artifacts/pkg/oppid/sign/schemes"
+	"OPPID-artifacts/pkg/oppid/utils"
 	OPPID "OPPID-artifacts/protocol/oppid"
+	"fmt"
 	"testing"
 	"time"
 )
 
-func setupOPPIDBenchmark() (*OPPID.PublicParams, []byte, []byte, []byte, []byte, *OPPID.PrivateKey, *OPPID.PublicKey, OPPID.Credential, OPPID.UsrOpening, OPPID.UsrCommitment, OPPID.Auth, OPPID.Token, OPPID.FinalizedToken, OPPID.PPID) {
-	oppid := OPPID.Setup()
-	isk, ipk := oppid.KeyGen()
+// oppidBench holds an OPPID instance with the messages of one login as inputs to the benchmarked steps.
+type oppidBench struct {
+	pp                 *OPPID.PublicParams
+	rid, uid, ctx, sid []byte
+	isk                *OPPID.PrivateKey
+	ipk                *OPPID.PublicKey
+	cred               OPPID.Credential
+	orid               OPPID.UsrOpening
+	crid               OPPID.UsrCommitment
+	auth               OPPID.Auth
+	tk                 OPPID.Token
+	ftk                OPPID.FinalizedToken
+	ppid               OPPID.PPID
+}
+
+func setupOPPIDBenchmark(opts ...OPPID.Option) *oppidBench {
+	o := &oppidBench{pp: OPPID.Setup(opts...)}
+	o.isk, o.ipk, _ = o.pp.KeyGen()
 
-	rid := []byte("Test-RID")
-	uid := []byte("alice.doe@idp.com")
-	ctx := []byte("Test-CTX")
-	sid := []byte("Test-SID")
+	o.rid = []byte("Test-RID")
+	o.uid = []byte("alice.doe@idp.com")
+	o.ctx = []byte("Test-CTX")
+	o.sid = []byte("Test-SID")
 
-	cred := oppid.Register(isk, rid)
-	orid, crid := oppid.Init(rid)
-	auth, _ := oppid.Request(ipk, rid, cred, crid, orid, sid)
-	tk, _ := oppid.Response(isk, auth, crid, uid, ctx, sid)
-	ftk, ppid, _ := oppid.Finalize(ipk, rid, ctx, sid, crid, orid, tk)
+	o.cred, _ = o.pp.Register(o.isk, o.rid)
+	o.orid, o.crid, _ = o.pp.Init(o.rid)
+	o.auth, _ = o.pp.Request(o.ipk, o.rid, o.cred, o.crid, o.orid, o.sid)
+	o.tk, _ = o.pp.Response(o.isk, o.auth, o.crid, o.uid, o.ctx, o.sid)
+	o.ftk, o.ppid, _ = o.pp.Finalize(o.ipk, o.rid, o.ctx, o.sid, o.crid, o.orid, o.tk)
 
-	return oppid, rid, uid, ctx, sid, isk, ipk, cred, orid, crid, auth, tk, ftk, ppid
+	return o
 }
 
 func BenchmarkOPPIDRegister(b *testing.B) {
-	oppid, rid, _, _, _, isk, _, _, _, _, _, _, _, _ := setupOPPIDBenchmark()
+	o := setupOPPIDBenchmark()
 	b.ResetTimer()
 	start := time.Now()
 	for i := 0; i < b.N; i++ {
-		oppid.Register(isk, rid)
+		o.pp.Register(o.isk, o.rid)
 	}
 	elapsed := time.Since(start)
 	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
 }
 
-func BenchmarkOPPIDInit(b *testing.B) {
-	oppid, rid, _, _, _, _, _, _, _, _, _, _, _, _ := setupOPPIDBenchmark()
+func BenchmarkOPPIDIssueCredential(b *testing.B) {
+	o := setupOPPIDBenchmark()
+	_, req, _ := o.pp.RequestCredential(o.ipk, o.rid)
 	b.ResetTimer()
 	start := time.Now()
 	for i := 0; i < b.N; i++ {
-		oppid.Init(rid)
+		_, err := o.pp.IssueCredential(o.isk, req)
+		if err != nil {
+			b.Fatal(err)
+		}
 	}
 	elapsed := time.Since(start)
 	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
 }
 
-func BenchmarkOPPIDRequest(b *testing.B) {
-	oppid, rid, _, _, sid, _, ipk, cred, orid, crid, _, _, _, _ := setupOPPIDBenchmark()
+func BenchmarkOPPIDInit(b *testing.B) {
+	o := setupOPPIDBenchmark()
 	b.ResetTimer()
 	start := time.Now()
 	for i := 0; i < b.N; i++ {
-		_, err := oppid.Request(ipk, rid, cred, crid, orid, sid)
-		if err != nil {
-			b.Fatal(err)
-		}
+		o.pp.Init(o.rid)
 	}
 	elapsed := time.Since(start)
 	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
 }
 
-func BenchmarkOPPIDResponse(b *testing.B) {
-	oppid, _, uid, ctx, sid, isk, _, _, _, crid, auth, _, _, _ := setupOPPIDBenchmark()
+func BenchmarkOPPIDRequest(b *testing.B) {
+	o := setupOPPIDBenchmark()
 	b.ResetTimer()
 	start := time.Now()
 	for i := 0; i < b.N; i++ {
-		_, err := oppid.Response(isk, auth, crid, uid, ctx, sid)
+		_, err := o.pp.Request(o.ipk, o.rid, o.cred, o.crid, o.orid, o.sid)
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -74,30 +93,185 @@ func BenchmarkOPPIDResponse(b *testing.B) {
 	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
 }
 
+func BenchmarkOPPIDResponse(b *testing.B) {
+	for _, s := range schemes.All() {
+		b.Run(s.Name(), func(b *testing.B) {
+			o := setupOPPIDBenchmark(OPPID.WithScheme(s))
+			b.ResetTimer()
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				_, err := o.pp.Response(o.isk, o.auth, o.crid, o.uid, o.ctx, o.sid)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+			elapsed := time.Since(start)
+			b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
+		})
+	}
+}
+
+// BenchmarkOPPIDResponseBatch answers batches of logins with ResponseBatch; ms/login is to be compared with
+// BenchmarkOPPIDResponse.
+func BenchmarkOPPIDResponseBatch(b *testing.B) {
+	for _, n := range []int{1, 8, 32, 128} {
+		b.Run(fmt.Sprintf("batch=%d", n), func(b *testing.B) {
+			o := setupOPPIDBenchmark()
+			in := make([]OPPID.ResponseInput, n)
+			for i := range in {
+				sid := []byte(fmt.Sprintf("Test-SID-%d", i))
+				orid, crid, _ := o.pp.Init(o.rid)
+				auth, _ := o.pp.Request(o.ipk, o.rid, o.cred, crid, orid, sid)
+				in[i] = OPPID.ResponseInput{Auth: auth, Crid: crid, Uid: o.uid, Ctx: o.ctx, Sid: sid}
+			}
+			b.ResetTimer()
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				_, errs := o.pp.ResponseBatch(o.isk, in)
+				for _, err := range errs {
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+			elapsed := time.Since(start)
+			b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
+			b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N*n), "ms/login")
+		})
+	}
+}
+
 func BenchmarkOPPIDFinalize(b *testing.B) {
-	oppid, rid, _, ctx, sid, _, ipk, _, orid, crid, _, tk, _, _ := setupOPPIDBenchmark()
-	b.ResetTimer()
-	start := time.Now()
-	for i := 0; i < b.N; i++ {
-		_, _, err := oppid.Finalize(ipk, rid, ctx, sid, crid, orid, tk)
-		if err != nil {
-			b.Fatal(err)
-		}
+	for _, s := range schemes.All() {
+		b.Run(s.Name(), func(b *testing.B) {
+			o := setupOPPIDBenchmark(OPPID.WithScheme(s))
+			b.ResetTimer()
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				_, _, err := o.pp.Finalize(o.ipk, o.rid, o.ctx, o.sid, o.crid, o.orid, o.tk)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+			elapsed := time.Since(start)
+			b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
+		})
 	}
-	elapsed := time.Since(start)
-	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
 }
 
 func BenchmarkOPPIDVerify(b *testing.B) {
-	oppid, rid, _, ctx, sid, _, ipk, _, _, _, _, _, ftk, ppid := setupOPPIDBenchmark()
-	b.ResetTimer()
-	start := time.Now()
-	for i := 0; i < b.N; i++ {
-		isValid := oppid.Verify(ipk, rid, ppid, ctx, sid, ftk)
-		if !isValid {
-			b.Fatalf("oppid verify failed")
+	for _, s := range schemes.All() {
+		b.Run(s.Name(), func(b *testing.B) {
+			o := setupOPPIDBenchmark(OPPID.WithScheme(s))
+			b.ResetTimer()
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				isValid := o.pp.Verify(o.ipk, o.rid, o.ppid, o.ctx, o.sid, o.ftk)
+				if !isValid {
+					b.Fatalf("oppid verify failed")
+				}
+			}
+			elapsed := time.Since(start)
+			b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
+		})
+	}
+}
+
+// BenchmarkOPPIDVerifyBatch verifies a batch of tokens that one IdP issued for one RP; compare with
+// BenchmarkOPPIDVerify times the batch size.
+func BenchmarkOPPIDVerifyBatch(b *testing.B) {
+	const batchSize = 16
+	for _, s := range schemes.All() {
+		b.Run(s.Name(), func(b *testing.B) {
+			oppid := OPPID.Setup(OPPID.WithScheme(s))
+			isk, ipk, _ := oppid.KeyGen()
+			rid := []byte("Test-RID")
+			ctx := []byte("Test-CTX")
+			cred, _ := oppid.Register(isk, rid)
+
+			batch := make([]OPPID.VerifyInput, batchSize)
+			for i := range batch {
+				uid := []byte(fmt.Sprintf("user-%d@idp.com", i))
+				sid := []byte(fmt.Sprintf("Test-SID-%d", i))
+				orid, crid, _ := oppid.Init(rid)
+				auth, _ := oppid.Request(ipk, rid, cred, crid, orid, sid)
+				tk, _ := oppid.Response(isk, auth, crid, uid, ctx, sid)
+				ftk, ppid, _ := oppid.Finalize(ipk, rid, ctx, sid, crid, orid, tk)
+				batch[i] = OPPID.VerifyInput{PPID: ppid, Ctx: ctx, Sid: sid, Token: ftk}
+			}
+
+			b.ResetTimer()
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				if !oppid.VerifyBatch(ipk, rid, batch) {
+					b.Fatalf("oppid batch verify failed")
+				}
+			}
+			elapsed := time.Since(start)
+			b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
+		})
+	}
+}
+
+// BenchmarkOPPIDResponseRevocation measures ResponseWithRevocation against revocation lists of growing size, each
+// revoked rid adding one non-revocation proof.
+func BenchmarkOPPIDResponseRevocation(b *testing.B) {
+	for _, revoked := range []int{0, 16, 64} {
+		b.Run(fmt.Sprintf("revoked=%d", revoked), func(b *testing.B) {
+			o := setupOPPIDBenchmark()
+			rl := OPPID.RevocationList{Epoch: 1}
+			for i := 0; i < revoked; i++ {
+				rl.Revoked = append(rl.Revoked, []byte(fmt.Sprintf("Revoked-RID-%d", i)))
+			}
+			cred, _ := o.pp.RegisterWithAttributes(o.isk, o.rid, OPPID.Attributes{Expiry: rl.Epoch})
+			auth, err := o.pp.RequestWithRevocation(o.ipk, o.rid, cred, o.crid, o.orid, o.sid, rl)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				if _, err := o.pp.ResponseWithRevocation(o.isk, auth, o.crid, o.uid, o.ctx, o.sid, rl); err != nil {
+					b.Fatal(err)
+				}
+			}
+			elapsed := time.Since(start)
+			b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
+		})
+	}
+}
+
+// BenchmarkOPPIDFixedBaseTables runs the user's steps with the fixed-base tables of the commitment generators, the PS
+// key, the generators and the hashed rid turned on and off, to compare both.
+func BenchmarkOPPIDFixedBaseTables(b *testing.B) {
+	o := setupOPPIDBenchmark()
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{"Init", func() error { _, _, err := o.pp.Init(o.rid); return err }},
+		{"Request", func() error { _, err := o.pp.Request(o.ipk, o.rid, o.cred, o.crid, o.orid, o.sid); return err }},
+		{"Finalize", func() error { _, _, err := o.pp.Finalize(o.ipk, o.rid, o.ctx, o.sid, o.crid, o.orid, o.tk); return err }},
+	}
+	for _, step := range steps {
+		for _, tables := range []bool{false, true} {
+			b.Run(fmt.Sprintf("%s/tables=%t", step.name, tables), func(b *testing.B) {
+				defer utils.UseFixedBaseTables(utils.UseFixedBaseTables(tables))
+				for i := 0; i < 4; i++ { // builds the tables on first uses
+					if err := step.run(); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ResetTimer()
+				start := time.Now()
+				for i := 0; i < b.N; i++ {
+					if err := step.run(); err != nil {
+						b.Fatal(err)
+					}
+				}
+				elapsed := time.Since(start)
+				b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
+			})
 		}
 	}
-	elapsed := time.Since(start)
-	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
 }
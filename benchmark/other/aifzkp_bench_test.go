@@ -1,85 +1,101 @@
 package other
 
 import (
+	"OPPID-artifacts/pkg/oppid/sign/schemes"
 	"OPPID-artifacts/protocol/other/aifzkp"
 	"testing"
 	"time"
 )
 
-func setupAIFZkPBenchmark() (*aifzkp.PublicParams, []byte, []byte, []byte, []byte, *aifzkp.PrivateKey, *aifzkp.PublicKey, aifzkp.Credential, aifzkp.UsrOpening, aifzkp.UsrCommitment) {
-	aifZkp := aifzkp.Setup()
-	isk, ipk := aifZkp.KeyGen()
+// aifZkpBench holds an AIF-ZKP instance with the messages of one login up to Init as inputs to the benchmarked steps.
+type aifZkpBench struct {
+	pp                 *aifzkp.PublicParams
+	rid, uid, ctx, sid []byte
+	isk                *aifzkp.PrivateKey
+	ipk                *aifzkp.PublicKey
+	cred               aifzkp.Credential
+	orid               aifzkp.UsrOpening
+	crid               aifzkp.UsrCommitment
+}
+
+func setupAIFZkPBenchmark(opts ...aifzkp.Option) *aifZkpBench {
+	a := &aifZkpBench{pp: aifzkp.Setup(opts...)}
+	a.isk, a.ipk, _ = a.pp.KeyGen()
 
-	rid := []byte("Test-RID")
-	uid := []byte("alice.doe@idp.com")
-	ctx := []byte("Test-CTX")
-	sid := []byte("Test-SID")
+	a.rid = []byte("Test-RID")
+	a.uid = []byte("alice.doe@idp.com")
+	a.ctx = []byte("Test-CTX")
+	a.sid = []byte("Test-SID")
 
-	cred := aifZkp.Register(isk, rid)
+	a.cred, _ = a.pp.Register(a.isk, a.rid)
 
-	orid, crid := aifZkp.Init(rid)
+	a.orid, a.crid, _ = a.pp.Init(a.rid)
 
-	return aifZkp, rid, uid, ctx, sid, isk, ipk, cred, orid, crid
+	return a
 }
 
 func BenchmarkAIFZKPRegister(b *testing.B) {
-	aifZkp, rid, _, _, _, isk, _, _, _, _ := setupAIFZkPBenchmark()
+	a := setupAIFZkPBenchmark()
 	b.ResetTimer()
 	start := time.Now()
 	for i := 0; i < b.N; i++ {
-		aifZkp.Register(isk, rid)
+		a.pp.Register(a.isk, a.rid)
 	}
 	elapsed := time.Since(start)
 	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
 }
 
 func BenchmarkAIFZKPInit(b *testing.B) {
-	aifZkp, rid, _, _, _, _, _, _, _, _ := setupAIFZkPBenchmark()
+	a := setupAIFZkPBenchmark()
 	b.ResetTimer()
 	start := time.Now()
 	for i := 0; i < b.N; i++ {
-		aifZkp.Init(rid)
+		a.pp.Init(a.rid)
 	}
 	elapsed := time.Since(start)
 	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
 }
 
 func BenchmarkAIFZKPRequest(b *testing.B) {
-	aifZkp, rid, _, _, sid, _, ipk, cred, orid, crid := setupAIFZkPBenchmark()
+	a := setupAIFZkPBenchmark()
 	b.ResetTimer()
 	start := time.Now()
 	for i := 0; i < b.N; i++ {
-		aifZkp.Request(ipk, rid, cred, crid, orid, sid)
+		a.pp.Request(a.ipk, a.rid, a.cred, a.crid, a.orid, a.sid)
 	}
 	elapsed := time.Since(start)
 	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
 }
 
 func BenchmarkAIFZKPResponse(b *testing.B) {
-	aifZkp, rid, uid, ctx, sid, isk, ipk, cred, orid, crid := setupAIFZkPBenchmark()
-	auth := aifZkp.Request(ipk, rid, cred, crid, orid, sid)
-	b.ResetTimer()
-	start := time.Now()
-	for i := 0; i < b.N; i++ {
-		_, err := aifZkp.Response(isk, auth, crid, uid, ctx, sid)
-		if err != nil {
-			b.Fatalf("Error at response: %v", err)
-		}
+	for _, s := range schemes.All() {
+		b.Run(s.Name(), func(b *testing.B) {
+			a := setupAIFZkPBenchmark(aifzkp.WithScheme(s))
+			auth, _ := a.pp.Request(a.ipk, a.rid, a.cred, a.crid, a.orid, a.sid)
+			b.ResetTimer()
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				_, err := a.pp.Response(a.isk, auth, a.crid, a.uid, a.ctx, a.sid)
+				if err != nil {
+					b.Fatalf("Error at response: %v", err)
+				}
+			}
+			elapsed := time.Since(start)
+			b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
+		})
 	}
-	elapsed := time.Since(start)
-	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
 }
 
 func BenchmarkAIFZKPFinalize(b *testing.B) {
-	aifZkp, rid, uid, ctx, sid, isk, ipk, cred, orid, crid := setupAIFZkPBenchmark()
+	a := setupAIFZkPBenchmark()
 
-	auth := aifZkp.Request(ipk, rid, cred, crid, orid, sid)
-	tk, _ := aifZkp.Response(isk, auth, crid, uid, ctx, sid)
+	auth, _ := a.pp.Request(a.ipk, a.rid, a.cred, a.crid, a.orid, a.sid)
+	tk, _ := a.pp.Response(a.isk, auth, a.crid, a.uid, a.ctx, a.sid)
 
 	b.ResetTimer()
 	start := time.Now()
 	for i := 0; i < b.N; i++ {
-		_, err := aifZkp.Finalize(ipk, rid, uid, ctx, sid, crid, orid, tk)
+		_, err := a.pp.Finalize(a.ipk, a.rid, a.uid, a.ctx, a.sid, a.crid, a.orid, tk)
 		if err != nil {
 			b.Fatalf("Error at finalization: %v", err)
 		}
@@ -89,20 +105,24 @@ func BenchmarkAIFZKPFinalize(b *testing.B) {
 }
 
 func BenchmarkAIFZKPVerify(b *testing.B) {
-	aifZkp, rid, uid, ctx, sid, isk, ipk, cred, orid, crid := setupAIFZkPBenchmark()
-
-	auth := aifZkp.Request(ipk, rid, cred, crid, orid, sid)
-	tk, _ := aifZkp.Response(isk, auth, crid, uid, ctx, sid)
-	ftk, _ := aifZkp.Finalize(ipk, rid, uid, ctx, sid, crid, orid, tk)
-
-	b.ResetTimer()
-	start := time.Now()
-	for i := 0; i < b.N; i++ {
-		isValid := aifZkp.Verify(ipk, rid, uid, ctx, sid, ftk)
-		if !isValid {
-			b.Fatalf("verification did not succeed")
-		}
+	for _, s := range schemes.All() {
+		b.Run(s.Name(), func(b *testing.B) {
+			a := setupAIFZkPBenchmark(aifzkp.WithScheme(s))
+
+			auth, _ := a.pp.Request(a.ipk, a.rid, a.cred, a.crid, a.orid, a.sid)
+			tk, _ := a.pp.Response(a.isk, auth, a.crid, a.uid, a.ctx, a.sid)
+			ftk, _ := a.pp.Finalize(a.ipk, a.rid, a.uid, a.ctx, a.sid, a.crid, a.orid, tk)
+
+			b.ResetTimer()
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				isValid := a.pp.Verify(a.ipk, a.rid, a.uid, a.ctx, a.sid, ftk)
+				if !isValid {
+					b.Fatalf("verification did not succeed")
+				}
+			}
+			elapsed := time.Since(start)
+			b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
+		})
 	}
-	elapsed := time.Since(start)
-	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
 }
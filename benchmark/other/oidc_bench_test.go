@@ -1,14 +1,15 @@
 package other
 
 import (
+	"OPPID-artifacts/pkg/oppid/sign/schemes"
 	OIDC "OPPID-artifacts/protocol/other/oidc"
 	"testing"
 	"time"
 )
 
-func setupOIDCBenchmark() (*OIDC.PublicParams, []byte, []byte, []byte, []byte, *OIDC.PrivateKey, *OIDC.PublicKey) {
-	oidc := OIDC.Setup()
-	isk, ipk := oidc.KeyGen()
+func setupOIDCBenchmark(opts ...OIDC.Option) (*OIDC.PublicParams, []byte, []byte, []byte, []byte, *OIDC.PrivateKey, *OIDC.PublicKey) {
+	oidc := OIDC.Setup(opts...)
+	isk, ipk, _ := oidc.KeyGen()
 
 	rid := []byte("Test-RID")
 	uid := []byte("alice.doe@idp.com")
@@ -19,29 +20,37 @@ func setupOIDCBenchmark() (*OIDC.PublicParams, []byte, []byte, []byte, []byte, *
 }
 
 func BenchmarkOIDCResponse(b *testing.B) {
-	oidc, rid, uid, ctx, sid, isk, _ := setupOIDCBenchmark()
-	b.ResetTimer()
-	start := time.Now()
-	for i := 0; i < b.N; i++ {
-		oidc.Response(isk, rid, uid, ctx, sid)
+	for _, s := range schemes.All() {
+		b.Run(s.Name(), func(b *testing.B) {
+			oidc, rid, uid, ctx, sid, isk, _ := setupOIDCBenchmark(OIDC.WithScheme(s))
+			b.ResetTimer()
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				oidc.Response(isk, rid, uid, ctx, sid)
+			}
+			elapsed := time.Since(start)
+			b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
+		})
 	}
-	elapsed := time.Since(start)
-	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
 }
 
 func BenchmarkOIDCVerify(b *testing.B) {
-	oidc, rid, uid, ctx, sid, isk, ipk := setupOIDCBenchmark()
-
-	tk := oidc.Response(isk, rid, uid, ctx, sid)
-
-	b.ResetTimer()
-	start := time.Now()
-	for i := 0; i < b.N; i++ {
-		isValid := oidc.Verify(ipk, rid, ctx, sid, tk)
-		if !isValid {
-			b.Fatalf("failed to verify response")
-		}
+	for _, s := range schemes.All() {
+		b.Run(s.Name(), func(b *testing.B) {
+			oidc, rid, uid, ctx, sid, isk, ipk := setupOIDCBenchmark(OIDC.WithScheme(s))
+
+			tk, _ := oidc.Response(isk, rid, uid, ctx, sid)
+
+			b.ResetTimer()
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				isValid := oidc.Verify(ipk, rid, ctx, sid, tk)
+				if !isValid {
+					b.Fatalf("failed to verify response")
+				}
+			}
+			elapsed := time.Since(start)
+			b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
+		})
 	}
-	elapsed := time.Since(start)
-	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
 }
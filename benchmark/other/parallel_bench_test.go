@@ -0,0 +1,88 @@
+package other
+
+import (
+	"OPPID-artifacts/pkg/oppid/sign/schemes"
+	"OPPID-artifacts/protocol/other/aifzkp"
+	OIDC "OPPID-artifacts/protocol/other/oidc"
+	UPPRESSO "OPPID-artifacts/protocol/other/uppresso"
+	"testing"
+	"time"
+)
+
+// benchmarkParallel runs op with b.RunParallel from GOMAXPROCS goroutines that share one IdP key. ms/op is the
+// wall-clock time per op, to be compared with the sequential benchmark, and ops/s the throughput of the IdP.
+func benchmarkParallel(b *testing.B, op func() error) {
+	b.ResetTimer()
+	start := time.Now()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := op(); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+	elapsed := time.Since(start)
+	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
+	b.ReportMetric(float64(b.N)/elapsed.Seconds(), "ops/s")
+}
+
+func BenchmarkAIFZKPRegisterParallel(b *testing.B) {
+	a := setupAIFZkPBenchmark()
+	benchmarkParallel(b, func() error {
+		_, err := a.pp.Register(a.isk, a.rid)
+		return err
+	})
+}
+
+func BenchmarkAIFZKPResponseParallel(b *testing.B) {
+	for _, s := range schemes.All() {
+		b.Run(s.Name(), func(b *testing.B) {
+			a := setupAIFZkPBenchmark(aifzkp.WithScheme(s))
+			auth, _ := a.pp.Request(a.ipk, a.rid, a.cred, a.crid, a.orid, a.sid)
+			benchmarkParallel(b, func() error {
+				_, err := a.pp.Response(a.isk, auth, a.crid, a.uid, a.ctx, a.sid)
+				return err
+			})
+		})
+	}
+}
+
+func BenchmarkOIDCResponseParallel(b *testing.B) {
+	for _, s := range schemes.All() {
+		b.Run(s.Name(), func(b *testing.B) {
+			oidc, rid, uid, ctx, sid, isk, _ := setupOIDCBenchmark(OIDC.WithScheme(s))
+			benchmarkParallel(b, func() error {
+				_, err := oidc.Response(isk, rid, uid, ctx, sid)
+				return err
+			})
+		})
+	}
+}
+
+func BenchmarkPPOIDCResponseParallel(b *testing.B) {
+	ppoidc, isk, ipk, uid, cert, nonceRP := setupPPOIDCBenchmark(b)
+	req, _, err := ppoidc.Init(ipk, uid, cert, nonceRP)
+	if err != nil {
+		b.Fatal(err)
+	}
+	ctx := []byte("context")
+	sid := []byte("sessionID")
+	benchmarkParallel(b, func() error {
+		_, err := ppoidc.Response(isk, uid, req, ctx, sid)
+		return err
+	})
+}
+
+func BenchmarkUPPRESSOResponseParallel(b *testing.B) {
+	for _, s := range schemes.All() {
+		b.Run(s.Name(), func(b *testing.B) {
+			uppresso, idU, ctx, sid, isk, ipk, cert := setupUPPRESSOBenchmark(UPPRESSO.WithScheme(s))
+			uPidRP, _, _ := uppresso.Init(ipk, &cert)
+			benchmarkParallel(b, func() error {
+				_, err := uppresso.Response(isk, uPidRP, idU, ctx, sid)
+				return err
+			})
+		})
+	}
+}
@@ -12,13 +12,19 @@ func setupPPOIDCBenchmark(b *testing.B) (*PPOIDC.PublicParams, *PPOIDC.PrivateKe
 	if err != nil {
 		b.Fatal(err)
 	}
-	isk, ipk := ppoidc.KeyGen()
+	isk, ipk, err := ppoidc.KeyGen()
+	if err != nil {
+		b.Fatal(err)
+	}
 
 	uid := PPOIDC.UserId("Test ID")
 	name := PPOIDC.ClientName("Test ID")
 	ruri := PPOIDC.RedirectUri("Test redirect URI")
 
-	cert := ppoidc.Register(isk, name, ruri)
+	cert, err := ppoidc.Register(isk, name, ruri)
+	if err != nil {
+		b.Fatal(err)
+	}
 
 	var nonceRP PPOIDC.Nonce
 	_, _ = rand.Read(nonceRP[:])
@@ -1,6 +1,7 @@
 package other
 
 import (
+	"OPPID-artifacts/pkg/oppid/sign/schemes"
 	"OPPID-artifacts/pkg/oppid/utils"
 	UPPRESSO "OPPID-artifacts/protocol/other/uppresso"
 	"testing"
@@ -9,18 +10,18 @@ import (
 	GG "github.com/cloudflare/circl/ecc/bls12381"
 )
 
-func setupUPPRESSOBenchmark() (*UPPRESSO.PublicParams, *GG.Scalar, []byte, []byte, *UPPRESSO.PrivateKey, *UPPRESSO.PublicKey, UPPRESSO.CertRP) {
-	uppresso := UPPRESSO.Setup()
-	isk, ipk := uppresso.KeyGen()
+func setupUPPRESSOBenchmark(opts ...UPPRESSO.Option) (*UPPRESSO.PublicParams, *GG.Scalar, []byte, []byte, *UPPRESSO.PrivateKey, *UPPRESSO.PublicKey, UPPRESSO.CertRP) {
+	uppresso := UPPRESSO.Setup(opts...)
+	isk, ipk, _ := uppresso.KeyGen()
 
 	id := []byte("test-RP")
-	idU := utils.GenerateRandomScalar()
+	idU, _ := utils.GenerateRandomScalar()
 
 	enPt := []byte("endpoint")
 	ctx := []byte("context")
 	sid := []byte("session-id")
 
-	cert := uppresso.Register(isk, id, enPt)
+	cert, _ := uppresso.Register(isk, id, enPt)
 
 	return uppresso, idU, ctx, sid, isk, ipk, cert
 }
@@ -51,28 +52,36 @@ func BenchmarkUPPRESSORequest(b *testing.B) {
 }
 
 func BenchmarkUPPRESSOResponse(b *testing.B) {
-	uppresso, idU, ctx, sid, isk, ipk, cert := setupUPPRESSOBenchmark()
-	uPidRP, _, _ := uppresso.Init(ipk, &cert)
+	for _, s := range schemes.All() {
+		b.Run(s.Name(), func(b *testing.B) {
+			uppresso, idU, ctx, sid, isk, ipk, cert := setupUPPRESSOBenchmark(UPPRESSO.WithScheme(s))
+			uPidRP, _, _ := uppresso.Init(ipk, &cert)
 
-	start := time.Now()
-	for i := 0; i < b.N; i++ {
-		uppresso.Response(isk, uPidRP, idU, ctx, sid)
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				uppresso.Response(isk, uPidRP, idU, ctx, sid)
+			}
+			elapsed := time.Since(start)
+			b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
+		})
 	}
-	elapsed := time.Since(start)
-	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
 }
 
 func BenchmarkUPPRESSOVerify(b *testing.B) {
-	uppresso, idU, ctx, sid, isk, ipk, cert := setupUPPRESSOBenchmark()
+	for _, s := range schemes.All() {
+		b.Run(s.Name(), func(b *testing.B) {
+			uppresso, idU, ctx, sid, isk, ipk, cert := setupUPPRESSOBenchmark(UPPRESSO.WithScheme(s))
 
-	uPidRP, t, _ := uppresso.Init(ipk, &cert)
-	rpPidRP := uppresso.Request(cert.Id, t)
-	token := uppresso.Response(isk, uPidRP, idU, ctx, sid)
+			uPidRP, t, _ := uppresso.Init(ipk, &cert)
+			rpPidRP := uppresso.Request(cert.Id, t)
+			token, _ := uppresso.Response(isk, uPidRP, idU, ctx, sid)
 
-	start := time.Now()
-	for i := 0; i < b.N; i++ {
-		uppresso.Verify(ipk, rpPidRP, t, ctx, sid, token)
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				uppresso.Verify(ipk, rpPidRP, t, ctx, sid, token)
+			}
+			elapsed := time.Since(start)
+			b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
+		})
 	}
-	elapsed := time.Since(start)
-	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
 }
@@ -0,0 +1,101 @@
+package benchmark
+
+import (
+	"OPPID-artifacts/pkg/oppid/sign/schemes"
+	OPPID "OPPID-artifacts/protocol/oppid"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// benchmarkParallel runs op with b.RunParallel from GOMAXPROCS goroutines that share one IdP key. ms/op is the
+// wall-clock time per op, to be compared with the sequential benchmark, and ops/s the throughput of the IdP.
+func benchmarkParallel(b *testing.B, op func() error) {
+	b.ResetTimer()
+	start := time.Now()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := op(); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+	elapsed := time.Since(start)
+	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
+	b.ReportMetric(float64(b.N)/elapsed.Seconds(), "ops/s")
+}
+
+func BenchmarkOPPIDRegisterParallel(b *testing.B) {
+	o := setupOPPIDBenchmark()
+	benchmarkParallel(b, func() error {
+		_, err := o.pp.Register(o.isk, o.rid)
+		return err
+	})
+}
+
+func BenchmarkOPPIDIssueCredentialParallel(b *testing.B) {
+	o := setupOPPIDBenchmark()
+	_, req, _ := o.pp.RequestCredential(o.ipk, o.rid)
+	benchmarkParallel(b, func() error {
+		_, err := o.pp.IssueCredential(o.isk, req)
+		return err
+	})
+}
+
+func BenchmarkOPPIDResponseParallel(b *testing.B) {
+	for _, s := range schemes.All() {
+		b.Run(s.Name(), func(b *testing.B) {
+			o := setupOPPIDBenchmark(OPPID.WithScheme(s))
+			benchmarkParallel(b, func() error {
+				_, err := o.pp.Response(o.isk, o.auth, o.crid, o.uid, o.ctx, o.sid)
+				return err
+			})
+		})
+	}
+}
+
+func BenchmarkOPPIDResponseBatchParallel(b *testing.B) {
+	for _, n := range []int{8, 32} {
+		b.Run(fmt.Sprintf("batch=%d", n), func(b *testing.B) {
+			o := setupOPPIDBenchmark()
+			in := make([]OPPID.ResponseInput, n)
+			for i := range in {
+				sid := []byte(fmt.Sprintf("Test-SID-%d", i))
+				orid, crid, _ := o.pp.Init(o.rid)
+				auth, _ := o.pp.Request(o.ipk, o.rid, o.cred, crid, orid, sid)
+				in[i] = OPPID.ResponseInput{Auth: auth, Crid: crid, Uid: o.uid, Ctx: o.ctx, Sid: sid}
+			}
+			benchmarkParallel(b, func() error {
+				_, errs := o.pp.ResponseBatch(o.isk, in)
+				for _, err := range errs {
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		})
+	}
+}
+
+func BenchmarkOPPIDResponseRevocationParallel(b *testing.B) {
+	for _, revoked := range []int{0, 16, 64} {
+		b.Run(fmt.Sprintf("revoked=%d", revoked), func(b *testing.B) {
+			o := setupOPPIDBenchmark()
+			rl := OPPID.RevocationList{Epoch: 1}
+			for i := 0; i < revoked; i++ {
+				rl.Revoked = append(rl.Revoked, []byte(fmt.Sprintf("Revoked-RID-%d", i)))
+			}
+			cred, _ := o.pp.RegisterWithAttributes(o.isk, o.rid, OPPID.Attributes{Expiry: rl.Epoch})
+			auth, err := o.pp.RequestWithRevocation(o.ipk, o.rid, cred, o.crid, o.orid, o.sid, rl)
+			if err != nil {
+				b.Fatal(err)
+			}
+			benchmarkParallel(b, func() error {
+				_, err := o.pp.ResponseWithRevocation(o.isk, auth, o.crid, o.uid, o.ctx, o.sid, rl)
+				return err
+			})
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package results
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Delta compares one metric of a benchmark in two result sets.
+type Delta struct {
+	Name        string  `json:"name"`
+	Old         Stat    `json:"old"`
+	New         Stat    `json:"new"`
+	Change      float64 `json:"change"`      // relative change of the mean, e.g., -0.1 for 10% less
+	Significant bool    `json:"significant"` // whether Welch's t-test rejects equal means at the 5% level
+}
+
+// Diff compares the metric of the benchmarks in both old and new, in the order of new.
+func Diff(old, new []Result, metric string) []Delta {
+	before := make(map[string]Stat)
+	for _, r := range old {
+		if s, ok := r.Metrics[metric]; ok {
+			before[r.Name] = s
+		}
+	}
+	var deltas []Delta
+	for _, r := range new {
+		a, ok1 := before[r.Name]
+		b, ok2 := r.Metrics[metric]
+		if !ok1 || !ok2 {
+			continue
+		}
+		d := Delta{Name: r.Name, Old: a, New: b, Significant: significant(a, b)}
+		if a.Mean != 0 {
+			d.Change = (b.Mean - a.Mean) / a.Mean
+		}
+		deltas = append(deltas, d)
+	}
+	return deltas
+}
+
+// significant runs Welch's t-test, which needs at least two runs on both sides.
+func significant(a, b Stat) bool {
+	if a.N < 2 || b.N < 2 {
+		return false
+	}
+	va, vb := a.Stddev*a.Stddev/float64(a.N), b.Stddev*b.Stddev/float64(b.N)
+	if va+vb == 0 {
+		return a.Mean != b.Mean
+	}
+	t := math.Abs(a.Mean-b.Mean) / math.Sqrt(va+vb)
+	df := (va + vb) * (va + vb) / (va*va/float64(a.N-1) + vb*vb/float64(b.N-1))
+	return t > tCritical(df)
+}
+
+// formatChange shows the change in percent, or "~" if it is not significant.
+func formatChange(d Delta) string {
+	if !d.Significant {
+		return "~"
+	}
+	return fmt.Sprintf("%+.2f%%", 100*d.Change)
+}
+
+// WriteDiff writes the deltas of the metric in the given format.
+func WriteDiff(w io.Writer, deltas []Delta, metric, format string) error {
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"name", "unit", "old_n", "old_mean", "old_ci95", "new_n", "new_mean", "new_ci95", "change",
+			"significant"})
+		for _, d := range deltas {
+			cw.Write([]string{d.Name, metric, strconv.Itoa(d.Old.N), formatFloat(d.Old.Mean), formatFloat(d.Old.CI),
+				strconv.Itoa(d.New.N), formatFloat(d.New.Mean), formatFloat(d.New.CI), formatFloat(d.Change),
+				strconv.FormatBool(d.Significant)})
+		}
+		cw.Flush()
+		return cw.Error()
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(deltas)
+	case "markdown", "latex":
+		header := []string{"Benchmark", "old " + metric, "new " + metric, "Change"}
+		var sb strings.Builder
+		if format == "latex" {
+			sb.WriteString("\\begin{tabular}{lrrr}\n\\toprule\n")
+			sb.WriteString(latexRow(header))
+			sb.WriteString("\\midrule\n")
+		} else {
+			sb.WriteString(markdownRow(header))
+			sb.WriteString("|---|---:|---:|---:|\n")
+		}
+		for _, d := range deltas {
+			row := []string{d.Name, withCI(d.Old), withCI(d.New), formatChange(d)}
+			if format == "latex" {
+				sb.WriteString(latexRow(row))
+			} else {
+				sb.WriteString(markdownRow(row))
+			}
+		}
+		if format == "latex" {
+			sb.WriteString("\\bottomrule\n\\end{tabular}\n")
+		}
+		_, err := io.WriteString(w, sb.String())
+		return err
+	}
+	return fmt.Errorf("results: unknown format %q", format)
+}
+
+func withCI(s Stat) string {
+	if s.N < 2 {
+		return formatValue(s.Mean)
+	}
+	return formatValue(s.Mean) + " ± " + formatValue(s.CI)
+}
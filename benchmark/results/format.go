@@ -0,0 +1,180 @@
+package results
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Formats lists the output formats of Write and WriteDiff.
+var Formats = []string{"markdown", "latex", "csv", "json"}
+
+// Write writes the results in the given format. CSV and JSON hold all metrics of all benchmarks; the Markdown and
+// LaTeX tables show the given metric, grouped by protocol and role.
+func Write(w io.Writer, results []Result, metric, format string) error {
+	switch format {
+	case "csv":
+		return writeCSV(w, results)
+	case "json":
+		return writeJSON(w, results)
+	case "markdown", "latex":
+		return writeTable(w, tableRows(results, metric), metric, format)
+	}
+	return fmt.Errorf("results: unknown format %q", format)
+}
+
+func writeCSV(w io.Writer, results []Result) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"pkg", "name", "protocol", "role", "op", "variant", "unit", "n", "mean", "stddev", "ci95"})
+	for _, r := range results {
+		for _, unit := range units(r) {
+			s := r.Metrics[unit]
+			cw.Write([]string{r.Pkg, r.Name, r.Protocol, r.Role, r.Op, r.Variant, unit, strconv.Itoa(s.N),
+				formatFloat(s.Mean), formatFloat(s.Stddev), formatFloat(s.CI)})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeJSON(w io.Writer, results []Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
+
+func units(r Result) []string {
+	us := make([]string, 0, len(r.Metrics))
+	for u := range r.Metrics {
+		us = append(us, u)
+	}
+	sort.Strings(us)
+	return us
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// rank orders v by its position in order, with unknown values last.
+func rank(order []string, v string) int {
+	if i := slices.Index(order, v); i >= 0 {
+		return i
+	}
+	return len(order)
+}
+
+var protocolNames = func() []string {
+	var names []string
+	for _, p := range Protocols {
+		names = append(names, p.Name)
+	}
+	return names
+}()
+
+// tableRows returns the results with the metric, sorted by protocol and role and otherwise in their order.
+func tableRows(results []Result, metric string) []Result {
+	var rows []Result
+	for _, r := range results {
+		if _, ok := r.Metrics[metric]; ok {
+			rows = append(rows, r)
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if pi, pj := rank(protocolNames, rows[i].Protocol), rank(protocolNames, rows[j].Protocol); pi != pj {
+			return pi < pj
+		}
+		if rows[i].Protocol != rows[j].Protocol {
+			return rows[i].Protocol < rows[j].Protocol
+		}
+		return rank(Roles, rows[i].Role) < rank(Roles, rows[j].Role)
+	})
+	return rows
+}
+
+// writeTable writes one row per benchmark and names protocol and role only on the first row of their group, as in
+// the tables of the paper.
+func writeTable(w io.Writer, rows []Result, metric, format string) error {
+	header := []string{"Protocol", "Role", "Operation", metric, "± 95% CI", "n"}
+	cells := make([][]string, 0, len(rows))
+	var prev Result
+	for i, r := range rows {
+		protocol, role := r.Protocol, r.Role
+		if protocol == "" {
+			protocol = "other"
+		}
+		if role == "" {
+			role = "–"
+		}
+		if i > 0 && r.Protocol == prev.Protocol {
+			protocol = ""
+			if r.Role == prev.Role {
+				role = ""
+			}
+		}
+		op := r.Op
+		if r.Variant != "" {
+			op += " (" + r.Variant + ")"
+		}
+		s := r.Metrics[metric]
+		cells = append(cells, []string{protocol, role, op, formatValue(s.Mean), formatValue(s.CI), strconv.Itoa(s.N)})
+		prev = r
+	}
+
+	var sb strings.Builder
+	if format == "latex" {
+		sb.WriteString("\\begin{tabular}{lllrrr}\n\\toprule\n")
+		sb.WriteString(latexRow(header))
+		sb.WriteString("\\midrule\n")
+		for i, c := range cells {
+			if i > 0 && c[0] != "" {
+				sb.WriteString("\\midrule\n")
+			}
+			sb.WriteString(latexRow(c))
+		}
+		sb.WriteString("\\bottomrule\n\\end{tabular}\n")
+	} else {
+		sb.WriteString(markdownRow(header))
+		sb.WriteString("|---|---|---|---:|---:|---:|\n")
+		for _, c := range cells {
+			sb.WriteString(markdownRow(c))
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', decimals(v), 64)
+}
+
+// decimals returns the number of decimals that show v with four significant digits, but at most six.
+func decimals(v float64) int {
+	d := 3
+	for a := v; a != 0 && a < 1 && d < 6; a *= 10 {
+		d++
+	}
+	for a := v; a >= 10 && d > 0; a /= 10 {
+		d--
+	}
+	return d
+}
+
+func markdownRow(cells []string) string {
+	return "| " + strings.Join(cells, " | ") + " |\n"
+}
+
+var latexEscaper = strings.NewReplacer("&", "\\&", "%", "\\%", "_", "\\_", "#", "\\#", "±", "$\\pm$", "–", "--")
+
+func latexRow(cells []string) string {
+	escaped := make([]string, len(cells))
+	for i, c := range cells {
+		escaped[i] = latexEscaper.Replace(c)
+	}
+	return strings.Join(escaped, " & ") + " \\\\\n"
+}
@@ -0,0 +1,126 @@
+// Package results parses the output of the benchmarks, aggregates repeated runs and renders them as tables grouped by
+// protocol and role, see cmd/oppid-bench.
+
+package results
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Run is one result line of a benchmark.
+type Run struct {
+	Pkg        string
+	Name       string // without the "Benchmark" prefix and the GOMAXPROCS suffix
+	Procs      int
+	Iterations int
+	Metrics    map[string]float64 // by unit, e.g., "ns/op"
+}
+
+// event is the part of a test2json event that carries benchmark output.
+type event struct {
+	Action  string
+	Package string
+	Output  string
+}
+
+// Parse reads the output of `go test -bench`, either as text, e.g., a log of run_benchmarks.sh, or as the events of
+// `go test -json`.
+func Parse(r io.Reader) ([]Run, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		return parseJSON(data)
+	}
+	return parseText(data, "")
+}
+
+// parseJSON joins the output events per package, as test2json may split a result line across events.
+func parseJSON(data []byte) ([]Run, error) {
+	var pkgs []string
+	out := make(map[string]*strings.Builder)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var ev event
+		if err := dec.Decode(&ev); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("results: invalid test2json event: %w", err)
+		}
+		if ev.Action != "output" {
+			continue
+		}
+		sb, ok := out[ev.Package]
+		if !ok {
+			sb = new(strings.Builder)
+			out[ev.Package] = sb
+			pkgs = append(pkgs, ev.Package)
+		}
+		sb.WriteString(ev.Output)
+	}
+	var runs []Run
+	for _, pkg := range pkgs {
+		rs, err := parseText([]byte(out[pkg].String()), pkg)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, rs...)
+	}
+	return runs, nil
+}
+
+func parseText(data []byte, pkg string) ([]Run, error) {
+	var runs []Run
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		if p, ok := strings.CutPrefix(line, "pkg: "); ok {
+			pkg = strings.TrimSpace(p)
+			continue
+		}
+		if r, ok := parseLine(line); ok {
+			r.Pkg = pkg
+			runs = append(runs, r)
+		}
+	}
+	return runs, sc.Err()
+}
+
+// parseLine parses a line "BenchmarkName-8  100  123 ns/op  0.1 ms/op ..."; lines without results, e.g., the names
+// that -v prints before running a benchmark, are skipped.
+func parseLine(line string) (Run, bool) {
+	f := strings.Fields(line)
+	if len(f) < 4 || len(f)%2 != 0 || !strings.HasPrefix(f[0], "Benchmark") {
+		return Run{}, false
+	}
+	n, err := strconv.Atoi(f[1])
+	if err != nil {
+		return Run{}, false
+	}
+	r := Run{Name: strings.TrimPrefix(f[0], "Benchmark"), Procs: 1, Iterations: n, Metrics: make(map[string]float64)}
+	if i := strings.LastIndexByte(r.Name, '-'); i >= 0 {
+		if p, err := strconv.Atoi(r.Name[i+1:]); err == nil {
+			r.Name, r.Procs = r.Name[:i], p
+		}
+	}
+	for i := 2; i < len(f); i += 2 {
+		v, err := strconv.ParseFloat(f[i], 64)
+		if err != nil {
+			return Run{}, false
+		}
+		r.Metrics[f[i+1]] = v
+	}
+	// The ms/op that the benchmarks report themselves are truncated to whole milliseconds
+	if ns, ok := r.Metrics["ns/op"]; ok {
+		r.Metrics["ms/op"] = ns / 1e6
+	}
+	return r, true
+}
@@ -0,0 +1,198 @@
+package results
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+const textLog = `goos: linux
+goarch: amd64
+pkg: OPPID-artifacts/benchmark
+cpu: Test CPU
+BenchmarkOPPIDInit-8   	     100	   2000000 ns/op	         2.000 ms/op
+BenchmarkOPPIDInit-8   	     100	   4000000 ns/op	         4.000 ms/op
+BenchmarkOPPIDResponse/RS256-8         	      10	  12000000 ns/op	         12.00 ms/op
+BenchmarkSSO/OIDC/Response
+BenchmarkSSO/OIDC/Response-8              	      50	   1500000 ns/op	       512.0 msg-bytes
+PASS
+ok  	OPPID-artifacts/benchmark	3.000s
+`
+
+func TestParseText(t *testing.T) {
+	runs, err := Parse(strings.NewReader(textLog))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(runs) != 4 {
+		t.Fatalf("Parse returned %d runs, want 4", len(runs))
+	}
+	r := runs[3]
+	if r.Pkg != "OPPID-artifacts/benchmark" || r.Name != "SSO/OIDC/Response" || r.Procs != 8 || r.Iterations != 50 {
+		t.Errorf("unexpected run %+v", r)
+	}
+	if r.Metrics["msg-bytes"] != 512 || r.Metrics["ms/op"] != 1.5 {
+		t.Errorf("unexpected metrics %v", r.Metrics)
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.Encode(event{Action: "start", Package: "p"})
+	// test2json may split a result line across events
+	enc.Encode(event{Action: "output", Package: "p", Output: "BenchmarkOIDCVerify-4   \t"})
+	enc.Encode(event{Action: "output", Package: "p", Output: "100\t  50000 ns/op\n"})
+	enc.Encode(event{Action: "pass", Package: "p"})
+
+	runs, err := Parse(&buf)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(runs) != 1 || runs[0].Pkg != "p" || runs[0].Name != "OIDCVerify" || runs[0].Metrics["ms/op"] != 0.05 {
+		t.Fatalf("unexpected runs %+v", runs)
+	}
+
+	_, err = Parse(strings.NewReader("{not json"))
+	if err == nil {
+		t.Errorf("Parse accepted invalid events")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	s := Summarize([]float64{2, 4})
+	if s.N != 2 || s.Mean != 3 || math.Abs(s.Stddev-math.Sqrt2) > 1e-12 {
+		t.Fatalf("unexpected stat %+v", s)
+	}
+	if want := 12.706 * math.Sqrt2 / math.Sqrt2; math.Abs(s.CI-want) > 1e-9 {
+		t.Errorf("CI = %v, want %v", s.CI, want)
+	}
+	if s := Summarize([]float64{5}); s.Mean != 5 || s.Stddev != 0 || s.CI != 0 {
+		t.Errorf("unexpected stat of a single run %+v", s)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	runs, _ := Parse(strings.NewReader(textLog))
+	results := Aggregate(runs)
+	if len(results) != 3 {
+		t.Fatalf("Aggregate returned %d results, want 3", len(results))
+	}
+
+	tests := []struct {
+		protocol, role, op, variant string
+		n                           int
+		mean                        float64
+	}{
+		{"OPPID", RoleUser, "Init", "", 2, 3},
+		{"OPPID", RoleIdP, "Response", "RS256", 1, 12},
+		{"OIDC", RoleIdP, "Response", "", 1, 1.5},
+	}
+	for i, tt := range tests {
+		r := results[i]
+		if r.Protocol != tt.protocol || r.Role != tt.role || r.Op != tt.op || r.Variant != tt.variant {
+			t.Errorf("%s: unexpected classification %+v", r.Name, r)
+		}
+		if s := r.Metrics["ms/op"]; s.N != tt.n || s.Mean != tt.mean {
+			t.Errorf("%s: unexpected stat %+v", r.Name, s)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name, protocol, role, op, variant string
+	}{
+		{"AIFZKPVerifyBatch/64", "AIF-ZKP", RoleRP, "VerifyBatch", "64"},
+		{"SSO/UPPRESSO/Request", "UPPRESSO", RoleUser, "Request", ""},
+		{"SSOLogin/ES256/OPPID", "OPPID", "", "Login", "ES256"},
+		{"PPOIDCResponse", "PPOIDC", RoleIdP, "Response", ""},
+		{"OPPIDResponseParallel/RS256", "OPPID", RoleIdP, "Response", "parallel/RS256"},
+		{"OIDCResponseParallel", "OIDC", RoleIdP, "Response", "parallel"},
+		{"PSSign", "", "", "PSSign", ""},
+	}
+	for _, tt := range tests {
+		r := classify(tt.name)
+		if r.Protocol != tt.protocol || r.Role != tt.role || r.Op != tt.op || r.Variant != tt.variant {
+			t.Errorf("classify(%q) = %+v", tt.name, r)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	runs, _ := Parse(strings.NewReader(textLog))
+	results := Aggregate(runs)
+
+	var buf bytes.Buffer
+	if err := Write(&buf, results, "ms/op", "markdown"); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	want := "| OPPID | User | Init | 3.000 | 12.71 | 2 |\n|  | IdP | Response (RS256) | 12.00 | 0.000 | 1 |\n"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("unexpected markdown table:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := Write(&buf, results, "ms/op", "latex"); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "OIDC & IdP & Response & 1.500 & 0.000 & 1 \\\\") {
+		t.Errorf("unexpected latex table:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := Write(&buf, results, "ms/op", "csv"); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 1+7 {
+		t.Errorf("csv has %d lines, want 8:\n%s", n, buf.String())
+	}
+
+	buf.Reset()
+	if err := Write(&buf, results, "ms/op", "json"); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	var decoded []Result
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded) != len(results) {
+		t.Errorf("unexpected json output %q: %v", buf.String(), err)
+	}
+
+	if err := Write(&buf, results, "ms/op", "html"); err == nil {
+		t.Errorf("Write accepted an unknown format")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	stat := func(vs ...float64) map[string]Stat { return map[string]Stat{"ms/op": Summarize(vs)} }
+	old := []Result{
+		{Name: "A", Metrics: stat(10, 10.1, 9.9)},
+		{Name: "B", Metrics: stat(10, 11, 9)},
+		{Name: "C", Metrics: stat(1)},
+	}
+	new := []Result{
+		{Name: "B", Metrics: stat(10.5, 9.5, 10)},
+		{Name: "A", Metrics: stat(5, 5.1, 4.9)},
+		{Name: "D", Metrics: stat(1)},
+	}
+	deltas := Diff(old, new, "ms/op")
+	if len(deltas) != 2 || deltas[0].Name != "B" || deltas[1].Name != "A" {
+		t.Fatalf("unexpected deltas %+v", deltas)
+	}
+	if deltas[0].Significant {
+		t.Errorf("B changed significantly: %+v", deltas[0])
+	}
+	if !deltas[1].Significant || math.Abs(deltas[1].Change+0.5) > 1e-12 {
+		t.Errorf("unexpected delta of A: %+v", deltas[1])
+	}
+
+	var buf bytes.Buffer
+	if err := WriteDiff(&buf, deltas, "ms/op", "markdown"); err != nil {
+		t.Fatalf("WriteDiff failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "| B | 10.00 ± 2.484 | 10.00 ± 1.242 | ~ |") ||
+		!strings.Contains(buf.String(), "| -50.00% |") {
+		t.Errorf("unexpected markdown diff:\n%s", buf.String())
+	}
+}
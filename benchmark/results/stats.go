@@ -0,0 +1,157 @@
+package results
+
+import (
+	"math"
+	"strings"
+)
+
+// Stat summarizes one metric of a benchmark over repeated runs.
+type Stat struct {
+	N      int     `json:"n"`
+	Mean   float64 `json:"mean"`
+	Stddev float64 `json:"stddev"` // sample standard deviation, 0 for a single run
+	CI     float64 `json:"ci95"`   // half-width of the 95% confidence interval of the mean, 0 for a single run
+}
+
+// Summarize returns the statistics of vs.
+func Summarize(vs []float64) Stat {
+	s := Stat{N: len(vs)}
+	if s.N == 0 {
+		return s
+	}
+	for _, v := range vs {
+		s.Mean += v
+	}
+	s.Mean /= float64(s.N)
+	if s.N < 2 {
+		return s
+	}
+	var ss float64
+	for _, v := range vs {
+		ss += (v - s.Mean) * (v - s.Mean)
+	}
+	s.Stddev = math.Sqrt(ss / float64(s.N-1))
+	s.CI = tCritical(float64(s.N-1)) * s.Stddev / math.Sqrt(float64(s.N))
+	return s
+}
+
+// tTable holds the two-sided 95% critical values of Student's t-distribution for 1 to 30 degrees of freedom.
+var tTable = [...]float64{12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228, 2.201, 2.179, 2.160,
+	2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086, 2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042}
+
+// tCritical returns the two-sided 95% critical value of the t-distribution with df degrees of freedom, rounding df
+// down, i.e., conservatively.
+func tCritical(df float64) float64 {
+	switch {
+	case df < 1:
+		return math.Inf(1)
+	case df <= float64(len(tTable)):
+		return tTable[int(df)-1]
+	case df < 60:
+		return 2.021 // df = 40
+	case df < 120:
+		return 2.000 // df = 60
+	default:
+		return 1.960
+	}
+}
+
+// Result is a benchmark aggregated over its runs.
+type Result struct {
+	Pkg      string          `json:"pkg,omitempty"`
+	Name     string          `json:"name"`
+	Protocol string          `json:"protocol,omitempty"`
+	Role     string          `json:"role,omitempty"`
+	Op       string          `json:"op"`
+	Variant  string          `json:"variant,omitempty"` // e.g., the token-signature scheme
+	Metrics  map[string]Stat `json:"metrics"`
+}
+
+// Aggregate groups runs by benchmark name and summarizes every metric, in the order in which the benchmarks first
+// appear. Runs of the same benchmark from different packages, e.g., of logs from before a module rename, are merged.
+func Aggregate(runs []Run) []Result {
+	var names []string
+	byName := make(map[string][]Run)
+	for _, r := range runs {
+		if _, ok := byName[r.Name]; !ok {
+			names = append(names, r.Name)
+		}
+		byName[r.Name] = append(byName[r.Name], r)
+	}
+	results := make([]Result, 0, len(names))
+	for _, name := range names {
+		rs := byName[name]
+		res := classify(name)
+		res.Pkg = rs[0].Pkg
+		res.Metrics = make(map[string]Stat)
+		values := make(map[string][]float64)
+		for _, r := range rs {
+			for unit, v := range r.Metrics {
+				values[unit] = append(values[unit], v)
+			}
+		}
+		for unit, vs := range values {
+			res.Metrics[unit] = Summarize(vs)
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+const (
+	RoleUser = "User"
+	RoleIdP  = "IdP"
+	RoleRP   = "RP"
+)
+
+// Protocols lists the protocols in the order of the tables, with the prefixes of their benchmark names.
+var Protocols = []struct{ Prefix, Name string }{
+	{"OPPID", "OPPID"}, {"AIFZKP", "AIF-ZKP"}, {"PPOIDC", "PPOIDC"}, {"UPPRESSO", "UPPRESSO"}, {"OIDC", "OIDC"},
+}
+
+// Roles lists the roles in the order of the tables.
+var Roles = []string{RoleUser, RoleIdP, RoleRP}
+
+// opRoles maps the operations of the protocols, and the steps of BenchmarkSSO, to the role that runs them.
+var opRoles = map[string]string{
+	"Init":               RoleUser,
+	"Request":            RoleUser,
+	"Finalize":           RoleUser,
+	"Register":           RoleIdP,
+	"IssueCredential":    RoleIdP,
+	"Response":           RoleIdP,
+	"ResponseBatch":      RoleIdP,
+	"ResponseRevocation": RoleIdP,
+	"Begin":              RoleRP,
+	"Complete":           RoleRP,
+	"Verify":             RoleRP,
+	"VerifyBatch":        RoleRP,
+}
+
+// classify derives protocol, operation, variant and role from a benchmark name, e.g., "OPPIDResponse/RS256",
+// "SSO/OPPID/Request" or "SSOLogin/RS256/OIDC". Parallel variants, e.g., "OPPIDResponseParallel/RS256", get the
+// variant "parallel/RS256". Other benchmarks keep their name as operation.
+func classify(name string) Result {
+	res := Result{Name: name, Op: name}
+	parts := strings.Split(name, "/")
+	switch {
+	case parts[0] == "SSO" && len(parts) >= 3:
+		res.Protocol, res.Op, res.Variant = parts[1], parts[2], strings.Join(parts[3:], "/")
+	case parts[0] == "SSOLogin" && len(parts) == 3:
+		res.Protocol, res.Op, res.Variant = parts[2], "Login", parts[1]
+	default:
+		for _, p := range Protocols {
+			if op, ok := strings.CutPrefix(parts[0], p.Prefix); ok && op != "" {
+				res.Protocol, res.Op, res.Variant = p.Name, op, strings.Join(parts[1:], "/")
+				break
+			}
+		}
+	}
+	if op, ok := strings.CutSuffix(res.Op, "Parallel"); ok && op != "" && res.Protocol != "" {
+		res.Op, res.Variant = op, strings.TrimSuffix("parallel/"+res.Variant, "/")
+	}
+	if res.Protocol != "" {
+		res.Role = opRoles[res.Op]
+	}
+	return res
+}
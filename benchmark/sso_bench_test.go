@@ -0,0 +1,129 @@
+package benchmark
+
+import (
+	"OPPID-artifacts/pkg/oppid/sign/schemes"
+	"OPPID-artifacts/protocol"
+	"OPPID-artifacts/protocol/metrics"
+	"OPPID-artifacts/protocol/protocols"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// ssoBench holds the roles of a protocol with the messages of one login as inputs to the benchmarked steps.
+type ssoBench struct {
+	idp protocol.IdP
+	rp  protocol.RP
+	u   protocol.User
+	ch  protocol.Challenge
+	req protocol.Message
+	st  protocol.State
+	tk  protocol.Message
+	res protocol.Message
+}
+
+func setupSSOBenchmark(b *testing.B, p protocol.SSO) *ssoBench {
+	b.Helper()
+	if testing.Short() && protocols.Slow(p) {
+		b.Skip("skipping slow protocol in short mode")
+	}
+	var s ssoBench
+	var err error
+	if s.idp, err = p.Setup(); err != nil {
+		b.Fatal(err)
+	}
+	if s.rp, err = s.idp.Register([]byte("Test-RID")); err != nil {
+		b.Fatal(err)
+	}
+	if s.u, err = s.idp.User([]byte("alice.doe@idp.com")); err != nil {
+		b.Fatal(err)
+	}
+	if s.ch, err = s.rp.Begin(); err != nil {
+		b.Fatal(err)
+	}
+	if s.req, s.st, err = s.u.Request(s.ch); err != nil {
+		b.Fatal(err)
+	}
+	if s.tk, err = s.idp.Response(s.u.ID(), s.req, s.ch.Ctx, s.ch.Sid); err != nil {
+		b.Fatal(err)
+	}
+	if s.res, err = s.u.Finalize(s.st, s.tk); err != nil {
+		b.Fatal(err)
+	}
+	return &s
+}
+
+func benchmarkStep(b *testing.B, step func() error) {
+	b.ResetTimer()
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		if err := step(); err != nil {
+			b.Fatal(err)
+		}
+	}
+	elapsed := time.Since(start)
+	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
+}
+
+// BenchmarkSSO runs every step of a login, and a whole login, of every protocol through its protocol.SSO adapter.
+// The steps of a protocol are those of its adapter, e.g., Request of OPPID includes Init. Steps that send a message
+// report its encoded size, and the table of all message sizes is printed at the end.
+func BenchmarkSSO(b *testing.B) {
+	var reports []metrics.Report
+	for _, p := range protocols.All() {
+		b.Run(p.Name(), func(b *testing.B) {
+			s := setupSSOBenchmark(b, p)
+			msgs, err := metrics.Measure(s.idp, s.rp, s.u)
+			if err != nil {
+				b.Fatal(err)
+			}
+			r := metrics.Report{Protocol: p.Name(), Messages: msgs}
+			reports = append(reports, r)
+
+			steps := []struct {
+				name string
+				msg  protocol.Step
+				run  func() error
+			}{
+				{"Begin", protocol.StepChallenge, func() error { _, err := s.rp.Begin(); return err }},
+				{"Request", protocol.StepRequest, func() error { _, _, err := s.u.Request(s.ch); return err }},
+				{"Response", protocol.StepToken, func() error {
+					_, err := s.idp.Response(s.u.ID(), s.req, s.ch.Ctx, s.ch.Sid)
+					return err
+				}},
+				{"Finalize", protocol.StepResult, func() error { _, err := s.u.Finalize(s.st, s.tk); return err }},
+				{"Complete", -1, func() error { _, err := s.rp.Complete(s.ch, s.res); return err }},
+				{"Login", -1, func() error { _, err := protocol.Login(s.idp, s.rp, s.u); return err }},
+			}
+			for _, step := range steps {
+				b.Run(step.name, func(b *testing.B) {
+					benchmarkStep(b, step.run)
+					if step.msg >= 0 {
+						b.ReportMetric(float64(r.Size(step.msg)), "msg-bytes")
+					} else if step.name == "Login" {
+						b.ReportMetric(float64(r.Total()), "msg-bytes")
+					}
+				})
+			}
+		})
+	}
+	if len(reports) > 0 { // printed rather than logged, as logs of benchmarks with sub-benchmarks need -v
+		fmt.Println("Message sizes in bytes:")
+		if err := metrics.WriteTable(os.Stdout, reports); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkSSOLogin runs whole logins of every protocol with every token-signature scheme.
+func BenchmarkSSOLogin(b *testing.B) {
+	for _, sch := range schemes.All() {
+		for _, p := range protocols.AllWithScheme(sch) {
+			b.Run(sch.Name()+"/"+p.Name(), func(b *testing.B) {
+				s := setupSSOBenchmark(b, p)
+				benchmarkStep(b, func() error { _, err := protocol.Login(s.idp, s.rp, s.u); return err })
+			})
+		}
+	}
+}
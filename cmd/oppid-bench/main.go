@@ -0,0 +1,122 @@
+// Command oppid-bench aggregates benchmark results, i.e., the output of `go test -bench` as text or as `go test -json`
+// events, over repeated runs (-count) and writes them as Markdown or LaTeX tables grouped by protocol and role, or as
+// CSV or JSON. With -diff, it compares the results in two files instead.
+//
+// Usage:
+//
+//	go test -bench=. -count=5 -json ./benchmark/... > new.json
+//	oppid-bench -format latex new.json
+//	oppid-bench -diff benchmark_results_pets25.log new.json
+
+package main
+
+import (
+	"OPPID-artifacts/benchmark/results"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strings"
+)
+
+func main() {
+	format := flag.String("format", "markdown", "output format: "+strings.Join(results.Formats, ", "))
+	metric := flag.String("metric", "ms/op", "metric of the tables and of -diff")
+	diff := flag.Bool("diff", false, "compare the results of two files: old and new")
+	out := flag.String("o", "", "output file (default: stdout)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: oppid-bench [flags] [file ...]\n       oppid-bench -diff [flags] old new\n\nflags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	log.SetFlags(0)
+
+	if !slices.Contains(results.Formats, *format) {
+		log.Fatalf("unknown format %q", *format)
+	}
+
+	var buf bytes.Buffer
+	if *diff {
+		if flag.NArg() != 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		old, err := load(flag.Arg(0))
+		if err != nil {
+			log.Fatal(err)
+		}
+		new, err := load(flag.Arg(1))
+		if err != nil {
+			log.Fatal(err)
+		}
+		deltas := results.Diff(old, new, *metric)
+		if len(deltas) == 0 {
+			log.Fatalf("no benchmark with %s in both files", *metric)
+		}
+		err = results.WriteDiff(&buf, deltas, *metric, *format)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		files := flag.Args()
+		if len(files) == 0 {
+			files = []string{"-"}
+		}
+		var runs []results.Run
+		for _, f := range files {
+			rs, err := parse(f)
+			if err != nil {
+				log.Fatal(err)
+			}
+			runs = append(runs, rs...)
+		}
+		if len(runs) == 0 {
+			log.Fatal("no benchmark results found")
+		}
+		if err := results.Write(&buf, results.Aggregate(runs), *metric, *format); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var err error
+	if *out == "" {
+		_, err = os.Stdout.Write(buf.Bytes())
+	} else {
+		err = os.WriteFile(*out, buf.Bytes(), 0644)
+	}
+	if err != nil {
+		log.Fatalf("failed to write results: %v", err)
+	}
+}
+
+// parse reads the runs in file, or on stdin for "-".
+func parse(file string) ([]results.Run, error) {
+	var r io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	runs, err := results.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return runs, nil
+}
+
+func load(file string) ([]results.Result, error) {
+	runs, err := parse(file)
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, fmt.Errorf("%s: no benchmark results found", file)
+	}
+	return results.Aggregate(runs), nil
+}
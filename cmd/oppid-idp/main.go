@@ -0,0 +1,162 @@
+// Command oppid-idp runs the reference OPPID identity provider.
+//
+// Users are read from a file with one "username:password" pair per line and authenticate with HTTP basic
+// authentication. If -rp-token is set, RP registrations must present it as a bearer token.
+//
+// The IdP key is read from the -key file, or generated for the -scheme token-signature scheme and written there if the
+// file does not exist. If the OPPID_KEY_PASSPHRASE environment variable is set, the key file is encrypted with it. The
+// IdP signs tokens with the scheme of its key.
+//
+// Credentials are valid for one epoch of length -epoch, after which RPs register again. RPs whose rid is listed in the
+// -revoked file, one per line, can neither register nor log users in.
+
+package main
+
+import (
+	"OPPID-artifacts/pkg/oppid/sign/schemes"
+	"OPPID-artifacts/protocol/oppid"
+	"OPPID-artifacts/protocol/oppid/idp"
+	"bufio"
+	"crypto/subtle"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+func loadUsers(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+
+	users := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		user, password, ok := strings.Cut(entry, ":")
+		if !ok || user == "" {
+			return nil, fmt.Errorf("%s:%d: expected username:password", path, line)
+		}
+		users[user] = password
+	}
+	return users, scanner.Err()
+}
+
+func loadRevoked(path string) ([][]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rids [][]byte
+	for _, line := range strings.Split(string(data), "\n") {
+		rid := strings.TrimSpace(line)
+		if rid != "" && !strings.HasPrefix(rid, "#") {
+			rids = append(rids, []byte(rid))
+		}
+	}
+	return rids, nil
+}
+
+func bearerToken(token string) idp.Authenticator {
+	return idp.AuthenticatorFunc(func(r *http.Request) ([]byte, error) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			return nil, errors.New("invalid bearer token")
+		}
+		return []byte("rp"), nil
+	})
+}
+
+// loadOrCreateKey returns the IdP key and public parameters for the scheme of the key.
+func loadOrCreateKey(path, scheme string, passphrase []byte) (*oppid.PublicParams, *oppid.PrivateKey, *oppid.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		sk, pk, err := oppid.ParsePrivateKeyPEM(data, passphrase)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		s, err := schemes.New(sk.Scheme())
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return oppid.Setup(oppid.WithScheme(s)), sk, pk, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil, err
+	}
+
+	s, err := schemes.New(scheme)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	pp := oppid.Setup(oppid.WithScheme(s))
+	sk, pk, err := pp.KeyGen()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if data, err = oppid.MarshalPrivateKeyPEM(sk, passphrase); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return nil, nil, nil, err
+	}
+	log.Printf("generated new %s IdP key in %s", scheme, path)
+	return pp, sk, pk, nil
+}
+
+func main() {
+	addr := flag.String("addr", "localhost:8080", "listen address")
+	usersFile := flag.String("users", "", "file with username:password pairs (required)")
+	rpToken := flag.String("rp-token", "", "bearer token required for RP registration (optional)")
+	keyFile := flag.String("key", "oppid-idp.key", "IdP key file, created if it does not exist")
+	scheme := flag.String("scheme", schemes.Default().Name(), "token-signature scheme of a new key: RS256, PS256, ES256, Ed25519, BLS12381-MinSig or BLS12381-MinPK")
+	epoch := flag.Duration("epoch", 24*time.Hour, "validity period of RP credentials")
+	revokedFile := flag.String("revoked", "", "file with revoked RP rids, one per line (optional)")
+	flag.Parse()
+
+	if *usersFile == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	users, err := loadUsers(*usersFile)
+	if err != nil {
+		log.Fatalf("failed to load users: %v", err)
+	}
+
+	pp, sk, pk, err := loadOrCreateKey(*keyFile, *scheme, []byte(os.Getenv("OPPID_KEY_PASSPHRASE")))
+	if err != nil {
+		log.Fatalf("failed to load IdP key: %v", err)
+	}
+
+	cfg := idp.Config{Users: idp.BasicAuth(users), EpochLength: *epoch}
+	if *rpToken != "" {
+		cfg.RPs = bearerToken(*rpToken)
+	}
+	srv, err := idp.New(pp, sk, pk, cfg)
+	if err != nil {
+		log.Fatalf("failed to create IdP: %v", err)
+	}
+	if *revokedFile != "" {
+		revoked, err := loadRevoked(*revokedFile)
+		if err != nil {
+			log.Fatalf("failed to load revoked rids: %v", err)
+		}
+		for _, rid := range revoked {
+			srv.Revoke(rid)
+		}
+	}
+
+	log.Printf("OPPID IdP listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, srv))
+}
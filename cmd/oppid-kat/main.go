@@ -0,0 +1,54 @@
+// Command oppid-kat writes known-answer test vectors for the OPPID protocol as JSON, or checks a vectors file
+// against this implementation. See package kat for how the vectors are derived.
+
+package main
+
+import (
+	"OPPID-artifacts/protocol/oppid/kat"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+)
+
+func main() {
+	seed := flag.String("seed", kat.DefaultSeed, "seed from which all randomness is derived")
+	out := flag.String("o", "", "output file (default: stdout)")
+	check := flag.String("check", "", "check the vectors in this file instead of writing new ones")
+	flag.Parse()
+
+	if *check != "" {
+		data, err := os.ReadFile(*check)
+		if err != nil {
+			log.Fatalf("failed to read vectors: %v", err)
+		}
+		var v kat.Vectors
+		if err := json.Unmarshal(data, &v); err != nil {
+			log.Fatalf("failed to parse vectors: %v", err)
+		}
+		if err := v.Check(); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("%s: all steps match", *check)
+		return
+	}
+
+	v, err := kat.Generate([]byte(*seed), kat.DefaultInputs())
+	if err != nil {
+		log.Fatalf("failed to generate vectors: %v", err)
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	data = append(data, '\n')
+
+	if *out == "" {
+		_, err = os.Stdout.Write(data)
+	} else {
+		err = os.WriteFile(*out, data, 0644)
+	}
+	if err != nil {
+		log.Fatalf("failed to write vectors: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+// Command oppid-load drives concurrent simulated users through complete logins with OPPID and the other protocols and
+// reports the logins per second and the p50/p95/p99 latencies of every step, see package load.
+//
+// Every protocol runs the same workload against an in-process IdP. With -http, OPPID also runs against the reference
+// IdP server over HTTP. PPOIDC takes minutes per login and only runs if listed in -protocols.
+
+package main
+
+import (
+	"OPPID-artifacts/pkg/oppid/sign/schemes"
+	"OPPID-artifacts/protocol/load"
+	"OPPID-artifacts/protocol/oppid"
+	"OPPID-artifacts/protocol/protocols"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+func main() {
+	users := flag.Int("users", runtime.GOMAXPROCS(0), "number of concurrent users")
+	logins := flag.Int("logins", 0, "number of logins per protocol (default: run for -duration)")
+	duration := flag.Duration("duration", 10*time.Second, "duration of the run per protocol, if -logins is not set")
+	names := flag.String("protocols", "", "comma-separated protocols to run (default: all but PPOIDC)")
+	scheme := flag.String("scheme", schemes.Default().Name(), "token-signature scheme of the IdPs")
+	overHTTP := flag.Bool("http", false, "also run OPPID against the reference IdP server over HTTP")
+	flag.Parse()
+	log.SetFlags(0)
+
+	cfg := load.Config{Users: *users, Logins: *logins}
+	if *logins == 0 {
+		cfg.Duration = *duration
+	}
+	s, err := schemes.New(*scheme)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var workloads []load.Workload
+	selected := strings.Split(*names, ",")
+	for _, p := range protocols.AllWithScheme(s) {
+		if *names == "" && protocols.Slow(p) || *names != "" && !slices.Contains(selected, p.Name()) {
+			continue
+		}
+		w, err := load.NewSSO(p)
+		if err != nil {
+			log.Fatalf("failed to set up %s: %v", p.Name(), err)
+		}
+		workloads = append(workloads, w)
+	}
+	if *overHTTP {
+		h, err := load.NewHTTP(oppid.WithScheme(s))
+		if err != nil {
+			log.Fatalf("failed to start the IdP server: %v", err)
+		}
+		defer h.Close()
+		workloads = append(workloads, h)
+	}
+	if len(workloads) == 0 {
+		log.Fatalf("no protocol among %q", *names)
+	}
+
+	var reports []*load.Report
+	for _, w := range workloads {
+		log.Printf("running %s with %d users", w.Name(), cfg.Users)
+		r, err := load.Run(w, cfg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		reports = append(reports, r)
+	}
+	fmt.Printf("Latencies in ms, %s tokens, GOMAXPROCS=%d:\n", s.Name(), runtime.GOMAXPROCS(0))
+	if err := load.WriteTable(os.Stdout, reports); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -1,60 +1,182 @@
 // Package implements basic Pedersen commitments [1].
+// The generator H is derived transparently by hashing a public seed to G1 [2], so nobody knows its discrete
+// logarithm to the base G and the parameters can be checked by anyone with VerifyParams.
+
+// References:
 // [1] https://link.springer.com/chapter/10.1007/3-540-46766-1_9
+// [2] https://datatracker.ietf.org/doc/html/rfc9380
 
 package pc
 
 import (
 	"OPPID-artifacts/pkg/oppid/utils"
-	"log"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
 
 	GG "github.com/cloudflare/circl/ecc/bls12381"
 )
 
 const dstStr = "OPPID_BLS12384_XMD:SHA-256_COM_PC_"
 
+// DefaultSeed is the public nothing-up-my-sleeve seed from which Setup derives the generators.
+const DefaultSeed = "OPPID Pedersen commitment generators v1"
+
 type PublicParams struct {
-	G   *GG.G1
-	H   *GG.G1
-	Dst []byte
+	G    *GG.G1
+	H    *GG.G1
+	Dst  []byte
+	Seed []byte
+	// Rand is the source of randomness for Commit. If nil, crypto/rand is used.
+	Rand io.Reader
+
+	tables *tables
+}
+
+// tables holds fixed-base tables of G and H, which parameters from Setup and SetupWithSeed build on first use.
+type tables struct {
+	once sync.Once
+	g, h *utils.G1Table
 }
 
 type Commitment struct{ Element *GG.G1 }
 type Opening struct{ Scalar *GG.Scalar }
 
+// Setup derives the parameters from DefaultSeed.
 func Setup(dst []byte) *PublicParams {
-	r := utils.GenerateRandomScalar()
-	g := GG.G1Generator()
-	h := utils.GenerateG1Point(r, g)
+	return SetupWithSeed([]byte(DefaultSeed), dst)
+}
 
+func SetupWithSeed(seed, dst []byte) *PublicParams {
 	if dst == nil {
-		return &PublicParams{g, h, []byte(dstStr)}
+		dst = []byte(dstStr)
+	}
+	return New(GG.G1Generator(), DeriveGenerators(seed, dst, 1)[0], dst, seed)
+}
+
+// New returns parameters with the given generators, e.g., decoded ones, which build fixed-base tables on first use
+// like those from Setup. Parameters that are constructed as struct literals have none.
+func New(g, h *GG.G1, dst, seed []byte) *PublicParams {
+	return &PublicParams{G: g, H: h, Dst: dst, Seed: seed, tables: new(tables)}
+}
+
+func (p *PublicParams) table() *tables {
+	if p.tables == nil || p.G == nil || p.H == nil {
+		return nil
+	}
+	p.tables.once.Do(func() { p.tables.g, p.tables.h = utils.NewG1Table(p.G), utils.NewG1Table(p.H) })
+	return p.tables
+}
+
+// MulG returns s*G, with a fixed-base table if the parameters are from Setup and G was not changed since.
+func (p *PublicParams) MulG(s *GG.Scalar) *GG.G1 {
+	if t := p.table(); t != nil {
+		return utils.MulG1(t.g, s, p.G)
+	}
+	return utils.GenerateG1Point(s, p.G)
+}
+
+// MulH returns s*H like MulG.
+func (p *PublicParams) MulH(s *GG.Scalar) *GG.G1 {
+	if t := p.table(); t != nil {
+		return utils.MulG1(t.h, s, p.H)
+	}
+	return utils.GenerateG1Point(s, p.H)
+}
+
+// DeriveGenerators returns n independent generators of G1, where the i-th one is the hash of seed || i under dst.
+func DeriveGenerators(seed, dst []byte, n int) []*GG.G1 {
+	genDst := append([]byte(nil), dst...)
+	genDst = append(genDst, "GENERATOR"...)
+
+	gens := make([]*GG.G1, n)
+	for i := range gens {
+		input := binary.BigEndian.AppendUint32(append([]byte(nil), seed...), uint32(i))
+		gens[i] = new(GG.G1)
+		gens[i].Hash(input, genDst)
+	}
+	return gens
+}
+
+// VerifyParams checks that G is the standard generator and that H was derived from Seed and Dst.
+func (p *PublicParams) VerifyParams() error {
+	if p.G == nil || p.H == nil || p.Seed == nil {
+		return errors.New("pc: incomplete public parameters")
+	}
+	if !p.G.IsEqual(GG.G1Generator()) {
+		return errors.New("pc: G is not the standard generator")
 	}
-	return &PublicParams{g, h, dst}
+	if p.H.IsIdentity() || !p.H.IsEqual(DeriveGenerators(p.Seed, p.Dst, 1)[0]) {
+		return errors.New("pc: H was not derived from the seed")
+	}
+	return nil
 }
 
-func (p *PublicParams) Commit(msg []byte) (Commitment, Opening) {
+func (p *PublicParams) Commit(msg []byte) (Commitment, Opening, error) {
 	m := utils.HashToScalar(msg, p.Dst)
-	g := utils.GenerateG1Point(&m, p.G)
+	g := p.MulG(&m)
 
 	var o Opening
-	o.Scalar = utils.GenerateRandomScalar()
-	h := utils.GenerateG1Point(o.Scalar, p.H)
+	var err error
+	if o.Scalar, err = utils.GenerateRandomScalarFrom(p.Rand); err != nil {
+		return Commitment{}, Opening{}, err
+	}
+	h := p.MulH(o.Scalar)
 
 	var c Commitment
 	c.Element = utils.AddG1Points(g, h)
 
-	if !c.Element.IsOnG1() || o.Scalar.IsZero() == 1 {
-		log.Fatalf("Fatal error: invalid commitment")
+	if !utils.IsValidG1(c.Element) || !utils.IsValidScalar(o.Scalar) {
+		return Commitment{}, Opening{}, errors.New("pc: invalid commitment")
 	}
 
-	return c, o
+	return c, o, nil
 }
 
+// Open returns false for malformed commitments and openings, e.g., with missing, identity or zero components.
 func (p *PublicParams) Open(msg []byte, c Commitment, o Opening) bool {
+	if !utils.IsValidG1(c.Element) || !utils.IsValidScalar(o.Scalar) {
+		return false
+	}
 	m := utils.HashToScalar(msg, p.Dst)
-	g := utils.GenerateG1Point(&m, p.G)
-	h := utils.GenerateG1Point(o.Scalar, p.H)
+	g := p.MulG(&m)
+	h := p.MulH(o.Scalar)
 	c1 := utils.AddG1Points(g, h)
 
 	return c1.IsEqual(c.Element)
 }
+
+// MarshalBinary encodes the commitment as a compressed G1 point.
+func (c Commitment) MarshalBinary() ([]byte, error) {
+	if c.Element == nil {
+		return nil, errors.New("pc: empty commitment")
+	}
+	return c.Element.BytesCompressed(), nil
+}
+
+func (c *Commitment) UnmarshalBinary(data []byte) error {
+	e, err := utils.DecodeG1(data)
+	if err != nil {
+		return err
+	}
+	c.Element = e
+	return nil
+}
+
+// MarshalBinary encodes the opening as a fixed-size scalar.
+func (o Opening) MarshalBinary() ([]byte, error) {
+	if o.Scalar == nil {
+		return nil, errors.New("pc: empty opening")
+	}
+	return utils.ScalarBytes(o.Scalar), nil
+}
+
+func (o *Opening) UnmarshalBinary(data []byte) error {
+	s, err := utils.DecodeScalar(data)
+	if err != nil {
+		return err
+	}
+	o.Scalar = s
+	return nil
+}
@@ -12,7 +12,7 @@ func BenchmarkPCCommit(b *testing.B) {
 	b.ResetTimer()
 	start := time.Now()
 	for i := 0; i < b.N; i++ {
-		pc.Commit(msg)
+		_, _, _ = pc.Commit(msg)
 	}
 	elapsed := time.Since(start)
 	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
@@ -22,7 +22,7 @@ func BenchmarkPCOpen(b *testing.B) {
 	pc := Setup(nil)
 	msg := []byte("test message")
 
-	c, o := pc.Commit(msg)
+	c, o, _ := pc.Commit(msg)
 
 	b.ResetTimer()
 	start := time.Now()
@@ -2,6 +2,7 @@ package pc
 
 import (
 	"OPPID-artifacts/pkg/oppid/utils"
+	"bytes"
 	"testing"
 
 	GG "github.com/cloudflare/circl/ecc/bls12381"
@@ -21,7 +22,7 @@ func TestCommitValidMessage(t *testing.T) {
 	pc := Setup(nil)
 	msg := []byte("test message")
 
-	commitment, opening := pc.Commit(msg)
+	commitment, opening, _ := pc.Commit(msg)
 	if !commitment.Element.IsOnG1() {
 		t.Fatalf("Commitment should be on G1")
 	}
@@ -34,7 +35,7 @@ func TestOpenWithCorrectParameters(t *testing.T) {
 	pc := Setup(nil)
 	msg := []byte("test message")
 
-	commitment, opening := pc.Commit(msg)
+	commitment, opening, _ := pc.Commit(msg)
 	isValid := pc.Open(msg, commitment, opening)
 	if !isValid {
 		t.Fatalf("Open should validate the correct commitment")
@@ -45,7 +46,7 @@ func TestOpenWithIncorrectMessage(t *testing.T) {
 	pc := Setup(nil)
 	msg := []byte("test message")
 
-	commitment, opening := pc.Commit(msg)
+	commitment, opening, _ := pc.Commit(msg)
 
 	invalidMsg := []byte("wrong message")
 	isValid := pc.Open(invalidMsg, commitment, opening)
@@ -58,9 +59,9 @@ func TestOpenWithIncorrectRandomness(t *testing.T) {
 	pc := Setup([]byte("Test dst"))
 
 	msg := []byte("test message")
-	commitment, _ := pc.Commit(msg)
+	commitment, _, _ := pc.Commit(msg)
 
-	r := utils.GenerateRandomScalar()
+	r, _ := utils.GenerateRandomScalar()
 
 	invalidRandomOpening := new(Opening)
 	invalidRandomOpening.Scalar = r
@@ -73,3 +74,73 @@ func TestOpenWithIncorrectRandomness(t *testing.T) {
 		t.Fatalf("Open should not validate the incorrect commitment with wrong randomness")
 	}
 }
+
+func TestSetupIsTransparent(t *testing.T) {
+	pc1 := Setup([]byte("Test dst"))
+	pc2 := Setup([]byte("Test dst"))
+	if !pc1.H.IsEqual(pc2.H) {
+		t.Fatalf("Setup should derive the same H from the same seed and dst")
+	}
+	if pc1.H.IsEqual(Setup(nil).H) || pc1.H.IsEqual(SetupWithSeed([]byte("other seed"), []byte("Test dst")).H) {
+		t.Fatalf("H should depend on the seed and dst")
+	}
+	if err := pc1.VerifyParams(); err != nil {
+		t.Fatalf("VerifyParams rejected transparent parameters: %v", err)
+	}
+}
+
+func TestVerifyParamsRejectsTrapdoor(t *testing.T) {
+	pc := Setup(nil)
+	s, _ := utils.GenerateRandomScalar()
+	pc.H = utils.GenerateG1Point(s, pc.G) // H with a known discrete log
+	if err := pc.VerifyParams(); err == nil {
+		t.Fatalf("VerifyParams accepted an H that was not derived from the seed")
+	}
+
+	pc = Setup(nil)
+	s, _ = utils.GenerateRandomScalar()
+	pc.G = utils.GenerateG1Point(s, pc.G)
+	if err := pc.VerifyParams(); err == nil {
+		t.Fatalf("VerifyParams accepted a non-standard G")
+	}
+}
+
+func TestOpenMalformedCommitment(t *testing.T) {
+	pc := Setup([]byte("Test dst"))
+
+	msg := []byte("test message")
+	_, opening, _ := pc.Commit(msg)
+
+	identity := new(GG.G1)
+	identity.SetIdentity()
+
+	if pc.Open(msg, Commitment{identity}, opening) || pc.Open(msg, Commitment{}, opening) || pc.Open(msg, Commitment{}, Opening{}) {
+		t.Fatalf("Open should reject missing or identity commitments")
+	}
+}
+
+func TestCommitFailingRandomness(t *testing.T) {
+	pc := Setup([]byte("Test dst"))
+	pc.Rand = bytes.NewReader(nil)
+
+	if _, _, err := pc.Commit([]byte("test message")); err == nil {
+		t.Fatalf("Commit should fail when the randomness source is exhausted")
+	}
+}
+
+func TestMulTablesFollowParams(t *testing.T) {
+	pc := Setup(nil)
+	k, _ := utils.GenerateRandomScalar()
+	if !pc.MulG(k).IsEqual(utils.GenerateG1Point(k, pc.G)) || !pc.MulH(k).IsEqual(utils.GenerateG1Point(k, pc.H)) {
+		t.Fatalf("Expected multiplications with tables to match ScalarMult")
+	}
+
+	pc.H = DeriveGenerators([]byte("other seed"), pc.Dst, 1)[0]
+	if !pc.MulH(k).IsEqual(utils.GenerateG1Point(k, pc.H)) {
+		t.Errorf("Expected MulH to follow a changed generator")
+	}
+	literal := &PublicParams{G: pc.G, H: pc.H, Dst: pc.Dst}
+	if !literal.MulH(k).IsEqual(utils.GenerateG1Point(k, pc.H)) {
+		t.Errorf("Expected MulH to work without tables")
+	}
+}
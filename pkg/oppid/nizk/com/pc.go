@@ -1,11 +1,15 @@
-// Package provides a NIZK for Pedersen commitments (PC). It is not directly used and is kept here for testing.
+// Package provides a NIZK of the message and opening of a Pedersen commitment (PC). RPs use it to request blind
+// credentials, committing to their rid under the generators of ps.CommitmentParams.
 
 package com
 
 import (
 	PC "OPPID-artifacts/pkg/oppid/commit/pc"
+	"OPPID-artifacts/pkg/oppid/transcript"
 	"OPPID-artifacts/pkg/oppid/utils"
-	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 
 	GG "github.com/cloudflare/circl/ecc/bls12381"
@@ -14,13 +18,13 @@ import (
 const DST = "OPPID_BLS12384_XMD:SHA-256_NIZK_PC"
 
 type Witness struct {
-	msg     []byte
-	opening *PC.Opening
+	Msg     []byte
+	Opening *PC.Opening
 }
 
 type PublicInput struct {
-	params *PC.PublicParams
-	com    *PC.Commitment
+	Params *PC.PublicParams
+	Com    *PC.Commitment
 }
 
 type Proof struct {
@@ -29,60 +33,77 @@ type Proof struct {
 	s2 *GG.Scalar
 }
 
-func Prove(p *PublicInput, w *Witness) *Proof {
-	u1 := utils.GenerateRandomScalar()
-	u2 := utils.GenerateRandomScalar()
+func challenge(p *PublicInput, a1 *GG.G1, aux []byte) GG.Scalar {
+	data := transcript.New(DST+"Z").
+		Append("g", p.Params.G.Bytes()).
+		Append("h", p.Params.H.Bytes()).
+		Append("com", p.Com.Element.Bytes()).
+		Append("a1", a1.Bytes()).
+		Append("aux", aux).
+		Bytes()
+	return utils.HashToScalar(data, []byte(DST+"Z"))
+}
+
+// validInput reports whether the generators and the commitment are valid group elements.
+func validInput(p *PublicInput) bool {
+	return p != nil && p.Params != nil && utils.IsValidG1(p.Params.G) && utils.IsValidG1(p.Params.H) &&
+		p.Com != nil && utils.IsValidG1(p.Com.Element)
+}
+
+// Prove draws its randomness from rnd, or from crypto/rand if rnd is nil. The proof is bound to aux.
+func Prove(rnd io.Reader, p *PublicInput, w *Witness, aux []byte) (*Proof, error) {
+	if !validInput(p) || w == nil || w.Opening == nil || !utils.IsValidScalar(w.Opening.Scalar) {
+		return nil, errors.New("com: malformed public input or witness")
+	}
+	u1, err := utils.GenerateRandomScalarFrom(rnd)
+	if err != nil {
+		return nil, err
+	}
+	u2, err := utils.GenerateRandomScalarFrom(rnd)
+	if err != nil {
+		return nil, err
+	}
 
 	// Announcement
-	g := utils.GenerateG1Point(u1, p.params.G)
-	h := utils.GenerateG1Point(u2, p.params.H)
+	g := p.Params.MulG(u1)
+	h := p.Params.MulH(u2)
 
 	a1 := utils.AddG1Points(g, h)
 
 	// Challenge
-	var buff bytes.Buffer
-
-	buff.Write(a1.Bytes())
-	buff.Write(p.com.Element.Bytes())
-
-	data := buff.Bytes()
-
-	z := utils.HashToScalar(data, []byte(DST+"Z"))
+	z := challenge(p, a1, aux)
 
 	// Responses
-	m := utils.HashToScalar(w.msg, p.params.Dst)
+	m := utils.HashToScalar(w.Msg, p.Params.Dst)
 
 	mz, err1 := utils.MulScalars(&m, &z)
 	s1, err2 := utils.AddScalars(u1, mz)
 
-	oz, err3 := utils.MulScalars(w.opening.Scalar, &z)
+	oz, err3 := utils.MulScalars(w.Opening.Scalar, &z)
 	s2, err4 := utils.AddScalars(u2, oz)
 
-	if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
-		log.Fatalf("error generating proof of a message/opening: %v, %v, %v, %v", err1, err2, err3, err4)
+	if err := errors.Join(err1, err2, err3, err4); err != nil {
+		return nil, fmt.Errorf("com: generating proof of a message/opening: %w", err)
 	}
 
 	return &Proof{
 		a1: a1, s1: s1, s2: s2,
-	}
+	}, nil
 }
 
-func Verify(p *PublicInput, pi *Proof) bool {
-	var buf bytes.Buffer
-
-	buf.Write(pi.a1.Bytes())
-	buf.Write(p.com.Element.Bytes())
-
-	data := buf.Bytes()
-
-	z := utils.HashToScalar(data, []byte(DST+"Z"))
+// Verify returns false for malformed proofs, e.g., with missing or identity components.
+func Verify(p *PublicInput, pi *Proof, aux []byte) bool {
+	if pi == nil || !utils.IsValidG1(pi.a1) || pi.s1 == nil || pi.s2 == nil || !validInput(p) {
+		return false
+	}
+	z := challenge(p, pi.a1, aux)
 
-	g := utils.GenerateG1Point(pi.s1, p.params.G)
-	h := utils.GenerateG1Point(pi.s2, p.params.H)
+	g := p.Params.MulG(pi.s1)
+	h := p.Params.MulH(pi.s2)
 
 	lhs := utils.AddG1Points(g, h)
 
-	c := utils.GenerateG1Point(&z, p.com.Element)
+	c := utils.GenerateG1Point(&z, p.Com.Element)
 
 	rhs := utils.AddG1Points(pi.a1, c)
 
@@ -93,3 +114,38 @@ func Verify(p *PublicInput, pi *Proof) bool {
 
 	return isValid
 }
+
+// ProofSize is the length of an encoded proof: a1 and the two responses.
+const ProofSize = GG.G1SizeCompressed + 2*GG.ScalarSize
+
+func (pi *Proof) MarshalBinary() ([]byte, error) {
+	if pi.a1 == nil || pi.s1 == nil || pi.s2 == nil {
+		return nil, errors.New("com: empty proof")
+	}
+	buf := make([]byte, 0, ProofSize)
+	buf = append(buf, pi.a1.BytesCompressed()...)
+	buf = append(buf, utils.ScalarBytes(pi.s1)...)
+	buf = append(buf, utils.ScalarBytes(pi.s2)...)
+	return buf, nil
+}
+
+func (pi *Proof) UnmarshalBinary(data []byte) error {
+	if len(data) != ProofSize {
+		return errors.New("com: invalid proof length")
+	}
+	a1, err := utils.DecodeG1(data[:GG.G1SizeCompressed])
+	if err != nil {
+		return err
+	}
+	data = data[GG.G1SizeCompressed:]
+	s1, err := utils.DecodeScalar(data[:GG.ScalarSize])
+	if err != nil {
+		return err
+	}
+	s2, err := utils.DecodeScalar(data[GG.ScalarSize:])
+	if err != nil {
+		return err
+	}
+	pi.a1, pi.s1, pi.s2 = a1, s1, s2
+	return nil
+}
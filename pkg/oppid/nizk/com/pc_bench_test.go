@@ -10,7 +10,7 @@ func BenchmarkPCGenProof(b *testing.B) {
 	pc := PC.Setup(nil)
 
 	msg := []byte("test")
-	com, opn := pc.Commit(msg)
+	com, opn, _ := pc.Commit(msg)
 
 	pubInput := &PublicInput{pc, &com}
 	witness := &Witness{msg, &opn}
@@ -18,7 +18,7 @@ func BenchmarkPCGenProof(b *testing.B) {
 	b.ResetTimer()
 	start := time.Now()
 	for i := 0; i < b.N; i++ {
-		Prove(pubInput, witness)
+		_, _ = Prove(nil, pubInput, witness, nil)
 	}
 	elapsed := time.Since(start)
 	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
@@ -28,17 +28,17 @@ func BenchmarkPCProofVerify(b *testing.B) {
 	pc := PC.Setup(nil)
 
 	msg := []byte("test")
-	com, opn := pc.Commit(msg)
+	com, opn, _ := pc.Commit(msg)
 
 	pubInput := &PublicInput{pc, &com}
 	witness := &Witness{msg, &opn}
 
-	pi := Prove(pubInput, witness)
+	pi, _ := Prove(nil, pubInput, witness, nil)
 
 	b.ResetTimer()
 	start := time.Now()
 	for i := 0; i < b.N; i++ {
-		isValid := Verify(pubInput, pi)
+		isValid := Verify(pubInput, pi, nil)
 		if !isValid {
 			b.Fatal("verification failed")
 		}
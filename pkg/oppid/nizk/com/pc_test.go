@@ -3,21 +3,78 @@ package com
 import (
 	PC "OPPID-artifacts/pkg/oppid/commit/pc"
 	"testing"
+
+	GG "github.com/cloudflare/circl/ecc/bls12381"
 )
 
 func TestProveVerify(t *testing.T) {
 	pc := PC.Setup(nil)
 
 	msg := []byte("test")
-	com, opn := pc.Commit(msg)
+	com, opn, _ := pc.Commit(msg)
 
 	p := &PublicInput{pc, &com}
 	w := &Witness{msg, &opn}
 
-	pi := Prove(p, w)
+	pi, _ := Prove(nil, p, w, []byte("aux"))
 
-	isValid := Verify(p, pi)
+	isValid := Verify(p, pi, []byte("aux"))
 	if !isValid {
 		t.Errorf("Verify(%v, %v) returned %v", p, pi, isValid)
 	}
+	if Verify(p, pi, []byte("other aux")) {
+		t.Errorf("Expected proof to be invalid for another aux")
+	}
+
+	// The proof is bound to the generators.
+	other := PC.SetupWithSeed([]byte("other seed"), nil)
+	if Verify(&PublicInput{other, &com}, pi, []byte("aux")) {
+		t.Errorf("Expected proof to be invalid under other generators")
+	}
+}
+
+func TestProofEncoding(t *testing.T) {
+	pc := PC.Setup(nil)
+	msg := []byte("test")
+	com, opn, _ := pc.Commit(msg)
+	p := &PublicInput{pc, &com}
+	pi, _ := Prove(nil, p, &Witness{msg, &opn}, nil)
+
+	data, err := pi.MarshalBinary()
+	if err != nil || len(data) != ProofSize {
+		t.Fatalf("MarshalBinary returned %d bytes, %v", len(data), err)
+	}
+	decoded := new(Proof)
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary returned an error: %v", err)
+	}
+	if !Verify(p, decoded, nil) {
+		t.Errorf("Expected decoded proof to be valid")
+	}
+	if err := decoded.UnmarshalBinary(data[1:]); err == nil {
+		t.Errorf("Expected truncated proof to be rejected")
+	}
+}
+
+func TestVerifyMalformedProof(t *testing.T) {
+	pc := PC.Setup(nil)
+
+	msg := []byte("test")
+	com, opn, _ := pc.Commit(msg)
+
+	p := &PublicInput{pc, &com}
+	pi, _ := Prove(nil, p, &Witness{msg, &opn}, nil)
+
+	identity := new(GG.G1)
+	identity.SetIdentity()
+
+	for name, malformed := range map[string]*Proof{
+		"nil":         nil,
+		"identity a1": {a1: identity, s1: pi.s1, s2: pi.s2},
+		"missing s2":  {a1: pi.a1, s1: pi.s1},
+	} {
+		if Verify(p, malformed, nil) {
+			t.Errorf("%s: malformed proof should not verify", name)
+		}
+	}
 }
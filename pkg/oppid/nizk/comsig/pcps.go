@@ -1,8 +1,10 @@
 // Package provides a NIZK that proves knowledge of a signature and the opening to a commitment, as required in Sec. 4
-// in the OPPID paper [1].
+// in the OPPID paper [1]. The PS signature may be on a vector of messages ([2], Sec. 6.2): the first one is the
+// committed message, and any of the others can be disclosed while the rest stay hidden.
 
 // References:
 // [1] https://eprint.iacr.org/2024/1124
+// [2] https://eprint.iacr.org/2015/525.pdf
 
 package comsig
 
@@ -10,113 +12,213 @@ import (
 	PC "OPPID-artifacts/pkg/oppid/commit/pc"
 	NIZKPS "OPPID-artifacts/pkg/oppid/nizk/sig"
 	PS "OPPID-artifacts/pkg/oppid/sign/ps"
+	"OPPID-artifacts/pkg/oppid/transcript"
 	"OPPID-artifacts/pkg/oppid/utils"
 	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"sort"
 
 	GG "github.com/cloudflare/circl/ecc/bls12381"
 )
 
 const dstStr = "OPPID_BLS12384_XMD:SHA-256_NIZK_PC_PS_"
 
+// Witnesses holds all signed messages; Msgs[0] is the committed one.
 type Witnesses struct {
-	Msg     []byte
+	Msgs    [][]byte
 	Sig     *PS.Signature
 	Opening *PC.Opening
 }
 
+// PublicInputs may disclose signed messages by their index in Disclosed. The committed message 0 cannot be disclosed.
 type PublicInputs struct {
-	PS  *PS.PublicKey
-	PC  *PC.PublicParams
-	Com *PC.Commitment
+	PS        *PS.PublicKey
+	PC        *PC.PublicParams
+	Com       *PC.Commitment
+	Disclosed map[int][]byte
 }
 
 type Proof struct {
 	sig *PS.Signature // randomized signature
 	a1  *GG.G1
 	a2  *GG.Gt
-	r1  *GG.Scalar
-	r2  *GG.Scalar
-	r3  *GG.Scalar
+	rm  []*GG.Scalar // responses for the hidden messages, in the order of their index
+	ro  *GG.Scalar   // response for the opening
+	rt  *GG.Scalar   // response for the randomization of the signature
 }
 
-func challenge(c *PC.Commitment, a1 *GG.G1, a2 *GG.Gt, aux []byte) GG.Scalar {
-	var buf bytes.Buffer
+// hidden returns the indices of the messages that are not disclosed, or an error if Disclosed does not fit the key.
+func hidden(p PublicInputs) ([]int, error) {
+	n := p.PS.Len()
+	for i := range p.Disclosed {
+		if i < 1 || i >= n {
+			return nil, fmt.Errorf("comsig: cannot disclose message %d of %d", i, n)
+		}
+	}
+	idx := make([]int, 0, n-len(p.Disclosed))
+	for i := 0; i < n; i++ {
+		if _, ok := p.Disclosed[i]; !ok {
+			idx = append(idx, i)
+		}
+	}
+	return idx, nil
+}
 
-	buf.Write(c.Element.Bytes())
-	buf.Write(a1.Bytes())
+func challenge(p PublicInputs, sig *PS.Signature, a1 *GG.G1, a2 *GG.Gt, aux []byte) (GG.Scalar, error) {
 	a2Bytes, err := a2.MarshalBinary()
 	if err != nil {
-		log.Fatalf("Failed to marshal a2 announcement: %v", err)
+		return GG.Scalar{}, fmt.Errorf("comsig: marshaling a2 announcement: %w", err)
+	}
+	t := transcript.New(dstStr).
+		Append("com", p.Com.Element.Bytes()).
+		Append("sig1", sig.One.Bytes()).
+		Append("sig2", sig.Two.Bytes())
+	disclosed := make([]int, 0, len(p.Disclosed))
+	for i := range p.Disclosed {
+		disclosed = append(disclosed, i)
+	}
+	sort.Ints(disclosed)
+	for _, i := range disclosed {
+		t.Append(fmt.Sprintf("m%d", i), p.Disclosed[i])
 	}
-	buf.Write(a2Bytes)
-	buf.Write(aux)
+	data := t.
+		Append("a1", a1.Bytes()).
+		Append("a2", a2Bytes).
+		Append("aux", aux).
+		Bytes()
+	return utils.HashToScalar(data, []byte(dstStr)), nil
+}
 
-	data := buf.Bytes()
-	return utils.HashToScalar(data, []byte(dstStr))
+// validInputs reports whether the public inputs are complete and consist of valid group elements.
+func validInputs(p PublicInputs) bool {
+	return p.PS.IsValid() && p.PC != nil && utils.IsValidG1(p.PC.G) && utils.IsValidG1(p.PC.H) &&
+		p.Com != nil && utils.IsValidG1(p.Com.Element)
 }
 
-func Prove(w Witnesses, p PublicInputs, aux []byte, dst []byte) Proof {
-	u1 := utils.GenerateRandomScalar() // for commitment
-	u2 := utils.GenerateRandomScalar() // for commitment
-	u3 := utils.GenerateRandomScalar() // for signature
+// Prove draws its randomness from rnd, or from crypto/rand if rnd is nil. The messages are hashed to scalars with
+// dst, which must be the one of the PS signature.
+func Prove(rnd io.Reader, w Witnesses, p PublicInputs, aux []byte, dst []byte) (Proof, error) {
+	if !validInputs(p) || w.Opening == nil || !utils.IsValidScalar(w.Opening.Scalar) || len(w.Msgs) != p.PS.Len() {
+		return Proof{}, errors.New("comsig: malformed public inputs or witnesses")
+	}
+	idx, err := hidden(p)
+	if err != nil {
+		return Proof{}, err
+	}
+	for i, msg := range p.Disclosed {
+		if !bytes.Equal(msg, w.Msgs[i]) {
+			return Proof{}, fmt.Errorf("comsig: disclosed message %d differs from the signed one", i)
+		}
+	}
 
-	t, randSig := NIZKPS.Randomize(w.Sig)
+	u := make([]*GG.Scalar, len(idx)+2) // one per hidden message, then for the opening and the signature
+	for i := range u {
+		if u[i], err = utils.GenerateRandomScalarFrom(rnd); err != nil {
+			return Proof{}, err
+		}
+	}
+	um, uo, ut := u[:len(idx)], u[len(idx)], u[len(idx)+1]
+
+	t, randSig, err := NIZKPS.Randomize(rnd, w.Sig)
+	if err != nil {
+		return Proof{}, err
+	}
 
 	var pi Proof
 	pi.sig = randSig
 
-	// Announcement commitment
-	g := utils.GenerateG1Point(u1, p.PC.G)
-	h := utils.GenerateG1Point(u2, p.PC.H)
+	// Announcement commitment, sharing the randomness of message 0 with the signature
+	g := p.PC.MulG(um[0])
+	h := p.PC.MulH(uo)
 
-	pi.a1 = utils.AddG1Points(g, h) // a1 = g^u1 * h^u2
+	pi.a1 = utils.AddG1Points(g, h) // a1 = g^u0 * h^uo
 
 	// Announcement signature
 
 	// Moved to G2 before calculating pairing
-	rSig2 := utils.GenerateG2Point(u1, p.PS.Y)
-	tSig2 := utils.GenerateG2Point(u3, p.PS.G)
-	sig2 := utils.AddG2Points(rSig2, tSig2)
+	sig2 := p.PS.MulG(ut)
+	for j, i := range idx {
+		sig2 = utils.AddG2Points(sig2, p.PS.MulY(i, um[j]))
+	}
 
 	pi.a2 = GG.Pair(randSig.One, sig2)
 
-	z := challenge(p.Com, pi.a1, pi.a2, aux)
+	z, err := challenge(p, randSig, pi.a1, pi.a2, aux)
+	if err != nil {
+		return Proof{}, err
+	}
 
 	// Responses
-	m := utils.HashToScalar(w.Msg, dst)
-	mz, err1 := utils.MulScalars(&m, &z)
-	r1, err2 := utils.AddScalars(u1, mz)
-
-	o, err3 := utils.MulScalars(w.Opening.Scalar, &z)
-	r2, err4 := utils.AddScalars(u2, o)
+	var errs []error
+	response := func(u, secret *GG.Scalar) *GG.Scalar {
+		sz, err1 := utils.MulScalars(secret, &z)
+		r, err2 := utils.AddScalars(u, sz)
+		errs = append(errs, err1, err2)
+		return r
+	}
 
-	tz, err5 := utils.MulScalars(t, &z)
-	r3, err6 := utils.AddScalars(u3, tz)
+	pi.rm = make([]*GG.Scalar, len(idx))
+	for j, i := range idx {
+		m := utils.HashToScalar(w.Msgs[i], dst)
+		pi.rm[j] = response(um[j], &m)
+	}
+	pi.ro = response(uo, w.Opening.Scalar)
+	pi.rt = response(ut, t)
 
-	if err1 != nil || err2 != nil || err3 != nil || err4 != nil || err6 != nil {
-		log.Fatalf("error generating proof for commitment/signature: %v, %v, %v, %v, %v, %v", err1, err2, err3, err4, err5, err6)
+	if err := errors.Join(errs...); err != nil {
+		return Proof{}, fmt.Errorf("comsig: generating proof for commitment/signature: %w", err)
 	}
 
-	pi.r1 = r1
-	pi.r2 = r2
-	pi.r3 = r3
+	return pi, nil
+}
+
+// prepare checks that the proof is well-formed for the public inputs, which the caller has checked with validInputs.
+// It returns the challenge and the indices of the hidden messages, to which the responses pi.rm belong.
+func prepare(pi Proof, p PublicInputs, aux []byte) (GG.Scalar, []int, bool) {
+	if pi.sig == nil || !utils.IsValidG1(pi.sig.One) || !utils.IsValidG1(pi.sig.Two) || !utils.IsValidG1(pi.a1) ||
+		pi.a2 == nil || pi.ro == nil || pi.rt == nil {
+		return GG.Scalar{}, nil, false
+	}
+	idx, err := hidden(p)
+	if err != nil || len(pi.rm) != len(idx) {
+		return GG.Scalar{}, nil, false
+	}
+	for _, r := range pi.rm {
+		if r == nil {
+			return GG.Scalar{}, nil, false
+		}
+	}
 
-	return pi
+	z, err := challenge(p, pi.sig, pi.a1, pi.a2, aux)
+	if err != nil {
+		return GG.Scalar{}, nil, false
+	}
+	return z, idx, true
 }
 
+// Verify returns false for malformed proofs and public inputs, e.g., with missing or identity components. Disclosed
+// messages are hashed with the dst of the commitment parameters.
 func Verify(pi Proof, p PublicInputs, aux []byte) bool {
-	z := challenge(p.Com, pi.a1, pi.a2, aux)
+	if !validInputs(p) {
+		return false
+	}
+	z, idx, ok := prepare(pi, p, aux)
+	if !ok {
+		return false
+	}
 
 	// Verify commitment
-	g := utils.GenerateG1Point(pi.r1, p.PC.G)
-	h := utils.GenerateG1Point(pi.r2, p.PC.H)
+	g := p.PC.MulG(pi.rm[0])
+	h := p.PC.MulH(pi.ro)
 
-	lhs1 := utils.AddG1Points(g, h) // lhs1 = g^r1 * h^r2 = g^(u1+m*z) * h^(u2+o*z)
+	lhs1 := utils.AddG1Points(g, h) // lhs1 = g^r0 * h^ro = g^(u0+m0*z) * h^(uo+o*z)
 
 	c := utils.GenerateG1Point(&z, p.Com.Element)
 
-	rhs1 := utils.AddG1Points(c, pi.a1) // rhs1 = Com^z * a1 = g^(m*z+u1) * h^(o*z+u2)
+	rhs1 := utils.AddG1Points(c, pi.a1) // rhs1 = Com^z * a1 = g^(m0*z+u0) * h^(o*z+uo)
 
 	validCommitment := lhs1.IsEqual(rhs1)
 	if !validCommitment {
@@ -124,32 +226,242 @@ func Verify(pi Proof, p PublicInputs, aux []byte) bool {
 	}
 
 	// Verify signature
-	sig1z := utils.GenerateG1Point(&z, pi.sig.One)
-	sig2z := utils.GenerateG1Point(&z, pi.sig.Two)
+	one := new(GG.Scalar)
+	one.SetOne()
+	points := signaturePoints(p.PS)
+	addSignatureTerms(points, pi, p, &z, idx, one)
+	res := GG.ProdPairFrac(points, keyPoints(p.PS), ones(len(points)))
+	res.Mul(res, pi.a2)
+
+	validSignature := res.IsIdentity()
+	if !validSignature {
+		log.Println("Invalid signature")
+	}
 
-	lhsP1 := GG.Pair(sig1z, p.PS.X)
-	lhsP2 := GG.Pair(sig2z, p.PS.G)
+	return validSignature && validCommitment
+}
 
-	inv := new(GG.Gt)
-	inv.Inv(lhsP1)
+// The signature part of a proof holds if
+//
+//	e(z*sig2 - rt*sig1, g2) * e(-z*sig1, X) * prod_{i disclosed} e(-z*m_i*sig1, Y_i) * prod_{j hidden} e(-r_j*sig1, Y_j) * a2 = 1,
+//
+// which is the equation e(z*sig2, g2) * e(z*sig1, X')^-1 * a2 = e(sig1, rt*g2 + sum(r_j*Y_j)) of [1] with the
+// scalars moved to G1, X' = X + sum(m_i*Y_i) over the disclosed messages. It pairs G1 points with the points of the
+// public key only, so that it takes one product of n+2 pairings, i.e., one final exponentiation, and no scalar
+// multiplications in G2, which cost three times as much as those in G1. Equations under the same key, raised to
+// random d, can be summed up in the G1 points.
+
+// signaturePoints returns the G1 points of the pairings with keyPoints(pk), set to the identity.
+func signaturePoints(pk *PS.PublicKey) []*GG.G1 {
+	points := make([]*GG.G1, pk.Len()+2)
+	for k := range points {
+		points[k] = new(GG.G1)
+		points[k].SetIdentity()
+	}
+	return points
+}
 
-	lhsP3 := new(GG.Gt)
-	lhsP3.Mul(lhsP2, inv)
+// keyPoints returns g2, X, Y_1, ..., Y_n of pk.
+func keyPoints(pk *PS.PublicKey) []*GG.G2 {
+	return append([]*GG.G2{pk.G, pk.X}, pk.Y...)
+}
 
-	lhsP4 := new(GG.Gt)
-	lhsP4.Mul(lhsP3, pi.a2)
+// addSignatureTerms adds the G1 points of the signature equation of pi, raised to d, to points.
+func addSignatureTerms(points []*GG.G1, pi Proof, p PublicInputs, z *GG.Scalar, idx []int, d *GG.Scalar) {
+	negSig1 := new(GG.G1)
+	*negSig1 = *pi.sig.One
+	negSig1.Neg()
+	ds := new(GG.Scalar)
+	add := func(k int, s *GG.Scalar, q *GG.G1) { // points[k] += d*s*q
+		ds.Mul(d, s)
+		points[k].Add(points[k], utils.GenerateG1Point(ds, q))
+	}
+	add(0, z, pi.sig.Two)
+	add(0, pi.rt, negSig1)
+	add(1, z, negSig1)
+	for k, msg := range p.Disclosed {
+		m := utils.HashToScalar(msg, p.PC.Dst)
+		m.Mul(&m, z)
+		add(k+2, &m, negSig1)
+	}
+	for j, k := range idx {
+		add(k+2, pi.rm[j], negSig1)
+	}
+}
 
-	gt := utils.GenerateG2Point(pi.r3, p.PS.G)
-	ym := utils.GenerateG2Point(pi.r1, p.PS.Y)
+// ones returns n signs for GG.ProdPairFrac. The scalars are in the G1 points already, which saves the exponentiations
+// in Fp12 that GG.ProdPair does.
+func ones(n int) []int {
+	signs := make([]int, n)
+	for i := range signs {
+		signs[i] = 1
+	}
+	return signs
+}
 
-	rhsG2 := utils.AddG2Points(ym, gt)
+// batchCommitment accumulates the commitment equations r0*G + ro*H = z*Com + a1 under one set of parameters, raised
+// to the randomizers d, as (sum d*r0)*G + (sum d*ro)*H = sum d*(z*Com + a1).
+type batchCommitment struct {
+	g, h GG.Scalar
+	rhs  *GG.G1
+}
 
-	rhsP1 := GG.Pair(pi.sig.One, rhsG2)
+// BatchVerify checks proofs[i] for inputs[i] and aux[i] at once, by verifying a random linear combination of the
+// equations of Verify. The commitment equations are summed up per set of commitment parameters, and the signature
+// equations per key, so that a batch under one key with n messages costs one product of n+2 pairings, whatever its
+// size, plus a few scalar multiplications in G1 and one exponentiation in Gt per proof. Keys and commitment parameters
+// are told apart by pointer, and each key is checked once. The randomizers d are drawn from rnd, or from crypto/rand if
+// rnd is nil. BatchVerify returns false if any proof is invalid, without telling which; an empty batch is invalid.
+func BatchVerify(rnd io.Reader, proofs []Proof, inputs []PublicInputs, aux [][]byte) bool {
+	if len(proofs) == 0 || len(proofs) != len(inputs) || len(proofs) != len(aux) {
+		return false
+	}
+	var (
+		keys  []*PS.PublicKey
+		sig   = make(map[*PS.PublicKey][]*GG.G1) // G1 points paired with g2, X, Y_1, ..., Y_n of each key
+		pcs   []*PC.PublicParams
+		com   = make(map[*PC.PublicParams]*batchCommitment)
+		a2    = new(GG.Gt)
+		terms = new(GG.Scalar)
+	)
+	a2.SetIdentity()
+	for i, pi := range proofs {
+		p := inputs[i]
+		points, seen := sig[p.PS]
+		if !seen {
+			if !p.PS.IsValid() {
+				return false
+			}
+			points = signaturePoints(p.PS)
+			sig[p.PS] = points
+			keys = append(keys, p.PS)
+		}
+		if p.PC == nil || !utils.IsValidG1(p.PC.G) || !utils.IsValidG1(p.PC.H) || p.Com == nil ||
+			!utils.IsValidG1(p.Com.Element) {
+			return false
+		}
+		bc, seen := com[p.PC]
+		if !seen {
+			bc = &batchCommitment{rhs: new(GG.G1)}
+			bc.rhs.SetIdentity()
+			com[p.PC] = bc
+			pcs = append(pcs, p.PC)
+		}
+
+		z, idx, ok := prepare(pi, p, aux[i])
+		if !ok {
+			return false
+		}
+		d, err := utils.GenerateRandomScalarFrom(rnd)
+		if err != nil || d.IsZero() == 1 {
+			return false
+		}
+		dz := new(GG.Scalar)
+		dz.Mul(d, &z)
+
+		// Commitment
+		terms.Mul(d, pi.rm[0])
+		bc.g.Add(&bc.g, terms)
+		terms.Mul(d, pi.ro)
+		bc.h.Add(&bc.h, terms)
+		bc.rhs.Add(bc.rhs, utils.GenerateG1Point(dz, p.Com.Element))
+		bc.rhs.Add(bc.rhs, utils.GenerateG1Point(d, pi.a1))
+
+		// Signature
+		addSignatureTerms(points, pi, p, &z, idx, d)
+
+		a2d := new(GG.Gt)
+		a2d.Exp(pi.a2, d)
+		a2.Mul(a2, a2d)
+	}
 
-	validSignature := lhsP4.IsEqual(rhsP1)
-	if !validSignature {
-		log.Println("Invalid signature")
+	for _, pc := range pcs {
+		bc := com[pc]
+		lhs := utils.AddG1Points(pc.MulG(&bc.g), pc.MulH(&bc.h))
+		if !lhs.IsEqual(bc.rhs) {
+			return false
+		}
 	}
 
-	return validSignature && validCommitment
+	var (
+		P []*GG.G1
+		Q []*GG.G2
+	)
+	for _, pk := range keys {
+		P = append(P, sig[pk]...)
+		Q = append(Q, keyPoints(pk)...)
+	}
+	res := GG.ProdPairFrac(P, Q, ones(len(P)))
+	res.Mul(res, a2)
+	return res.IsIdentity()
+}
+
+// ProofSize returns the length of an encoded proof with the given number of hidden messages: the randomized signature,
+// a1, a2 and the responses.
+func ProofSize(hidden int) int {
+	return PS.SignatureSize + GG.G1SizeCompressed + GG.GtSize + (hidden+2)*GG.ScalarSize
+}
+
+func (pi Proof) MarshalBinary() ([]byte, error) {
+	if pi.sig == nil || pi.a1 == nil || pi.a2 == nil || len(pi.rm) == 0 || pi.ro == nil || pi.rt == nil {
+		return nil, errors.New("comsig: empty proof")
+	}
+	sigBytes, err := pi.sig.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	a2Bytes, err := pi.a2.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 0, ProofSize(len(pi.rm)))
+	buf = append(buf, sigBytes...)
+	buf = append(buf, pi.a1.BytesCompressed()...)
+	buf = append(buf, a2Bytes...)
+	buf = append(buf, utils.ScalarBytes(pi.ro)...)
+	buf = append(buf, utils.ScalarBytes(pi.rt)...)
+	for _, r := range pi.rm {
+		if r == nil {
+			return nil, errors.New("comsig: empty proof")
+		}
+		buf = append(buf, utils.ScalarBytes(r)...)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary decodes a proof; the number of hidden messages follows from the length of data.
+func (pi *Proof) UnmarshalBinary(data []byte) error {
+	rest := len(data) - ProofSize(0)
+	if rest < GG.ScalarSize || rest%GG.ScalarSize != 0 {
+		return errors.New("comsig: invalid proof length")
+	}
+
+	sig := new(PS.Signature)
+	if err := sig.UnmarshalBinary(data[:PS.SignatureSize]); err != nil {
+		return err
+	}
+	data = data[PS.SignatureSize:]
+
+	a1, err := utils.DecodeG1(data[:GG.G1SizeCompressed])
+	if err != nil {
+		return err
+	}
+	data = data[GG.G1SizeCompressed:]
+
+	a2, err := utils.DecodeGt(data[:GG.GtSize])
+	if err != nil {
+		return err
+	}
+	data = data[GG.GtSize:]
+
+	r := make([]*GG.Scalar, len(data)/GG.ScalarSize)
+	for i := range r {
+		if r[i], err = utils.DecodeScalar(data[i*GG.ScalarSize : (i+1)*GG.ScalarSize]); err != nil {
+			return err
+		}
+	}
+
+	*pi = Proof{sig, a1, a2, r[2:], r[0], r[1]}
+	return nil
 }
@@ -3,6 +3,7 @@ package comsig
 import (
 	PC "OPPID-artifacts/pkg/oppid/commit/pc"
 	PS "OPPID-artifacts/pkg/oppid/sign/ps"
+	"fmt"
 	"testing"
 	"time"
 )
@@ -11,22 +12,22 @@ func BenchmarkPCPSGenProof(b *testing.B) {
 	ps := PS.Setup([]byte(dstStr))
 	pc := PC.Setup([]byte(dstStr))
 
-	sk, pk := ps.KeyGen()
+	sk, pk, _ := ps.KeyGen()
 
 	msg := []byte("Test")
 
-	sig := ps.Sign(sk, msg)
-	com, opn := pc.Commit(msg)
+	sig, _ := ps.Sign(sk, msg)
+	com, opn, _ := pc.Commit(msg)
 
-	witness := Witnesses{msg, &sig, &opn}
-	pubInput := PublicInputs{pk, pc, &com}
+	witness := Witnesses{[][]byte{msg}, &sig, &opn}
+	pubInput := PublicInputs{PS: pk, PC: pc, Com: &com}
 
 	aux := []byte("auxiliary data")
 
 	b.ResetTimer()
 	start := time.Now()
 	for i := 0; i < b.N; i++ {
-		Prove(witness, pubInput, aux, []byte(dstStr))
+		_, _ = Prove(nil, witness, pubInput, aux, []byte(dstStr))
 	}
 	elapsed := time.Since(start)
 	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
@@ -36,19 +37,19 @@ func BenchmarkPCPSProofVerify(b *testing.B) {
 	ps := PS.Setup([]byte(dstStr))
 	pc := PC.Setup([]byte(dstStr))
 
-	sk, pk := ps.KeyGen()
+	sk, pk, _ := ps.KeyGen()
 
 	msg := []byte("Test")
 
-	sig := ps.Sign(sk, msg)
-	com, opn := pc.Commit(msg)
+	sig, _ := ps.Sign(sk, msg)
+	com, opn, _ := pc.Commit(msg)
 
-	witness := Witnesses{msg, &sig, &opn}
-	pubInput := PublicInputs{pk, pc, &com}
+	witness := Witnesses{[][]byte{msg}, &sig, &opn}
+	pubInput := PublicInputs{PS: pk, PC: pc, Com: &com}
 
 	aux := []byte("auxiliary data")
 
-	proof := Prove(witness, pubInput, aux, []byte(dstStr))
+	proof, _ := Prove(nil, witness, pubInput, aux, []byte(dstStr))
 
 	b.ResetTimer()
 	start := time.Now()
@@ -61,3 +62,55 @@ func BenchmarkPCPSProofVerify(b *testing.B) {
 	elapsed := time.Since(start)
 	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
 }
+
+// BenchmarkPCPSProofVerifyDisclosed verifies a proof on four messages of which two are disclosed, as for OPPID
+// credentials with attributes.
+func BenchmarkPCPSProofVerifyDisclosed(b *testing.B) {
+	ps := PS.Setup([]byte(dstStr))
+	pc := PC.Setup([]byte(dstStr))
+
+	sk, pk, _ := ps.KeyGenMulti(4)
+
+	msgs := [][]byte{[]byte("Test"), []byte("expiry"), []byte("tier"), []byte("scopes")}
+
+	sig, _ := ps.SignMulti(sk, msgs)
+	com, opn, _ := pc.Commit(msgs[0])
+
+	witness := Witnesses{msgs, &sig, &opn}
+	pubInput := PublicInputs{PS: pk, PC: pc, Com: &com, Disclosed: map[int][]byte{1: msgs[1], 2: msgs[2]}}
+
+	aux := []byte("auxiliary data")
+
+	proof, _ := Prove(nil, witness, pubInput, aux, []byte(dstStr))
+
+	b.ResetTimer()
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		isValid := Verify(proof, pubInput, aux)
+		if !isValid {
+			b.Fatalf("verify fail")
+		}
+	}
+	elapsed := time.Since(start)
+	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
+}
+
+// BenchmarkPCPSBatchVerify verifies batches of growing size at once; ms/proof is to be compared with
+// BenchmarkPCPSProofVerify.
+func BenchmarkPCPSBatchVerify(b *testing.B) {
+	for _, n := range []int{1, 8, 32, 128} {
+		b.Run(fmt.Sprintf("batch=%d", n), func(b *testing.B) {
+			proofs, inputs, aux := batch(b, n)
+			b.ResetTimer()
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				if !BatchVerify(nil, proofs, inputs, aux) {
+					b.Fatalf("verify fail")
+				}
+			}
+			elapsed := time.Since(start)
+			b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
+			b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N*n), "ms/proof")
+		})
+	}
+}
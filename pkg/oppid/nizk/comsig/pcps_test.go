@@ -4,25 +4,29 @@ import (
 	PC "OPPID-artifacts/pkg/oppid/commit/pc"
 	PS "OPPID-artifacts/pkg/oppid/sign/ps"
 	"OPPID-artifacts/pkg/oppid/utils"
+	"fmt"
+	"slices"
 	"testing"
+
+	GG "github.com/cloudflare/circl/ecc/bls12381"
 )
 
 func TestProveVerify(t *testing.T) {
 	ps := PS.Setup([]byte(dstStr))
 	pc := PC.Setup([]byte(dstStr))
 
-	sk, pk := ps.KeyGen()
+	sk, pk, _ := ps.KeyGen()
 
 	msg := []byte("Test")
 
-	sig := ps.Sign(sk, msg)
-	com, opn := pc.Commit(msg)
+	sig, _ := ps.Sign(sk, msg)
+	com, opn, _ := pc.Commit(msg)
 
-	witness := Witnesses{msg, &sig, &opn}
-	pubInput := PublicInputs{pk, pc, &com}
+	witness := Witnesses{[][]byte{msg}, &sig, &opn}
+	pubInput := PublicInputs{PS: pk, PC: pc, Com: &com}
 
 	aux := []byte("auxiliary data")
-	proof := Prove(witness, pubInput, aux, []byte(dstStr))
+	proof, _ := Prove(nil, witness, pubInput, aux, []byte(dstStr))
 
 	isValid := Verify(proof, pubInput, aux)
 
@@ -35,21 +39,21 @@ func TestVerifyInvalidProof(t *testing.T) {
 	ps := PS.Setup([]byte(dstStr))
 	pc := PC.Setup([]byte(dstStr))
 
-	sk, pk := ps.KeyGen()
+	sk, pk, _ := ps.KeyGen()
 
 	msg := []byte("Test")
 
-	sig := ps.Sign(sk, msg)
-	com, opn := pc.Commit(msg)
+	sig, _ := ps.Sign(sk, msg)
+	com, opn, _ := pc.Commit(msg)
 
-	witness := Witnesses{msg, &sig, &opn}
-	pubInput := PublicInputs{pk, pc, &com}
+	witness := Witnesses{[][]byte{msg}, &sig, &opn}
+	pubInput := PublicInputs{PS: pk, PC: pc, Com: &com}
 
 	aux := []byte("auxiliary data")
-	proof := Prove(witness, pubInput, aux, []byte(dstStr))
+	proof, _ := Prove(nil, witness, pubInput, aux, []byte(dstStr))
 
 	// Modify the proof
-	proof.r1 = utils.GenerateRandomScalar()
+	proof.rm[0], _ = utils.GenerateRandomScalar()
 
 	isValid := Verify(proof, pubInput, aux)
 
@@ -57,3 +61,197 @@ func TestVerifyInvalidProof(t *testing.T) {
 		t.Error("invalid proof was accepted as valid")
 	}
 }
+
+func TestProofMarshalBinary(t *testing.T) {
+	ps := PS.Setup([]byte(dstStr))
+	pc := PC.Setup([]byte(dstStr))
+
+	sk, pk, _ := ps.KeyGen()
+
+	msg := []byte("Test")
+
+	sig, _ := ps.Sign(sk, msg)
+	com, opn, _ := pc.Commit(msg)
+
+	witness := Witnesses{[][]byte{msg}, &sig, &opn}
+	pubInput := PublicInputs{PS: pk, PC: pc, Com: &com}
+
+	aux := []byte("auxiliary data")
+	proof, _ := Prove(nil, witness, pubInput, aux, []byte(dstStr))
+
+	data, err := proof.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to marshal proof: %v", err)
+	}
+	if len(data) != ProofSize(1) {
+		t.Fatalf("unexpected proof size %d, want %d", len(data), ProofSize(1))
+	}
+
+	var decoded Proof
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("failed to unmarshal proof: %v", err)
+	}
+	if !Verify(decoded, pubInput, aux) {
+		t.Error("decoded proof is not valid")
+	}
+}
+
+func TestMalformedInputs(t *testing.T) {
+	ps := PS.Setup([]byte(dstStr))
+	pc := PC.Setup([]byte(dstStr))
+
+	sk, pk, _ := ps.KeyGen()
+
+	msg := []byte("Test")
+
+	sig, _ := ps.Sign(sk, msg)
+	com, opn, _ := pc.Commit(msg)
+
+	pubInput := PublicInputs{PS: pk, PC: pc, Com: &com}
+	aux := []byte("auxiliary data")
+	proof, _ := Prove(nil, Witnesses{[][]byte{msg}, &sig, &opn}, pubInput, aux, []byte(dstStr))
+
+	identity := new(GG.G1)
+	identity.SetIdentity()
+
+	if _, err := Prove(nil, Witnesses{[][]byte{msg}, &PS.Signature{One: identity, Two: identity}, &opn}, pubInput, aux, []byte(dstStr)); err == nil {
+		t.Error("Prove accepted a signature with identity components")
+	}
+	if _, err := Prove(nil, Witnesses{[][]byte{msg}, &sig, &PC.Opening{Scalar: new(GG.Scalar)}}, pubInput, aux, []byte(dstStr)); err == nil {
+		t.Error("Prove accepted a zero opening")
+	}
+
+	identityCom := PublicInputs{PS: pk, PC: pc, Com: &PC.Commitment{Element: identity}}
+	if Verify(proof, identityCom, aux) {
+		t.Error("proof was accepted for an identity commitment")
+	}
+
+	tampered := []func(p *Proof){
+		func(p *Proof) { p.sig = &PS.Signature{One: identity, Two: identity} },
+		func(p *Proof) { p.a1 = identity },
+		func(p *Proof) { p.a2 = nil },
+		func(p *Proof) { p.rt = nil },
+	}
+	for i, tamper := range tampered {
+		p := proof
+		tamper(&p)
+		if Verify(p, pubInput, aux) {
+			t.Errorf("malformed proof %d was accepted", i)
+		}
+	}
+	if Verify(Proof{}, pubInput, aux) || Verify(proof, PublicInputs{}, aux) {
+		t.Error("empty proof or public inputs were accepted")
+	}
+}
+
+func TestSelectiveDisclosure(t *testing.T) {
+	ps := PS.Setup([]byte(dstStr))
+	pc := PC.Setup([]byte(dstStr))
+
+	sk, pk, _ := ps.KeyGenMulti(4)
+
+	msgs := [][]byte{[]byte("hidden rid"), []byte("expiry"), []byte("tier"), []byte("hidden scopes")}
+
+	sig, _ := ps.SignMulti(sk, msgs)
+	com, opn, _ := pc.Commit(msgs[0])
+
+	witness := Witnesses{msgs, &sig, &opn}
+	pubInput := PublicInputs{PS: pk, PC: pc, Com: &com, Disclosed: map[int][]byte{1: msgs[1], 2: msgs[2]}}
+
+	aux := []byte("auxiliary data")
+	proof, err := Prove(nil, witness, pubInput, aux, []byte(dstStr))
+	if err != nil {
+		t.Fatalf("Prove returned an error: %v", err)
+	}
+	if !Verify(proof, pubInput, aux) {
+		t.Fatalf("proof disclosing messages 1 and 2 is not valid")
+	}
+
+	data, _ := proof.MarshalBinary()
+	if len(data) != ProofSize(2) {
+		t.Fatalf("unexpected proof size %d, want %d", len(data), ProofSize(2))
+	}
+	var decoded Proof
+	if err := decoded.UnmarshalBinary(data); err != nil || !Verify(decoded, pubInput, aux) {
+		t.Fatalf("decoded proof is not valid: %v", err)
+	}
+
+	forged := PublicInputs{PS: pk, PC: pc, Com: &com, Disclosed: map[int][]byte{1: []byte("later expiry"), 2: msgs[2]}}
+	if Verify(proof, forged, aux) {
+		t.Error("proof was accepted for another disclosed message")
+	}
+	fewer := PublicInputs{PS: pk, PC: pc, Com: &com, Disclosed: map[int][]byte{1: msgs[1]}}
+	if Verify(proof, fewer, aux) {
+		t.Error("proof was accepted for another set of disclosed messages")
+	}
+
+	if _, err := Prove(nil, witness, forged, aux, []byte(dstStr)); err == nil {
+		t.Error("Prove accepted a disclosed message that differs from the signed one")
+	}
+	committed := PublicInputs{PS: pk, PC: pc, Com: &com, Disclosed: map[int][]byte{0: msgs[0]}}
+	if _, err := Prove(nil, witness, committed, aux, []byte(dstStr)); err == nil {
+		t.Error("Prove accepted disclosing the committed message")
+	}
+	if _, err := Prove(nil, Witnesses{msgs[:3], &sig, &opn}, pubInput, aux, []byte(dstStr)); err == nil {
+		t.Error("Prove accepted too few messages")
+	}
+}
+
+// batch returns n proofs, alternating between one-message keys and four-message keys with disclosed messages.
+func batch(tb testing.TB, n int) ([]Proof, []PublicInputs, [][]byte) {
+	ps := PS.Setup([]byte(dstStr))
+	pc := PC.Setup([]byte(dstStr))
+
+	sk1, pk1, _ := ps.KeyGen()
+	sk4, pk4, _ := ps.KeyGenMulti(4)
+
+	proofs, inputs, aux := make([]Proof, n), make([]PublicInputs, n), make([][]byte, n)
+	for i := range proofs {
+		msgs := [][]byte{[]byte(fmt.Sprintf("rid %d", i))}
+		sk, pubInput := sk1, PublicInputs{PS: pk1, PC: pc}
+		if i%2 == 1 {
+			msgs = append(msgs, []byte("expiry"), []byte(fmt.Sprintf("tier %d", i)), []byte("scopes"))
+			sk, pubInput = sk4, PublicInputs{PS: pk4, PC: pc, Disclosed: map[int][]byte{1: msgs[1], 2: msgs[2]}}
+		}
+		sig, _ := ps.SignMulti(sk, msgs)
+		com, opn, _ := pc.Commit(msgs[0])
+		pubInput.Com = &com
+
+		aux[i] = []byte(fmt.Sprintf("auxiliary data %d", i))
+		proof, err := Prove(nil, Witnesses{msgs, &sig, &opn}, pubInput, aux[i], []byte(dstStr))
+		if err != nil {
+			tb.Fatalf("Prove returned an error: %v", err)
+		}
+		proofs[i], inputs[i] = proof, pubInput
+	}
+	return proofs, inputs, aux
+}
+
+func TestBatchVerify(t *testing.T) {
+	proofs, inputs, aux := batch(t, 5)
+	if !BatchVerify(nil, proofs, inputs, aux) {
+		t.Fatalf("batch of valid proofs is not valid")
+	}
+	if !BatchVerify(nil, proofs[:1], inputs[:1], aux[:1]) {
+		t.Errorf("batch of one valid proof is not valid")
+	}
+
+	swapped := slices.Clone(aux)
+	swapped[1], swapped[3] = swapped[3], swapped[1]
+	if BatchVerify(nil, proofs, inputs, swapped) {
+		t.Errorf("batch was accepted with proofs bound to other aux")
+	}
+
+	tampered := slices.Clone(proofs)
+	tampered[2].rt, _ = utils.GenerateRandomScalar()
+	if BatchVerify(nil, tampered, inputs, aux) {
+		t.Errorf("batch with an invalid proof was accepted")
+	}
+
+	if BatchVerify(nil, nil, nil, nil) {
+		t.Errorf("empty batch was accepted")
+	}
+	if BatchVerify(nil, proofs, inputs[1:], aux) {
+		t.Errorf("batch of mismatched lengths was accepted")
+	}
+}
@@ -0,0 +1,122 @@
+// Package provides a Chaum-Pedersen NIZK [1] of discrete-logarithm equality (DLEQ): for points G, X, H, Y in G1 it
+// proves knowledge of k with X = k*G and Y = k*H. The IdP uses it to show that it evaluated the PRF with the key it
+// committed to. Proofs are in the compact form (challenge, response) of [2].
+
+// References:
+// [1] https://link.springer.com/chapter/10.1007/3-540-48071-4_7
+// [2] https://www.rfc-editor.org/rfc/rfc9497#section-2.2
+
+package dleq
+
+import (
+	"OPPID-artifacts/pkg/oppid/transcript"
+	"OPPID-artifacts/pkg/oppid/utils"
+	"errors"
+	"fmt"
+	"io"
+
+	GG "github.com/cloudflare/circl/ecc/bls12381"
+)
+
+const dstStr = "OPPID_BLS12384_XMD:SHA-256_NIZK_DLEQ_"
+
+// Statement claims that X = k*G and Y = k*H for the same k.
+type Statement struct {
+	G, X *GG.G1
+	H, Y *GG.G1
+}
+
+type Proof struct {
+	c *GG.Scalar // challenge
+	s *GG.Scalar // response
+}
+
+func challenge(st Statement, a, b *GG.G1, aux []byte) GG.Scalar {
+	data := transcript.New(dstStr).
+		Append("g", st.G.Bytes()).
+		Append("x", st.X.Bytes()).
+		Append("h", st.H.Bytes()).
+		Append("y", st.Y.Bytes()).
+		Append("a", a.Bytes()).
+		Append("b", b.Bytes()).
+		Append("aux", aux).
+		Bytes()
+	return utils.HashToScalar(data, []byte(dstStr))
+}
+
+// validStatement reports whether all points of the statement are valid group elements.
+func validStatement(st Statement) bool {
+	return utils.IsValidG1(st.G) && utils.IsValidG1(st.X) && utils.IsValidG1(st.H) && utils.IsValidG1(st.Y)
+}
+
+// Prove draws its randomness from rnd, or from crypto/rand if rnd is nil. The proof is bound to aux.
+func Prove(rnd io.Reader, st Statement, k *GG.Scalar, aux []byte) (Proof, error) {
+	if !validStatement(st) || !utils.IsValidScalar(k) {
+		return Proof{}, errors.New("dleq: malformed statement or witness")
+	}
+	r, err := utils.GenerateRandomScalarFrom(rnd)
+	if err != nil {
+		return Proof{}, err
+	}
+
+	// Announcements
+	a := utils.MulG1(utils.G1GeneratorTable(), r, st.G)
+	b := utils.GenerateG1Point(r, st.H)
+
+	// Challenge
+	c := challenge(st, a, b, aux)
+
+	// Response s = r + c*k
+	ck, err1 := utils.MulScalars(&c, k)
+	s, err2 := utils.AddScalars(r, ck)
+	if err := errors.Join(err1, err2); err != nil {
+		return Proof{}, fmt.Errorf("dleq: generating proof: %w", err)
+	}
+	return Proof{&c, s}, nil
+}
+
+// Verify recomputes the announcements a = s*G - c*X and b = s*H - c*Y and checks the challenge. It returns false for
+// malformed statements and proofs.
+func Verify(st Statement, pi Proof, aux []byte) bool {
+	if !validStatement(st) || pi.c == nil || pi.s == nil {
+		return false
+	}
+	negC := new(GG.Scalar)
+	negC.Set(pi.c)
+	negC.Neg()
+
+	a := utils.AddG1Points(utils.MulG1(utils.G1GeneratorTable(), pi.s, st.G), utils.GenerateG1Point(negC, st.X))
+	b := utils.AddG1Points(utils.GenerateG1Point(pi.s, st.H), utils.GenerateG1Point(negC, st.Y))
+
+	c := challenge(st, a, b, aux)
+	return c.IsEqual(pi.c) == 1
+}
+
+// ProofSize is the length of an encoded proof: the challenge and the response.
+const ProofSize = 2 * GG.ScalarSize
+
+func (pi Proof) MarshalBinary() ([]byte, error) {
+	if pi.c == nil || pi.s == nil {
+		return nil, errors.New("dleq: empty proof")
+	}
+	buf := make([]byte, 0, ProofSize)
+	buf = append(buf, utils.ScalarBytes(pi.c)...)
+	buf = append(buf, utils.ScalarBytes(pi.s)...)
+	return buf, nil
+}
+
+func (pi *Proof) UnmarshalBinary(data []byte) error {
+	if len(data) != ProofSize {
+		return errors.New("dleq: invalid proof length")
+	}
+	c, err := utils.DecodeScalar(data[:GG.ScalarSize])
+	if err != nil {
+		return err
+	}
+	s, err := utils.DecodeScalar(data[GG.ScalarSize:])
+	if err != nil {
+		return err
+	}
+	pi.c, pi.s = c, s
+	return nil
+}
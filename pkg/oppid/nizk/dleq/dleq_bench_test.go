@@ -0,0 +1,31 @@
+package dleq
+
+import (
+	"testing"
+	"time"
+)
+
+func BenchmarkProve(b *testing.B) {
+	st, k := statement(b)
+	b.ResetTimer()
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		_, _ = Prove(nil, st, k, nil)
+	}
+	elapsed := time.Since(start)
+	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
+}
+
+func BenchmarkVerify(b *testing.B) {
+	st, k := statement(b)
+	pi, _ := Prove(nil, st, k, nil)
+	b.ResetTimer()
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		if !Verify(st, pi, nil) {
+			b.Fatalf("Expected proof to be valid")
+		}
+	}
+	elapsed := time.Since(start)
+	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
+}
@@ -0,0 +1,83 @@
+package dleq
+
+import (
+	"OPPID-artifacts/pkg/oppid/utils"
+	"testing"
+
+	GG "github.com/cloudflare/circl/ecc/bls12381"
+)
+
+func statement(tb testing.TB) (Statement, *GG.Scalar) {
+	k, err := utils.GenerateRandomScalar()
+	if err != nil {
+		tb.Fatalf("GenerateRandomScalar returned an error: %v", err)
+	}
+	g := GG.G1Generator()
+	h := new(GG.G1)
+	h.Hash([]byte("input"), []byte("Test dst"))
+	return Statement{G: g, X: utils.GenerateG1Point(k, g), H: h, Y: utils.GenerateG1Point(k, h)}, k
+}
+
+func TestProveVerify(t *testing.T) {
+	st, k := statement(t)
+	aux := []byte("aux")
+	pi, err := Prove(nil, st, k, aux)
+	if err != nil {
+		t.Fatalf("Prove returned an error: %v", err)
+	}
+	if !Verify(st, pi, aux) {
+		t.Fatalf("Expected proof to be valid")
+	}
+	if Verify(st, pi, []byte("other aux")) {
+		t.Errorf("Expected proof to be invalid for another aux")
+	}
+}
+
+func TestRejectUnequalLogs(t *testing.T) {
+	st, k := statement(t)
+	other, _ := utils.GenerateRandomScalar()
+	st.Y = utils.GenerateG1Point(other, st.H) // Y = k'*H with k' != k
+
+	pi, _ := Prove(nil, st, k, nil)
+	if Verify(st, pi, nil) {
+		t.Fatalf("Expected proof for unequal discrete logarithms to be invalid")
+	}
+}
+
+func TestVerifyMalformed(t *testing.T) {
+	st, k := statement(t)
+	pi, _ := Prove(nil, st, k, nil)
+
+	identity := new(GG.G1)
+	identity.SetIdentity()
+	malformed := st
+	malformed.Y = identity
+	if Verify(malformed, pi, nil) {
+		t.Errorf("Expected statement with identity to be rejected")
+	}
+	if Verify(st, Proof{}, nil) {
+		t.Errorf("Expected empty proof to be rejected")
+	}
+	if _, err := Prove(nil, malformed, k, nil); err == nil {
+		t.Errorf("Expected Prove to reject a malformed statement")
+	}
+}
+
+func TestProofEncoding(t *testing.T) {
+	st, k := statement(t)
+	pi, _ := Prove(nil, st, k, nil)
+	data, err := pi.MarshalBinary()
+	if err != nil || len(data) != ProofSize {
+		t.Fatalf("MarshalBinary returned %d bytes, %v", len(data), err)
+	}
+	var decoded Proof
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary returned an error: %v", err)
+	}
+	if !Verify(st, decoded, nil) {
+		t.Errorf("Expected decoded proof to be valid")
+	}
+	if err := decoded.UnmarshalBinary(data[1:]); err == nil {
+		t.Errorf("Expected truncated proof to be rejected")
+	}
+}
@@ -0,0 +1,154 @@
+// Package provides a NIZK that a Pedersen commitment C = m*G + o*H does not commit to a given value r [1]: with
+// C' = C - r*G = (m-r)*G + o*H it proves knowledge of a = 1/(m-r) and b = -o*a with G = a*C' + b*H, which exists only
+// if m != r. OPPID uses it to show that the rid of a credential is not on the IdP's revocation list. Proofs are in the
+// compact form (challenge, responses).
+
+// References:
+// [1] S. Brands, Rethinking Public Key Infrastructures and Digital Certificates, MIT Press, 2000
+
+package neq
+
+import (
+	PC "OPPID-artifacts/pkg/oppid/commit/pc"
+	"OPPID-artifacts/pkg/oppid/transcript"
+	"OPPID-artifacts/pkg/oppid/utils"
+	"errors"
+	"fmt"
+	"io"
+
+	GG "github.com/cloudflare/circl/ecc/bls12381"
+)
+
+const dstStr = "OPPID_BLS12384_XMD:SHA-256_NIZK_NEQ_"
+
+// Statement claims that Com does not commit to Value under Params. Value is hashed to a scalar like the message of
+// Params.Commit.
+type Statement struct {
+	Params *PC.PublicParams
+	Com    *PC.Commitment
+	Value  []byte
+}
+
+type Proof struct {
+	c  *GG.Scalar // challenge
+	sa *GG.Scalar // response for a
+	sb *GG.Scalar // response for b
+}
+
+// shifted returns C' = C - r*G.
+func shifted(st Statement) *GG.G1 {
+	r := utils.HashToScalar(st.Value, st.Params.Dst)
+	r.Neg()
+	return utils.AddG1Points(st.Com.Element, st.Params.MulG(&r))
+}
+
+func challenge(st Statement, a *GG.G1, aux []byte) GG.Scalar {
+	data := transcript.New(dstStr).
+		Append("g", st.Params.G.Bytes()).
+		Append("h", st.Params.H.Bytes()).
+		Append("com", st.Com.Element.Bytes()).
+		Append("value", st.Value).
+		Append("a", a.Bytes()).
+		Append("aux", aux).
+		Bytes()
+	return utils.HashToScalar(data, []byte(dstStr))
+}
+
+// validStatement reports whether the parameters and the commitment are valid group elements.
+func validStatement(st Statement) bool {
+	return st.Params != nil && utils.IsValidG1(st.Params.G) && utils.IsValidG1(st.Params.H) &&
+		st.Com != nil && utils.IsValidG1(st.Com.Element)
+}
+
+// Prove shows that the commitment to msg with opening opn in st does not commit to st.Value. It fails if msg does not
+// open the commitment or equals the value. It draws its randomness from rnd, or from crypto/rand if rnd is nil. The
+// proof is bound to aux.
+func Prove(rnd io.Reader, st Statement, msg []byte, opn PC.Opening, aux []byte) (Proof, error) {
+	if !validStatement(st) || !st.Params.Open(msg, *st.Com, opn) {
+		return Proof{}, errors.New("neq: malformed statement or witness")
+	}
+	m := utils.HashToScalar(msg, st.Params.Dst)
+	r := utils.HashToScalar(st.Value, st.Params.Dst)
+	if m.IsEqual(&r) == 1 {
+		return Proof{}, errors.New("neq: commitment is to the value")
+	}
+
+	// Witness a = 1/(m-r), b = -o*a
+	a := new(GG.Scalar)
+	a.Sub(&m, &r)
+	a.Inv(a)
+	b := new(GG.Scalar)
+	b.Mul(opn.Scalar, a)
+	b.Neg()
+
+	ua, err := utils.GenerateRandomScalarFrom(rnd)
+	if err != nil {
+		return Proof{}, err
+	}
+	ub, err := utils.GenerateRandomScalarFrom(rnd)
+	if err != nil {
+		return Proof{}, err
+	}
+
+	// Announcement
+	ann := utils.AddG1Points(utils.GenerateG1Point(ua, shifted(st)), st.Params.MulH(ub))
+
+	// Challenge
+	c := challenge(st, ann, aux)
+
+	// Responses s = u + c*w
+	ca, err1 := utils.MulScalars(&c, a)
+	sa, err2 := utils.AddScalars(ua, ca)
+	cb, err3 := utils.MulScalars(&c, b)
+	sb, err4 := utils.AddScalars(ub, cb)
+	if err := errors.Join(err1, err2, err3, err4); err != nil {
+		return Proof{}, fmt.Errorf("neq: generating proof: %w", err)
+	}
+	return Proof{&c, sa, sb}, nil
+}
+
+// Verify recomputes the announcement sa*C' + sb*H - c*G and checks the challenge. It returns false for malformed
+// statements and proofs.
+func Verify(st Statement, pi Proof, aux []byte) bool {
+	if !validStatement(st) || pi.c == nil || pi.sa == nil || pi.sb == nil {
+		return false
+	}
+	negC := new(GG.Scalar)
+	negC.Set(pi.c)
+	negC.Neg()
+
+	ann := utils.AddG1Points(utils.GenerateG1Point(pi.sa, shifted(st)), st.Params.MulH(pi.sb))
+	ann = utils.AddG1Points(ann, st.Params.MulG(negC))
+
+	c := challenge(st, ann, aux)
+	return c.IsEqual(pi.c) == 1
+}
+
+// ProofSize is the length of an encoded proof: the challenge and the two responses.
+const ProofSize = 3 * GG.ScalarSize
+
+func (pi Proof) MarshalBinary() ([]byte, error) {
+	if pi.c == nil || pi.sa == nil || pi.sb == nil {
+		return nil, errors.New("neq: empty proof")
+	}
+	buf := make([]byte, 0, ProofSize)
+	buf = append(buf, utils.ScalarBytes(pi.c)...)
+	buf = append(buf, utils.ScalarBytes(pi.sa)...)
+	buf = append(buf, utils.ScalarBytes(pi.sb)...)
+	return buf, nil
+}
+
+func (pi *Proof) UnmarshalBinary(data []byte) error {
+	if len(data) != ProofSize {
+		return errors.New("neq: invalid proof length")
+	}
+	var s [3]*GG.Scalar
+	for i := range s {
+		var err error
+		if s[i], err = utils.DecodeScalar(data[i*GG.ScalarSize : (i+1)*GG.ScalarSize]); err != nil {
+			return err
+		}
+	}
+	pi.c, pi.sa, pi.sb = s[0], s[1], s[2]
+	return nil
+}
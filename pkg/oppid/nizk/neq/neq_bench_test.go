@@ -0,0 +1,35 @@
+package neq
+
+import (
+	"testing"
+	"time"
+)
+
+func BenchmarkProve(b *testing.B) {
+	msg := []byte("rp.example.com")
+	st, opn := statement(b, msg, []byte("revoked.example.com"))
+	b.ResetTimer()
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		if _, err := Prove(nil, st, msg, opn, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+	elapsed := time.Since(start)
+	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
+}
+
+func BenchmarkVerify(b *testing.B) {
+	msg := []byte("rp.example.com")
+	st, opn := statement(b, msg, []byte("revoked.example.com"))
+	pi, _ := Prove(nil, st, msg, opn, nil)
+	b.ResetTimer()
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		if !Verify(st, pi, nil) {
+			b.Fatalf("Expected proof to be valid")
+		}
+	}
+	elapsed := time.Since(start)
+	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
+}
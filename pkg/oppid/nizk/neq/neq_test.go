@@ -0,0 +1,91 @@
+package neq
+
+import (
+	PC "OPPID-artifacts/pkg/oppid/commit/pc"
+	"testing"
+)
+
+func statement(tb testing.TB, msg, value []byte) (Statement, PC.Opening) {
+	pc := PC.Setup([]byte("Test dst"))
+	com, opn, err := pc.Commit(msg)
+	if err != nil {
+		tb.Fatalf("Commit returned an error: %v", err)
+	}
+	return Statement{Params: pc, Com: &com, Value: value}, opn
+}
+
+func TestProveVerify(t *testing.T) {
+	msg := []byte("rp.example.com")
+	st, opn := statement(t, msg, []byte("revoked.example.com"))
+	aux := []byte("aux")
+	pi, err := Prove(nil, st, msg, opn, aux)
+	if err != nil {
+		t.Fatalf("Prove returned an error: %v", err)
+	}
+	if !Verify(st, pi, aux) {
+		t.Fatalf("Expected proof to be valid")
+	}
+	if Verify(st, pi, []byte("other aux")) {
+		t.Errorf("Expected proof to be invalid for another aux")
+	}
+	other := st
+	other.Value = []byte("other.example.com")
+	if Verify(other, pi, aux) {
+		t.Errorf("Expected proof to be invalid for another value")
+	}
+}
+
+func TestRejectEqualValue(t *testing.T) {
+	msg := []byte("revoked.example.com")
+	st, opn := statement(t, msg, msg)
+	if _, err := Prove(nil, st, msg, opn, nil); err == nil {
+		t.Fatalf("Expected Prove to fail for a commitment to the value")
+	}
+
+	// A proof for another value does not carry over to the committed one.
+	other := st
+	other.Value = []byte("other.example.com")
+	pi, err := Prove(nil, other, msg, opn, nil)
+	if err != nil {
+		t.Fatalf("Prove returned an error: %v", err)
+	}
+	if Verify(st, pi, nil) {
+		t.Fatalf("Expected proof to be invalid for the committed value")
+	}
+}
+
+func TestVerifyMalformed(t *testing.T) {
+	msg := []byte("rp.example.com")
+	st, opn := statement(t, msg, []byte("revoked.example.com"))
+	pi, _ := Prove(nil, st, msg, opn, nil)
+
+	if Verify(Statement{Value: st.Value}, pi, nil) {
+		t.Errorf("Expected empty statement to be rejected")
+	}
+	if Verify(st, Proof{}, nil) {
+		t.Errorf("Expected empty proof to be rejected")
+	}
+	if _, err := Prove(nil, st, []byte("other.example.com"), opn, nil); err == nil {
+		t.Errorf("Expected Prove to reject a message that does not open the commitment")
+	}
+}
+
+func TestProofEncoding(t *testing.T) {
+	msg := []byte("rp.example.com")
+	st, opn := statement(t, msg, []byte("revoked.example.com"))
+	pi, _ := Prove(nil, st, msg, opn, nil)
+	data, err := pi.MarshalBinary()
+	if err != nil || len(data) != ProofSize {
+		t.Fatalf("MarshalBinary returned %d bytes, %v", len(data), err)
+	}
+	var decoded Proof
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary returned an error: %v", err)
+	}
+	if !Verify(st, decoded, nil) {
+		t.Errorf("Expected decoded proof to be valid")
+	}
+	if err := decoded.UnmarshalBinary(data[1:]); err == nil {
+		t.Errorf("Expected truncated proof to be rejected")
+	}
+}
@@ -7,8 +7,11 @@ package sig
 
 import (
 	PS "OPPID-artifacts/pkg/oppid/sign/ps"
+	"OPPID-artifacts/pkg/oppid/transcript"
 	"OPPID-artifacts/pkg/oppid/utils"
-	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 
 	GG "github.com/cloudflare/circl/ecc/bls12381"
@@ -38,10 +41,19 @@ type RandomizedSignature struct {
 	Sig      *PS.Signature
 }
 
-// Randomize corresponds to Sec. 6.2 of the paper [1, p.9].
-func Randomize(sig *PS.Signature) (*GG.Scalar, *PS.Signature) {
-	r := utils.GenerateRandomScalar()
-	t := utils.GenerateRandomScalar()
+// Randomize corresponds to Sec. 6.2 of the paper [1, p.9]. It draws from rnd, or from crypto/rand if rnd is nil.
+func Randomize(rnd io.Reader, sig *PS.Signature) (*GG.Scalar, *PS.Signature, error) {
+	if sig == nil || !utils.IsValidG1(sig.One) || !utils.IsValidG1(sig.Two) {
+		return nil, nil, errors.New("sig: malformed signature")
+	}
+	r, err := utils.GenerateRandomScalarNotOneFrom(rnd)
+	if err != nil {
+		return nil, nil, err
+	}
+	t, err := utils.GenerateRandomScalarFrom(rnd)
+	if err != nil {
+		return nil, nil, err
+	}
 	rndSig := new(PS.Signature)
 
 	rndSig.One = utils.GenerateG1Point(r, sig.One) // sig1^r
@@ -50,33 +62,48 @@ func Randomize(sig *PS.Signature) (*GG.Scalar, *PS.Signature) {
 	t1 := utils.GenerateG1Point(t, rndSig.One) // sig1^rt
 	rndSig.Two = utils.AddG1Points(r2, t1)     // sig2^r * sig1^tr
 
-	return t, rndSig // (sig1^r, (sig2 * sig1^BldValue)^r)
+	return t, rndSig, nil // (sig1^r, (sig2 * sig1^BldValue)^r)
 }
 
-func Prove(p PublicInput, w Witness) Proof {
-	u1 := utils.GenerateRandomScalar()
-	u2 := utils.GenerateRandomScalar()
-	t, rndSig := Randomize(w.sig)
+func challenge(rndSig *PS.Signature, a1 *GG.Gt) GG.Scalar {
+	a1Bytes, _ := a1.MarshalBinary()
+	data := transcript.New(DSTStr).
+		Append("sig1", rndSig.One.Bytes()).
+		Append("sig2", rndSig.Two.Bytes()).
+		Append("a1", a1Bytes).
+		Bytes()
+	return utils.HashToScalar(data, []byte(DSTStr))
+}
+
+func Prove(rnd io.Reader, p PublicInput, w Witness) (Proof, error) {
+	if !p.psPk.IsValid() || p.psPk.Len() != 1 {
+		return Proof{}, errors.New("sig: malformed public key")
+	}
+	u1, err := utils.GenerateRandomScalarFrom(rnd)
+	if err != nil {
+		return Proof{}, err
+	}
+	u2, err := utils.GenerateRandomScalarFrom(rnd)
+	if err != nil {
+		return Proof{}, err
+	}
+	t, rndSig, err := Randomize(rnd, w.sig)
+	if err != nil {
+		return Proof{}, err
+	}
 
 	var pi Proof
 	pi.rndSig = rndSig
 
 	// Announcements
-	y := utils.GenerateG2Point(u1, p.psPk.Y)
-	g := utils.GenerateG2Point(u2, p.psPk.G)
+	y := p.psPk.MulY(0, u1)
+	g := p.psPk.MulG(u2)
 	yg := utils.AddG2Points(y, g)
 
 	pi.a1 = GG.Pair(rndSig.One, yg)
 
 	// Challenge
-	var buf bytes.Buffer
-
-	a1Bytes, _ := pi.a1.MarshalBinary()
-	buf.Write(rndSig.One.Bytes())
-	buf.Write(rndSig.Two.Bytes())
-	buf.Write(a1Bytes)
-
-	z := utils.HashToScalar(buf.Bytes(), []byte(DSTStr))
+	z := challenge(rndSig, pi.a1)
 
 	// Responses
 	m := utils.HashToScalar(w.msg, p.psPp.Dst)
@@ -87,50 +114,39 @@ func Prove(p PublicInput, w Witness) Proof {
 	tz, err3 := utils.MulScalars(t, &z)
 	s2, err4 := utils.AddScalars(u2, tz)
 
-	if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
-		log.Fatalf("error generating proof of a PS signature: %v, %v, %v, %v", err1, err2, err3, err4)
+	if err := errors.Join(err1, err2, err3, err4); err != nil {
+		return Proof{}, fmt.Errorf("sig: generating proof of a PS signature: %w", err)
 	}
 
 	pi.s1 = s1
 	pi.s2 = s2
 
-	return pi
+	return pi, nil
 }
 
+// Verify returns false for malformed proofs, e.g., with missing or identity components.
 func Verify(p PublicInput, pi Proof) bool {
-	var buf bytes.Buffer
-
-	a1Bytes, _ := pi.a1.MarshalBinary()
-	buf.Write(pi.rndSig.One.Bytes())
-	buf.Write(pi.rndSig.Two.Bytes())
-	buf.Write(a1Bytes)
-
-	z := utils.HashToScalar(buf.Bytes(), []byte(DSTStr))
-
-	z1 := utils.GenerateG1Point(&z, pi.rndSig.Two)
-
-	p1 := GG.Pair(z1, p.psPk.G)
-
-	z2 := utils.GenerateG1Point(&z, pi.rndSig.One)
-
-	p2 := GG.Pair(z2, p.psPk.X)
-
-	p2Inv := new(GG.Gt)
-	p2Inv.Inv(p2)
-
-	h1 := new(GG.Gt)
-	h1.Mul(p1, p2Inv)
+	if !p.psPk.IsValid() || p.psPk.Len() != 1 || pi.rndSig == nil || !utils.IsValidG1(pi.rndSig.One) || !utils.IsValidG1(pi.rndSig.Two) ||
+		pi.a1 == nil || pi.s1 == nil || pi.s2 == nil {
+		return false
+	}
 
-	lhs := new(GG.Gt)
-	lhs.Mul(h1, pi.a1)
+	z := challenge(pi.rndSig, pi.a1)
 
-	y := utils.GenerateG2Point(pi.s1, p.psPk.Y)
-	g := utils.GenerateG2Point(pi.s2, p.psPk.G)
-	yg := utils.AddG2Points(y, g)
+	// e(z*sig2, g2) * e(z*sig1, X)^-1 * a1 = e(sig1, s1*Y + s2*g2), with the scalars moved to G1 so that it takes
+	// one product of pairings with the points of the key:
+	// e(z*sig2 - s2*sig1, g2) * e(z*sig1, X)^-1 * e(s1*sig1, Y)^-1 * a1 = 1
+	negS2 := new(GG.Scalar)
+	negS2.Set(pi.s2)
+	negS2.Neg()
+	g := utils.AddG1Points(utils.GenerateG1Point(&z, pi.rndSig.Two), utils.GenerateG1Point(negS2, pi.rndSig.One))
+	x := utils.GenerateG1Point(&z, pi.rndSig.One)
+	y := utils.GenerateG1Point(pi.s1, pi.rndSig.One)
 
-	rhs := GG.Pair(pi.rndSig.One, yg)
+	res := GG.ProdPairFrac([]*GG.G1{g, x, y}, []*GG.G2{p.psPk.G, p.psPk.X, p.psPk.Y[0]}, []int{1, -1, -1})
+	res.Mul(res, pi.a1)
 
-	isValid := lhs.IsEqual(rhs)
+	isValid := res.IsIdentity()
 	if !isValid {
 		log.Println("Invalid PS signature")
 	}
@@ -8,10 +8,10 @@ import (
 
 func BenchmarkPSGenProof(b *testing.B) {
 	ps := PS.Setup(nil)
-	sk, pk := ps.KeyGen()
+	sk, pk, _ := ps.KeyGen()
 	msg := []byte("test")
 
-	sig := ps.Sign(sk, msg)
+	sig, _ := ps.Sign(sk, msg)
 
 	publicInput := PublicInput{ps, pk}
 	witness := Witness{msg, &sig}
@@ -19,7 +19,7 @@ func BenchmarkPSGenProof(b *testing.B) {
 	b.ResetTimer()
 	start := time.Now()
 	for i := 0; i < b.N; i++ {
-		Prove(publicInput, witness)
+		_, _ = Prove(nil, publicInput, witness)
 	}
 	elapsed := time.Since(start)
 	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
@@ -27,15 +27,15 @@ func BenchmarkPSGenProof(b *testing.B) {
 
 func BenchmarkPSProofVerify(b *testing.B) {
 	ps := PS.Setup(nil)
-	sk, pk := ps.KeyGen()
+	sk, pk, _ := ps.KeyGen()
 	msg := []byte("test")
 
-	sig := ps.Sign(sk, msg)
+	sig, _ := ps.Sign(sk, msg)
 
 	pubInput := PublicInput{ps, pk}
 	witness := Witness{msg, &sig}
 
-	proof := Prove(pubInput, witness)
+	proof, _ := Prove(nil, pubInput, witness)
 
 	b.ResetTimer()
 	start := time.Now()
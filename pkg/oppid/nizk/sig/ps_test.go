@@ -3,15 +3,17 @@ package sig
 import (
 	PS "OPPID-artifacts/pkg/oppid/sign/ps"
 	"testing"
+
+	GG "github.com/cloudflare/circl/ecc/bls12381"
 )
 
 func TestRandomizeSignature(t *testing.T) {
 	ps := PS.Setup(nil)
-	sk, _ := ps.KeyGen()
+	sk, _, _ := ps.KeyGen()
 
-	sig := ps.Sign(sk, []byte("Test"))
+	sig, _ := ps.Sign(sk, []byte("Test"))
 
-	_, randSig := Randomize(&sig)
+	_, randSig, _ := Randomize(nil, &sig)
 
 	if randSig.One == nil {
 		t.Error("randomized signature One is nil")
@@ -33,18 +35,44 @@ func TestRandomizeSignature(t *testing.T) {
 
 func TestProveVerify(t *testing.T) {
 	ps := PS.Setup(nil)
-	sk, pk := ps.KeyGen()
+	sk, pk, _ := ps.KeyGen()
 	msg := []byte("test")
 
-	sig := ps.Sign(sk, msg)
+	sig, _ := ps.Sign(sk, msg)
 
 	pubInput := PublicInput{ps, pk}
 	witness := Witness{msg, &sig}
 
-	proof := Prove(pubInput, witness)
+	proof, _ := Prove(nil, pubInput, witness)
 
 	isValid := Verify(pubInput, proof)
 	if !isValid {
 		t.Errorf("Verify(%v, %v) returned %v", pubInput, proof, isValid)
 	}
 }
+
+func TestMalformedInputs(t *testing.T) {
+	ps := PS.Setup(nil)
+	sk, pk, _ := ps.KeyGen()
+	msg := []byte("test")
+	sig, _ := ps.Sign(sk, msg)
+
+	identity := new(GG.G1)
+	identity.SetIdentity()
+
+	if _, _, err := Randomize(nil, &PS.Signature{One: identity, Two: sig.Two}); err == nil {
+		t.Errorf("Randomize accepted a signature with an identity component")
+	}
+	if _, err := Prove(nil, PublicInput{ps, pk}, Witness{msg, &PS.Signature{}}); err == nil {
+		t.Errorf("Prove accepted an empty signature")
+	}
+
+	proof, _ := Prove(nil, PublicInput{ps, pk}, Witness{msg, &sig})
+	proof.rndSig = &PS.Signature{One: identity, Two: identity}
+	if Verify(PublicInput{ps, pk}, proof) {
+		t.Errorf("Verify accepted a proof with an identity signature")
+	}
+	if Verify(PublicInput{ps, pk}, Proof{}) {
+		t.Errorf("Verify accepted an empty proof")
+	}
+}
@@ -2,6 +2,7 @@ package dl
 
 import (
 	"OPPID-artifacts/pkg/oppid/utils"
+	"io"
 
 	GG "github.com/cloudflare/circl/ecc/bls12381"
 )
@@ -10,8 +11,8 @@ const dstStr = "OPPID_BLS12384_XMD:SHA-256_DL_PRF"
 
 type Key = GG.Scalar
 
-func KeyGen() *Key {
-	return utils.GenerateRandomScalar()
+func KeyGen(rnd io.Reader) (*Key, error) {
+	return utils.GenerateRandomScalarFrom(rnd)
 }
 
 func Eval(k *Key, msg []byte) *GG.G1 {
@@ -19,3 +20,8 @@ func Eval(k *Key, msg []byte) *GG.G1 {
 	g.Hash(msg, []byte(dstStr))
 	return utils.GenerateG1Point(k, g)
 }
+
+// BlindEval evaluates the PRF on an input that is already a (possibly blinded) point, i.e., returns x^k.
+func BlindEval(k *Key, x *GG.G1) *GG.G1 {
+	return utils.GenerateG1Point(k, x)
+}
@@ -1,9 +1,11 @@
 package fk
 
 import (
+	DLEQ "OPPID-artifacts/pkg/oppid/nizk/dleq"
 	DLPRF "OPPID-artifacts/pkg/oppid/prf/dl"
 	HMACPRF "OPPID-artifacts/pkg/oppid/prf/hmac256"
 	"OPPID-artifacts/pkg/oppid/utils"
+	"io"
 
 	GG "github.com/cloudflare/circl/ecc/bls12381"
 )
@@ -12,12 +14,48 @@ const dstStr = "OPPID_BLS12384_XMD:SHA-256_FK_"
 
 type Key = HMACPRF.Key
 
-func KeyGen() *Key {
-	return HMACPRF.KeyGen()
+func KeyGen(rnd io.Reader) (*Key, error) {
+	return HMACPRF.KeyGen(rnd)
 }
 
 func Eval(k *Key, msg1, msg2 []byte) *GG.G1 {
-	y := HMACPRF.Eval(k, msg2)
-	key := utils.HashToScalar(y, []byte(dstStr))
+	key := innerKey(k, msg2)
 	return DLPRF.Eval(&key, msg1)
 }
+
+// BlindEval evaluates the PRF on a blinded point bx = x^b, such that exponentiating the output with 1/b yields x^k',
+// where k' is the key derived from msg2.
+func BlindEval(k *Key, bx *GG.G1, msg2 []byte) *GG.G1 {
+	key := innerKey(k, msg2)
+	return DLPRF.BlindEval(&key, bx)
+}
+
+// Commit returns the public commitment k'*g to the key k' derived from msg2, with g the generator of G1.
+func Commit(k *Key, msg2 []byte) *GG.G1 {
+	key := innerKey(k, msg2)
+	return utils.MulG1Generator(&key)
+}
+
+// VerifiableBlindEval evaluates like BlindEval and proves that it used the key committed to by Commit. The proof is
+// bound to aux and draws its randomness from rnd, or from crypto/rand if rnd is nil.
+func VerifiableBlindEval(rnd io.Reader, k *Key, bx *GG.G1, msg2, aux []byte) (*GG.G1, DLEQ.Proof, error) {
+	key := innerKey(k, msg2)
+	by := DLPRF.BlindEval(&key, bx)
+	g := GG.G1Generator()
+	st := DLEQ.Statement{G: g, X: utils.MulG1Generator(&key), H: bx, Y: by}
+	pi, err := DLEQ.Prove(rnd, st, &key, aux)
+	if err != nil {
+		return nil, DLEQ.Proof{}, err
+	}
+	return by, pi, nil
+}
+
+// VerifyBlindEval checks that by was evaluated on bx with the key committed to by kc.
+func VerifyBlindEval(kc, bx, by *GG.G1, pi DLEQ.Proof, aux []byte) bool {
+	return DLEQ.Verify(DLEQ.Statement{G: GG.G1Generator(), X: kc, H: bx, Y: by}, pi, aux)
+}
+
+func innerKey(k *Key, msg []byte) GG.Scalar {
+	y := HMACPRF.Eval(k, msg)
+	return utils.HashToScalar(y, []byte(dstStr))
+}
@@ -1,11 +1,14 @@
 package fk
 
 import (
+	"OPPID-artifacts/pkg/oppid/utils"
 	"testing"
+
+	GG "github.com/cloudflare/circl/ecc/bls12381"
 )
 
 func TestFKEval(t *testing.T) {
-	key := KeyGen()
+	key, _ := KeyGen(nil)
 
 	msg1 := []byte("Inner test message")
 	msg2 := []byte("Outer test message")
@@ -15,3 +18,48 @@ func TestFKEval(t *testing.T) {
 		t.Fatalf("output is invalid")
 	}
 }
+
+func TestFKBlindEval(t *testing.T) {
+	key, _ := KeyGen(nil)
+
+	x := new(GG.G1)
+	x.Hash([]byte("Inner test message"), []byte("Test dst"))
+	msg2 := []byte("Outer test message")
+
+	b, _ := utils.GenerateRandomScalar()
+	bInv := new(GG.Scalar)
+	bInv.Inv(b)
+
+	y1 := utils.GenerateG1Point(bInv, BlindEval(key, utils.GenerateG1Point(b, x), msg2))
+	y2 := BlindEval(key, x, msg2)
+	if !y1.IsEqual(y2) {
+		t.Fatalf("unblinded output does not match the evaluation on the unblinded input")
+	}
+}
+
+func TestFKVerifiableBlindEval(t *testing.T) {
+	key, _ := KeyGen(nil)
+	other, _ := KeyGen(nil)
+
+	bx := new(GG.G1)
+	bx.Hash([]byte("Inner test message"), []byte("Test dst"))
+	msg2 := []byte("Outer test message")
+	aux := []byte("aux")
+
+	by, pi, err := VerifiableBlindEval(nil, key, bx, msg2, aux)
+	if err != nil {
+		t.Fatalf("VerifiableBlindEval returned an error: %v", err)
+	}
+	if !by.IsEqual(BlindEval(key, bx, msg2)) {
+		t.Fatalf("verifiable evaluation differs from BlindEval")
+	}
+	if !VerifyBlindEval(Commit(key, msg2), bx, by, pi, aux) {
+		t.Fatalf("Expected evaluation proof to be valid")
+	}
+	if VerifyBlindEval(Commit(key, []byte("Other outer message")), bx, by, pi, aux) {
+		t.Errorf("Expected proof to be invalid under the commitment of another derived key")
+	}
+	if VerifyBlindEval(Commit(other, msg2), bx, by, pi, aux) {
+		t.Errorf("Expected proof to be invalid under the commitment of another key")
+	}
+}
@@ -4,17 +4,22 @@ import (
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
-	"log"
+	"fmt"
+	"io"
 )
 
 type Key = []byte
 
-func KeyGen() *Key {
+// KeyGen draws a key from rnd, or from crypto/rand if rnd is nil.
+func KeyGen(rnd io.Reader) (*Key, error) {
+	if rnd == nil {
+		rnd = rand.Reader
+	}
 	k := make([]byte, 32)
-	if _, err := rand.Read(k); err != nil {
-		log.Fatalf("Fatal error creating random hmacPRF key: %v", err)
+	if _, err := io.ReadFull(rnd, k); err != nil {
+		return nil, fmt.Errorf("creating random hmacPRF key: %w", err)
 	}
-	return &k
+	return &k, nil
 }
 
 func Eval(k *Key, msg []byte) []byte {
@@ -0,0 +1,404 @@
+// Package implements BLS signatures [1] on BLS12-381 in the proof-of-possession scheme, in both variants: min-sig
+// (signatures in G1, public keys in G2) and min-pk (public keys in G1, signatures in G2). Signatures are deterministic
+// and can be aggregated; a batch of signatures is verified with a single multi-pairing.
+//
+// Aggregation is only secure for public keys whose possession has been proven, see Prove and VerifyPossession.
+
+// References:
+// [1] https://datatracker.ietf.org/doc/html/draft-irtf-cfrg-bls-signature-05
+// [2] https://eprint.iacr.org/2018/483.pdf (batch verification with random linear combinations, Sec. 6.1)
+
+package bls
+
+import (
+	"OPPID-artifacts/pkg/oppid/sign"
+	"OPPID-artifacts/pkg/oppid/utils"
+	"errors"
+	"io"
+
+	GG "github.com/cloudflare/circl/ecc/bls12381"
+)
+
+const (
+	MinSig = "BLS12381-MinSig"
+	MinPK  = "BLS12381-MinPK"
+)
+
+// Ciphersuite identifiers of [1], Sec. 4.2.3, used as domain separation tags.
+const (
+	minSigDst    = "BLS_SIG_BLS12381G1_XMD:SHA-256_SSWU_RO_POP_"
+	minSigPopDst = "BLS_POP_BLS12381G1_XMD:SHA-256_SSWU_RO_POP_"
+	minPKDst     = "BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_"
+	minPKPopDst  = "BLS_POP_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_"
+)
+
+type PublicParams struct {
+	minPK bool
+}
+
+var (
+	_ sign.Scheme        = (*PublicParams)(nil)
+	_ sign.BatchVerifier = (*PublicParams)(nil)
+)
+
+// PublicKey is a point in G2 (min-sig) or in G1 (min-pk).
+type PublicKey struct {
+	g1 *GG.G1
+	g2 *GG.G2
+}
+
+type PrivateKey struct {
+	minPK bool
+	x     *GG.Scalar
+}
+
+// SetupMinSig returns the variant with the shorter signatures, which suits tokens.
+func SetupMinSig() *PublicParams {
+	return &PublicParams{minPK: false}
+}
+
+// SetupMinPK returns the variant with the shorter public keys, which suits aggregating many keys.
+func SetupMinPK() *PublicParams {
+	return &PublicParams{minPK: true}
+}
+
+func (pp *PublicParams) Name() string {
+	if pp.minPK {
+		return MinPK
+	}
+	return MinSig
+}
+
+// SignatureSize is the length of an encoded signature: a compressed point.
+func (pp *PublicParams) SignatureSize() int {
+	if pp.minPK {
+		return GG.G2SizeCompressed
+	}
+	return GG.G1SizeCompressed
+}
+
+func (pp *PublicParams) KeyGen(rnd io.Reader) (sign.PrivateKey, sign.PublicKey, error) {
+	x, err := utils.GenerateRandomScalarFrom(rnd)
+	if err != nil {
+		return nil, nil, err
+	}
+	if x.IsZero() == 1 {
+		return nil, nil, errors.New("bls: zero private key")
+	}
+	sk := &PrivateKey{pp.minPK, x}
+	return sk, sk.Public(), nil
+}
+
+func (pp *PublicParams) privateKey(k sign.PrivateKey) (*PrivateKey, error) {
+	sk, ok := k.(*PrivateKey)
+	if !ok || sk == nil || !utils.IsValidScalar(sk.x) || sk.minPK != pp.minPK {
+		return nil, errors.New("bls: empty private key")
+	}
+	return sk, nil
+}
+
+// publicKey returns the key if it is a valid point of the variant's key group ([1], Sec. 2.5).
+func (pp *PublicParams) publicKey(p sign.PublicKey) (*PublicKey, bool) {
+	pk, ok := p.(*PublicKey)
+	if !ok || pk == nil {
+		return nil, false
+	}
+	if pp.minPK {
+		return pk, utils.IsValidG1(pk.g1)
+	}
+	return pk, utils.IsValidG2(pk.g2)
+}
+
+// Sign ignores rnd, as BLS signatures are deterministic.
+func (pp *PublicParams) Sign(_ io.Reader, k sign.PrivateKey, message []byte) (sign.Signature, error) {
+	sk, err := pp.privateKey(k)
+	if err != nil {
+		return nil, err
+	}
+	return pp.sign(sk, message, pp.sigDst()), nil
+}
+
+func (pp *PublicParams) sign(sk *PrivateKey, message, dst []byte) sign.Signature {
+	if pp.minPK {
+		h := new(GG.G2)
+		h.Hash(message, dst)
+		return utils.GenerateG2Point(sk.x, h).BytesCompressed()
+	}
+	h := new(GG.G1)
+	h.Hash(message, dst)
+	return utils.GenerateG1Point(sk.x, h).BytesCompressed()
+}
+
+func (pp *PublicParams) Verify(p sign.PublicKey, message []byte, signature sign.Signature) bool {
+	pk, ok := pp.publicKey(p)
+	if !ok {
+		return false
+	}
+	return pp.verify([]*PublicKey{pk}, [][]byte{message}, signature, pp.sigDst())
+}
+
+// Prove returns a proof of possession of the private key: a signature on the public key under a separate domain.
+func (pp *PublicParams) Prove(k sign.PrivateKey) (sign.Signature, error) {
+	sk, err := pp.privateKey(k)
+	if err != nil {
+		return nil, err
+	}
+	pkBytes, _ := sk.Public().MarshalBinary() // never fails for a valid key
+	return pp.sign(sk, pkBytes, pp.popDst()), nil
+}
+
+// VerifyPossession checks a proof of possession created by Prove.
+func (pp *PublicParams) VerifyPossession(p sign.PublicKey, proof sign.Signature) bool {
+	pk, ok := pp.publicKey(p)
+	if !ok {
+		return false
+	}
+	pkBytes, _ := pk.MarshalBinary()
+	return pp.verify([]*PublicKey{pk}, [][]byte{pkBytes}, proof, pp.popDst())
+}
+
+// Aggregate combines signatures into one of the same size.
+func (pp *PublicParams) Aggregate(signatures []sign.Signature) (sign.Signature, error) {
+	if len(signatures) == 0 {
+		return nil, errors.New("bls: no signatures to aggregate")
+	}
+	if pp.minPK {
+		agg := new(GG.G2)
+		agg.SetIdentity()
+		for _, s := range signatures {
+			p, err := utils.DecodeG2(s)
+			if err != nil {
+				return nil, err
+			}
+			agg.Add(agg, p)
+		}
+		return agg.BytesCompressed(), nil
+	}
+	agg := new(GG.G1)
+	agg.SetIdentity()
+	for _, s := range signatures {
+		p, err := utils.DecodeG1(s)
+		if err != nil {
+			return nil, err
+		}
+		agg.Add(agg, p)
+	}
+	return agg.BytesCompressed(), nil
+}
+
+// VerifyAggregate checks an aggregate of signatures of pks[i] on messages[i]. The keys must have proven possession.
+func (pp *PublicParams) VerifyAggregate(pks []sign.PublicKey, messages [][]byte, signature sign.Signature) bool {
+	if len(pks) == 0 || len(pks) != len(messages) {
+		return false
+	}
+	keys := make([]*PublicKey, len(pks))
+	for i, p := range pks {
+		pk, ok := pp.publicKey(p)
+		if !ok {
+			return false
+		}
+		keys[i] = pk
+	}
+	return pp.verify(keys, messages, signature, pp.sigDst())
+}
+
+// verify checks e(sig, g) = prod_i e(H(messages[i]), pks[i]) for min-sig, and the mirrored equation for min-pk, with
+// one multi-pairing. The keys must be valid.
+func (pp *PublicParams) verify(pks []*PublicKey, messages [][]byte, signature sign.Signature, dst []byte) bool {
+	n := len(pks) + 1
+	P, Q, exps := make([]*GG.G1, n), make([]*GG.G2, n), make([]*GG.Scalar, n)
+	one := new(GG.Scalar)
+	one.SetOne()
+	for i := range exps {
+		exps[i] = one
+	}
+	if pp.minPK {
+		sig, err := utils.DecodeG2(signature)
+		if err != nil || sig.IsIdentity() {
+			return false
+		}
+		P[0], Q[0] = negG1Generator(), sig
+		for i, pk := range pks {
+			h := new(GG.G2)
+			h.Hash(messages[i], dst)
+			P[i+1], Q[i+1] = pk.g1, h
+		}
+	} else {
+		sig, err := utils.DecodeG1(signature)
+		if err != nil || sig.IsIdentity() {
+			return false
+		}
+		P[0], Q[0] = sig, negG2Generator()
+		for i, pk := range pks {
+			h := new(GG.G1)
+			h.Hash(messages[i], dst)
+			P[i+1], Q[i+1] = h, pk.g2
+		}
+	}
+	return GG.ProdPair(P, Q, exps).IsIdentity()
+}
+
+// BatchVerify checks independent signatures of pks[i] on messages[i] at once, by verifying a random linear combination
+// of them [2] with one multi-pairing. Signatures under the same key share one pairing, so that a batch of a single
+// signer costs two pairings. The randomizers are drawn from rnd, or from crypto/rand if rnd is nil. Unlike
+// VerifyAggregate, BatchVerify does not require proofs of possession, as signatures cannot cancel each other out.
+func (pp *PublicParams) BatchVerify(rnd io.Reader, pks []sign.PublicKey, messages [][]byte, signatures []sign.Signature) bool {
+	if len(pks) == 0 || len(pks) != len(messages) || len(pks) != len(signatures) {
+		return false
+	}
+	// For min-sig, check e(sum_i r_i*sig_i, -g) * prod_k e(sum_{i:pk_i=k} r_i*H(m_i), k) = 1; min-pk mirrors it.
+	var (
+		sig1, sig2 = new(GG.G1), new(GG.G2)
+		hs1        []*GG.G1
+		hs2        []*GG.G2
+		keys       []*PublicKey
+		index      = make(map[string]int)
+	)
+	sig1.SetIdentity()
+	sig2.SetIdentity()
+	for i, p := range pks {
+		pk, ok := pp.publicKey(p)
+		if !ok {
+			return false
+		}
+		r, err := utils.GenerateRandomScalarFrom(rnd)
+		if err != nil || r.IsZero() == 1 {
+			return false
+		}
+		pkBytes, _ := pk.MarshalBinary()
+		k, seen := index[string(pkBytes)]
+		if !seen {
+			k = len(keys)
+			index[string(pkBytes)] = k
+			keys = append(keys, pk)
+		}
+		if pp.minPK {
+			sig, err := utils.DecodeG2(signatures[i])
+			if err != nil || sig.IsIdentity() {
+				return false
+			}
+			sig2.Add(sig2, utils.GenerateG2Point(r, sig))
+			h := new(GG.G2)
+			h.Hash(messages[i], pp.sigDst())
+			if !seen {
+				hs2 = append(hs2, new(GG.G2))
+				hs2[k].SetIdentity()
+			}
+			hs2[k].Add(hs2[k], utils.GenerateG2Point(r, h))
+		} else {
+			sig, err := utils.DecodeG1(signatures[i])
+			if err != nil || sig.IsIdentity() {
+				return false
+			}
+			sig1.Add(sig1, utils.GenerateG1Point(r, sig))
+			h := new(GG.G1)
+			h.Hash(messages[i], pp.sigDst())
+			if !seen {
+				hs1 = append(hs1, new(GG.G1))
+				hs1[k].SetIdentity()
+			}
+			hs1[k].Add(hs1[k], utils.GenerateG1Point(r, h))
+		}
+	}
+
+	n := len(keys) + 1
+	P, Q, exps := make([]*GG.G1, n), make([]*GG.G2, n), make([]*GG.Scalar, n)
+	one := new(GG.Scalar)
+	one.SetOne()
+	for i := range exps {
+		exps[i] = one
+	}
+	if pp.minPK {
+		P[0], Q[0] = negG1Generator(), sig2
+		for k, pk := range keys {
+			P[k+1], Q[k+1] = pk.g1, hs2[k]
+		}
+	} else {
+		P[0], Q[0] = sig1, negG2Generator()
+		for k, pk := range keys {
+			P[k+1], Q[k+1] = hs1[k], pk.g2
+		}
+	}
+	return GG.ProdPair(P, Q, exps).IsIdentity()
+}
+
+func (pp *PublicParams) sigDst() []byte {
+	if pp.minPK {
+		return []byte(minPKDst)
+	}
+	return []byte(minSigDst)
+}
+
+func (pp *PublicParams) popDst() []byte {
+	if pp.minPK {
+		return []byte(minPKPopDst)
+	}
+	return []byte(minSigPopDst)
+}
+
+func negG1Generator() *GG.G1 {
+	g := GG.G1Generator()
+	g.Neg()
+	return g
+}
+
+func negG2Generator() *GG.G2 {
+	g := GG.G2Generator()
+	g.Neg()
+	return g
+}
+
+func (pp *PublicParams) UnmarshalPrivateKey(data []byte) (sign.PrivateKey, error) {
+	x, err := utils.DecodeScalar(data)
+	if err != nil {
+		return nil, err
+	}
+	if x.IsZero() == 1 {
+		return nil, errors.New("bls: invalid private key")
+	}
+	return &PrivateKey{pp.minPK, x}, nil
+}
+
+// UnmarshalPublicKey decodes a compressed point and rejects the identity.
+func (pp *PublicParams) UnmarshalPublicKey(data []byte) (sign.PublicKey, error) {
+	pk := new(PublicKey)
+	var err error
+	if pp.minPK {
+		pk.g1, err = utils.DecodeG1(data)
+	} else {
+		pk.g2, err = utils.DecodeG2(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := pp.publicKey(pk); !ok {
+		return nil, errors.New("bls: invalid public key")
+	}
+	return pk, nil
+}
+
+// MarshalBinary encodes the public key as a compressed point.
+func (p *PublicKey) MarshalBinary() ([]byte, error) {
+	switch {
+	case p.g1 != nil:
+		return p.g1.BytesCompressed(), nil
+	case p.g2 != nil:
+		return p.g2.BytesCompressed(), nil
+	}
+	return nil, errors.New("bls: empty public key")
+}
+
+// MarshalBinary encodes the private key as its scalar.
+func (k *PrivateKey) MarshalBinary() ([]byte, error) {
+	if k.x == nil {
+		return nil, errors.New("bls: empty private key")
+	}
+	return utils.ScalarBytes(k.x), nil
+}
+
+func (k *PrivateKey) Public() sign.PublicKey {
+	if k.minPK {
+		return &PublicKey{g1: utils.GenerateG1Point(k.x, GG.G1Generator())}
+	}
+	return &PublicKey{g2: utils.GenerateG2Point(k.x, GG.G2Generator())}
+}
@@ -0,0 +1,76 @@
+package bls
+
+import (
+	"OPPID-artifacts/pkg/oppid/sign"
+	"fmt"
+	"testing"
+	"time"
+)
+
+const benchBatchSize = 32
+
+func benchKeysAndSigs(pp *PublicParams, n int) ([]sign.PublicKey, [][]byte, []sign.Signature) {
+	pks, msgs, sigs := make([]sign.PublicKey, n), make([][]byte, n), make([]sign.Signature, n)
+	for i := range pks {
+		sk, pk, _ := pp.KeyGen(nil)
+		msgs[i] = []byte(fmt.Sprintf("message %d", i))
+		pks[i] = pk
+		sigs[i], _ = pp.Sign(nil, sk, msgs[i])
+	}
+	return pks, msgs, sigs
+}
+
+// BenchmarkVerifyEach verifies benchBatchSize signatures one by one, as the baseline of BenchmarkBatchVerify.
+func BenchmarkVerifyEach(b *testing.B) {
+	for _, pp := range variants() {
+		b.Run(pp.Name(), func(b *testing.B) {
+			pks, msgs, sigs := benchKeysAndSigs(pp, benchBatchSize)
+			b.ResetTimer()
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				for j := range pks {
+					if !pp.Verify(pks[j], msgs[j], sigs[j]) {
+						b.Fatalf("Expected signature to be valid")
+					}
+				}
+			}
+			elapsed := time.Since(start)
+			b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
+		})
+	}
+}
+
+func BenchmarkBatchVerify(b *testing.B) {
+	for _, pp := range variants() {
+		b.Run(pp.Name(), func(b *testing.B) {
+			pks, msgs, sigs := benchKeysAndSigs(pp, benchBatchSize)
+			b.ResetTimer()
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				if !pp.BatchVerify(nil, pks, msgs, sigs) {
+					b.Fatalf("Expected batch to be valid")
+				}
+			}
+			elapsed := time.Since(start)
+			b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
+		})
+	}
+}
+
+func BenchmarkVerifyAggregate(b *testing.B) {
+	for _, pp := range variants() {
+		b.Run(pp.Name(), func(b *testing.B) {
+			pks, msgs, sigs := benchKeysAndSigs(pp, benchBatchSize)
+			agg, _ := pp.Aggregate(sigs)
+			b.ResetTimer()
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				if !pp.VerifyAggregate(pks, msgs, agg) {
+					b.Fatalf("Expected aggregate signature to be valid")
+				}
+			}
+			elapsed := time.Since(start)
+			b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
+		})
+	}
+}
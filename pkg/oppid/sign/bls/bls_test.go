@@ -0,0 +1,156 @@
+package bls
+
+import (
+	"OPPID-artifacts/pkg/oppid/sign"
+	"OPPID-artifacts/pkg/oppid/utils"
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func variants() []*PublicParams {
+	return []*PublicParams{SetupMinSig(), SetupMinPK()}
+}
+
+// keysAndSigs returns n key pairs and their signatures on distinct messages.
+func keysAndSigs(t *testing.T, pp *PublicParams, n int) ([]sign.PrivateKey, []sign.PublicKey, [][]byte, []sign.Signature) {
+	sks, pks, msgs, sigs := make([]sign.PrivateKey, n), make([]sign.PublicKey, n), make([][]byte, n), make([]sign.Signature, n)
+	for i := range sks {
+		sk, pk, err := pp.KeyGen(nil)
+		if err != nil {
+			t.Fatalf("KeyGen returned an error: %v", err)
+		}
+		msgs[i] = []byte(fmt.Sprintf("message %d", i))
+		sig, err := pp.Sign(nil, sk, msgs[i])
+		if err != nil {
+			t.Fatalf("Sign returned an error: %v", err)
+		}
+		sks[i], pks[i], sigs[i] = sk, pk, sig
+	}
+	return sks, pks, msgs, sigs
+}
+
+func TestSignatureSize(t *testing.T) {
+	for _, pp := range variants() {
+		_, _, _, sigs := keysAndSigs(t, pp, 1)
+		if len(sigs[0]) != pp.SignatureSize() {
+			t.Errorf("%s: signature of %d bytes, want %d", pp.Name(), len(sigs[0]), pp.SignatureSize())
+		}
+	}
+	if SetupMinSig().SignatureSize() >= SetupMinPK().SignatureSize() {
+		t.Errorf("Expected min-sig signatures to be shorter than min-pk signatures")
+	}
+}
+
+func TestSignIsDeterministic(t *testing.T) {
+	for _, pp := range variants() {
+		sk, _, _ := pp.KeyGen(nil)
+		sig1, _ := pp.Sign(nil, sk, []byte("Hello, World!"))
+		sig2, _ := pp.Sign(nil, sk, []byte("Hello, World!"))
+		if !bytes.Equal(sig1, sig2) {
+			t.Errorf("%s: expected signatures to be deterministic", pp.Name())
+		}
+	}
+}
+
+func TestRejectIdentity(t *testing.T) {
+	for _, pp := range variants() {
+		_, pks, msgs, sigs := keysAndSigs(t, pp, 1)
+		pkBytes, _ := pks[0].MarshalBinary()
+
+		identity := make([]byte, len(pkBytes))
+		identity[0] = 0xc0 // compressed point at infinity
+		if _, err := pp.UnmarshalPublicKey(identity); err == nil {
+			t.Errorf("%s: expected the identity to be rejected as public key", pp.Name())
+		}
+		identity = make([]byte, len(sigs[0]))
+		identity[0] = 0xc0
+		if pp.Verify(pks[0], msgs[0], identity) {
+			t.Errorf("%s: expected the identity to be rejected as signature", pp.Name())
+		}
+		if _, err := pp.UnmarshalPrivateKey(make([]byte, 32)); err == nil {
+			t.Errorf("%s: expected a zero private key to be rejected", pp.Name())
+		}
+	}
+}
+
+func TestProofOfPossession(t *testing.T) {
+	for _, pp := range variants() {
+		sks, pks, _, _ := keysAndSigs(t, pp, 2)
+		proof, err := pp.Prove(sks[0])
+		if err != nil {
+			t.Fatalf("Prove returned an error: %v", err)
+		}
+		if !pp.VerifyPossession(pks[0], proof) {
+			t.Errorf("%s: expected proof of possession to be valid", pp.Name())
+		}
+		if pp.VerifyPossession(pks[1], proof) {
+			t.Errorf("%s: expected proof for another key to be invalid", pp.Name())
+		}
+
+		// A proof is not a signature on the encoded public key, as both use separate domains.
+		pkBytes, _ := pks[0].MarshalBinary()
+		if pp.Verify(pks[0], pkBytes, proof) {
+			t.Errorf("%s: expected proof of possession to be invalid as signature", pp.Name())
+		}
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	for _, pp := range variants() {
+		_, pks, msgs, sigs := keysAndSigs(t, pp, 4)
+		agg, err := pp.Aggregate(sigs)
+		if err != nil {
+			t.Fatalf("Aggregate returned an error: %v", err)
+		}
+		if len(agg) != pp.SignatureSize() {
+			t.Errorf("%s: aggregate of %d bytes, want %d", pp.Name(), len(agg), pp.SignatureSize())
+		}
+		if !pp.VerifyAggregate(pks, msgs, agg) {
+			t.Errorf("%s: expected aggregate signature to be valid", pp.Name())
+		}
+		if pp.VerifyAggregate(pks[1:], msgs[1:], agg) {
+			t.Errorf("%s: expected aggregate to be invalid for a subset of signers", pp.Name())
+		}
+		msgs[0], msgs[1] = msgs[1], msgs[0]
+		if pp.VerifyAggregate(pks, msgs, agg) {
+			t.Errorf("%s: expected aggregate to be invalid for swapped messages", pp.Name())
+		}
+		if _, err := pp.Aggregate(nil); err == nil {
+			t.Errorf("%s: expected an error when aggregating no signatures", pp.Name())
+		}
+	}
+}
+
+func TestBatchVerify(t *testing.T) {
+	for _, pp := range variants() {
+		_, pks, msgs, sigs := keysAndSigs(t, pp, 4)
+		if !pp.BatchVerify(nil, pks, msgs, sigs) {
+			t.Errorf("%s: expected batch to be valid", pp.Name())
+		}
+		if pp.BatchVerify(nil, pks, msgs, sigs[1:]) {
+			t.Errorf("%s: expected batch of mismatched lengths to be invalid", pp.Name())
+		}
+		if pp.BatchVerify(nil, nil, nil, nil) {
+			t.Errorf("%s: expected empty batch to be invalid", pp.Name())
+		}
+
+		// Two invalid signatures whose errors cancel out pass an unrandomized check, but not the batch verification.
+		sigs[0], sigs[1] = sigs[1], sigs[0]
+		if pp.BatchVerify(utils.NewDeterministicReader([]byte("seed")), pks, msgs, sigs) {
+			t.Errorf("%s: expected batch with swapped signatures to be invalid", pp.Name())
+		}
+	}
+}
+
+func TestKeysAreBoundToVariant(t *testing.T) {
+	minSig, minPK := SetupMinSig(), SetupMinPK()
+	sk, pk, _ := minSig.KeyGen(nil)
+	if _, err := minPK.Sign(nil, sk, []byte("Hello, World!")); err == nil {
+		t.Errorf("Expected a min-sig key to be rejected by min-pk")
+	}
+	sig, _ := minSig.Sign(nil, sk, []byte("Hello, World!"))
+	if minPK.Verify(pk, []byte("Hello, World!"), sig) {
+		t.Errorf("Expected a min-sig public key to be rejected by min-pk")
+	}
+}
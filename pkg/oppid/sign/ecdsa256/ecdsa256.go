@@ -0,0 +1,182 @@
+// Package implements ECDSA signatures on P-256 with SHA-256 (ES256). Signatures are encoded as the 64-byte
+// concatenation r || s as in JWS [1], rather than in ASN.1.
+
+// References:
+// [1] https://www.rfc-editor.org/rfc/rfc7518#section-3.4
+
+package ecdsa256
+
+import (
+	"OPPID-artifacts/pkg/oppid/sign"
+	"OPPID-artifacts/pkg/oppid/utils"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+const (
+	ES256 = "ES256"
+
+	scalarSize    = 32
+	SignatureSize = 2 * scalarSize
+)
+
+type PublicParams struct{}
+
+var _ sign.Scheme = (*PublicParams)(nil)
+
+type PublicKey struct{ key *ecdsa.PublicKey }
+type PrivateKey struct{ key *ecdsa.PrivateKey }
+
+func Setup() *PublicParams {
+	return &PublicParams{}
+}
+
+func (pp *PublicParams) Name() string { return ES256 }
+
+// KeyGen creates a key pair. With a custom rnd, the private scalar is derived from it by generateKey, because
+// ecdsa.GenerateKey ignores custom readers.
+func (pp *PublicParams) KeyGen(rnd io.Reader) (sign.PrivateKey, sign.PublicKey, error) {
+	var privateKey *ecdsa.PrivateKey
+	var err error
+	if rnd == nil {
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	} else {
+		privateKey, err = generateKey(rnd)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating ecdsa key pair: %w", err)
+	}
+	return &PrivateKey{key: privateKey}, &PublicKey{key: &privateKey.PublicKey}, nil
+}
+
+// generateKey reads 64 bytes from rnd and reduces them to a scalar in [1, n-1], with negligible bias.
+func generateKey(rnd io.Reader) (*ecdsa.PrivateKey, error) {
+	var buf [64]byte
+	if _, err := io.ReadFull(rnd, buf[:]); err != nil {
+		return nil, err
+	}
+	nMinusOne := new(big.Int).Sub(elliptic.P256().Params().N, big.NewInt(1))
+	d := new(big.Int).SetBytes(buf[:])
+	d.Mod(d, nMinusOne).Add(d, big.NewInt(1))
+	return newPrivateKey(d.FillBytes(make([]byte, scalarSize)))
+}
+
+// newPrivateKey parses a big-endian scalar in [1, n-1] and computes its public key.
+func newPrivateKey(d []byte) (*ecdsa.PrivateKey, error) {
+	sk, err := ecdh.P256().NewPrivateKey(d)
+	if err != nil {
+		return nil, err
+	}
+	pk, err := newPublicKey(sk.PublicKey().Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return &ecdsa.PrivateKey{PublicKey: *pk, D: new(big.Int).SetBytes(d)}, nil
+}
+
+// newPublicKey parses an uncompressed point and checks that it is on the curve.
+func newPublicKey(data []byte) (*ecdsa.PublicKey, error) {
+	if _, err := ecdh.P256().NewPublicKey(data); err != nil {
+		return nil, err
+	}
+	if len(data) != 1+2*scalarSize {
+		return nil, errors.New("ecdsa256: public key is not an uncompressed point")
+	}
+	return &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(data[1 : 1+scalarSize]),
+		Y:     new(big.Int).SetBytes(data[1+scalarSize:]),
+	}, nil
+}
+
+func (pp *PublicParams) Sign(rnd io.Reader, k sign.PrivateKey, message []byte) (sign.Signature, error) {
+	sk, ok := k.(*PrivateKey)
+	if !ok || sk.key == nil {
+		return nil, errors.New("ecdsa256: empty private key")
+	}
+	hashed := sha256.Sum256(message)
+	r, s, err := ecdsa.Sign(utils.Reader(rnd), sk.key, hashed[:])
+	if err != nil {
+		return nil, fmt.Errorf("creating ecdsa signature: %w", err)
+	}
+	sig := make([]byte, SignatureSize)
+	r.FillBytes(sig[:scalarSize])
+	s.FillBytes(sig[scalarSize:])
+	return sig, nil
+}
+
+func (pp *PublicParams) Verify(p sign.PublicKey, message []byte, signature sign.Signature) bool {
+	pk, ok := p.(*PublicKey)
+	if !ok || pk == nil || pk.key == nil || len(signature) != SignatureSize {
+		return false
+	}
+	hashed := sha256.Sum256(message)
+	r := new(big.Int).SetBytes(signature[:scalarSize])
+	s := new(big.Int).SetBytes(signature[scalarSize:])
+	return ecdsa.Verify(pk.key, hashed[:], r, s)
+}
+
+func (pp *PublicParams) UnmarshalPrivateKey(data []byte) (sign.PrivateKey, error) {
+	sk := new(PrivateKey)
+	if err := sk.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return sk, nil
+}
+
+func (pp *PublicParams) UnmarshalPublicKey(data []byte) (sign.PublicKey, error) {
+	pk := new(PublicKey)
+	if err := pk.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return pk, nil
+}
+
+// MarshalBinary encodes the public key as an uncompressed point (SEC 1, Version 2.0, Section 2.3.3).
+func (p *PublicKey) MarshalBinary() ([]byte, error) {
+	if p.key == nil {
+		return nil, errors.New("ecdsa256: empty public key")
+	}
+	key, err := p.key.ECDH()
+	if err != nil {
+		return nil, err
+	}
+	return key.Bytes(), nil
+}
+
+func (p *PublicKey) UnmarshalBinary(data []byte) error {
+	key, err := newPublicKey(data)
+	if err != nil {
+		return err
+	}
+	p.key = key
+	return nil
+}
+
+// MarshalBinary encodes the private key as a fixed-length, big-endian scalar (SEC 1, Version 2.0, Section 2.3.6).
+func (k *PrivateKey) MarshalBinary() ([]byte, error) {
+	if k.key == nil {
+		return nil, errors.New("ecdsa256: empty private key")
+	}
+	return k.key.D.FillBytes(make([]byte, scalarSize)), nil
+}
+
+func (k *PrivateKey) UnmarshalBinary(data []byte) error {
+	key, err := newPrivateKey(data)
+	if err != nil {
+		return err
+	}
+	k.key = key
+	return nil
+}
+
+func (k *PrivateKey) Public() sign.PublicKey {
+	return &PublicKey{key: &k.key.PublicKey}
+}
@@ -0,0 +1,38 @@
+package ecdsa256
+
+import (
+	"testing"
+)
+
+func TestSignatureEncoding(t *testing.T) {
+	es := Setup()
+	sk, pk, _ := es.KeyGen(nil)
+
+	msg := []byte("Hello, World!")
+	sig, err := es.Sign(nil, sk, msg)
+	if err != nil {
+		t.Fatalf("Sign returned an error: %v", err)
+	}
+	if len(sig) != SignatureSize {
+		t.Fatalf("Expected a %d-byte signature, got %d bytes", SignatureSize, len(sig))
+	}
+	if !es.Verify(pk, msg, sig) {
+		t.Fatalf("Expected signature to be valid")
+	}
+	if es.Verify(pk, msg, append(sig, 0)) {
+		t.Fatalf("Expected signature with trailing data to be invalid")
+	}
+}
+
+func TestUnmarshalInvalidPoint(t *testing.T) {
+	_, pk, _ := Setup().KeyGen(nil)
+	data, _ := pk.MarshalBinary()
+	data[len(data)-1] ^= 1 // no longer on the curve
+
+	if _, err := Setup().UnmarshalPublicKey(data); err == nil {
+		t.Fatalf("Expected a point off the curve to be rejected")
+	}
+	if _, err := Setup().UnmarshalPrivateKey(make([]byte, scalarSize)); err == nil {
+		t.Fatalf("Expected a zero private key to be rejected")
+	}
+}
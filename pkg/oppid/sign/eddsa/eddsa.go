@@ -0,0 +1,113 @@
+// Package implements Ed25519 signatures [1]. Ed25519 hashes the message itself with SHA-512, and its signatures are
+// deterministic.
+
+// References:
+// [1] https://www.rfc-editor.org/rfc/rfc8032
+
+package eddsa
+
+import (
+	"OPPID-artifacts/pkg/oppid/sign"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const Ed25519 = "Ed25519"
+
+type PublicParams struct{}
+
+var _ sign.Scheme = (*PublicParams)(nil)
+
+type PublicKey struct{ key ed25519.PublicKey }
+type PrivateKey struct{ key ed25519.PrivateKey }
+
+func Setup() *PublicParams {
+	return &PublicParams{}
+}
+
+func (pp *PublicParams) Name() string { return Ed25519 }
+
+// KeyGen derives the key pair from a 32-byte seed read from rnd.
+func (pp *PublicParams) KeyGen(rnd io.Reader) (sign.PrivateKey, sign.PublicKey, error) {
+	if rnd == nil {
+		rnd = rand.Reader
+	}
+	seed := make([]byte, ed25519.SeedSize)
+	if _, err := io.ReadFull(rnd, seed); err != nil {
+		return nil, nil, fmt.Errorf("creating ed25519 key pair: %w", err)
+	}
+	sk := &PrivateKey{ed25519.NewKeyFromSeed(seed)}
+	return sk, sk.Public(), nil
+}
+
+// Sign ignores rnd, as Ed25519 signatures are deterministic.
+func (pp *PublicParams) Sign(_ io.Reader, k sign.PrivateKey, message []byte) (sign.Signature, error) {
+	sk, ok := k.(*PrivateKey)
+	if !ok || len(sk.key) != ed25519.PrivateKeySize {
+		return nil, errors.New("eddsa: empty private key")
+	}
+	return ed25519.Sign(sk.key, message), nil
+}
+
+func (pp *PublicParams) Verify(p sign.PublicKey, message []byte, signature sign.Signature) bool {
+	pk, ok := p.(*PublicKey)
+	if !ok || pk == nil || len(pk.key) != ed25519.PublicKeySize || len(signature) != ed25519.SignatureSize {
+		return false
+	}
+	return ed25519.Verify(pk.key, message, signature)
+}
+
+func (pp *PublicParams) UnmarshalPrivateKey(data []byte) (sign.PrivateKey, error) {
+	sk := new(PrivateKey)
+	if err := sk.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return sk, nil
+}
+
+func (pp *PublicParams) UnmarshalPublicKey(data []byte) (sign.PublicKey, error) {
+	pk := new(PublicKey)
+	if err := pk.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return pk, nil
+}
+
+// MarshalBinary encodes the public key in its 32-byte form.
+func (p *PublicKey) MarshalBinary() ([]byte, error) {
+	if len(p.key) != ed25519.PublicKeySize {
+		return nil, errors.New("eddsa: empty public key")
+	}
+	return append([]byte(nil), p.key...), nil
+}
+
+func (p *PublicKey) UnmarshalBinary(data []byte) error {
+	if len(data) != ed25519.PublicKeySize {
+		return fmt.Errorf("eddsa: public key of %d bytes, want %d", len(data), ed25519.PublicKeySize)
+	}
+	p.key = append(ed25519.PublicKey(nil), data...)
+	return nil
+}
+
+// MarshalBinary encodes the private key as its 32-byte seed.
+func (k *PrivateKey) MarshalBinary() ([]byte, error) {
+	if len(k.key) != ed25519.PrivateKeySize {
+		return nil, errors.New("eddsa: empty private key")
+	}
+	return k.key.Seed(), nil
+}
+
+func (k *PrivateKey) UnmarshalBinary(data []byte) error {
+	if len(data) != ed25519.SeedSize {
+		return fmt.Errorf("eddsa: private key of %d bytes, want %d", len(data), ed25519.SeedSize)
+	}
+	k.key = ed25519.NewKeyFromSeed(data)
+	return nil
+}
+
+func (k *PrivateKey) Public() sign.PublicKey {
+	return &PublicKey{key: k.key.Public().(ed25519.PublicKey)}
+}
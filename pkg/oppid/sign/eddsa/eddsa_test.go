@@ -0,0 +1,24 @@
+package eddsa
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSignIsDeterministic(t *testing.T) {
+	ed := Setup()
+	sk, pk, _ := ed.KeyGen(nil)
+
+	msg := []byte("Hello, World!")
+	sig1, _ := ed.Sign(nil, sk, msg)
+	sig2, _ := ed.Sign(nil, sk, msg)
+	if !bytes.Equal(sig1, sig2) {
+		t.Fatalf("Expected Ed25519 signatures to be deterministic")
+	}
+	if !ed.Verify(pk, msg, sig1) {
+		t.Fatalf("Expected signature to be valid")
+	}
+	if ed.Verify(pk, msg, sig1[:len(sig1)-1]) {
+		t.Fatalf("Expected truncated signature to be invalid")
+	}
+}
@@ -0,0 +1,95 @@
+package ps
+
+import (
+	PC "OPPID-artifacts/pkg/oppid/commit/pc"
+	"OPPID-artifacts/pkg/oppid/utils"
+	"errors"
+
+	GG "github.com/cloudflare/circl/ecc/bls12381"
+)
+
+// Blind signing ([1], Sec. 6.1): the requester commits to the first message as C = m_1*Y1_1 + t*g1, a Pedersen
+// commitment with the generators (Y1_1, g1), and proves knowledge of its opening. The signer adds the remaining
+// messages m_2, ..., m_n in the clear and returns (u*g1, u*(x*g1 + C + sum(m_i*Y1_i))), which the requester unblinds
+// with t into the signature (u*g1, u*(x + sum(y_i*m_i))*g1) on (m_1, ..., m_n).
+
+// CommitmentParams returns the Pedersen parameters under which the first message is committed to for BlindSign by pk.
+func (pp *PublicParams) CommitmentParams(pk *PublicKey) *PC.PublicParams {
+	return &PC.PublicParams{G: pk.Y1[0], H: GG.G1Generator(), Dst: pp.Dst, Rand: pp.Rand}
+}
+
+// VerifyCommitmentBase checks that every Y1_i matches Y_i, i.e., e(Y1_i, g2) = e(g1, Y_i), so that a commitment under
+// CommitmentParams binds the message that the signature will be on, and the clear messages of BlindSign are signed
+// under Y. The result is cached like the one of IsValid.
+func (pk *PublicKey) VerifyCommitmentBase() bool {
+	if pk == nil {
+		return false
+	}
+	if pre := pk.cached(); pre != nil {
+		pre.baseOnce.Do(func() { pre.base = pk.verifyCommitmentBase() })
+		return pre.base
+	}
+	return pk.verifyCommitmentBase()
+}
+
+func (pk *PublicKey) verifyCommitmentBase() bool {
+	if !pk.IsValid() || len(pk.Y1) != len(pk.Y) {
+		return false
+	}
+	g1 := GG.G1Generator()
+	for i := range pk.Y {
+		if !utils.IsValidG1(pk.Y1[i]) ||
+			!GG.ProdPairFrac([]*GG.G1{pk.Y1[i], g1}, []*GG.G2{pk.G, pk.Y[i]}, []int{1, -1}).IsIdentity() {
+			return false
+		}
+	}
+	return true
+}
+
+// BlindSign signs the message committed to in com as the first message and msgs as the remaining ones. The caller
+// must have verified a proof of knowledge of the opening.
+func (pp *PublicParams) BlindSign(k *PrivateKey, com PC.Commitment, msgs ...[]byte) (Signature, error) {
+	if k == nil || k.x == nil || len(k.y) == 0 || k.Pk == nil {
+		return Signature{}, errors.New("ps: empty private key")
+	}
+	if !utils.IsValidG1(com.Element) {
+		return Signature{}, errors.New("ps: malformed commitment")
+	}
+	m, err := pp.hashMessages(msgs, len(k.y)-1)
+	if err != nil {
+		return Signature{}, err
+	}
+	u, err := utils.GenerateRandomScalarFrom(pp.Rand)
+	if err != nil {
+		return Signature{}, err
+	}
+	if u.IsZero() == 1 {
+		return Signature{}, errors.New("ps: zero randomness")
+	}
+
+	xc := utils.AddG1Points(utils.MulG1Generator(k.x), com.Element) // x*g1 + C + sum(m_i*Y1_i)
+	for i := range m {
+		xc = utils.AddG1Points(xc, utils.GenerateG1Point(&m[i], k.Pk.Y1[i+1]))
+	}
+
+	var sig Signature
+	sig.One = utils.MulG1Generator(u)
+	sig.Two = utils.GenerateG1Point(u, xc)
+	return sig, nil
+}
+
+// Unblind removes the commitment randomness from a blind signature. The result is a signature on the committed
+// message, which the caller should check with Verify.
+func (pp *PublicParams) Unblind(blind Signature, opn PC.Opening) (Signature, error) {
+	if !utils.IsValidG1(blind.One) || !utils.IsValidG1(blind.Two) || !utils.IsValidScalar(opn.Scalar) {
+		return Signature{}, errors.New("ps: malformed blind signature or opening")
+	}
+	negT := new(GG.Scalar)
+	negT.Set(opn.Scalar)
+	negT.Neg()
+
+	var sig Signature
+	sig.One = blind.One
+	sig.Two = utils.AddG1Points(blind.Two, utils.GenerateG1Point(negT, blind.One)) // u*(x*g1 + C) - t*u*g1
+	return sig, nil
+}
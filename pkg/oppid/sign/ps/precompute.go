@@ -0,0 +1,84 @@
+package ps
+
+import (
+	"OPPID-artifacts/pkg/oppid/utils"
+	"sync"
+
+	GG "github.com/cloudflare/circl/ecc/bls12381"
+)
+
+// precomputation caches what depends on a public key only: the subgroup checks of IsValid, which every verifier runs,
+// the 2n pairings of VerifyCommitmentBase, and fixed-base tables of G and Y_i for MulG and MulY. Each part is computed
+// once, on first use. The cache is bound to copies of the points it was created for, so that a key whose points are
+// changed afterwards is checked anew and multiplied without tables.
+type precomputation struct {
+	g, x GG.G2
+	y    []GG.G2
+	y1   []GG.G1
+
+	validOnce sync.Once
+	valid     bool
+	baseOnce  sync.Once
+	base      bool
+
+	tablesOnce sync.Once
+	gTable     *utils.G2Table
+	yTables    []*utils.G2Table
+}
+
+// precompute attaches a cache to a key from KeyGenMulti or UnmarshalBinary.
+func (pk *PublicKey) precompute() {
+	if pk.G == nil || pk.X == nil || len(pk.Y1) != len(pk.Y) {
+		return
+	}
+	pre := &precomputation{g: *pk.G, x: *pk.X, y: make([]GG.G2, len(pk.Y)), y1: make([]GG.G1, len(pk.Y1))}
+	for i := range pk.Y {
+		if pk.Y[i] == nil || pk.Y1[i] == nil {
+			return
+		}
+		pre.y[i], pre.y1[i] = *pk.Y[i], *pk.Y1[i]
+	}
+	pk.pre = pre
+}
+
+// cached returns the cache of the key if it still matches the points of the key, or nil.
+func (pk *PublicKey) cached() *precomputation {
+	pre := pk.pre
+	if pre == nil || pk.G == nil || pk.X == nil || len(pk.Y) != len(pre.y) || len(pk.Y1) != len(pre.y1) ||
+		!pk.G.IsEqual(&pre.g) || !pk.X.IsEqual(&pre.x) {
+		return nil
+	}
+	for i := range pk.Y {
+		if pk.Y[i] == nil || pk.Y1[i] == nil || !pk.Y[i].IsEqual(&pre.y[i]) || !pk.Y1[i].IsEqual(&pre.y1[i]) {
+			return nil
+		}
+	}
+	return pre
+}
+
+func (pre *precomputation) tables() *precomputation {
+	pre.tablesOnce.Do(func() {
+		pre.gTable = utils.NewG2Table(&pre.g)
+		pre.yTables = make([]*utils.G2Table, len(pre.y))
+		for i := range pre.y {
+			pre.yTables[i] = utils.NewG2Table(&pre.y[i])
+		}
+	})
+	return pre
+}
+
+// MulG returns s*G, with a fixed-base table for keys from KeyGenMulti and UnmarshalBinary.
+func (pk *PublicKey) MulG(s *GG.Scalar) *GG.G2 {
+	if pre := pk.cached(); pre != nil {
+		return pre.tables().gTable.Mul(s)
+	}
+	return utils.GenerateG2Point(s, pk.G)
+}
+
+// MulY returns s*Y_i like MulG.
+func (pk *PublicKey) MulY(i int, s *GG.Scalar) *GG.G2 {
+	if pre := pk.cached(); pre != nil {
+		return pre.tables().yTables[i].Mul(s)
+	}
+	return utils.GenerateG2Point(s, pk.Y[i])
+}
@@ -1,4 +1,6 @@
-// Package implements PS signatures [1], used for signatures with efficient proofs of knowledge.
+// Package implements PS signatures [1], used for signatures with efficient proofs of knowledge. Keys sign a single
+// message or, from KeyGenMulti, a fixed-length vector of messages ([1], Sec. 4.2). Signatures can also be issued
+// blindly on a Pedersen commitment to the first message ([1], Sec. 6.1), see BlindSign.
 
 // References:
 // [1] https://eprint.iacr.org/2015/525.pdf
@@ -7,7 +9,9 @@ package ps
 
 import (
 	"OPPID-artifacts/pkg/oppid/utils"
-	"log"
+	"errors"
+	"fmt"
+	"io"
 
 	GG "github.com/cloudflare/circl/ecc/bls12381"
 )
@@ -16,17 +20,23 @@ const dstStr = "OPPID_BLS12384_XMD:SHA-256_PS_"
 
 type PublicParams struct {
 	Dst []byte
+	// Rand is the source of randomness for KeyGen and Sign. If nil, crypto/rand is used.
+	Rand io.Reader
 }
 
+// PublicKey verifies signatures on vectors of len(Y) messages; Y[i] and Y1[i] belong to the i-th message.
 type PublicKey struct {
-	G *GG.G2
-	X *GG.G2
-	Y *GG.G2
+	G  *GG.G2
+	X  *GG.G2
+	Y  []*GG.G2
+	Y1 []*GG.G1 // y_i*g1, the commitment base of the messages for blind signing
+
+	pre *precomputation
 }
 
 type PrivateKey struct {
 	x  *GG.Scalar
-	y  *GG.Scalar
+	y  []*GG.Scalar
 	Pk *PublicKey
 }
 
@@ -39,53 +49,279 @@ func Setup(dst []byte) *PublicParams {
 	if dst == nil {
 		return &PublicParams{Dst: []byte(dstStr)}
 	}
-	return &PublicParams{dst}
+	return &PublicParams{Dst: dst}
 }
 
-func (pp *PublicParams) KeyGen() (*PrivateKey, *PublicKey) {
-	x := utils.GenerateRandomScalar()
-	y := utils.GenerateRandomScalar()
+// KeyGen generates a key for single messages.
+func (pp *PublicParams) KeyGen() (*PrivateKey, *PublicKey, error) {
+	return pp.KeyGenMulti(1)
+}
 
-	g := GG.G2Generator()
+// KeyGenMulti generates a key for vectors of n messages ([1], Sec. 4.2).
+func (pp *PublicParams) KeyGenMulti(n int) (*PrivateKey, *PublicKey, error) {
+	if n < 1 {
+		return nil, nil, errors.New("ps: key must sign at least one message")
+	}
+	x, err := utils.GenerateRandomScalarFrom(pp.Rand)
+	if err != nil {
+		return nil, nil, err
+	}
+	if x.IsZero() == 1 {
+		return nil, nil, errors.New("ps: zero private key")
+	}
+	y := make([]*GG.Scalar, n)
+	for i := range y {
+		if y[i], err = utils.GenerateRandomScalarFrom(pp.Rand); err != nil {
+			return nil, nil, err
+		}
+		if y[i].IsZero() == 1 {
+			return nil, nil, errors.New("ps: zero private key")
+		}
+	}
+	k := &PrivateKey{x: x, y: y}
+	k.Pk = k.publicKey()
+	return k, k.Pk, nil
+}
 
-	X := utils.GenerateG2Point(x, g)
-	Y := utils.GenerateG2Point(y, g)
+func (k *PrivateKey) publicKey() *PublicKey {
+	pk := &PublicKey{G: GG.G2Generator(), X: utils.MulG2Generator(k.x), Y: make([]*GG.G2, len(k.y)), Y1: make([]*GG.G1, len(k.y))}
+	for i, y := range k.y {
+		pk.Y[i] = utils.MulG2Generator(y)
+		pk.Y1[i] = utils.MulG1Generator(y)
+	}
+	pk.precompute()
+	return pk
+}
 
-	pk := &PublicKey{g, X, Y}
+// Len returns the number of messages that the key signs.
+func (pk *PublicKey) Len() int { return len(pk.Y) }
 
-	return &PrivateKey{x, y, pk}, pk
+// hashMessages maps the messages to scalars. It fails if their number does not match the key.
+func (pp *PublicParams) hashMessages(msgs [][]byte, n int) ([]GG.Scalar, error) {
+	if len(msgs) != n {
+		return nil, fmt.Errorf("ps: got %d messages, key signs %d", len(msgs), n)
+	}
+	m := make([]GG.Scalar, n)
+	for i, msg := range msgs {
+		m[i] = utils.HashToScalar(msg, pp.Dst)
+	}
+	return m, nil
 }
 
-func (pp *PublicParams) Sign(k *PrivateKey, msg []byte) Signature {
-	var sig Signature
+// Sign signs a single message with a key from KeyGen.
+func (pp *PublicParams) Sign(k *PrivateKey, msg []byte) (Signature, error) {
+	return pp.SignMulti(k, [][]byte{msg})
+}
 
-	u := utils.GenerateRandomScalar()
-	sig.One = utils.GenerateG1Point(u, GG.G1Generator())
+// SignMulti signs the vector msgs, whose length must match the key.
+func (pp *PublicParams) SignMulti(k *PrivateKey, msgs [][]byte) (Signature, error) {
+	if k == nil || k.x == nil || len(k.y) == 0 {
+		return Signature{}, errors.New("ps: empty private key")
+	}
+	m, err := pp.hashMessages(msgs, len(k.y))
+	if err != nil {
+		return Signature{}, err
+	}
 
-	m := utils.HashToScalar(msg, pp.Dst)
-	ym, err1 := utils.MulScalars(k.y, &m)
-	exp, err2 := utils.AddScalars(k.x, ym) // x+y*m
+	u, err := utils.GenerateRandomScalarFrom(pp.Rand)
+	if err != nil {
+		return Signature{}, err
+	}
+	if u.IsZero() == 1 {
+		return Signature{}, errors.New("ps: zero randomness")
+	}
 
-	if err1 != nil || err2 != nil {
-		log.Fatalf("error generating PS signature: multiplication %v, addition %v", err1, err2)
+	exp := new(GG.Scalar) // x+sum(y_i*m_i)
+	exp.Set(k.x)
+	for i := range m {
+		ym, err := utils.MulScalars(k.y[i], &m[i])
+		if err != nil {
+			return Signature{}, fmt.Errorf("ps: generating signature: %w", err)
+		}
+		if exp, err = utils.AddScalars(exp, ym); err != nil {
+			return Signature{}, fmt.Errorf("ps: generating signature: %w", err)
+		}
 	}
 
+	var sig Signature
+	sig.One = utils.MulG1Generator(u)
 	sig.Two = utils.GenerateG1Point(exp, sig.One)
 
-	return sig
+	return sig, nil
 }
 
+// Verify checks a signature on a single message. It returns false for malformed keys and signatures, e.g., with
+// missing or identity components.
 func (pp *PublicParams) Verify(pk *PublicKey, msg []byte, sig Signature) bool {
-	if !sig.One.IsOnG1() || sig.One.IsIdentity() {
-		log.Fatalf("Error verifying PS signature: sigma one not on G1 curve or is identity")
+	return pp.VerifyMulti(pk, [][]byte{msg}, sig)
+}
+
+// VerifyMulti checks a signature on the vector msgs. It returns false if the number of messages does not match the
+// key.
+func (pp *PublicParams) VerifyMulti(pk *PublicKey, msgs [][]byte, sig Signature) bool {
+	if !pk.IsValid() || !utils.IsValidG1(sig.One) || !utils.IsValidG1(sig.Two) {
+		return false
+	}
+	m, err := pp.hashMessages(msgs, pk.Len())
+	if err != nil {
+		return false
+	}
+
+	XYm := pk.X // X*prod(Y_i^m_i)
+	for i := range m {
+		XYm = utils.AddG2Points(XYm, pk.MulY(i, &m[i]))
+	}
+
+	// e(sig1, X*prod(Y_i^m_i)) * e(sig2, g2)^-1 = 1, with one final exponentiation
+	return GG.ProdPairFrac([]*GG.G1{sig.One, sig.Two}, []*GG.G2{XYm, pk.G}, []int{1, -1}).IsIdentity()
+}
+
+// IsValid reports whether all components of the public key are set, in G2 and not the identity, and whether it signs
+// at least one message. The result is cached for keys from KeyGenMulti and UnmarshalBinary.
+func (pk *PublicKey) IsValid() bool {
+	if pk == nil {
+		return false
+	}
+	if pre := pk.cached(); pre != nil {
+		pre.validOnce.Do(func() { pre.valid = pk.isValid() })
+		return pre.valid
+	}
+	return pk.isValid()
+}
+
+func (pk *PublicKey) isValid() bool {
+	if pk == nil || !utils.IsValidG2(pk.G) || !utils.IsValidG2(pk.X) || len(pk.Y) == 0 {
+		return false
 	}
+	for _, y := range pk.Y {
+		if !utils.IsValidG2(y) {
+			return false
+		}
+	}
+	return true
+}
+
+// SignatureSize is the length of an encoded signature: two compressed G1 points.
+const SignatureSize = 2 * GG.G1SizeCompressed
+
+// PublicKeySize returns the length of an encoded public key for n messages: the compressed G2 points G and X, and
+// Y_i and Y1_i for every message.
+func PublicKeySize(n int) int {
+	return 2*GG.G2SizeCompressed + n*(GG.G2SizeCompressed+GG.G1SizeCompressed)
+}
+
+func (sig Signature) MarshalBinary() ([]byte, error) {
+	if sig.One == nil || sig.Two == nil {
+		return nil, errors.New("ps: empty signature")
+	}
+	buf := make([]byte, 0, SignatureSize)
+	buf = append(buf, sig.One.BytesCompressed()...)
+	buf = append(buf, sig.Two.BytesCompressed()...)
+	return buf, nil
+}
 
-	m := utils.HashToScalar(msg, pp.Dst)
-	Ym := utils.GenerateG2Point(&m, pk.Y)
-	XYm := utils.AddG2Points(pk.X, Ym)
+func (sig *Signature) UnmarshalBinary(data []byte) error {
+	if len(data) != SignatureSize {
+		return errors.New("ps: invalid signature length")
+	}
+	one, err := utils.DecodeG1(data[:GG.G1SizeCompressed])
+	if err != nil {
+		return err
+	}
+	two, err := utils.DecodeG1(data[GG.G1SizeCompressed:])
+	if err != nil {
+		return err
+	}
+	sig.One, sig.Two = one, two
+	return nil
+}
+
+func (pk *PublicKey) MarshalBinary() ([]byte, error) {
+	if pk.G == nil || pk.X == nil || len(pk.Y) == 0 || len(pk.Y1) != len(pk.Y) {
+		return nil, errors.New("ps: empty public key")
+	}
+	buf := make([]byte, 0, PublicKeySize(len(pk.Y)))
+	buf = append(buf, pk.G.BytesCompressed()...)
+	buf = append(buf, pk.X.BytesCompressed()...)
+	for i := range pk.Y {
+		if pk.Y[i] == nil || pk.Y1[i] == nil {
+			return nil, errors.New("ps: empty public key")
+		}
+		buf = append(buf, pk.Y[i].BytesCompressed()...)
+		buf = append(buf, pk.Y1[i].BytesCompressed()...)
+	}
+	return buf, nil
+}
 
-	lhs := GG.Pair(sig.One, XYm)
-	rhs := GG.Pair(sig.Two, pk.G)
+// UnmarshalBinary decodes a public key; the number of messages follows from the length of data.
+func (pk *PublicKey) UnmarshalBinary(data []byte) error {
+	const perMsg = GG.G2SizeCompressed + GG.G1SizeCompressed
+	rest := len(data) - 2*GG.G2SizeCompressed
+	if rest < perMsg || rest%perMsg != 0 {
+		return errors.New("ps: invalid public key length")
+	}
+	g, err := utils.DecodeG2(data[:GG.G2SizeCompressed])
+	if err != nil {
+		return err
+	}
+	x, err := utils.DecodeG2(data[GG.G2SizeCompressed : 2*GG.G2SizeCompressed])
+	if err != nil {
+		return err
+	}
+	data = data[2*GG.G2SizeCompressed:]
+
+	n := rest / perMsg
+	key := PublicKey{G: g, X: x, Y: make([]*GG.G2, n), Y1: make([]*GG.G1, n)}
+	for i := 0; i < n; i++ {
+		if key.Y[i], err = utils.DecodeG2(data[:GG.G2SizeCompressed]); err != nil {
+			return err
+		}
+		if key.Y1[i], err = utils.DecodeG1(data[GG.G2SizeCompressed:perMsg]); err != nil {
+			return err
+		}
+		data = data[perMsg:]
+	}
+	key.precompute()
+	*pk = key
+	return nil
+}
+
+// PrivateKeySize returns the length of an encoded private key for n messages: the scalars x and y_1, ..., y_n.
+func PrivateKeySize(n int) int {
+	return (1 + n) * GG.ScalarSize
+}
+
+func (k *PrivateKey) MarshalBinary() ([]byte, error) {
+	if k.x == nil || len(k.y) == 0 {
+		return nil, errors.New("ps: empty private key")
+	}
+	buf := make([]byte, 0, PrivateKeySize(len(k.y)))
+	buf = append(buf, utils.ScalarBytes(k.x)...)
+	for _, y := range k.y {
+		buf = append(buf, utils.ScalarBytes(y)...)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary decodes a private key and recomputes its public key. The number of messages follows from the length
+// of data.
+func (k *PrivateKey) UnmarshalBinary(data []byte) error {
+	if len(data) < PrivateKeySize(1) || len(data)%GG.ScalarSize != 0 {
+		return errors.New("ps: invalid private key length")
+	}
+	scalars := make([]*GG.Scalar, len(data)/GG.ScalarSize)
+	for i := range scalars {
+		s, err := utils.DecodeScalar(data[i*GG.ScalarSize : (i+1)*GG.ScalarSize])
+		if err != nil {
+			return err
+		}
+		if s.IsZero() == 1 {
+			return errors.New("ps: invalid private key")
+		}
+		scalars[i] = s
+	}
 
-	return lhs.IsEqual(rhs)
+	k.x, k.y = scalars[0], scalars[1:]
+	k.Pk = k.publicKey()
+	return nil
 }
@@ -9,19 +9,19 @@ import (
 
 func TestNewParams(t *testing.T) {
 	ps := Setup(nil)
-	_, pk := ps.KeyGen()
+	_, pk, _ := ps.KeyGen()
 
-	if !pk.X.IsOnG2() || !pk.Y.IsOnG2() {
+	if !pk.X.IsOnG2() || !pk.Y[0].IsOnG2() {
 		t.Fatalf("Generated points are not on G2 curve")
 	}
 }
 
 func TestSign(t *testing.T) {
 	ps := Setup(nil)
-	sk, _ := ps.KeyGen()
+	sk, _, _ := ps.KeyGen()
 
 	msg := []byte("test message")
-	sig := ps.Sign(sk, msg)
+	sig, _ := ps.Sign(sk, msg)
 
 	if !sig.One.IsOnG1() || !sig.Two.IsOnG1() {
 		t.Fatalf("Signature points are not on G1 curve")
@@ -30,10 +30,10 @@ func TestSign(t *testing.T) {
 
 func TestVerify(t *testing.T) {
 	ps := Setup(nil)
-	sk, pk := ps.KeyGen()
+	sk, pk, _ := ps.KeyGen()
 
 	msg := []byte("test message")
-	sig := ps.Sign(sk, msg)
+	sig, _ := ps.Sign(sk, msg)
 
 	isValid := ps.Verify(pk, msg, sig)
 	if !isValid {
@@ -50,10 +50,10 @@ func TestVerify(t *testing.T) {
 
 func TestEmptyMessageSign(t *testing.T) {
 	ps := Setup(nil)
-	sk, pk := ps.KeyGen()
+	sk, pk, _ := ps.KeyGen()
 
 	msg := []byte("")
-	sig := ps.Sign(sk, msg)
+	sig, _ := ps.Sign(sk, msg)
 
 	isValid := ps.Verify(pk, msg, sig)
 	if !isValid {
@@ -63,11 +63,11 @@ func TestEmptyMessageSign(t *testing.T) {
 
 func TestRepeatedSignatures(t *testing.T) {
 	ps := Setup(nil)
-	sk, pk := ps.KeyGen()
+	sk, pk, _ := ps.KeyGen()
 
 	msg := []byte("test message")
-	sig1 := ps.Sign(sk, msg)
-	sig2 := ps.Sign(sk, msg)
+	sig1, _ := ps.Sign(sk, msg)
+	sig2, _ := ps.Sign(sk, msg)
 
 	if ps.Verify(pk, msg, sig1) && ps.Verify(pk, msg, sig2) {
 		if sig1.One.IsEqual(sig2.One) && sig1.Two.IsEqual(sig2.Two) {
@@ -80,11 +80,11 @@ func TestRepeatedSignatures(t *testing.T) {
 
 func TestDifferentKeys(t *testing.T) {
 	ps := Setup(nil)
-	sk1, _ := ps.KeyGen()
-	_, pk2 := ps.KeyGen()
+	sk1, _, _ := ps.KeyGen()
+	_, pk2, _ := ps.KeyGen()
 
 	msg := []byte("test message")
-	sig := ps.Sign(sk1, msg)
+	sig, _ := ps.Sign(sk1, msg)
 
 	isValid := ps.Verify(pk2, msg, sig)
 	if isValid {
@@ -95,10 +95,10 @@ func TestDifferentKeys(t *testing.T) {
 func TestDifferentPublicParams(t *testing.T) {
 	ps1 := Setup([]byte("Different dst"))
 	ps2 := Setup(nil)
-	sk, pk := ps1.KeyGen()
+	sk, pk, _ := ps1.KeyGen()
 
 	msg := []byte("test message")
-	sig := ps1.Sign(sk, msg)
+	sig, _ := ps1.Sign(sk, msg)
 
 	isValid := ps2.Verify(pk, msg, sig)
 	if isValid {
@@ -108,16 +108,264 @@ func TestDifferentPublicParams(t *testing.T) {
 
 func TestInvalidSignature(t *testing.T) {
 	ps := Setup(nil)
-	sk, pk := ps.KeyGen()
+	sk, pk, _ := ps.KeyGen()
 
 	msg := []byte("test message")
-	sig := ps.Sign(sk, msg)
+	sig, _ := ps.Sign(sk, msg)
 
 	// Tamper with the signature
-	sig.One = utils.GenerateG1Point(utils.GenerateRandomScalar(), GG.G1Generator())
+	s, _ := utils.GenerateRandomScalar()
+	sig.One = utils.GenerateG1Point(s, GG.G1Generator())
 
 	isValid := ps.Verify(pk, msg, sig)
 	if isValid {
 		t.Fatalf("Invalid signature should not be verified")
 	}
 }
+
+func TestSignatureMarshalBinary(t *testing.T) {
+	ps := Setup(nil)
+	sk, pk, _ := ps.KeyGen()
+
+	msg := []byte("test message")
+	sig, _ := ps.Sign(sk, msg)
+
+	sigBytes, err := sig.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Failed to marshal signature: %v", err)
+	}
+	pkBytes, err := pk.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Failed to marshal public key: %v", err)
+	}
+
+	var decodedSig Signature
+	if err := decodedSig.UnmarshalBinary(sigBytes); err != nil {
+		t.Fatalf("Failed to unmarshal signature: %v", err)
+	}
+	var decodedPk PublicKey
+	if err := decodedPk.UnmarshalBinary(pkBytes); err != nil {
+		t.Fatalf("Failed to unmarshal public key: %v", err)
+	}
+
+	if !ps.Verify(&decodedPk, msg, decodedSig) {
+		t.Fatalf("Decoded signature should verify under the decoded public key")
+	}
+
+	if err := decodedSig.UnmarshalBinary(sigBytes[1:]); err == nil {
+		t.Fatalf("Signature with invalid length should not be decoded")
+	}
+}
+
+func TestVerifyMalformedSignature(t *testing.T) {
+	ps := Setup(nil)
+	sk, pk, _ := ps.KeyGen()
+
+	msg := []byte("test message")
+	sig, _ := ps.Sign(sk, msg)
+
+	identity := new(GG.G1)
+	identity.SetIdentity()
+
+	cases := map[string]Signature{
+		"identity one":  {One: identity, Two: sig.Two},
+		"identity two":  {One: sig.One, Two: identity},
+		"identity both": {One: identity, Two: identity},
+		"missing one":   {Two: sig.Two},
+		"empty":         {},
+	}
+	for name, malformed := range cases {
+		if ps.Verify(pk, msg, malformed) {
+			t.Fatalf("%s: malformed signature should not be verified", name)
+		}
+	}
+
+	if ps.Verify(&PublicKey{}, msg, sig) || ps.Verify(nil, msg, sig) {
+		t.Fatalf("Signature should not verify under an empty public key")
+	}
+}
+
+func TestSignWithZeroKey(t *testing.T) {
+	ps := Setup(nil)
+	zero := new(GG.Scalar)
+	sk := &PrivateKey{x: zero, y: []*GG.Scalar{zero}}
+
+	if _, err := ps.Sign(sk, []byte("test message")); err == nil {
+		t.Fatalf("Signing with a zero key should fail")
+	}
+	if _, err := ps.Sign(&PrivateKey{}, []byte("test message")); err == nil {
+		t.Fatalf("Signing with an empty key should fail")
+	}
+}
+
+func TestBlindSign(t *testing.T) {
+	ps := Setup(nil)
+	sk, pk, _ := ps.KeyGen()
+	if !pk.VerifyCommitmentBase() {
+		t.Fatalf("Expected commitment base of a fresh key to be valid")
+	}
+
+	msg := []byte("test message")
+	com, opn, err := ps.CommitmentParams(pk).Commit(msg)
+	if err != nil {
+		t.Fatalf("Commit returned an error: %v", err)
+	}
+	blind, err := ps.BlindSign(sk, com)
+	if err != nil {
+		t.Fatalf("BlindSign returned an error: %v", err)
+	}
+	if ps.Verify(pk, msg, blind) {
+		t.Fatalf("Expected blind signature to be invalid before unblinding")
+	}
+	sig, err := ps.Unblind(blind, opn)
+	if err != nil {
+		t.Fatalf("Unblind returned an error: %v", err)
+	}
+	if !ps.Verify(pk, msg, sig) {
+		t.Fatalf("Expected unblinded signature to be valid")
+	}
+	if ps.Verify(pk, []byte("modified message"), sig) {
+		t.Fatalf("Expected unblinded signature on another message to be invalid")
+	}
+}
+
+func TestVerifyCommitmentBase(t *testing.T) {
+	ps := Setup(nil)
+	_, pk, _ := ps.KeyGen()
+	_, other, _ := ps.KeyGen()
+
+	pk.Y1 = other.Y1
+	if pk.VerifyCommitmentBase() {
+		t.Fatalf("Expected a commitment base of another key to be rejected")
+	}
+}
+
+func TestSignMulti(t *testing.T) {
+	ps := Setup(nil)
+	sk, pk, err := ps.KeyGenMulti(3)
+	if err != nil {
+		t.Fatalf("KeyGenMulti returned an error: %v", err)
+	}
+	msgs := [][]byte{[]byte("first"), []byte("second"), []byte("third")}
+	sig, err := ps.SignMulti(sk, msgs)
+	if err != nil {
+		t.Fatalf("SignMulti returned an error: %v", err)
+	}
+	if !ps.VerifyMulti(pk, msgs, sig) {
+		t.Fatalf("Expected signature on all messages to be valid")
+	}
+
+	swapped := [][]byte{msgs[1], msgs[0], msgs[2]}
+	if ps.VerifyMulti(pk, swapped, sig) {
+		t.Fatalf("Expected signature on reordered messages to be invalid")
+	}
+	if ps.VerifyMulti(pk, msgs[:2], sig) {
+		t.Fatalf("Expected signature on too few messages to be invalid")
+	}
+	if _, err := ps.SignMulti(sk, msgs[:2]); err == nil {
+		t.Fatalf("Expected SignMulti to reject too few messages")
+	}
+	if _, err := ps.Sign(sk, msgs[0]); err == nil {
+		t.Fatalf("Expected Sign to reject a key for several messages")
+	}
+}
+
+func TestMultiKeyMarshalBinary(t *testing.T) {
+	ps := Setup(nil)
+	sk, pk, _ := ps.KeyGenMulti(3)
+	msgs := [][]byte{[]byte("first"), []byte("second"), []byte("third")}
+	sig, _ := ps.SignMulti(sk, msgs)
+
+	pkBytes, err := pk.MarshalBinary()
+	if err != nil || len(pkBytes) != PublicKeySize(3) {
+		t.Fatalf("MarshalBinary returned %d bytes, %v", len(pkBytes), err)
+	}
+	var decodedPk PublicKey
+	if err := decodedPk.UnmarshalBinary(pkBytes); err != nil {
+		t.Fatalf("Failed to unmarshal public key: %v", err)
+	}
+	if decodedPk.Len() != 3 || !ps.VerifyMulti(&decodedPk, msgs, sig) {
+		t.Fatalf("Decoded public key should verify the signature")
+	}
+	if err := decodedPk.UnmarshalBinary(pkBytes[1:]); err == nil {
+		t.Fatalf("Public key with invalid length should not be decoded")
+	}
+
+	skBytes, err := sk.MarshalBinary()
+	if err != nil || len(skBytes) != PrivateKeySize(3) {
+		t.Fatalf("MarshalBinary returned %d bytes, %v", len(skBytes), err)
+	}
+	var decodedSk PrivateKey
+	if err := decodedSk.UnmarshalBinary(skBytes); err != nil {
+		t.Fatalf("Failed to unmarshal private key: %v", err)
+	}
+	sig, _ = ps.SignMulti(&decodedSk, msgs)
+	if !ps.VerifyMulti(pk, msgs, sig) {
+		t.Fatalf("Decoded private key should sign under the original public key")
+	}
+}
+
+func TestBlindSignMulti(t *testing.T) {
+	ps := Setup(nil)
+	sk, pk, _ := ps.KeyGenMulti(3)
+	if !pk.VerifyCommitmentBase() {
+		t.Fatalf("Expected commitment base of a fresh key to be valid")
+	}
+
+	msgs := [][]byte{[]byte("hidden"), []byte("second"), []byte("third")}
+	com, opn, _ := ps.CommitmentParams(pk).Commit(msgs[0])
+	blind, err := ps.BlindSign(sk, com, msgs[1:]...)
+	if err != nil {
+		t.Fatalf("BlindSign returned an error: %v", err)
+	}
+	sig, _ := ps.Unblind(blind, opn)
+	if !ps.VerifyMulti(pk, msgs, sig) {
+		t.Fatalf("Expected unblinded signature on all messages to be valid")
+	}
+	if _, err := ps.BlindSign(sk, com, msgs[1]); err == nil {
+		t.Fatalf("Expected BlindSign to reject too few clear messages")
+	}
+}
+
+func TestPrecomputationFollowsKey(t *testing.T) {
+	ps := Setup(nil)
+	_, pk, _ := ps.KeyGenMulti(2)
+	_, other, _ := ps.KeyGenMulti(2)
+	if pk.cached() == nil {
+		t.Fatalf("Expected KeyGenMulti to attach a precomputation")
+	}
+	if !pk.IsValid() || !pk.VerifyCommitmentBase() {
+		t.Fatalf("Expected key to be valid")
+	}
+
+	data, _ := pk.MarshalBinary()
+	var decoded PublicKey
+	if err := decoded.UnmarshalBinary(data); err != nil || decoded.cached() == nil {
+		t.Fatalf("Expected UnmarshalBinary to attach a precomputation: %v", err)
+	}
+
+	pk.Y1 = other.Y1
+	if pk.cached() != nil || pk.VerifyCommitmentBase() {
+		t.Errorf("Expected a commitment base of another key to be rejected after caching")
+	}
+	identity := new(GG.G2)
+	identity.SetIdentity()
+	decoded.X = identity
+	if decoded.cached() != nil || decoded.IsValid() {
+		t.Errorf("Expected a key changed to the identity to be rejected after caching")
+	}
+}
+
+func TestMulTablesFollowKey(t *testing.T) {
+	ps := Setup(nil)
+	_, pk, _ := ps.KeyGenMulti(2)
+	k, _ := utils.GenerateRandomScalar()
+	if !pk.MulG(k).IsEqual(utils.GenerateG2Point(k, pk.G)) || !pk.MulY(1, k).IsEqual(utils.GenerateG2Point(k, pk.Y[1])) {
+		t.Fatalf("Expected multiplications with tables to match ScalarMult")
+	}
+	_, other, _ := ps.KeyGenMulti(2)
+	pk.Y = other.Y
+	if !pk.MulY(1, k).IsEqual(utils.GenerateG2Point(k, other.Y[1])) {
+		t.Errorf("Expected MulY to follow a changed key")
+	}
+}
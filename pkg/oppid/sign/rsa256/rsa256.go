@@ -1,42 +1,212 @@
+// Package implements RSA signatures with SHA-256, either with PKCS #1 v1.5 padding (RS256) or with PSS (PS256).
+
 package rsa256
 
 import (
+	"OPPID-artifacts/pkg/oppid/sign"
+	"OPPID-artifacts/pkg/oppid/utils"
 	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
-	"log"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+const (
+	RS256 = "RS256"
+	PS256 = "PS256"
 )
 
-type PublicParams struct{ keySize int }
+type PublicParams struct {
+	keySize int
+	pss     bool
+}
+
+var _ sign.Scheme = (*PublicParams)(nil)
 
 type PublicKey struct{ key *rsa.PublicKey }
 type PrivateKey struct{ key *rsa.PrivateKey }
 
-type Signature = []byte
-
+// Setup returns RS256 with keys of keySize bits.
 func Setup(keySize int) *PublicParams {
-	return &PublicParams{keySize}
+	return &PublicParams{keySize: keySize}
 }
 
-func (pp *PublicParams) KeyGen() (*PrivateKey, *PublicKey) {
-	privateKey, err := rsa.GenerateKey(rand.Reader, pp.keySize)
+// SetupPSS returns PS256 with keys of keySize bits.
+func SetupPSS(keySize int) *PublicParams {
+	return &PublicParams{keySize: keySize, pss: true}
+}
+
+func (pp *PublicParams) Name() string {
+	if pp.pss {
+		return PS256
+	}
+	return RS256
+}
+
+func (pp *PublicParams) KeySize() int { return pp.keySize }
+
+// KeyGen creates a key pair. With a custom rnd, the key is derived from it by generateKey, because rsa.GenerateKey
+// deliberately does not produce the same key twice for the same random stream.
+func (pp *PublicParams) KeyGen(rnd io.Reader) (sign.PrivateKey, sign.PublicKey, error) {
+	var privateKey *rsa.PrivateKey
+	var err error
+	if rnd == nil {
+		privateKey, err = rsa.GenerateKey(rand.Reader, pp.keySize)
+	} else {
+		privateKey, err = generateKey(rnd, pp.keySize)
+	}
 	if err != nil {
-		log.Fatalf("Fatal error creating rsa key pair: %v", err)
+		return nil, nil, fmt.Errorf("creating rsa key pair: %w", err)
+	}
+	return &PrivateKey{key: privateKey}, &PublicKey{key: &privateKey.PublicKey}, nil
+}
+
+// generateKey derives a two-prime key with e = 65537 deterministically from rnd.
+func generateKey(rnd io.Reader, bits int) (*rsa.PrivateKey, error) {
+	e := big.NewInt(65537)
+	one := big.NewInt(1)
+	for {
+		p, err := generatePrime(rnd, bits-bits/2)
+		if err != nil {
+			return nil, err
+		}
+		q, err := generatePrime(rnd, bits/2)
+		if err != nil {
+			return nil, err
+		}
+		n := new(big.Int).Mul(p, q)
+		if p.Cmp(q) == 0 || n.BitLen() != bits {
+			continue
+		}
+		phi := new(big.Int).Mul(new(big.Int).Sub(p, one), new(big.Int).Sub(q, one))
+		d := new(big.Int).ModInverse(e, phi)
+		if d == nil {
+			continue
+		}
+
+		key := &rsa.PrivateKey{PublicKey: rsa.PublicKey{N: n, E: int(e.Int64())}, D: d, Primes: []*big.Int{p, q}}
+		key.Precompute()
+		if err := key.Validate(); err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+}
+
+// generatePrime reads candidates of the given bit length from rnd until one is prime. The two top bits are set, so
+// the product of two such primes has twice their length.
+func generatePrime(rnd io.Reader, bits int) (*big.Int, error) {
+	buf := make([]byte, (bits+7)/8)
+	top := uint(bits-1) % 8 // position of the top bit in buf[0]
+	for {
+		if _, err := io.ReadFull(rnd, buf); err != nil {
+			return nil, err
+		}
+		buf[0] &= byte(1<<(top+1) - 1)
+		p := new(big.Int).SetBytes(buf)
+		p.SetBit(p, bits-1, 1)
+		p.SetBit(p, bits-2, 1)
+		p.SetBit(p, 0, 1)
+		if p.ProbablyPrime(20) {
+			return p, nil
+		}
 	}
-	return &PrivateKey{key: privateKey}, &PublicKey{key: &privateKey.PublicKey}
 }
 
-func (pp *PublicParams) Sign(k *PrivateKey, message []byte) Signature {
+// Sign draws the PSS salt from rnd; PKCS #1 v1.5 signatures are deterministic.
+func (pp *PublicParams) Sign(rnd io.Reader, k sign.PrivateKey, message []byte) (sign.Signature, error) {
+	sk, ok := k.(*PrivateKey)
+	if !ok || sk.key == nil {
+		return nil, errors.New("rsa256: empty private key")
+	}
 	hashed := sha256.Sum256(message)
-	signature, err := rsa.SignPKCS1v15(rand.Reader, k.key, crypto.SHA256, hashed[:])
+	var signature []byte
+	var err error
+	if pp.pss {
+		signature, err = rsa.SignPSS(utils.Reader(rnd), sk.key, crypto.SHA256, hashed[:], nil)
+	} else {
+		signature, err = rsa.SignPKCS1v15(nil, sk.key, crypto.SHA256, hashed[:])
+	}
 	if err != nil {
-		log.Fatalf("Fatal error creating rsa signature: %v", err)
+		return nil, fmt.Errorf("creating rsa signature: %w", err)
 	}
-	return signature
+	return signature, nil
 }
 
-func (pp *PublicParams) Verify(p *PublicKey, message, signature []byte) bool {
+func (pp *PublicParams) Verify(p sign.PublicKey, message []byte, signature sign.Signature) bool {
+	pk, ok := p.(*PublicKey)
+	if !ok || pk == nil || pk.key == nil {
+		return false
+	}
 	hashed := sha256.Sum256(message)
-	return rsa.VerifyPKCS1v15(p.key, crypto.SHA256, hashed[:], signature) == nil
+	if pp.pss {
+		return rsa.VerifyPSS(pk.key, crypto.SHA256, hashed[:], signature, nil) == nil
+	}
+	return rsa.VerifyPKCS1v15(pk.key, crypto.SHA256, hashed[:], signature) == nil
+}
+
+func (pp *PublicParams) UnmarshalPrivateKey(data []byte) (sign.PrivateKey, error) {
+	sk := new(PrivateKey)
+	if err := sk.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	if sk.key.N.BitLen() < pp.keySize {
+		return nil, fmt.Errorf("rsa256: key of %d bits is shorter than %d bits", sk.key.N.BitLen(), pp.keySize)
+	}
+	return sk, nil
+}
+
+// UnmarshalPublicKey rejects keys that are shorter than the key size of the scheme.
+func (pp *PublicParams) UnmarshalPublicKey(data []byte) (sign.PublicKey, error) {
+	pk := new(PublicKey)
+	if err := pk.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	if pk.key.N.BitLen() < pp.keySize {
+		return nil, fmt.Errorf("rsa256: key of %d bits is shorter than %d bits", pk.key.N.BitLen(), pp.keySize)
+	}
+	return pk, nil
+}
+
+// MarshalBinary encodes the public key in PKCS #1, ASN.1 DER form.
+func (p *PublicKey) MarshalBinary() ([]byte, error) {
+	if p.key == nil {
+		return nil, errors.New("rsa256: empty public key")
+	}
+	return x509.MarshalPKCS1PublicKey(p.key), nil
+}
+
+func (p *PublicKey) UnmarshalBinary(data []byte) error {
+	key, err := x509.ParsePKCS1PublicKey(data)
+	if err != nil {
+		return err
+	}
+	p.key = key
+	return nil
+}
+
+// MarshalBinary encodes the private key in PKCS #1, ASN.1 DER form.
+func (k *PrivateKey) MarshalBinary() ([]byte, error) {
+	if k.key == nil {
+		return nil, errors.New("rsa256: empty private key")
+	}
+	return x509.MarshalPKCS1PrivateKey(k.key), nil
+}
+
+func (k *PrivateKey) UnmarshalBinary(data []byte) error {
+	key, err := x509.ParsePKCS1PrivateKey(data)
+	if err != nil {
+		return err
+	}
+	k.key = key
+	return nil
+}
+
+func (k *PrivateKey) Public() sign.PublicKey {
+	return &PublicKey{key: &k.key.PublicKey}
 }
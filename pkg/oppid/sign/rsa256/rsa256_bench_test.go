@@ -7,14 +7,14 @@ import (
 
 func BenchmarkRSASign(b *testing.B) {
 	rsa := Setup(2048)
-	sk, _ := rsa.KeyGen()
+	sk, _, _ := rsa.KeyGen(nil)
 
 	msg := []byte("Hello, World!")
 
 	b.ResetTimer()
 	start := time.Now()
 	for i := 0; i < b.N; i++ {
-		rsa.Sign(sk, msg)
+		_, _ = rsa.Sign(nil, sk, msg)
 	}
 	elapsed := time.Since(start)
 	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
@@ -22,10 +22,10 @@ func BenchmarkRSASign(b *testing.B) {
 
 func BenchmarkRSAVerify(b *testing.B) {
 	rsa := Setup(2048)
-	sk, pk := rsa.KeyGen()
+	sk, pk, _ := rsa.KeyGen(nil)
 
 	msg := []byte("Hello, World!")
-	sig := rsa.Sign(sk, msg)
+	sig, _ := rsa.Sign(nil, sk, msg)
 
 	b.ResetTimer()
 	start := time.Now()
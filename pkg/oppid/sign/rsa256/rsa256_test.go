@@ -1,13 +1,17 @@
 package rsa256
 
-import "testing"
+import (
+	"OPPID-artifacts/pkg/oppid/utils"
+	"bytes"
+	"testing"
+)
 
 func TestRSA256SignAndVerify(t *testing.T) {
 	rsa := Setup(2048)
-	sk, pk := rsa.KeyGen()
+	sk, pk, _ := rsa.KeyGen(nil)
 
 	msg := []byte("Hello, World!")
-	sig := rsa.Sign(sk, msg)
+	sig, _ := rsa.Sign(nil, sk, msg)
 
 	isValid := rsa.Verify(pk, msg, sig)
 	if !isValid {
@@ -17,10 +21,10 @@ func TestRSA256SignAndVerify(t *testing.T) {
 
 func TestRSAWrapperBadSignature(t *testing.T) {
 	rsa := Setup(2048)
-	sk, pk := rsa.KeyGen()
+	sk, pk, _ := rsa.KeyGen(nil)
 
 	msg := []byte("Hello, World!")
-	sig := rsa.Sign(sk, msg)
+	sig, _ := rsa.Sign(nil, sk, msg)
 
 	// Modify signature (simulating a bad sign)
 	sig[0] ^= 0xFF
@@ -33,10 +37,10 @@ func TestRSAWrapperBadSignature(t *testing.T) {
 
 func TestRSAWrapperBadMessage(t *testing.T) {
 	rsa := Setup(2048)
-	sk, pk := rsa.KeyGen()
+	sk, pk, _ := rsa.KeyGen(nil)
 
 	msg := []byte("Hello, World!")
-	sig := rsa.Sign(sk, msg)
+	sig, _ := rsa.Sign(nil, sk, msg)
 
 	// Modify message (simulating a different message)
 	msg[0] ^= 0xFF
@@ -46,3 +50,75 @@ func TestRSAWrapperBadMessage(t *testing.T) {
 		t.Fatalf("Expected sign verification to fail with modified message, but succeeded")
 	}
 }
+
+func TestRSA256DeterministicKeyGen(t *testing.T) {
+	keyGen := func(seed string) []byte {
+		rsa := Setup(2048)
+		sk, pk, _ := rsa.KeyGen(utils.NewDeterministicReader([]byte(seed)))
+		sig, err := rsa.Sign(nil, sk, []byte("msg"))
+		if err != nil || !rsa.Verify(pk, []byte("msg"), sig) {
+			t.Fatalf("Expected signature under derived key to be valid")
+		}
+		enc, _ := sk.MarshalBinary()
+		return enc
+	}
+
+	k1, k2 := keyGen("seed"), keyGen("seed")
+	if !bytes.Equal(k1, k2) {
+		t.Fatalf("Expected the same seed to yield the same key")
+	}
+	if bytes.Equal(k1, keyGen("other seed")) {
+		t.Fatalf("Expected different seeds to yield different keys")
+	}
+}
+
+func TestRSAWrapperMalformedInputs(t *testing.T) {
+	rsa := Setup(2048)
+	if _, err := rsa.Sign(nil, &PrivateKey{}, []byte("msg")); err == nil {
+		t.Fatalf("Expected an error when signing with an empty key")
+	}
+	if rsa.Verify(&PublicKey{}, []byte("msg"), []byte("sig")) || rsa.Verify(nil, []byte("msg"), nil) {
+		t.Fatalf("Expected verification with an empty key to fail")
+	}
+}
+
+func TestPSSSignAndVerify(t *testing.T) {
+	pss := SetupPSS(2048)
+	sk, pk, _ := pss.KeyGen(nil)
+
+	msg := []byte("Hello, World!")
+	sig, err := pss.Sign(nil, sk, msg)
+	if err != nil {
+		t.Fatalf("Sign returned an error: %v", err)
+	}
+	if !pss.Verify(pk, msg, sig) {
+		t.Fatalf("Expected PSS signature to be valid")
+	}
+	if Setup(2048).Verify(pk, msg, sig) {
+		t.Fatalf("Expected PSS signature to be invalid under PKCS #1 v1.5")
+	}
+	if pss.Name() != PS256 || Setup(2048).Name() != RS256 {
+		t.Fatalf("Unexpected scheme names")
+	}
+}
+
+func TestUnmarshalShortKey(t *testing.T) {
+	key, err := generateKey(utils.NewDeterministicReader([]byte("seed")), 1024)
+	if err != nil {
+		t.Fatalf("generateKey returned an error: %v", err)
+	}
+	sk := &PrivateKey{key}
+	skBytes, _ := sk.MarshalBinary()
+	pkBytes, _ := sk.Public().MarshalBinary()
+
+	rsa := Setup(2048)
+	if _, err := rsa.UnmarshalPublicKey(pkBytes); err == nil {
+		t.Fatalf("Expected a 1024-bit public key to be rejected")
+	}
+	if _, err := rsa.UnmarshalPrivateKey(skBytes); err == nil {
+		t.Fatalf("Expected a 1024-bit private key to be rejected")
+	}
+	if _, err := Setup(1024).UnmarshalPublicKey(pkBytes); err != nil {
+		t.Fatalf("UnmarshalPublicKey returned an error: %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+// Package schemes resolves the token-signature schemes by name, e.g., when decoding public parameters or key files.
+
+package schemes
+
+import (
+	"OPPID-artifacts/pkg/oppid/sign"
+	"OPPID-artifacts/pkg/oppid/sign/bls"
+	"OPPID-artifacts/pkg/oppid/sign/ecdsa256"
+	"OPPID-artifacts/pkg/oppid/sign/eddsa"
+	"OPPID-artifacts/pkg/oppid/sign/rsa256"
+	"fmt"
+)
+
+// RSAKeySize is the key size of the RSA schemes returned by New and All.
+const RSAKeySize = 2048
+
+// Default is the scheme that protocols use unless configured otherwise.
+func Default() sign.Scheme {
+	return rsa256.Setup(RSAKeySize)
+}
+
+// All returns every supported scheme, with Default first.
+func All() []sign.Scheme {
+	return []sign.Scheme{Default(), rsa256.SetupPSS(RSAKeySize), ecdsa256.Setup(), eddsa.Setup(), bls.SetupMinSig(),
+		bls.SetupMinPK()}
+}
+
+// New returns the scheme with the given name.
+func New(name string) (sign.Scheme, error) {
+	for _, s := range All() {
+		if s.Name() == name {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("sign: unknown scheme %q", name)
+}
@@ -0,0 +1,42 @@
+package schemes
+
+import (
+	"testing"
+	"time"
+)
+
+func BenchmarkSign(b *testing.B) {
+	msg := []byte("Hello, World!")
+	for _, s := range All() {
+		b.Run(s.Name(), func(b *testing.B) {
+			sk, _, _ := s.KeyGen(nil)
+			b.ResetTimer()
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				_, _ = s.Sign(nil, sk, msg)
+			}
+			elapsed := time.Since(start)
+			b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
+		})
+	}
+}
+
+func BenchmarkVerify(b *testing.B) {
+	msg := []byte("Hello, World!")
+	for _, s := range All() {
+		b.Run(s.Name(), func(b *testing.B) {
+			sk, pk, _ := s.KeyGen(nil)
+			sig, _ := s.Sign(nil, sk, msg)
+			b.ReportMetric(float64(len(sig)), "sig-bytes")
+			b.ResetTimer()
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				if !s.Verify(pk, msg, sig) {
+					b.Fatalf("Expected signature to be valid")
+				}
+			}
+			elapsed := time.Since(start)
+			b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
+		})
+	}
+}
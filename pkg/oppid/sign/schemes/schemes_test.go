@@ -0,0 +1,130 @@
+package schemes
+
+import (
+	"OPPID-artifacts/pkg/oppid/utils"
+	"bytes"
+	"testing"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	msg := []byte("Hello, World!")
+	for _, s := range All() {
+		t.Run(s.Name(), func(t *testing.T) {
+			sk, pk, err := s.KeyGen(nil)
+			if err != nil {
+				t.Fatalf("KeyGen returned an error: %v", err)
+			}
+			sig, err := s.Sign(nil, sk, msg)
+			if err != nil {
+				t.Fatalf("Sign returned an error: %v", err)
+			}
+			if !s.Verify(pk, msg, sig) {
+				t.Fatalf("Expected signature to be valid")
+			}
+			if s.Verify(pk, []byte("Hello, World?"), sig) {
+				t.Fatalf("Expected signature on another message to be invalid")
+			}
+			sig[len(sig)-1] ^= 1
+			if s.Verify(pk, msg, sig) {
+				t.Fatalf("Expected modified signature to be invalid")
+			}
+			if s.Verify(pk, msg, nil) || s.Verify(nil, msg, sig) {
+				t.Fatalf("Expected verification of malformed inputs to fail")
+			}
+			if _, err := s.Sign(nil, nil, msg); err == nil {
+				t.Fatalf("Expected an error when signing without a key")
+			}
+		})
+	}
+}
+
+func TestKeyEncoding(t *testing.T) {
+	msg := []byte("Hello, World!")
+	for _, s := range All() {
+		t.Run(s.Name(), func(t *testing.T) {
+			sk, pk, _ := s.KeyGen(nil)
+			skBytes, err := sk.MarshalBinary()
+			if err != nil {
+				t.Fatalf("MarshalBinary returned an error: %v", err)
+			}
+			pkBytes, err := pk.MarshalBinary()
+			if err != nil {
+				t.Fatalf("MarshalBinary returned an error: %v", err)
+			}
+
+			sk2, err := s.UnmarshalPrivateKey(skBytes)
+			if err != nil {
+				t.Fatalf("UnmarshalPrivateKey returned an error: %v", err)
+			}
+			pk2, err := s.UnmarshalPublicKey(pkBytes)
+			if err != nil {
+				t.Fatalf("UnmarshalPublicKey returned an error: %v", err)
+			}
+			if pub, _ := sk2.Public().MarshalBinary(); !bytes.Equal(pub, pkBytes) {
+				t.Fatalf("Decoded private key has another public key")
+			}
+			sig, _ := s.Sign(nil, sk2, msg)
+			if !s.Verify(pk2, msg, sig) {
+				t.Fatalf("Expected signature under decoded keys to be valid")
+			}
+
+			if _, err := s.UnmarshalPublicKey(pkBytes[1:]); err == nil {
+				t.Fatalf("Expected truncated public key to be rejected")
+			}
+			if _, err := s.UnmarshalPrivateKey(nil); err == nil {
+				t.Fatalf("Expected empty private key to be rejected")
+			}
+		})
+	}
+}
+
+func TestDeterministicKeyGen(t *testing.T) {
+	for _, s := range All() {
+		t.Run(s.Name(), func(t *testing.T) {
+			keyGen := func(seed string) []byte {
+				sk, _, err := s.KeyGen(utils.NewDeterministicReader([]byte(seed)))
+				if err != nil {
+					t.Fatalf("KeyGen returned an error: %v", err)
+				}
+				enc, _ := sk.MarshalBinary()
+				return enc
+			}
+			k1 := keyGen("seed")
+			if !bytes.Equal(k1, keyGen("seed")) {
+				t.Fatalf("Expected the same seed to yield the same key")
+			}
+			if bytes.Equal(k1, keyGen("other seed")) {
+				t.Fatalf("Expected different seeds to yield different keys")
+			}
+		})
+	}
+}
+
+func TestKeysAreBoundToScheme(t *testing.T) {
+	msg := []byte("Hello, World!")
+	all := All()
+	for _, s := range all {
+		sk, pk, _ := s.KeyGen(nil)
+		for _, other := range all {
+			if other.Name() == s.Name() {
+				continue
+			}
+			sig, err := other.Sign(nil, sk, msg)
+			if err == nil && other.Verify(pk, msg, sig) && s.Verify(pk, msg, sig) {
+				t.Errorf("%s signature with a %s key verifies under both schemes", other.Name(), s.Name())
+			}
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, s := range All() {
+		got, err := New(s.Name())
+		if err != nil || got.Name() != s.Name() {
+			t.Errorf("New(%q) returned %v, %v", s.Name(), got, err)
+		}
+	}
+	if _, err := New("none"); err == nil {
+		t.Errorf("Expected an unknown scheme to be rejected")
+	}
+}
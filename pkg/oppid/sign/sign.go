@@ -0,0 +1,45 @@
+// Package sign defines the interface of the signature schemes that IdPs use to sign tokens. Implementations are
+// rsa256 (RS256 and PS256), ecdsa256 (ES256), eddsa (Ed25519) and bls (BLS12381-MinSig and BLS12381-MinPK); package
+// schemes resolves them by name.
+
+// References:
+// [1] https://www.iana.org/assignments/jose/jose.xhtml#web-signature-encryption-algorithms
+
+package sign
+
+import (
+	"io"
+)
+
+type Signature = []byte
+
+// PublicKey is a verification key in the encoding of its scheme.
+type PublicKey interface {
+	MarshalBinary() ([]byte, error)
+}
+
+// PrivateKey is a signing key in the encoding of its scheme.
+type PrivateKey interface {
+	MarshalBinary() ([]byte, error)
+	Public() PublicKey
+}
+
+// Scheme is a signature scheme. Keys of one scheme are rejected by the others, except for the RSA schemes, which
+// share their keys. KeyGen and Sign draw their randomness from rnd, or from crypto/rand if rnd is nil.
+type Scheme interface {
+	// Name is the JOSE algorithm name [1] of the scheme, e.g., "RS256", or, for schemes without one, a descriptive
+	// name, e.g., "BLS12381-MinSig".
+	Name() string
+	KeyGen(rnd io.Reader) (PrivateKey, PublicKey, error)
+	Sign(rnd io.Reader, k PrivateKey, msg []byte) (Signature, error)
+	// Verify returns false for malformed keys and signatures.
+	Verify(p PublicKey, msg []byte, sig Signature) bool
+	UnmarshalPrivateKey(data []byte) (PrivateKey, error)
+	UnmarshalPublicKey(data []byte) (PublicKey, error)
+}
+
+// BatchVerifier is implemented by schemes that verify many signatures faster at once than one by one. BatchVerify
+// reports whether all signatures are valid; it draws its randomness from rnd, or from crypto/rand if rnd is nil.
+type BatchVerifier interface {
+	BatchVerify(rnd io.Reader, pks []PublicKey, msgs [][]byte, sigs []Signature) bool
+}
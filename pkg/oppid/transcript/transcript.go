@@ -0,0 +1,45 @@
+// Package transcript provides an unambiguous encoding of labeled fields, used for the bytes that are signed or hashed
+// into a challenge. Plain concatenation of variable-length fields is ambiguous, e.g., ("ab", "c") and ("a", "bc")
+// yield the same bytes; here every label and value is length-prefixed, so distinct field lists never collide.
+//
+// A transcript starts with the encoding version and a domain separation tag (dst), followed by the appended fields:
+//
+//	field = len(label) (4 bytes) || label || len(value) (8 bytes) || value
+//
+// with lengths in big-endian.
+
+package transcript
+
+import (
+	"encoding/binary"
+)
+
+// Version identifies the encoding. It is the first field of every transcript, so that a future encoding cannot
+// collide with this one.
+const Version = "OPPID_TRANSCRIPT_V1"
+
+type Transcript struct {
+	buf []byte
+}
+
+// New starts a transcript in the domain dst.
+func New(dst string) *Transcript {
+	t := &Transcript{}
+	t.Append("version", []byte(Version))
+	t.Append("dst", []byte(dst))
+	return t
+}
+
+// Append adds a labeled field and returns t to allow chaining.
+func (t *Transcript) Append(label string, value []byte) *Transcript {
+	t.buf = binary.BigEndian.AppendUint32(t.buf, uint32(len(label)))
+	t.buf = append(t.buf, label...)
+	t.buf = binary.BigEndian.AppendUint64(t.buf, uint64(len(value)))
+	t.buf = append(t.buf, value...)
+	return t
+}
+
+// Bytes returns the encoded transcript.
+func (t *Transcript) Bytes() []byte {
+	return t.buf
+}
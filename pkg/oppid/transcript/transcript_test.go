@@ -0,0 +1,47 @@
+package transcript
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFieldBoundaries(t *testing.T) {
+	cases := []struct {
+		name string
+		a, b *Transcript
+	}{
+		{"shifted boundary",
+			New("dst").Append("ctx", []byte("ab")).Append("sid", []byte("c")),
+			New("dst").Append("ctx", []byte("a")).Append("sid", []byte("bc"))},
+		{"empty field",
+			New("dst").Append("ctx", nil).Append("sid", []byte("abc")),
+			New("dst").Append("ctx", []byte("abc")).Append("sid", nil)},
+		{"label and value",
+			New("dst").Append("ab", []byte("c")),
+			New("dst").Append("a", []byte("bc"))},
+		{"dst and field",
+			New("dstctx").Append("", nil),
+			New("dst").Append("ctx", nil)},
+		{"domain",
+			New("TOKEN").Append("sid", []byte("abc")),
+			New("CERT").Append("sid", []byte("abc"))},
+	}
+	for _, c := range cases {
+		if bytes.Equal(c.a.Bytes(), c.b.Bytes()) {
+			t.Errorf("%s: distinct transcripts encode to the same bytes", c.name)
+		}
+	}
+}
+
+func TestEncoding(t *testing.T) {
+	got := New("D").Append("l", []byte("v")).Bytes()
+	want := []byte{
+		0, 0, 0, 7, 'v', 'e', 'r', 's', 'i', 'o', 'n', 0, 0, 0, 0, 0, 0, 0, byte(len(Version)),
+	}
+	want = append(want, Version...)
+	want = append(want, 0, 0, 0, 3, 'd', 's', 't', 0, 0, 0, 0, 0, 0, 0, 1, 'D')
+	want = append(want, 0, 0, 0, 1, 'l', 0, 0, 0, 0, 0, 0, 0, 1, 'v')
+	if !bytes.Equal(got, want) {
+		t.Fatalf("unexpected encoding\ngot  %x\nwant %x", got, want)
+	}
+}
@@ -2,14 +2,16 @@ package utils
 
 import (
 	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
 	"crypto/rand"
-	"encoding/hex"
+	"crypto/sha256"
 	"errors"
+	"fmt"
 	GG "github.com/cloudflare/circl/ecc/bls12381"
 	"github.com/cloudflare/circl/expander"
-	"log"
+	"io"
 	"math/big"
-	"strings"
 )
 
 // HashToScalar hashes a byte array to a scalar using sha256 hash function
@@ -45,79 +47,109 @@ func MulScalars(s1, s2 *GG.Scalar) (*GG.Scalar, error) {
 	return scalar, nil
 }
 
-func GenerateRandomScalar() *GG.Scalar {
+// Reader returns rnd, or crypto/rand.Reader if rnd is nil.
+func Reader(rnd io.Reader) io.Reader {
+	if rnd == nil {
+		return rand.Reader
+	}
+	return rnd
+}
+
+// NewDeterministicReader returns a reproducible stream of bytes derived from seed: AES-256-CTR under the key
+// SHA-256(seed) with an all-zero IV. It is meant for test vectors only and must never be used for real keys. It is not
+// safe for concurrent use.
+func NewDeterministicReader(seed []byte) io.Reader {
+	key := sha256.Sum256(seed)
+	block, _ := aes.NewCipher(key[:]) // never fails for a 32-byte key
+	stream := cipher.NewCTR(block, make([]byte, aes.BlockSize))
+	return cipher.StreamReader{S: stream, R: zeroReader{}}
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func GenerateRandomScalar() (*GG.Scalar, error) {
+	return GenerateRandomScalarFrom(nil)
+}
+
+// GenerateRandomScalarFrom draws a scalar from rnd, or from crypto/rand if rnd is nil.
+func GenerateRandomScalarFrom(rnd io.Reader) (*GG.Scalar, error) {
 	scalar := new(GG.Scalar)
-	if err := scalar.Random(rand.Reader); err != nil {
-		log.Fatalf("Fatal error creating random sclar: %v", err)
+	if err := scalar.Random(Reader(rnd)); err != nil {
+		return nil, fmt.Errorf("creating random scalar: %w", err)
 	}
-	return scalar
+	return scalar, nil
 }
 
-func GenerateRandomScalarNotOne() *GG.Scalar {
+func GenerateRandomScalarNotOne() (*GG.Scalar, error) {
+	return GenerateRandomScalarNotOneFrom(nil)
+}
+
+func GenerateRandomScalarNotOneFrom(rnd io.Reader) (*GG.Scalar, error) {
 	one := new(GG.Scalar)
 	one.SetOne()
 	retryLimit := 3
 	for retries := 0; retries <= retryLimit; retries++ {
-		scalar := GenerateRandomScalar()
-		if scalar.IsEqual(one) == 0 {
-			return scalar
+		scalar, err := GenerateRandomScalarFrom(rnd)
+		if err != nil {
+			return nil, err
 		}
-		if retries == retryLimit {
-			log.Fatalf("Fatal error: failed to generate a random scalar not equal to 1 after %d attempts", retries+1)
+		if scalar.IsEqual(one) == 0 && scalar.IsZero() == 0 {
+			return scalar, nil
 		}
 	}
-	return nil // This line will never be reached because of log.Fatalf
+	return nil, fmt.Errorf("failed to generate a random scalar other than 0 and 1 after %d attempts", retryLimit+1)
 }
 
+// The group operations below are closed: for valid group elements they always return a valid group element. Inputs
+// from other parties must therefore be checked at the boundary, with the Decode functions or IsValidG1/IsValidG2.
+
 func GenerateG1Point(scalar *GG.Scalar, base *GG.G1) *GG.G1 {
 	point := new(GG.G1)
 	point.ScalarMult(scalar, base)
-	if !point.IsOnG1() {
-		log.Fatalf("Fatal error: invalid G1 point after scalar multiplication")
-	}
 	return point
 }
 
 func AddG1Points(g1 *GG.G1, g2 *GG.G1) *GG.G1 {
 	point := new(GG.G1)
 	point.Add(g1, g2)
-	if !point.IsOnG1() {
-		log.Fatalf("Fatal error: invalid G1 point after addition")
-	}
 	return point
 }
 
 func GenerateG2Point(scalar *GG.Scalar, generator *GG.G2) *GG.G2 {
 	point := new(GG.G2)
 	point.ScalarMult(scalar, generator)
-	if !point.IsOnG2() {
-		log.Fatalf("Fatal error: invalid G2 point after multiplication")
-	}
 	return point
 }
 
 func AddG2Points(g1 *GG.G2, g2 *GG.G2) *GG.G2 {
 	point := new(GG.G2)
 	point.Add(g1, g2)
-	if !point.IsOnG2() {
-		log.Fatalf("Fatal error: invalid G2 point after addition")
-	}
 	return point
 }
 
-func ScalarToBytes(scalar *GG.Scalar) []byte {
-	hexString := scalar.String()
-	// Check if the string starts with "0x" and remove it
-	if strings.HasPrefix(hexString, "0x") {
-		hexString = hexString[2:]
-	}
+// IsValidG1 reports whether p is set, in G1 and not the identity.
+func IsValidG1(p *GG.G1) bool {
+	return p != nil && p.IsOnG1() && !p.IsIdentity()
+}
 
-	bytes, err := hex.DecodeString(hexString)
-	if err != nil {
-		log.Fatalf("Fatal error decoding string to bytes: %v", err)
-	}
+// IsValidG2 reports whether p is set, in G2 and not the identity.
+func IsValidG2(p *GG.G2) bool {
+	return p != nil && p.IsOnG2() && !p.IsIdentity()
+}
 
-	return bytes
+// IsValidScalar reports whether s is set and not zero.
+func IsValidScalar(s *GG.Scalar) bool {
+	return s != nil && s.IsZero() == 0
+}
+
+// ScalarToBytes returns the big-endian encoding of a scalar, like ScalarBytes.
+func ScalarToBytes(scalar *GG.Scalar) []byte {
+	return ScalarBytes(scalar)
 }
 
 func BytesToScalar(data []byte) *GG.Scalar {
@@ -126,3 +158,57 @@ func BytesToScalar(data []byte) *GG.Scalar {
 	scalar.SetBytes(bigInt.Bytes())
 	return scalar
 }
+
+// ScalarBytes returns the fixed-size (GG.ScalarSize) big-endian encoding of a scalar.
+func ScalarBytes(scalar *GG.Scalar) []byte {
+	b, _ := scalar.MarshalBinary() // never fails
+	return b
+}
+
+// DecodeScalar decodes a fixed-size scalar and rejects inputs of the wrong length or outside the scalar field.
+func DecodeScalar(data []byte) (*GG.Scalar, error) {
+	if len(data) != GG.ScalarSize {
+		return nil, errors.New("invalid scalar length")
+	}
+	scalar := new(GG.Scalar)
+	if err := scalar.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return scalar, nil
+}
+
+// DecodeG1 decodes a compressed G1 point and rejects inputs of the wrong length or not in G1.
+func DecodeG1(data []byte) (*GG.G1, error) {
+	if len(data) != GG.G1SizeCompressed {
+		return nil, errors.New("invalid G1 point length")
+	}
+	point := new(GG.G1)
+	if err := point.SetBytes(data); err != nil {
+		return nil, err
+	}
+	return point, nil
+}
+
+// DecodeG2 decodes a compressed G2 point and rejects inputs of the wrong length or not in G2.
+func DecodeG2(data []byte) (*GG.G2, error) {
+	if len(data) != GG.G2SizeCompressed {
+		return nil, errors.New("invalid G2 point length")
+	}
+	point := new(GG.G2)
+	if err := point.SetBytes(data); err != nil {
+		return nil, err
+	}
+	return point, nil
+}
+
+// DecodeGt decodes a Gt element and rejects inputs of the wrong length.
+func DecodeGt(data []byte) (*GG.Gt, error) {
+	if len(data) != GG.GtSize {
+		return nil, errors.New("invalid Gt element length")
+	}
+	element := new(GG.Gt)
+	if err := element.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return element, nil
+}
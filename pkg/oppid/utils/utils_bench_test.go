@@ -23,15 +23,15 @@ func BenchmarkGenerateRandomScalar(b *testing.B) {
 	b.ResetTimer()
 	start := time.Now()
 	for i := 0; i < b.N; i++ {
-		GenerateRandomScalar()
+		_, _ = GenerateRandomScalar()
 	}
 	elapsed := time.Since(start)
 	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
 }
 
 func BenchmarkAddScalars(b *testing.B) {
-	s1 := GenerateRandomScalar()
-	s2 := GenerateRandomScalar()
+	s1, _ := GenerateRandomScalar()
+	s2, _ := GenerateRandomScalar()
 
 	b.ResetTimer()
 	start := time.Now()
@@ -43,8 +43,8 @@ func BenchmarkAddScalars(b *testing.B) {
 }
 
 func BenchmarkMulScalars(b *testing.B) {
-	s1 := GenerateRandomScalar()
-	s2 := GenerateRandomScalar()
+	s1, _ := GenerateRandomScalar()
+	s2, _ := GenerateRandomScalar()
 
 	b.ResetTimer()
 	start := time.Now()
@@ -57,7 +57,7 @@ func BenchmarkMulScalars(b *testing.B) {
 
 func BenchmarkGenG1Point(b *testing.B) {
 	g := GG.G1Generator()
-	s := GenerateRandomScalar()
+	s, _ := GenerateRandomScalar()
 
 	b.ResetTimer()
 	start := time.Now()
@@ -70,7 +70,8 @@ func BenchmarkGenG1Point(b *testing.B) {
 
 func BenchmarkAddG1Point(b *testing.B) {
 	g1 := GG.G1Generator()
-	g2 := GenerateG1Point(GenerateRandomScalar(), g1)
+	s, _ := GenerateRandomScalar()
+	g2 := GenerateG1Point(s, g1)
 
 	b.ResetTimer()
 	start := time.Now()
@@ -83,7 +84,7 @@ func BenchmarkAddG1Point(b *testing.B) {
 
 func BenchmarkGenG2Point(b *testing.B) {
 	g := GG.G2Generator()
-	s := GenerateRandomScalar()
+	s, _ := GenerateRandomScalar()
 
 	b.ResetTimer()
 	start := time.Now()
@@ -96,7 +97,8 @@ func BenchmarkGenG2Point(b *testing.B) {
 
 func BenchmarkAddG2Point(b *testing.B) {
 	g1 := GG.G2Generator()
-	g2 := GenerateG2Point(GenerateRandomScalar(), g1)
+	s, _ := GenerateRandomScalar()
+	g2 := GenerateG2Point(s, g1)
 
 	b.ResetTimer()
 	start := time.Now()
@@ -108,8 +110,10 @@ func BenchmarkAddG2Point(b *testing.B) {
 }
 
 func BenchmarkPairing(b *testing.B) {
-	g1 := GenerateG1Point(GenerateRandomScalar(), GG.G1Generator())
-	g2 := GenerateG2Point(GenerateRandomScalar(), GG.G2Generator())
+	s1, _ := GenerateRandomScalar()
+	s2, _ := GenerateRandomScalar()
+	g1 := GenerateG1Point(s1, GG.G1Generator())
+	g2 := GenerateG2Point(s2, GG.G2Generator())
 
 	b.ResetTimer()
 	start := time.Now()
@@ -119,3 +123,63 @@ func BenchmarkPairing(b *testing.B) {
 	elapsed := time.Since(start)
 	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
 }
+
+// BenchmarkG1ScalarMult is the baseline of BenchmarkG1TableMul.
+func BenchmarkG1ScalarMult(b *testing.B) {
+	k, _ := GenerateRandomScalar()
+	g := GG.G1Generator()
+	b.ResetTimer()
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		GenerateG1Point(k, g)
+	}
+	elapsed := time.Since(start)
+	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
+}
+
+func BenchmarkG1TableMul(b *testing.B) {
+	k, _ := GenerateRandomScalar()
+	t := NewG1Table(GG.G1Generator())
+	b.ResetTimer()
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		t.Mul(k)
+	}
+	elapsed := time.Since(start)
+	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
+}
+
+// BenchmarkG2ScalarMult is the baseline of BenchmarkG2TableMul.
+func BenchmarkG2ScalarMult(b *testing.B) {
+	k, _ := GenerateRandomScalar()
+	g := GG.G2Generator()
+	b.ResetTimer()
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		GenerateG2Point(k, g)
+	}
+	elapsed := time.Since(start)
+	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
+}
+
+func BenchmarkG2TableMul(b *testing.B) {
+	k, _ := GenerateRandomScalar()
+	t := NewG2Table(GG.G2Generator())
+	b.ResetTimer()
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		t.Mul(k)
+	}
+	elapsed := time.Since(start)
+	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
+}
+
+func BenchmarkNewG1Table(b *testing.B) {
+	b.ResetTimer()
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		NewG1Table(GG.G1Generator())
+	}
+	elapsed := time.Since(start)
+	b.ReportMetric(float64(elapsed.Milliseconds())/float64(b.N), "ms/op")
+}
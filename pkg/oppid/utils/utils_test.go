@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"errors"
 	GG "github.com/cloudflare/circl/ecc/bls12381"
 	"testing"
 )
@@ -12,9 +13,22 @@ func TestHashToScalar(t *testing.T) {
 	}
 }
 
+func TestDeterministicReader(t *testing.T) {
+	s1, _ := GenerateRandomScalarFrom(NewDeterministicReader([]byte("seed")))
+	s2, _ := GenerateRandomScalarFrom(NewDeterministicReader([]byte("seed")))
+	s3, _ := GenerateRandomScalarFrom(NewDeterministicReader([]byte("other seed")))
+
+	if s1.IsEqual(s2) != 1 {
+		t.Errorf("Expected the same seed to yield the same scalar")
+	}
+	if s1.IsEqual(s3) == 1 {
+		t.Errorf("Expected different seeds to yield different scalars")
+	}
+}
+
 func TestAddScalars(t *testing.T) {
-	s1 := GenerateRandomScalar()
-	s2 := GenerateRandomScalar()
+	s1, _ := GenerateRandomScalar()
+	s2, _ := GenerateRandomScalar()
 
 	sum, _ := AddScalars(s1, s2)
 
@@ -24,8 +38,8 @@ func TestAddScalars(t *testing.T) {
 }
 
 func TestMulScalars(t *testing.T) {
-	s1 := GenerateRandomScalar()
-	s2 := GenerateRandomScalar()
+	s1, _ := GenerateRandomScalar()
+	s2, _ := GenerateRandomScalar()
 
 	product, _ := MulScalars(s1, s2)
 
@@ -36,7 +50,7 @@ func TestMulScalars(t *testing.T) {
 
 func TestGenerateG1Point(t *testing.T) {
 	base := GG.G1Generator()
-	scalar := GenerateRandomScalar()
+	scalar, _ := GenerateRandomScalar()
 
 	point := GenerateG1Point(scalar, base)
 	if !point.IsOnG1() {
@@ -57,7 +71,7 @@ func TestAddG1Points(t *testing.T) {
 
 func TestGenerateG2Point(t *testing.T) {
 	generator := GG.G2Generator()
-	scalar := GenerateRandomScalar()
+	scalar, _ := GenerateRandomScalar()
 
 	point := GenerateG2Point(scalar, generator)
 
@@ -78,7 +92,7 @@ func TestAddG2Points(t *testing.T) {
 }
 
 func TestScalarToBytes(t *testing.T) {
-	scalar := GenerateRandomScalar()
+	scalar, _ := GenerateRandomScalar()
 
 	bytes := ScalarToBytes(scalar)
 
@@ -88,7 +102,7 @@ func TestScalarToBytes(t *testing.T) {
 }
 
 func TestBytesToScalar(t *testing.T) {
-	scalar := GenerateRandomScalar()
+	scalar, _ := GenerateRandomScalar()
 	bytes := ScalarToBytes(scalar)
 
 	reconstructedScalar := BytesToScalar(bytes)
@@ -131,7 +145,7 @@ func TestMulScalarsErrorOnZero(t *testing.T) {
 	if zero.IsZero() != 1 {
 		t.Errorf("Zero scalar is not zero")
 	}
-	rndScalar := GenerateRandomScalar()
+	rndScalar, _ := GenerateRandomScalar()
 
 	_, err := MulScalars(rndScalar, zero)
 	if err == nil {
@@ -140,3 +154,90 @@ func TestMulScalarsErrorOnZero(t *testing.T) {
 		t.Logf("Received expected error: %v", err)
 	}
 }
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("no entropy") }
+
+func TestGenerateRandomScalarFromFailingReader(t *testing.T) {
+	if _, err := GenerateRandomScalarFrom(failingReader{}); err == nil {
+		t.Errorf("Expected error from a failing randomness source, but got nil")
+	}
+	if _, err := GenerateRandomScalarNotOneFrom(failingReader{}); err == nil {
+		t.Errorf("Expected error from a failing randomness source, but got nil")
+	}
+}
+
+func TestIsValid(t *testing.T) {
+	identity1 := new(GG.G1)
+	identity1.SetIdentity()
+	identity2 := new(GG.G2)
+	identity2.SetIdentity()
+
+	if IsValidG1(nil) || IsValidG1(identity1) || !IsValidG1(GG.G1Generator()) {
+		t.Errorf("IsValidG1 misclassified a point")
+	}
+	if IsValidG2(nil) || IsValidG2(identity2) || !IsValidG2(GG.G2Generator()) {
+		t.Errorf("IsValidG2 misclassified a point")
+	}
+	if IsValidScalar(nil) || IsValidScalar(new(GG.Scalar)) {
+		t.Errorf("IsValidScalar accepted a missing or zero scalar")
+	}
+}
+
+func TestFixedBaseTables(t *testing.T) {
+	base1 := new(GG.G1)
+	base1.Hash([]byte("base"), []byte("Test dst"))
+	base2 := GenerateG2Point(hashToScalarPtr(t, "base"), GG.G2Generator())
+	t1, t2 := NewG1Table(base1), NewG2Table(base2)
+
+	edge := []*GG.Scalar{new(GG.Scalar), new(GG.Scalar), new(GG.Scalar)}
+	edge[1].SetOne()
+	edge[2].SetOne()
+	edge[2].Neg() // r-1, all windows in use
+	scalars := edge
+	for i := 0; i < 8; i++ {
+		k, _ := GenerateRandomScalar()
+		scalars = append(scalars, k)
+	}
+	for i, k := range scalars {
+		if !t1.Mul(k).IsEqual(GenerateG1Point(k, base1)) {
+			t.Errorf("G1 table multiplication %d differs from ScalarMult", i)
+		}
+		if !t2.Mul(k).IsEqual(GenerateG2Point(k, base2)) {
+			t.Errorf("G2 table multiplication %d differs from ScalarMult", i)
+		}
+		if !MulG1Generator(k).IsEqual(GenerateG1Point(k, GG.G1Generator())) ||
+			!MulG2Generator(k).IsEqual(GenerateG2Point(k, GG.G2Generator())) {
+			t.Errorf("generator table multiplication %d differs from ScalarMult", i)
+		}
+	}
+
+	if !t1.IsBase(base1) || t1.IsBase(GG.G1Generator()) {
+		t.Errorf("Expected G1 table to match its base only")
+	}
+	k := scalars[len(scalars)-1]
+	if !MulG1(t1, k, GG.G1Generator()).IsEqual(GenerateG1Point(k, GG.G1Generator())) {
+		t.Errorf("Expected MulG1 to fall back to ScalarMult for another base")
+	}
+	if !MulG2(nil, k, base2).IsEqual(GenerateG2Point(k, base2)) {
+		t.Errorf("Expected MulG2 to fall back to ScalarMult without a table")
+	}
+}
+
+func hashToScalarPtr(tb testing.TB, s string) *GG.Scalar {
+	k := HashToScalar([]byte(s), []byte("Test dst"))
+	return &k
+}
+
+func TestUseFixedBaseTables(t *testing.T) {
+	k, _ := GenerateRandomScalar()
+	want := GenerateG2Point(k, GG.G2Generator())
+	if prev := UseFixedBaseTables(false); !prev {
+		t.Fatalf("Expected tables to be on by default")
+	}
+	defer UseFixedBaseTables(true)
+	if !MulG2Generator(k).IsEqual(want) || !MulG1Generator(k).IsEqual(GenerateG1Point(k, GG.G1Generator())) {
+		t.Errorf("Expected the same result without tables")
+	}
+}
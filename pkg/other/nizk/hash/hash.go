@@ -8,6 +8,11 @@ package hash
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark/backend/groth16"
 	"github.com/consensys/gnark/backend/witness"
@@ -15,16 +20,38 @@ import (
 	"github.com/consensys/gnark/frontend"
 	"github.com/consensys/gnark/frontend/cs/r1cs"
 	"github.com/consensys/gnark/std/math/uints"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
 )
 
+// Setup and KeyGen keep the circuit and its Groth16 keys as artifacts in a directory, together with a manifest of the
+// circuit they belong to and the SHA-256 digest of every file.
 const (
-	circuitFileName = "circuit.r1cs.bin"
-	pkFileName      = "proving_key.bin"
-	vkFileName      = "verification_key.bin"
+	circuitFileName  = "circuit.r1cs.bin"
+	pkFileName       = "proving_key.bin"
+	vkFileName       = "verification_key.bin"
+	manifestFileName = "manifest.json"
 )
 
-type PublicParams struct{ CS constraint.ConstraintSystem }
+// ErrCorruptArtifact is returned by KeyGen if an artifact does not match its digest in the manifest.
+var ErrCorruptArtifact = errors.New("hash: artifact does not match the manifest")
+
+// errStale reports artifacts that are missing or belong to another circuit, which KeyGen generates anew.
+var errStale = errors.New("hash: artifacts are missing or stale")
+
+type PublicParams struct {
+	CS          constraint.ConstraintSystem
+	dir         string
+	circuitHash string
+}
+
+// manifest describes the artifacts in a directory.
+type manifest struct {
+	Circuit string            `json:"circuit"` // SHA-256 of the serialized constraint system
+	Files   map[string]string `json:"files"`   // SHA-256 of every artifact by file name
+}
 
 type ProvingKey struct{ key groth16.ProvingKey }
 type VerifyingKey struct{ key groth16.VerifyingKey }
@@ -37,182 +64,167 @@ type PublicWitness struct{ witness witness.Witness }
 
 type Proof struct{ proof groth16.Proof }
 
-func loadCS(csFilePath string) (constraint.ConstraintSystem, error) {
-	csFile, err := os.Open(csFilePath)
-	if err != nil {
-		return nil, err
-	}
-	defer func(pkFile *os.File) {
-		_ = pkFile.Close()
-	}(csFile)
+func digest(data []byte) string {
+	d := sha256.Sum256(data)
+	return hex.EncodeToString(d[:])
+}
 
+func encode(w io.WriterTo) ([]byte, error) {
 	var buf bytes.Buffer
-	_, err = buf.ReadFrom(csFile)
-	if err != nil {
+	if _, err := w.WriteTo(&buf); err != nil {
 		return nil, err
 	}
+	return buf.Bytes(), nil
+}
 
-	cs := groth16.NewCS(ecc.BLS12_381)
-	_, err = cs.ReadFrom(&buf)
+// writeFileAtomic writes data to a temporary file in dir and renames it to name, such that a reader never sees a
+// partially written file.
+func writeFileAtomic(dir, name string, data []byte) error {
+	f, err := os.CreateTemp(dir, name+".tmp*")
 	if err != nil {
-		return nil, err
+		return err
 	}
+	tmp := f.Name()
+	defer func() {
+		_ = os.Remove(tmp) // fails after the rename
+	}()
 
-	return cs, nil
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, name))
 }
 
-func loadKeys(pkFilePath, vkFilePath string) (groth16.ProvingKey, groth16.VerifyingKey, error) {
-	pkFile, err := os.Open(pkFilePath)
+// readArtifact reads the file name in dir and checks it against its digest in m.
+func readArtifact(dir, name string, m *manifest) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, errStale
+	}
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	defer func(pkFile *os.File) {
-		_ = pkFile.Close()
-	}(pkFile)
+	if want, ok := m.Files[name]; !ok || digest(data) != want {
+		return nil, fmt.Errorf("%w: %s", ErrCorruptArtifact, filepath.Join(dir, name))
+	}
+	return data, nil
+}
 
-	var buf bytes.Buffer
-	_, err = buf.ReadFrom(pkFile)
+// loadKeys loads the keys from the artifacts in pp.dir after verifying them against the manifest.
+func (pp *PublicParams) loadKeys() (groth16.ProvingKey, groth16.VerifyingKey, error) {
+	data, err := os.ReadFile(filepath.Join(pp.dir, manifestFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, errStale
+	}
 	if err != nil {
 		return nil, nil, err
 	}
+	var m manifest
+	if err = json.Unmarshal(data, &m); err != nil {
+		return nil, nil, fmt.Errorf("%w: %s: %v", ErrCorruptArtifact, filepath.Join(pp.dir, manifestFileName), err)
+	}
+	if m.Circuit != pp.circuitHash {
+		return nil, nil, errStale
+	}
 
-	pk := groth16.NewProvingKey(ecc.BLS12_381)
-	_, err = pk.ReadFrom(&buf)
-	if err != nil {
+	if _, err = readArtifact(pp.dir, circuitFileName, &m); err != nil {
 		return nil, nil, err
 	}
-
-	vkFile, err := os.Open(vkFilePath)
+	pkData, err := readArtifact(pp.dir, pkFileName, &m)
 	if err != nil {
 		return nil, nil, err
 	}
-	defer func(vkFile *os.File) {
-		_ = vkFile.Close()
-	}(vkFile)
-
-	buf.Reset()
-	_, err = buf.ReadFrom(vkFile)
+	vkData, err := readArtifact(pp.dir, vkFileName, &m)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	pk := groth16.NewProvingKey(ecc.BLS12_381)
+	if _, err = pk.ReadFrom(bytes.NewReader(pkData)); err != nil {
+		return nil, nil, err
+	}
 	vk := groth16.NewVerifyingKey(ecc.BLS12_381)
-	_, err = vk.ReadFrom(&buf)
-	if err != nil {
+	if _, err = vk.ReadFrom(bytes.NewReader(vkData)); err != nil {
 		return nil, nil, err
 	}
-
 	return pk, vk, nil
 }
 
-func saveCSToFile(cs constraint.ConstraintSystem, csFilePath string) error {
-	csFile, err := os.OpenFile(csFilePath, os.O_RDWR|os.O_CREATE, 0755)
-	if err != nil {
-		return nil
-	}
-	defer func(pkFile *os.File) {
-		_ = pkFile.Close()
-	}(csFile)
-
-	var buf bytes.Buffer
-	_, err = cs.WriteTo(&buf)
-	if err != nil {
-		return err
-	}
-
-	_, err = csFile.Write(buf.Bytes())
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func generateAndSaveKeysToFiles(cs constraint.ConstraintSystem, pkFilePath, vkFilePath string) (groth16.ProvingKey, groth16.VerifyingKey, error) {
-
-	pk, vk, err := groth16.Setup(cs)
+// generateKeys runs the Groth16 setup and replaces the artifacts in pp.dir. The manifest is removed first and
+// written last, so an interrupted run leaves artifacts that are regenerated rather than loaded.
+func (pp *PublicParams) generateKeys() (groth16.ProvingKey, groth16.VerifyingKey, error) {
+	pk, vk, err := groth16.Setup(pp.CS)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	pkFile, err := os.OpenFile(pkFilePath, os.O_RDWR|os.O_CREATE, 0755)
-	if err != nil {
+	if err = os.MkdirAll(pp.dir, 0755); err != nil {
 		return nil, nil, err
 	}
-	defer func(pkFile *os.File) {
-		_ = pkFile.Close()
-	}(pkFile)
-
-	var buf bytes.Buffer
-	_, err = pk.WriteTo(&buf)
-	if err != nil {
+	if err = os.Remove(filepath.Join(pp.dir, manifestFileName)); err != nil && !errors.Is(err, fs.ErrNotExist) {
 		return nil, nil, err
 	}
 
-	_, err = pkFile.Write(buf.Bytes())
-	if err != nil {
-		return nil, nil, err
-
+	m := manifest{Circuit: pp.circuitHash, Files: make(map[string]string)}
+	artifacts := []struct {
+		name string
+		w    io.WriterTo
+	}{{circuitFileName, pp.CS}, {pkFileName, pk}, {vkFileName, vk}}
+	for _, a := range artifacts {
+		data, err := encode(a.w)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err = writeFileAtomic(pp.dir, a.name, data); err != nil {
+			return nil, nil, err
+		}
+		m.Files[a.name] = digest(data)
 	}
 
-	buf.Reset()
-
-	vkFile, err := os.OpenFile(vkFilePath, os.O_RDWR|os.O_CREATE, 0755)
+	data, err := json.MarshalIndent(m, "", "  ")
 	if err != nil {
 		return nil, nil, err
 	}
-	defer func(vkFile *os.File) {
-		_ = vkFile.Close()
-	}(vkFile)
-
-	_, err = vk.WriteTo(&buf)
-	if err != nil {
+	if err = writeFileAtomic(pp.dir, manifestFileName, data); err != nil {
 		return nil, nil, err
 	}
-
-	_, err = vkFile.Write(buf.Bytes())
-	if err != nil {
-		return nil, nil, err
-
-	}
-
 	return pk, vk, nil
 }
 
-func Setup() (*PublicParams, error) {
-	if _, errCSFile := os.Stat(circuitFileName); errCSFile == nil {
-		cs, csErr := loadCS(circuitFileName)
-		if csErr != nil {
-			return nil, csErr
-		}
-		return &PublicParams{cs}, nil
-	} else {
-		cs, csErr := frontend.Compile(ecc.BLS12_381.ScalarField(), r1cs.NewBuilder, &Circuit{})
-		if csErr != nil {
-			return nil, csErr
-		}
-		errStoreCS := saveCSToFile(cs, circuitFileName)
-		if errStoreCS != nil {
-			return nil, errStoreCS
-		}
-		return &PublicParams{cs}, nil
+// Setup compiles the circuit, whose artifacts KeyGen keeps in dir.
+func Setup(dir string) (*PublicParams, error) {
+	cs, err := frontend.Compile(ecc.BLS12_381.ScalarField(), r1cs.NewBuilder, &Circuit{})
+	if err != nil {
+		return nil, err
+	}
+	data, err := encode(cs)
+	if err != nil {
+		return nil, err
 	}
+	return &PublicParams{cs, dir, digest(data)}, nil
 }
 
+// KeyGen loads the keys of the circuit from the artifact directory. If the manifest or an artifact is missing, or the
+// artifacts belong to another circuit, it generates the keys anew and replaces the artifacts. It returns an error
+// wrapping ErrCorruptArtifact if an artifact does not match its digest.
 func (pp *PublicParams) KeyGen() (*ProvingKey, *VerifyingKey, error) {
-	if _, errVkFile := os.Stat(vkFileName); errVkFile == nil {
-		pk, vk, errKGen := loadKeys(pkFileName, vkFileName)
-		if errKGen != nil {
-			return nil, nil, errKGen
-		}
-		return &ProvingKey{pk}, &VerifyingKey{vk}, nil
-	} else {
-		pk, vk, errKeys := generateAndSaveKeysToFiles(pp.CS, pkFileName, vkFileName)
-		if errKeys != nil {
-			return nil, nil, errKeys
-		}
-		return &ProvingKey{pk}, &VerifyingKey{vk}, nil
+	pk, vk, err := pp.loadKeys()
+	if errors.Is(err, errStale) {
+		pk, vk, err = pp.generateKeys()
+	}
+	if err != nil {
+		return nil, nil, err
 	}
+	return &ProvingKey{pk}, &VerifyingKey{vk}, nil
 }
 
 func (pp *PublicParams) NewWitness(x, y, sharedInput [MaxInputLength]byte, image [MaxOutputLength]byte) (Witness, error) {
@@ -276,3 +288,15 @@ func (pp *PublicParams) Verify(p Proof, pw PublicWitness, vk *VerifyingKey) bool
 	}
 	return true
 }
+
+// MarshalBinary encodes the proof with compressed points.
+func (p Proof) MarshalBinary() ([]byte, error) {
+	if p.proof == nil {
+		return nil, errors.New("hash: empty proof")
+	}
+	var buf bytes.Buffer
+	if _, err := p.proof.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -6,7 +6,7 @@ import (
 )
 
 func BenchmarkHashGenProof(b *testing.B) {
-	hashProof, err := Setup()
+	hashProof, err := Setup(".")
 	if err != nil {
 		b.Fatal(err)
 	}
@@ -36,7 +36,7 @@ func BenchmarkHashGenProof(b *testing.B) {
 }
 
 func BenchmarkHashProofVerify(b *testing.B) {
-	hashProof, err := Setup()
+	hashProof, err := Setup(".")
 	if err != nil {
 		b.Fatal(err)
 	}
@@ -1,11 +1,13 @@
 package hash
 
 import (
-	"fmt"
+	"encoding/json"
+	"errors"
 	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark/std/math/uints"
 	"github.com/consensys/gnark/test"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -22,15 +24,9 @@ func getFileSizeInMB(filePath string) (float64, error) {
 	return sizeMB, nil
 }
 
-func deleteFile(filePath string) {
-	err := os.Remove(filePath)
-	if err != nil {
-		fmt.Printf("Error deleting file: %v\n", err)
-	}
-}
-
 func TestCircuitMetadata(t *testing.T) {
-	pp, err := Setup()
+	dir := t.TempDir()
+	pp, err := Setup(dir)
 	if err != nil {
 		t.Errorf("Error generating hash proof system: %v\n", err)
 		return
@@ -43,17 +39,17 @@ func TestCircuitMetadata(t *testing.T) {
 	}
 	elapsedTime := time.Since(startTime)
 
-	circuitSizeMB, err := getFileSizeInMB(circuitFileName)
+	circuitSizeMB, err := getFileSizeInMB(filepath.Join(dir, circuitFileName))
 	if err != nil {
 		t.Errorf("Error getting circuit file size: %v\n", err)
 		return
 	}
-	provingKeySizeMB, err := getFileSizeInMB(pkFileName)
+	provingKeySizeMB, err := getFileSizeInMB(filepath.Join(dir, pkFileName))
 	if err != nil {
 		t.Errorf("Error getting proving key file size: %v\n", err)
 		return
 	}
-	verificationKeySizeMB, err := getFileSizeInMB(vkFileName)
+	verificationKeySizeMB, err := getFileSizeInMB(filepath.Join(dir, vkFileName))
 	if err != nil {
 		t.Errorf("Error getting verification key file size: %v\n", err)
 		return
@@ -64,14 +60,10 @@ func TestCircuitMetadata(t *testing.T) {
 	t.Logf("Circuit size MB: %v\n", circuitSizeMB)
 	t.Logf("Proving key size MB: %v\n", provingKeySizeMB)
 	t.Logf("Verification key size MB: %v\n", verificationKeySizeMB)
-
-	deleteFile(circuitFileName)
-	deleteFile(pkFileName)
-	deleteFile(vkFileName)
 }
 
 func TestHashCircuit(t *testing.T) {
-	hashProof, err := Setup()
+	hashProof, err := Setup(".")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -91,7 +83,7 @@ func TestHashCircuit(t *testing.T) {
 }
 
 func TestHashCircuitWithManipulatedImage(t *testing.T) {
-	hashProof, err := Setup()
+	hashProof, err := Setup(".")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -113,7 +105,7 @@ func TestHashCircuitWithManipulatedImage(t *testing.T) {
 }
 
 func TestHashCircuitWithManipulatedSharedInput(t *testing.T) {
-	hashProof, err := Setup()
+	hashProof, err := Setup(".")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -138,7 +130,7 @@ func TestHashCircuitWithManipulatedSharedInput(t *testing.T) {
 }
 
 func TestHashKeyGen(t *testing.T) {
-	sha256Proof, err := Setup()
+	sha256Proof, err := Setup(".")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -150,7 +142,7 @@ func TestHashKeyGen(t *testing.T) {
 }
 
 func TestHashProveVerify(t *testing.T) {
-	hashProof, err := Setup()
+	hashProof, err := Setup(".")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -182,3 +174,97 @@ func TestHashProveVerify(t *testing.T) {
 		t.Fatal("invalid proof, expected proof to be valid")
 	}
 }
+
+// writeArtifacts writes fake artifacts and a manifest with their digests for the circuit hash "circuit".
+func writeArtifacts(t *testing.T, dir string) *PublicParams {
+	t.Helper()
+	m := manifest{Circuit: "circuit", Files: make(map[string]string)}
+	for _, name := range []string{circuitFileName, pkFileName, vkFileName} {
+		data := []byte("artifact " + name)
+		if err := writeFileAtomic(dir, name, data); err != nil {
+			t.Fatalf("writeFileAtomic failed: %v", err)
+		}
+		m.Files[name] = digest(data)
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = writeFileAtomic(dir, manifestFileName, data); err != nil {
+		t.Fatalf("writeFileAtomic failed: %v", err)
+	}
+	return &PublicParams{dir: dir, circuitHash: m.Circuit}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	for _, data := range []string{"first", "second"} {
+		if err := writeFileAtomic(dir, vkFileName, []byte(data)); err != nil {
+			t.Fatalf("writeFileAtomic failed: %v", err)
+		}
+		got, err := os.ReadFile(filepath.Join(dir, vkFileName))
+		if err != nil || string(got) != data {
+			t.Fatalf("read %q, %v, expected %q", got, err, data)
+		}
+	}
+	info, err := os.Stat(filepath.Join(dir, vkFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("mode is %v, expected 0644", info.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("expected no temporary files, found %v", entries)
+	}
+	if err := writeFileAtomic(filepath.Join(dir, "missing"), vkFileName, nil); err == nil {
+		t.Error("writeFileAtomic succeeded in a missing directory")
+	}
+}
+
+func TestLoadKeysStale(t *testing.T) {
+	pp := &PublicParams{dir: t.TempDir(), circuitHash: "circuit"}
+	if _, _, err := pp.loadKeys(); !errors.Is(err, errStale) {
+		t.Errorf("loadKeys without a manifest returned %v, expected errStale", err)
+	}
+
+	pp = writeArtifacts(t, t.TempDir())
+	pp.circuitHash = "other circuit"
+	if _, _, err := pp.loadKeys(); !errors.Is(err, errStale) {
+		t.Errorf("loadKeys for another circuit returned %v, expected errStale", err)
+	}
+
+	pp = writeArtifacts(t, t.TempDir())
+	if err := os.Remove(filepath.Join(pp.dir, pkFileName)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := pp.loadKeys(); !errors.Is(err, errStale) {
+		t.Errorf("loadKeys without a proving key returned %v, expected errStale", err)
+	}
+}
+
+func TestLoadKeysCorrupt(t *testing.T) {
+	for _, name := range []string{circuitFileName, pkFileName, vkFileName, manifestFileName} {
+		t.Run(name, func(t *testing.T) {
+			pp := writeArtifacts(t, t.TempDir())
+			if err := os.WriteFile(filepath.Join(pp.dir, name), []byte("corrupt"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := pp.loadKeys(); !errors.Is(err, ErrCorruptArtifact) {
+				t.Errorf("loadKeys returned %v, expected ErrCorruptArtifact", err)
+			}
+			// KeyGen must not regenerate over corrupt artifacts
+			if _, _, err := pp.KeyGen(); !errors.Is(err, ErrCorruptArtifact) {
+				t.Errorf("KeyGen returned %v, expected ErrCorruptArtifact", err)
+			}
+		})
+	}
+}
+
+func TestLoadKeysMalformed(t *testing.T) {
+	// Artifacts that match the manifest but are no keys
+	pp := writeArtifacts(t, t.TempDir())
+	if _, _, err := pp.loadKeys(); err == nil || errors.Is(err, errStale) || errors.Is(err, ErrCorruptArtifact) {
+		t.Errorf("loadKeys returned %v, expected a decoding error", err)
+	}
+}
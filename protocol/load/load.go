@@ -0,0 +1,190 @@
+// Package load measures the throughput and latency of an IdP under concurrent logins. Simulated users each run one
+// complete login after the other, concurrently with each other, and every step of every login is timed.
+
+package load
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+// Flow runs one complete login of a simulated user and returns the duration of each of its steps.
+type Flow func() ([]time.Duration, error)
+
+// Workload is a protocol and an IdP to log in with.
+type Workload interface {
+	Name() string
+	// Steps names the timed steps of a login, in order.
+	Steps() []string
+	// User sets up simulated user i. Run calls the flows of different users concurrently, but the flow of a user
+	// only once at a time.
+	User(i int) (Flow, error)
+}
+
+type Config struct {
+	// Users is the number of concurrent simulated users.
+	Users int
+	// Logins is the number of logins to run in total. If 0, users log in until Duration has passed.
+	Logins int
+	// Duration bounds the time of the run, after which no further logins start. If 0, the run is not bounded.
+	Duration time.Duration
+}
+
+// Latency summarizes the durations of a step.
+type Latency struct {
+	Step                string
+	Mean, P50, P95, P99 time.Duration
+	Max                 time.Duration
+}
+
+type Report struct {
+	Workload string
+	Users    int
+	Logins   int
+	Elapsed  time.Duration
+	// Steps holds the latencies of the steps of the workload, followed by those of the complete login.
+	Steps []Latency
+}
+
+// Throughput returns the logins per second. Every step runs once per login, so it is also the rate of requests of each
+// step, e.g., of the IdP's responses.
+func (r *Report) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Logins) / r.Elapsed.Seconds()
+}
+
+// LoginStep names the complete login in Report.Steps.
+const LoginStep = "Login"
+
+// Run sets up the users and runs logins until cfg.Logins logins have completed or cfg.Duration has passed. Before the
+// clock starts, the first user logs in once, so that lazily computed state, e.g., fixed-base tables, does not count
+// against the first logins. Run stops at the first failed login and returns its error.
+func Run(w Workload, cfg Config) (*Report, error) {
+	if cfg.Users < 1 {
+		return nil, errors.New("load: at least one user is required")
+	}
+	if cfg.Logins <= 0 && cfg.Duration <= 0 {
+		return nil, errors.New("load: either the number of logins or the duration is required")
+	}
+	steps := w.Steps()
+	flows := make([]Flow, cfg.Users)
+	for i := range flows {
+		var err error
+		if flows[i], err = w.User(i); err != nil {
+			return nil, fmt.Errorf("load: %s: setting up user %d: %w", w.Name(), i, err)
+		}
+	}
+	if _, err := flows[0](); err != nil {
+		return nil, fmt.Errorf("load: %s: warm-up login: %w", w.Name(), err)
+	}
+
+	var (
+		remaining atomic.Int64
+		stop      atomic.Bool
+		errOnce   sync.Once
+		runErr    error
+		wg        sync.WaitGroup
+	)
+	remaining.Store(int64(cfg.Logins))
+	// samples[i][j] holds the durations of step j of user i, and the last entry those of the complete logins
+	samples := make([][][]time.Duration, cfg.Users)
+	start := time.Now()
+	for i, flow := range flows {
+		samples[i] = make([][]time.Duration, len(steps)+1)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !stop.Load() {
+				if cfg.Duration > 0 && time.Since(start) >= cfg.Duration {
+					return
+				}
+				if cfg.Logins > 0 && remaining.Add(-1) < 0 {
+					return
+				}
+				t := time.Now()
+				ds, err := flow()
+				total := time.Since(t)
+				if err == nil && len(ds) != len(steps) {
+					err = fmt.Errorf("timed %d steps instead of %d", len(ds), len(steps))
+				}
+				if err != nil {
+					errOnce.Do(func() { runErr = fmt.Errorf("load: %s: user %d: %w", w.Name(), i, err) })
+					stop.Store(true)
+					return
+				}
+				for j, d := range ds {
+					samples[i][j] = append(samples[i][j], d)
+				}
+				samples[i][len(steps)] = append(samples[i][len(steps)], total)
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	if runErr != nil {
+		return nil, runErr
+	}
+
+	r := &Report{Workload: w.Name(), Users: cfg.Users, Elapsed: elapsed}
+	for j, step := range append(slices.Clone(steps), LoginStep) {
+		var ds []time.Duration
+		for i := range samples {
+			ds = append(ds, samples[i][j]...)
+		}
+		r.Steps = append(r.Steps, summarize(step, ds))
+		r.Logins = len(ds) // ends with the number of complete logins
+	}
+	return r, nil
+}
+
+func summarize(step string, ds []time.Duration) Latency {
+	l := Latency{Step: step}
+	if len(ds) == 0 {
+		return l
+	}
+	slices.Sort(ds)
+	var sum time.Duration
+	for _, d := range ds {
+		sum += d
+	}
+	l.Mean = sum / time.Duration(len(ds))
+	l.P50, l.P95, l.P99 = percentile(ds, 0.50), percentile(ds, 0.95), percentile(ds, 0.99)
+	l.Max = ds[len(ds)-1]
+	return l
+}
+
+// percentile returns the nearest-rank p-th percentile of the sorted durations ds.
+func percentile(ds []time.Duration, p float64) time.Duration {
+	i := int(math.Ceil(p*float64(len(ds)))) - 1
+	return ds[max(i, 0)]
+}
+
+// WriteTable writes the reports as a table with one row per step, with latencies in milliseconds.
+func WriteTable(w io.Writer, reports []*Report) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(tw, "workload\tusers\tlogins\tlogins/s\tstep\tmean\tp50\tp95\tp99\tmax\t\n")
+	for _, r := range reports {
+		for i, l := range r.Steps {
+			if i == 0 {
+				fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t", r.Workload, r.Users, r.Logins, r.Throughput())
+			} else {
+				fmt.Fprint(tw, "\t\t\t\t")
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t\n", l.Step, ms(l.Mean), ms(l.P50), ms(l.P95), ms(l.P99), ms(l.Max))
+		}
+	}
+	return tw.Flush()
+}
+
+func ms(d time.Duration) string {
+	return fmt.Sprintf("%.3f", float64(d)/float64(time.Millisecond))
+}
@@ -0,0 +1,142 @@
+package load
+
+import (
+	"OPPID-artifacts/protocol"
+	OPPID "OPPID-artifacts/protocol/oppid"
+	"OPPID-artifacts/protocol/other/oidc"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func checkReport(t *testing.T, r *Report, w Workload, cfg Config) {
+	t.Helper()
+	if r.Workload != w.Name() || r.Users != cfg.Users || r.Logins != cfg.Logins || r.Elapsed <= 0 {
+		t.Fatalf("unexpected report %+v", r)
+	}
+	steps := w.Steps()
+	if len(r.Steps) != len(steps)+1 || r.Steps[len(steps)].Step != LoginStep {
+		t.Fatalf("unexpected steps %+v", r.Steps)
+	}
+	for i, l := range r.Steps {
+		if i < len(steps) && l.Step != steps[i] {
+			t.Errorf("step %d is %s, expected %s", i, l.Step, steps[i])
+		}
+		if l.P50 <= 0 || l.P50 > l.P95 || l.P95 > l.P99 || l.P99 > l.Max {
+			t.Errorf("%s: latencies are not ordered: %+v", l.Step, l)
+		}
+	}
+	if r.Throughput() <= 0 {
+		t.Errorf("Throughput is %v", r.Throughput())
+	}
+}
+
+func TestRunSSO(t *testing.T) {
+	for _, p := range []protocol.SSO{OPPID.NewSSO(), oidc.NewSSO()} {
+		t.Run(p.Name(), func(t *testing.T) {
+			w, err := NewSSO(p)
+			if err != nil {
+				t.Fatalf("NewSSO returned an error: %v", err)
+			}
+			cfg := Config{Users: 4, Logins: 10}
+			r, err := Run(w, cfg)
+			if err != nil {
+				t.Fatalf("Run returned an error: %v", err)
+			}
+			checkReport(t, r, w, cfg)
+		})
+	}
+}
+
+func TestRunHTTP(t *testing.T) {
+	h, err := NewHTTP()
+	if err != nil {
+		t.Fatalf("NewHTTP returned an error: %v", err)
+	}
+	defer h.Close()
+	cfg := Config{Users: 3, Logins: 6}
+	r, err := Run(h, cfg)
+	if err != nil {
+		t.Fatalf("Run returned an error: %v", err)
+	}
+	checkReport(t, r, h, cfg)
+}
+
+func TestRunDuration(t *testing.T) {
+	w, err := NewSSO(oidc.NewSSO())
+	if err != nil {
+		t.Fatalf("NewSSO returned an error: %v", err)
+	}
+	r, err := Run(w, Config{Users: 2, Duration: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Run returned an error: %v", err)
+	}
+	if r.Logins == 0 || r.Elapsed < 50*time.Millisecond {
+		t.Errorf("unexpected report %+v", r)
+	}
+}
+
+// failing fails the logins of user 1 after the warm-up.
+type failing struct{}
+
+var errLogin = errors.New("login failed")
+
+func (failing) Name() string    { return "failing" }
+func (failing) Steps() []string { return []string{"Step"} }
+func (failing) User(i int) (Flow, error) {
+	return func() ([]time.Duration, error) {
+		if i == 1 {
+			return nil, errLogin
+		}
+		return []time.Duration{time.Millisecond}, nil
+	}, nil
+}
+
+func TestRunError(t *testing.T) {
+	_, err := Run(failing{}, Config{Users: 2, Duration: time.Minute})
+	if !errors.Is(err, errLogin) {
+		t.Errorf("Run returned %v, expected the error of the login", err)
+	}
+	for _, cfg := range []Config{{Users: 0, Logins: 1}, {Users: 1}} {
+		if _, err := Run(failing{}, cfg); err == nil {
+			t.Errorf("Run accepted %+v", cfg)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var ds []time.Duration
+	for i := 100; i >= 1; i-- {
+		ds = append(ds, time.Duration(i))
+	}
+	l := summarize("Step", ds)
+	if l.P50 != 50 || l.P95 != 95 || l.P99 != 99 || l.Max != 100 || l.Mean != 50 {
+		t.Errorf("unexpected latencies %+v", l)
+	}
+	if p := percentile([]time.Duration{7}, 0.99); p != 7 {
+		t.Errorf("percentile of a single duration is %v", p)
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	r := &Report{Workload: "Test", Users: 2, Logins: 10, Elapsed: time.Second, Steps: []Latency{
+		{"Step", time.Millisecond, time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond, 4 * time.Millisecond},
+		{LoginStep, 1500 * time.Microsecond, 0, 0, 0, 0},
+	}}
+	var buf bytes.Buffer
+	if err := WriteTable(&buf, []*Report{r}); err != nil {
+		t.Fatalf("WriteTable returned an error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected a header and two rows, got:\n%s", buf.String())
+	}
+	if got := strings.Fields(lines[1]); strings.Join(got, " ") != "Test 2 10 10.0 Step 1.000 1.000 2.000 3.000 4.000" {
+		t.Errorf("unexpected row %q", lines[1])
+	}
+	if got := strings.Fields(lines[2]); strings.Join(got, " ") != "Login 1.500 0.000 0.000 0.000 0.000" {
+		t.Errorf("unexpected row %q", lines[2])
+	}
+}
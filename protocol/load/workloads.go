@@ -0,0 +1,163 @@
+package load
+
+import (
+	"OPPID-artifacts/protocol"
+	"OPPID-artifacts/protocol/oppid"
+	"OPPID-artifacts/protocol/oppid/idp"
+	"OPPID-artifacts/protocol/oppid/rp"
+	"OPPID-artifacts/protocol/oppid/wallet"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+)
+
+// stopwatch records the time between laps.
+type stopwatch struct {
+	last time.Time
+	laps []time.Duration
+}
+
+func newStopwatch() *stopwatch {
+	return &stopwatch{last: time.Now()}
+}
+
+func (s *stopwatch) lap() {
+	now := time.Now()
+	s.laps = append(s.laps, now.Sub(s.last))
+	s.last = now
+}
+
+func uid(i int) string {
+	return fmt.Sprintf("user%d@idp.example.com", i)
+}
+
+type ssoWorkload struct {
+	p   protocol.SSO
+	idp protocol.IdP
+	rp  protocol.RP
+}
+
+// NewSSO sets up an IdP of p in process and registers one RP, at which all users log in with protocol.Login. The
+// steps are those of the adapters, e.g., Request of OPPID includes Init, and Complete is the RP's Verify.
+func NewSSO(p protocol.SSO) (Workload, error) {
+	i, err := p.Setup()
+	if err != nil {
+		return nil, err
+	}
+	r, err := i.Register([]byte("rp.example.com"))
+	if err != nil {
+		return nil, err
+	}
+	return &ssoWorkload{p, i, r}, nil
+}
+
+func (w *ssoWorkload) Name() string { return w.p.Name() }
+
+func (w *ssoWorkload) Steps() []string {
+	return []string{"Begin", "Request", "Response", "Finalize", "Complete"}
+}
+
+func (w *ssoWorkload) User(i int) (Flow, error) {
+	u, err := w.idp.User([]byte(uid(i)))
+	if err != nil {
+		return nil, err
+	}
+	return func() ([]time.Duration, error) {
+		sw := newStopwatch()
+		// The observer sees every message right after the step that produced it
+		_, err := protocol.LoginObserved(w.idp, w.rp, u, func(protocol.Step, protocol.Message) { sw.lap() })
+		if err != nil {
+			return nil, err
+		}
+		sw.lap()
+		return sw.laps, nil
+	}, nil
+}
+
+// HTTP is a workload of OPPID logins against the reference IdP server of package idp, served by an httptest.Server.
+// Users log in with a wallet at an RP of package rp, both of which talk to the IdP with an idp.Client. The steps are
+// those of the wallet, where Response is the round trip to the IdP and Verify the RP's Complete.
+type HTTP struct {
+	pp  *oppid.PublicParams
+	srv *httptest.Server
+	rp  *rp.RP
+}
+
+// NewHTTP starts an IdP with public parameters set up with opts and registers an RP over HTTP. The IdP takes the
+// username of HTTP basic authentication as uid, without checking a password.
+func NewHTTP(opts ...oppid.Option) (*HTTP, error) {
+	pp := oppid.Setup(opts...)
+	sk, pk, err := pp.KeyGen()
+	if err != nil {
+		return nil, err
+	}
+	users := idp.AuthenticatorFunc(func(r *http.Request) ([]byte, error) {
+		user, _, ok := r.BasicAuth()
+		if !ok {
+			return nil, errors.New("load: no user")
+		}
+		return []byte(user), nil
+	})
+	srv, err := idp.New(pp, sk, pk, idp.Config{Users: users})
+	if err != nil {
+		return nil, err
+	}
+	h := &HTTP{pp: pp, srv: httptest.NewServer(srv)}
+
+	client := &idp.Client{BaseURL: h.srv.URL, HTTPClient: h.srv.Client()}
+	rid := []byte("rp.example.com")
+	cred, err := client.Register(rid)
+	if err != nil {
+		h.Close()
+		return nil, err
+	}
+	if h.rp, err = rp.New(pp, rp.Config{RID: rid, Credential: cred, Keys: client}); err != nil {
+		h.Close()
+		return nil, err
+	}
+	return h, nil
+}
+
+// Close shuts the IdP down.
+func (h *HTTP) Close() { h.srv.Close() }
+
+func (h *HTTP) Name() string { return "OPPID/HTTP" }
+
+func (h *HTTP) Steps() []string {
+	return []string{"Init", "Request", "Response", "Finalize", "Verify"}
+}
+
+func (h *HTTP) User(i int) (Flow, error) {
+	name := uid(i)
+	// Every user has connections of its own, as separate browsers would
+	client := &idp.Client{
+		BaseURL:      h.srv.URL,
+		HTTPClient:   &http.Client{Transport: &http.Transport{}},
+		Authenticate: func(r *http.Request) { r.SetBasicAuth(name, "") },
+	}
+	w := wallet.New(h.pp, client, wallet.NewMemoryStore(), wallet.Config{})
+	deliver := func(sid []byte, idToken string) error {
+		_, err := h.rp.Complete(sid, idToken)
+		return err
+	}
+	return func() ([]time.Duration, error) {
+		ch, err := h.rp.Begin()
+		if err != nil {
+			return nil, err
+		}
+		l, err := w.Start(wallet.Challenge{RID: ch.RID, SID: ch.SID, Credential: &ch.Credential})
+		if err != nil {
+			return nil, err
+		}
+		sw := newStopwatch()
+		for l.Step != wallet.StepDelivered {
+			if err := w.Next(l, deliver); err != nil {
+				return nil, err
+			}
+			sw.lap()
+		}
+		return sw.laps, nil
+	}, nil
+}
@@ -0,0 +1,167 @@
+// Package metrics accounts for the communication cost of the SSO protocols: the encoded size of every message of a
+// login and the bytes that each role sends. Sizes exclude the session id and the context of the challenge, which every
+// protocol sends alike.
+
+package metrics
+
+import (
+	"OPPID-artifacts/protocol"
+	"encoding"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// Role is a party of a login.
+type Role string
+
+const (
+	IdP  Role = "IdP"
+	User Role = "User"
+	RP   Role = "RP"
+)
+
+// Roles lists the roles in the order of the columns of WriteTable.
+var Roles = []Role{User, IdP, RP}
+
+// Steps lists the messages of a login in order.
+var Steps = []protocol.Step{protocol.StepChallenge, protocol.StepRequest, protocol.StepToken, protocol.StepResult}
+
+// Route returns the sender and the receiver of the message of step s.
+func Route(s protocol.Step) (from, to Role) {
+	switch s {
+	case protocol.StepChallenge:
+		return RP, User
+	case protocol.StepRequest:
+		return User, IdP
+	case protocol.StepToken:
+		return IdP, User
+	case protocol.StepResult:
+		return User, RP
+	}
+	return "", ""
+}
+
+// Message is a message of a login with the size of its encoding.
+type Message struct {
+	Step     protocol.Step
+	From, To Role
+	Size     int
+}
+
+// Size returns the length of the encoding of m.
+func Size(m protocol.Message) (int, error) {
+	bm, ok := m.(encoding.BinaryMarshaler)
+	if !ok {
+		return 0, fmt.Errorf("metrics: %T cannot be encoded", m)
+	}
+	b, err := bm.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Measure runs one login of u at rp with idp and returns its messages in order.
+func Measure(idp protocol.IdP, rp protocol.RP, u protocol.User) ([]Message, error) {
+	var msgs []Message
+	var errSize error
+	_, err := protocol.LoginObserved(idp, rp, u, func(s protocol.Step, m protocol.Message) {
+		n, err := Size(m)
+		if err != nil && errSize == nil {
+			errSize = fmt.Errorf("metrics: %s: %w", s, err)
+		}
+		from, to := Route(s)
+		msgs = append(msgs, Message{s, from, to, n})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if errSize != nil {
+		return nil, errSize
+	}
+	return msgs, nil
+}
+
+// Report holds the messages of one login of a protocol.
+type Report struct {
+	Protocol string
+	Messages []Message
+}
+
+// MeasureSSO sets up p with one RP and one user and measures a login.
+func MeasureSSO(p protocol.SSO) (Report, error) {
+	idp, err := p.Setup()
+	if err != nil {
+		return Report{}, err
+	}
+	rp, err := idp.Register([]byte("Test-RID"))
+	if err != nil {
+		return Report{}, err
+	}
+	u, err := idp.User([]byte("alice.doe@idp.com"))
+	if err != nil {
+		return Report{}, err
+	}
+	msgs, err := Measure(idp, rp, u)
+	if err != nil {
+		return Report{}, err
+	}
+	return Report{p.Name(), msgs}, nil
+}
+
+// Size returns the size of the message of step s, or 0 if there is none.
+func (r Report) Size(s protocol.Step) int {
+	for _, m := range r.Messages {
+		if m.Step == s {
+			return m.Size
+		}
+	}
+	return 0
+}
+
+// Sent returns the bytes that role sends during the login.
+func (r Report) Sent(role Role) int {
+	n := 0
+	for _, m := range r.Messages {
+		if m.From == role {
+			n += m.Size
+		}
+	}
+	return n
+}
+
+// Total returns the bytes of all messages of the login.
+func (r Report) Total() int {
+	n := 0
+	for _, m := range r.Messages {
+		n += m.Size
+	}
+	return n
+}
+
+// WriteTable writes the reports as a table with one row per protocol, the size of each message and the bytes sent by
+// each role, in bytes.
+func WriteTable(w io.Writer, reports []Report) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(tw, "protocol\t")
+	for _, s := range Steps {
+		from, to := Route(s)
+		fmt.Fprintf(tw, "%s (%s->%s)\t", s, from, to)
+	}
+	for _, role := range Roles {
+		fmt.Fprintf(tw, "sent by %s\t", role)
+	}
+	fmt.Fprint(tw, "total\t\n")
+	for _, r := range reports {
+		fmt.Fprintf(tw, "%s\t", r.Protocol)
+		for _, s := range Steps {
+			fmt.Fprintf(tw, "%d\t", r.Size(s))
+		}
+		for _, role := range Roles {
+			fmt.Fprintf(tw, "%d\t", r.Sent(role))
+		}
+		fmt.Fprintf(tw, "%d\t\n", r.Total())
+	}
+	return tw.Flush()
+}
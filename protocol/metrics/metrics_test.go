@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"OPPID-artifacts/protocol"
+	"OPPID-artifacts/protocol/protocols"
+	"strings"
+	"testing"
+)
+
+func TestMeasureSSO(t *testing.T) {
+	for _, p := range protocols.All() {
+		t.Run(p.Name(), func(t *testing.T) {
+			if testing.Short() && protocols.Slow(p) {
+				t.Skip("skipping slow protocol in short mode")
+			}
+			r, err := MeasureSSO(p)
+			if err != nil {
+				t.Fatalf("MeasureSSO returned an error: %v", err)
+			}
+			if len(r.Messages) != len(Steps) {
+				t.Fatalf("Expected %d messages, got %d", len(Steps), len(r.Messages))
+			}
+			for i, m := range r.Messages {
+				from, to := Route(Steps[i])
+				if m.Step != Steps[i] || m.From != from || m.To != to || m.Size <= 0 {
+					t.Errorf("Unexpected message %+v", m)
+				}
+			}
+			sent := 0
+			for _, role := range Roles {
+				sent += r.Sent(role)
+			}
+			if sent != r.Total() {
+				t.Errorf("Expected the bytes sent by all roles to add up to %d, got %d", r.Total(), sent)
+			}
+		})
+	}
+}
+
+// plain is a protocol whose messages cannot be encoded.
+type plain struct{}
+
+func (plain) Register([]byte) (protocol.RP, error) { return plain{}, nil }
+func (plain) User([]byte) (protocol.User, error)   { return plain{}, nil }
+func (plain) Response([]byte, protocol.Message, []byte, []byte) (protocol.Message, error) {
+	return "token", nil
+}
+func (plain) ID() []byte { return nil }
+func (plain) Request(protocol.Challenge) (protocol.Message, protocol.State, error) {
+	return "request", nil, nil
+}
+func (plain) Finalize(protocol.State, protocol.Message) (protocol.Message, error) {
+	return "result", nil
+}
+func (plain) Begin() (protocol.Challenge, error) { return protocol.Challenge{}, nil }
+func (plain) Complete(protocol.Challenge, protocol.Message) (protocol.Account, error) {
+	return protocol.Account("account"), nil
+}
+
+func TestMeasureUnencodable(t *testing.T) {
+	if _, err := Measure(plain{}, plain{}, plain{}); err == nil {
+		t.Errorf("Expected messages without encoding to be rejected")
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	r := Report{"Test", []Message{
+		{protocol.StepChallenge, RP, User, 1},
+		{protocol.StepRequest, User, IdP, 20},
+		{protocol.StepToken, IdP, User, 300},
+		{protocol.StepResult, User, RP, 4000},
+	}}
+	var sb strings.Builder
+	if err := WriteTable(&sb, []Report{r}); err != nil {
+		t.Fatalf("WriteTable returned an error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(sb.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected a header and one row, got %q", sb.String())
+	}
+	if got := strings.Fields(lines[1]); strings.Join(got, " ") != "Test 1 20 300 4000 4020 300 1 4321" {
+		t.Errorf("Unexpected row %q", lines[1])
+	}
+}
@@ -0,0 +1,637 @@
+// Wire format of the OPPID protocol messages. Every message starts with a version byte and a message tag, followed by
+// its fields in a fixed order. Group elements use the compressed encodings of BLS12-381 and scalars their fixed-size
+// big-endian form; variable-length fields (token signatures, signing keys and names) carry a 2-byte big-endian length
+// prefix. Signing keys are preceded by the name of their scheme.
+
+package oppid
+
+import (
+	PC "OPPID-artifacts/pkg/oppid/commit/pc"
+	NIZKCOM "OPPID-artifacts/pkg/oppid/nizk/com"
+	DLEQ "OPPID-artifacts/pkg/oppid/nizk/dleq"
+	NEQ "OPPID-artifacts/pkg/oppid/nizk/neq"
+	"OPPID-artifacts/pkg/oppid/sign"
+	PS "OPPID-artifacts/pkg/oppid/sign/ps"
+	"OPPID-artifacts/pkg/oppid/sign/schemes"
+	"OPPID-artifacts/pkg/oppid/utils"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	GG "github.com/cloudflare/circl/ecc/bls12381"
+)
+
+const wireVersion byte = 6
+
+const (
+	tagCredential byte = iota + 1
+	tagUsrCommitment
+	tagUsrOpening
+	tagAuth
+	tagToken
+	tagFinalizedToken
+	tagPublicKey
+	tagPublicParams
+	tagKeyCommitment
+	tagCredentialRequest
+	tagCredentialOpening
+	tagBlindCredential
+	tagRevocationList
+)
+
+var errTrailingData = errors.New("oppid: trailing data after message")
+
+type encoder struct{ buf []byte }
+
+func newEncoder(tag byte) *encoder {
+	return &encoder{[]byte{wireVersion, tag}}
+}
+
+func (e *encoder) fixed(b []byte) {
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) variable(b []byte) error {
+	if len(b) > math.MaxUint16 {
+		return fmt.Errorf("oppid: field of %d bytes exceeds maximum length", len(b))
+	}
+	e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(len(b)))
+	e.buf = append(e.buf, b...)
+	return nil
+}
+
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func newDecoder(data []byte, tag byte) *decoder {
+	d := &decoder{data: data}
+	header := d.fixed(2)
+	switch {
+	case d.err != nil:
+	case header[0] != wireVersion:
+		d.err = fmt.Errorf("oppid: unsupported wire version %d", header[0])
+	case header[1] != tag:
+		d.err = fmt.Errorf("oppid: unexpected message tag %d, want %d", header[1], tag)
+	}
+	return d
+}
+
+func (d *decoder) fixed(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if len(d.data) < n {
+		d.err = errors.New("oppid: message too short")
+		return nil
+	}
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b
+}
+
+func (d *decoder) variable() []byte {
+	l := d.fixed(2)
+	if d.err != nil {
+		return nil
+	}
+	return d.fixed(int(binary.BigEndian.Uint16(l)))
+}
+
+func (d *decoder) g1() *GG.G1 {
+	b := d.fixed(GG.G1SizeCompressed)
+	if d.err != nil {
+		return nil
+	}
+	p, err := utils.DecodeG1(b)
+	d.err = err
+	return p
+}
+
+func (d *decoder) scalar() *GG.Scalar {
+	b := d.fixed(GG.ScalarSize)
+	if d.err != nil {
+		return nil
+	}
+	s, err := utils.DecodeScalar(b)
+	d.err = err
+	return s
+}
+
+func (d *decoder) unmarshal(n int, v interface{ UnmarshalBinary([]byte) error }) {
+	b := d.fixed(n)
+	if d.err != nil {
+		return
+	}
+	d.err = v.UnmarshalBinary(b)
+}
+
+// attribute appends the encoding of attr in a: the expiry as 8-byte big-endian integer, the tier as variable-length
+// field, and the scopes as 2-byte big-endian count followed by a variable-length field for each.
+func (e *encoder) attribute(a Attributes, attr Attribute) error {
+	switch attr {
+	case AttrExpiry:
+		e.buf = binary.BigEndian.AppendUint64(e.buf, a.Expiry)
+	case AttrTier:
+		return e.variable([]byte(a.Tier))
+	case AttrScopes:
+		if len(a.Scopes) > math.MaxUint16 {
+			return fmt.Errorf("oppid: %d scopes exceed maximum count", len(a.Scopes))
+		}
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(len(a.Scopes)))
+		for _, scope := range a.Scopes {
+			if err := e.variable([]byte(scope)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// attributes appends the encoding of all attributes in a, in the order of their Attribute.
+func (e *encoder) attributes(a Attributes) error {
+	for attr := AttrExpiry; attr <= AttrScopes; attr++ {
+		if err := e.attribute(a, attr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *decoder) attribute(a *Attributes, attr Attribute) {
+	switch attr {
+	case AttrExpiry:
+		if b := d.fixed(8); d.err == nil {
+			a.Expiry = binary.BigEndian.Uint64(b)
+		}
+	case AttrTier:
+		a.Tier = string(d.variable())
+	case AttrScopes:
+		n := d.fixed(2)
+		if d.err != nil {
+			return
+		}
+		a.Scopes = nil
+		for i := 0; i < int(binary.BigEndian.Uint16(n)) && d.err == nil; i++ {
+			a.Scopes = append(a.Scopes, string(d.variable()))
+		}
+	}
+}
+
+func (d *decoder) attributes() Attributes {
+	var a Attributes
+	for attr := AttrExpiry; attr <= AttrScopes; attr++ {
+		d.attribute(&a, attr)
+	}
+	return a
+}
+
+// finish reports the first decoding error, or an error if bytes are left over.
+func (d *decoder) finish() error {
+	if d.err == nil && len(d.data) != 0 {
+		return errTrailingData
+	}
+	return d.err
+}
+
+func (c Credential) MarshalBinary() ([]byte, error) {
+	sig, err := c.sig.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	e := newEncoder(tagCredential)
+	e.fixed(sig)
+	if err := e.attributes(c.attrs); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+func (c *Credential) UnmarshalBinary(data []byte) error {
+	var cred Credential
+	d := newDecoder(data, tagCredential)
+	d.unmarshal(PS.SignatureSize, &cred.sig)
+	cred.attrs = d.attributes()
+	if err := d.finish(); err != nil {
+		return err
+	}
+	*c = cred
+	return nil
+}
+
+func (r CredentialRequest) MarshalBinary() ([]byte, error) {
+	com, err := r.com.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	if r.proof == nil {
+		return nil, errors.New("oppid: empty credential request")
+	}
+	proof, err := r.proof.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	e := newEncoder(tagCredentialRequest)
+	e.fixed(com)
+	e.fixed(proof)
+	return e.buf, nil
+}
+
+func (r *CredentialRequest) UnmarshalBinary(data []byte) error {
+	req := CredentialRequest{proof: new(NIZKCOM.Proof)}
+	d := newDecoder(data, tagCredentialRequest)
+	d.unmarshal(GG.G1SizeCompressed, &req.com)
+	d.unmarshal(NIZKCOM.ProofSize, req.proof)
+	if err := d.finish(); err != nil {
+		return err
+	}
+	*r = req
+	return nil
+}
+
+func (o CredentialOpening) MarshalBinary() ([]byte, error) {
+	opn, err := o.opn.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	e := newEncoder(tagCredentialOpening)
+	e.fixed(opn)
+	return e.buf, nil
+}
+
+func (o *CredentialOpening) UnmarshalBinary(data []byte) error {
+	var opn CredentialOpening
+	d := newDecoder(data, tagCredentialOpening)
+	d.unmarshal(GG.ScalarSize, &opn.opn)
+	if err := d.finish(); err != nil {
+		return err
+	}
+	*o = opn
+	return nil
+}
+
+func (c BlindCredential) MarshalBinary() ([]byte, error) {
+	sig, err := c.sig.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	e := newEncoder(tagBlindCredential)
+	e.fixed(sig)
+	if err := e.attributes(c.attrs); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+func (c *BlindCredential) UnmarshalBinary(data []byte) error {
+	var bc BlindCredential
+	d := newDecoder(data, tagBlindCredential)
+	d.unmarshal(PS.SignatureSize, &bc.sig)
+	bc.attrs = d.attributes()
+	if err := d.finish(); err != nil {
+		return err
+	}
+	*c = bc
+	return nil
+}
+
+func (c UsrCommitment) MarshalBinary() ([]byte, error) {
+	com, err := c.com.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	if c.bx == nil {
+		return nil, errors.New("oppid: empty blinded rid")
+	}
+	e := newEncoder(tagUsrCommitment)
+	e.fixed(com)
+	e.fixed(c.bx.BytesCompressed())
+	return e.buf, nil
+}
+
+func (c *UsrCommitment) UnmarshalBinary(data []byte) error {
+	var crid UsrCommitment
+	d := newDecoder(data, tagUsrCommitment)
+	d.unmarshal(GG.G1SizeCompressed, &crid.com)
+	crid.bx = d.g1()
+	if err := d.finish(); err != nil {
+		return err
+	}
+	*c = crid
+	return nil
+}
+
+func (o UsrOpening) MarshalBinary() ([]byte, error) {
+	opn, err := o.opn.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	if o.b == nil {
+		return nil, errors.New("oppid: empty blinding")
+	}
+	e := newEncoder(tagUsrOpening)
+	e.fixed(opn)
+	e.fixed(utils.ScalarBytes(o.b))
+	return e.buf, nil
+}
+
+func (o *UsrOpening) UnmarshalBinary(data []byte) error {
+	var orid UsrOpening
+	d := newDecoder(data, tagUsrOpening)
+	d.unmarshal(GG.ScalarSize, &orid.opn)
+	orid.b = d.scalar()
+	if err := d.finish(); err != nil {
+		return err
+	}
+	*o = orid
+	return nil
+}
+
+// An Auth is encoded as a byte with bit i set for each disclosed Attribute i, the disclosed attributes in ascending
+// order, the variable-length proof and the 2-byte big-endian count of non-revocation proofs followed by the proofs.
+func (a Auth) MarshalBinary() ([]byte, error) {
+	proof, err := a.proof.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	var mask byte
+	for _, attr := range a.disclosed {
+		mask |= 1 << attr
+	}
+	e := newEncoder(tagAuth)
+	e.fixed([]byte{mask})
+	for _, attr := range a.disclosed {
+		if err := e.attribute(a.attrs, attr); err != nil {
+			return nil, err
+		}
+	}
+	if err := e.variable(proof); err != nil {
+		return nil, err
+	}
+	if len(a.nonRevoked) > math.MaxUint16 {
+		return nil, fmt.Errorf("oppid: %d non-revocation proofs exceed maximum count", len(a.nonRevoked))
+	}
+	e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(len(a.nonRevoked)))
+	for _, pi := range a.nonRevoked {
+		b, err := pi.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		e.fixed(b)
+	}
+	return e.buf, nil
+}
+
+func (a *Auth) UnmarshalBinary(data []byte) error {
+	var auth Auth
+	d := newDecoder(data, tagAuth)
+	if b := d.fixed(1); d.err == nil {
+		mask := b[0]
+		for attr := AttrExpiry; attr <= AttrScopes; attr++ {
+			if mask&(1<<attr) != 0 {
+				auth.disclosed = append(auth.disclosed, attr)
+				d.attribute(&auth.attrs, attr)
+				mask &^= 1 << attr
+			}
+		}
+		if mask != 0 {
+			d.err = errors.New("oppid: unknown disclosed attribute")
+		}
+	}
+	proof := d.variable()
+	if d.err == nil {
+		d.err = auth.proof.UnmarshalBinary(proof)
+	}
+	if n := d.fixed(2); d.err == nil {
+		auth.nonRevoked = make([]NEQ.Proof, binary.BigEndian.Uint16(n))
+		for i := range auth.nonRevoked {
+			d.unmarshal(NEQ.ProofSize, &auth.nonRevoked[i])
+		}
+	}
+	if err := d.finish(); err != nil {
+		return err
+	}
+	*a = auth
+	return nil
+}
+
+func (t Token) MarshalBinary() ([]byte, error) {
+	if t.by == nil || t.kc == nil || t.sig == nil {
+		return nil, errors.New("oppid: empty token")
+	}
+	proof, err := t.proof.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	e := newEncoder(tagToken)
+	e.fixed(t.by.BytesCompressed())
+	e.fixed(t.kc.BytesCompressed())
+	e.fixed(proof)
+	if err := e.variable(t.sig); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+func (t *Token) UnmarshalBinary(data []byte) error {
+	var tk Token
+	d := newDecoder(data, tagToken)
+	tk.by = d.g1()
+	tk.kc = d.g1()
+	d.unmarshal(DLEQ.ProofSize, &tk.proof)
+	tk.sig = sign.Signature(append([]byte(nil), d.variable()...))
+	if err := d.finish(); err != nil {
+		return err
+	}
+	*t = tk
+	return nil
+}
+
+func (c KeyCommitment) MarshalBinary() ([]byte, error) {
+	if c.kc == nil {
+		return nil, errors.New("oppid: empty key commitment")
+	}
+	e := newEncoder(tagKeyCommitment)
+	e.fixed(c.kc.BytesCompressed())
+	return e.buf, nil
+}
+
+func (c *KeyCommitment) UnmarshalBinary(data []byte) error {
+	var kc KeyCommitment
+	d := newDecoder(data, tagKeyCommitment)
+	kc.kc = d.g1()
+	if err := d.finish(); err != nil {
+		return err
+	}
+	*c = kc
+	return nil
+}
+
+func (f FinalizedToken) MarshalBinary() ([]byte, error) {
+	com, err := f.com.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	opn, err := f.opening.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	if f.b == nil || f.by == nil || f.sig == nil {
+		return nil, errors.New("oppid: empty finalized token")
+	}
+	e := newEncoder(tagFinalizedToken)
+	e.fixed(com)
+	e.fixed(opn)
+	e.fixed(utils.ScalarBytes(f.b))
+	e.fixed(f.by.BytesCompressed())
+	if err := e.variable(f.sig); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+func (f *FinalizedToken) UnmarshalBinary(data []byte) error {
+	var ftk FinalizedToken
+	d := newDecoder(data, tagFinalizedToken)
+	d.unmarshal(GG.G1SizeCompressed, &ftk.com)
+	d.unmarshal(GG.ScalarSize, &ftk.opening)
+	ftk.b = d.scalar()
+	ftk.by = d.g1()
+	ftk.sig = sign.Signature(append([]byte(nil), d.variable()...))
+	if err := d.finish(); err != nil {
+		return err
+	}
+	*f = ftk
+	return nil
+}
+
+func (pk *PublicKey) MarshalBinary() ([]byte, error) {
+	if pk.sigPk == nil || pk.psPk == nil {
+		return nil, errors.New("oppid: empty public key")
+	}
+	sigPk, err := pk.sigPk.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	psPk, err := pk.psPk.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	e := newEncoder(tagPublicKey)
+	if err := e.variable([]byte(pk.scheme)); err != nil {
+		return nil, err
+	}
+	if err := e.variable(sigPk); err != nil {
+		return nil, err
+	}
+	if err := e.variable(psPk); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+func (pk *PublicKey) UnmarshalBinary(data []byte) error {
+	key := PublicKey{psPk: new(PS.PublicKey)}
+	d := newDecoder(data, tagPublicKey)
+	key.scheme = string(d.variable())
+	sigPk := d.variable()
+	if d.err == nil {
+		key.sigPk, d.err = unmarshalSigningKey(key.scheme, sigPk)
+	}
+	psPk := d.variable()
+	if d.err == nil {
+		d.err = key.psPk.UnmarshalBinary(psPk)
+	}
+	if d.err == nil {
+		d.err = checkPSKey(key.psPk)
+	}
+	if err := d.finish(); err != nil {
+		return err
+	}
+	*pk = key
+	return nil
+}
+
+func (rl RevocationList) MarshalBinary() ([]byte, error) {
+	if len(rl.Revoked) > math.MaxUint16 {
+		return nil, fmt.Errorf("oppid: %d revoked rids exceed maximum count", len(rl.Revoked))
+	}
+	e := newEncoder(tagRevocationList)
+	e.buf = binary.BigEndian.AppendUint64(e.buf, rl.Epoch)
+	e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(len(rl.Revoked)))
+	for _, rid := range rl.Revoked {
+		if err := e.variable(rid); err != nil {
+			return nil, err
+		}
+	}
+	return e.buf, nil
+}
+
+func (rl *RevocationList) UnmarshalBinary(data []byte) error {
+	var list RevocationList
+	d := newDecoder(data, tagRevocationList)
+	if b := d.fixed(8); d.err == nil {
+		list.Epoch = binary.BigEndian.Uint64(b)
+	}
+	if n := d.fixed(2); d.err == nil {
+		for i := 0; i < int(binary.BigEndian.Uint16(n)) && d.err == nil; i++ {
+			list.Revoked = append(list.Revoked, append([]byte(nil), d.variable()...))
+		}
+	}
+	if err := d.finish(); err != nil {
+		return err
+	}
+	*rl = list
+	return nil
+}
+
+func unmarshalSigningKey(name string, data []byte) (sign.PublicKey, error) {
+	scheme, err := schemes.New(name)
+	if err != nil {
+		return nil, err
+	}
+	return scheme.UnmarshalPublicKey(data)
+}
+
+// MarshalBinary encodes the public parameters so that they can be handed to users and RPs, who should check them with
+// VerifyParams after decoding.
+func (pp *PublicParams) MarshalBinary() ([]byte, error) {
+	e := newEncoder(tagPublicParams)
+	if err := e.variable([]byte(pp.scheme.Name())); err != nil {
+		return nil, err
+	}
+	if err := e.variable(pp.dst); err != nil {
+		return nil, err
+	}
+	if err := e.variable(pp.pc.Seed); err != nil {
+		return nil, err
+	}
+	e.fixed(pp.pc.H.BytesCompressed())
+	return e.buf, nil
+}
+
+func (pp *PublicParams) UnmarshalBinary(data []byte) error {
+	d := newDecoder(data, tagPublicParams)
+	name := string(d.variable())
+	dst := append([]byte(nil), d.variable()...)
+	seed := append([]byte(nil), d.variable()...)
+	h := d.g1()
+	if err := d.finish(); err != nil {
+		return err
+	}
+	scheme, err := schemes.New(name)
+	if err != nil {
+		return err
+	}
+
+	*pp = PublicParams{
+		scheme: scheme,
+		dst:    dst,
+		pc:     PC.New(GG.G1Generator(), h, dst, seed),
+		ps:     PS.Setup(dst),
+		rids:   newRidTables(),
+	}
+	return nil
+}
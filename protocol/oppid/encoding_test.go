@@ -0,0 +1,171 @@
+package oppid
+
+import (
+	"encoding"
+	"testing"
+)
+
+type wireMessage interface {
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+}
+
+// roundTrip encodes src and decodes the result into dst.
+func roundTrip(t *testing.T, src encoding.BinaryMarshaler, dst encoding.BinaryUnmarshaler) []byte {
+	t.Helper()
+	data, err := src.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+	if err := dst.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary failed: %v", err)
+	}
+	return data
+}
+
+func TestWireRoundTripFlow(t *testing.T) {
+	pp, sk, idpPk := setupAndKeyGen(t)
+	rid := []byte("registrationID")
+	uid := []byte("userID")
+	ctx := []byte("context")
+	sid := []byte("sessionID")
+
+	var pk PublicKey
+	roundTrip(t, idpPk, &pk)
+
+	var cred Credential
+	roundTrip(t, register(t, pp, sk, rid), &cred)
+
+	o, c := initUser(t, pp, rid)
+	var orid UsrOpening
+	var crid UsrCommitment
+	roundTrip(t, o, &orid)
+	roundTrip(t, c, &crid)
+
+	a, err := pp.Request(&pk, rid, cred, crid, orid, sid)
+	if err != nil {
+		t.Fatalf("Request returned an error: %v", err)
+	}
+	var auth Auth
+	roundTrip(t, a, &auth)
+
+	tk, err := pp.Response(sk, auth, crid, uid, ctx, sid)
+	if err != nil {
+		t.Fatalf("Response returned an error: %v", err)
+	}
+	var token Token
+	roundTrip(t, tk, &token)
+	var kc KeyCommitment
+	roundTrip(t, pp.KeyCommitment(sk, uid), &kc)
+	if !token.KeyCommitment().Equal(kc) {
+		t.Fatalf("decoded token and key commitment differ")
+	}
+
+	ftk, ppid, err := pp.Finalize(&pk, rid, ctx, sid, crid, orid, token)
+	if err != nil {
+		t.Fatalf("Finalize returned an error: %v", err)
+	}
+	var finalToken FinalizedToken
+	roundTrip(t, ftk, &finalToken)
+
+	if !pp.Verify(&pk, rid, ppid, ctx, sid, finalToken) {
+		t.Fatalf("Verify returned false for a decoded finalized token")
+	}
+}
+
+func TestWireRejectsMalformedMessages(t *testing.T) {
+	pp, sk, pk := setupAndKeyGen(t)
+	rid := []byte("registrationID")
+	sid := []byte("sessionID")
+	cred := register(t, pp, sk, rid)
+	orid, crid := initUser(t, pp, rid)
+	auth, err := pp.Request(pk, rid, cred, crid, orid, sid)
+	if err != nil {
+		t.Fatalf("Request returned an error: %v", err)
+	}
+	tk, err := pp.Response(sk, auth, crid, []byte("userID"), []byte("context"), sid)
+	if err != nil {
+		t.Fatalf("Response returned an error: %v", err)
+	}
+	ftk, _, err := pp.Finalize(pk, rid, []byte("context"), sid, crid, orid, tk)
+	if err != nil {
+		t.Fatalf("Finalize returned an error: %v", err)
+	}
+	credOpn, credReq, err := pp.RequestCredential(pk, rid)
+	if err != nil {
+		t.Fatalf("RequestCredential returned an error: %v", err)
+	}
+	blindCred, err := pp.IssueCredential(sk, credReq)
+	if err != nil {
+		t.Fatalf("IssueCredential returned an error: %v", err)
+	}
+
+	messages := map[string]struct {
+		src encoding.BinaryMarshaler
+		dst wireMessage
+	}{
+		"Credential":        {cred, new(Credential)},
+		"UsrCommitment":     {crid, new(UsrCommitment)},
+		"UsrOpening":        {orid, new(UsrOpening)},
+		"Auth":              {auth, new(Auth)},
+		"Token":             {tk, new(Token)},
+		"FinalizedToken":    {ftk, new(FinalizedToken)},
+		"PublicKey":         {pk, new(PublicKey)},
+		"KeyCommitment":     {tk.KeyCommitment(), new(KeyCommitment)},
+		"CredentialRequest": {credReq, new(CredentialRequest)},
+		"CredentialOpening": {credOpn, new(CredentialOpening)},
+		"BlindCredential":   {blindCred, new(BlindCredential)},
+		"RevocationList":    {RevocationList{Epoch: 3, Revoked: [][]byte{rid}}, new(RevocationList)},
+	}
+
+	for name, m := range messages {
+		t.Run(name, func(t *testing.T) {
+			data, err := m.src.MarshalBinary()
+			if err != nil {
+				t.Fatalf("MarshalBinary failed: %v", err)
+			}
+
+			if err := m.dst.UnmarshalBinary(data[:len(data)-1]); err == nil {
+				t.Errorf("truncated message was accepted")
+			}
+			if err := m.dst.UnmarshalBinary(append(append([]byte(nil), data...), 0)); err == nil {
+				t.Errorf("message with trailing data was accepted")
+			}
+
+			wrongVersion := append([]byte(nil), data...)
+			wrongVersion[0]++
+			if err := m.dst.UnmarshalBinary(wrongVersion); err == nil {
+				t.Errorf("message with unknown version was accepted")
+			}
+
+			wrongTag := append([]byte(nil), data...)
+			wrongTag[1] ^= 0xFF
+			if err := m.dst.UnmarshalBinary(wrongTag); err == nil {
+				t.Errorf("message with wrong tag was accepted")
+			}
+
+			if err := m.dst.UnmarshalBinary(nil); err == nil {
+				t.Errorf("empty message was accepted")
+			}
+		})
+	}
+}
+
+func TestWireRejectsInvalidPoint(t *testing.T) {
+	pp, _, _ := setupAndKeyGen(t)
+	_, crid := initUser(t, pp, []byte("registrationID"))
+	data, err := crid.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+
+	// Flip bits of the x-coordinate of bx so that it no longer decodes to a point in G1
+	for i := len(data) - 8; i < len(data); i++ {
+		data[i] ^= 0xFF
+	}
+
+	var decoded UsrCommitment
+	if err := decoded.UnmarshalBinary(data); err == nil {
+		t.Fatalf("commitment with an invalid point was accepted")
+	}
+}
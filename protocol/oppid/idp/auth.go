@@ -0,0 +1,32 @@
+package idp
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+)
+
+var errUnauthenticated = errors.New("idp: unauthenticated")
+
+// BasicAuth authenticates users with HTTP basic authentication against a fixed set of username/password pairs. The
+// username is used as uid.
+func BasicAuth(users map[string]string) Authenticator {
+	hashed := make(map[string][32]byte, len(users))
+	for user, password := range users {
+		hashed[user] = sha256.Sum256([]byte(password))
+	}
+
+	return AuthenticatorFunc(func(r *http.Request) ([]byte, error) {
+		user, password, ok := r.BasicAuth()
+		if !ok {
+			return nil, errUnauthenticated
+		}
+		want, known := hashed[user]
+		got := sha256.Sum256([]byte(password))
+		if subtle.ConstantTimeCompare(want[:], got[:]) != 1 || !known {
+			return nil, errUnauthenticated
+		}
+		return []byte(user), nil
+	})
+}
@@ -0,0 +1,175 @@
+package idp
+
+import (
+	"OPPID-artifacts/protocol/oppid"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Client talks to an IdP Server. Authenticate is applied to every request, e.g., to attach a session or credentials.
+type Client struct {
+	BaseURL      string
+	HTTPClient   *http.Client
+	Authenticate func(r *http.Request)
+}
+
+func (c *Client) PublicKey() (*oppid.PublicKey, error) {
+	var resp KeysResponse
+	if err := c.do(http.MethodGet, KeysPath, nil, &resp); err != nil {
+		return nil, err
+	}
+	pk := new(oppid.PublicKey)
+	if err := pk.UnmarshalBinary(resp.PublicKey); err != nil {
+		return nil, err
+	}
+	return pk, nil
+}
+
+// PublicParams fetches the IdP's public parameters and checks them with VerifyParams.
+func (c *Client) PublicParams() (*oppid.PublicParams, error) {
+	var resp ParamsResponse
+	if err := c.do(http.MethodGet, ParamsPath, nil, &resp); err != nil {
+		return nil, err
+	}
+	pp := new(oppid.PublicParams)
+	if err := pp.UnmarshalBinary(resp.PublicParams); err != nil {
+		return nil, err
+	}
+	if err := pp.VerifyParams(); err != nil {
+		return nil, fmt.Errorf("idp: rejecting public parameters: %w", err)
+	}
+	return pp, nil
+}
+
+// KeyCommitment fetches the commitment to the PRF key of the authenticated user, e.g., to pin it in a wallet.
+func (c *Client) KeyCommitment() (oppid.KeyCommitment, error) {
+	var resp KeyCommitmentResponse
+	if err := c.do(http.MethodGet, KeyCommitmentPath, nil, &resp); err != nil {
+		return oppid.KeyCommitment{}, err
+	}
+	var kc oppid.KeyCommitment
+	if err := kc.UnmarshalBinary(resp.KeyCommitment); err != nil {
+		return oppid.KeyCommitment{}, err
+	}
+	return kc, nil
+}
+
+// RevocationList fetches the IdP's current epoch and revoked rids, which a login has to be requested against.
+func (c *Client) RevocationList() (oppid.RevocationList, error) {
+	var resp RevocationListResponse
+	if err := c.do(http.MethodGet, RevocationListPath, nil, &resp); err != nil {
+		return oppid.RevocationList{}, err
+	}
+	var rl oppid.RevocationList
+	if err := rl.UnmarshalBinary(resp.RevocationList); err != nil {
+		return oppid.RevocationList{}, err
+	}
+	return rl, nil
+}
+
+// Register obtains a credential for the current epoch, which the RP renews by registering again in the next one.
+func (c *Client) Register(rid []byte) (oppid.Credential, error) {
+	var resp RegisterResponse
+	if err := c.do(http.MethodPost, RegisterPath, RegisterRequest{rid}, &resp); err != nil {
+		return oppid.Credential{}, err
+	}
+	var cred oppid.Credential
+	if err := cred.UnmarshalBinary(resp.Credential); err != nil {
+		return oppid.Credential{}, err
+	}
+	return cred, nil
+}
+
+// RegisterBlind sends a blind credential request, which an RP may also relay through a broker, and returns the
+// IdP's blind credential; the RP unblinds it with oppid.UnblindCredential.
+func (c *Client) RegisterBlind(req oppid.CredentialRequest) (oppid.BlindCredential, error) {
+	reqWire, err := req.MarshalBinary()
+	if err != nil {
+		return oppid.BlindCredential{}, err
+	}
+	var resp BlindRegisterResponse
+	if err := c.do(http.MethodPost, BlindRegisterPath, BlindRegisterRequest{reqWire}, &resp); err != nil {
+		return oppid.BlindCredential{}, err
+	}
+	var bc oppid.BlindCredential
+	if err := bc.UnmarshalBinary(resp.BlindCredential); err != nil {
+		return oppid.BlindCredential{}, err
+	}
+	return bc, nil
+}
+
+// Authorize sends an authentication request and returns the IdP's token together with the context it signed.
+func (c *Client) Authorize(auth oppid.Auth, crid oppid.UsrCommitment, sid []byte) (oppid.Token, []byte, error) {
+	authWire, err := auth.MarshalBinary()
+	if err != nil {
+		return oppid.Token{}, nil, err
+	}
+	cridWire, err := crid.MarshalBinary()
+	if err != nil {
+		return oppid.Token{}, nil, err
+	}
+
+	var resp AuthorizeResponse
+	if err := c.do(http.MethodPost, AuthorizePath, AuthorizeRequest{authWire, cridWire, sid}, &resp); err != nil {
+		return oppid.Token{}, nil, err
+	}
+	var tk oppid.Token
+	if err := tk.UnmarshalBinary(resp.Token); err != nil {
+		return oppid.Token{}, nil, err
+	}
+	return tk, resp.Context, nil
+}
+
+func (c *Client) do(method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.BaseURL, "/")+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Authenticate != nil {
+		c.Authenticate(req)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &StatusError{resp.StatusCode, strings.TrimSpace(string(msg))}
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxBodySize)).Decode(out)
+}
+
+// StatusError is returned by the Client when the IdP answers with a non-200 status.
+type StatusError struct {
+	Code    int
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("idp: %d %s: %s", e.Code, http.StatusText(e.Code), e.Message)
+}
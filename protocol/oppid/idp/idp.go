@@ -0,0 +1,357 @@
+// Package idp implements a reference OPPID identity provider (IdP) over HTTP. It exposes the IdP's operations of the
+// OPPID protocol: RP registration (Register, or IssueCredential for blind registration), the authorization step (Response) and key discovery (PublicKey), as
+// well as the public parameters, which clients check with VerifyParams, and every user's commitment to the PRF key
+// that the IdP derives for them (KeyCommitment).
+//
+// Credentials are bound to epochs: the IdP issues them for the current epoch only, so RPs register again in every
+// epoch, and it authorizes logins with ResponseWithRevocation against its RevocationList. Revoke takes an RP's rid
+// out of service immediately, including credentials that were issued blindly.
+//
+// All protocol messages travel in their binary wire format, embedded as base64 fields in JSON bodies.
+
+package idp
+
+import (
+	"OPPID-artifacts/protocol/oppid"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	RegisterPath       = "/register"
+	BlindRegisterPath  = "/register/blind"
+	AuthorizePath      = "/authorize"
+	KeysPath           = "/keys"
+	ParamsPath         = "/params"
+	KeyCommitmentPath  = "/key-commitment"
+	RevocationListPath = "/revocation-list"
+)
+
+const (
+	defaultEpochLength = 24 * time.Hour
+	defaultTokenTTL    = 5 * time.Minute
+)
+
+const maxBodySize = 1 << 16
+
+type RegisterRequest struct {
+	RID []byte `json:"rid"`
+}
+
+type RegisterResponse struct {
+	Credential []byte `json:"credential"`
+}
+
+type BlindRegisterRequest struct {
+	Request []byte `json:"request"`
+}
+
+type BlindRegisterResponse struct {
+	BlindCredential []byte `json:"blind_credential"`
+}
+
+type AuthorizeRequest struct {
+	Auth       []byte `json:"auth"`
+	Commitment []byte `json:"commitment"`
+	SID        []byte `json:"sid"`
+}
+
+type AuthorizeResponse struct {
+	Token   []byte `json:"token"`
+	Context []byte `json:"ctx"`
+}
+
+type KeysResponse struct {
+	PublicKey []byte `json:"public_key"`
+}
+
+type ParamsResponse struct {
+	PublicParams []byte `json:"public_params"`
+}
+
+type KeyCommitmentResponse struct {
+	KeyCommitment []byte `json:"key_commitment"`
+}
+
+type RevocationListResponse struct {
+	RevocationList []byte `json:"revocation_list"`
+}
+
+// Authenticator resolves the identity behind an HTTP request, e.g., from a session cookie or credentials.
+type Authenticator interface {
+	Authenticate(r *http.Request) ([]byte, error)
+}
+
+// AuthenticatorFunc adapts a function to the Authenticator interface.
+type AuthenticatorFunc func(r *http.Request) ([]byte, error)
+
+func (f AuthenticatorFunc) Authenticate(r *http.Request) ([]byte, error) { return f(r) }
+
+type Config struct {
+	// Users authenticates the user on whose behalf a token is requested; the result is used as uid. Required.
+	Users Authenticator
+	// RPs authorizes RP registrations. If nil, registration is open to anyone.
+	RPs Authenticator
+	// Issuer is the iss claim that the IdP signs into tokens, see oppid.Claims.
+	Issuer string
+	// TokenTTL is the validity of tokens, which the IdP signs into them as the exp claim. Defaults to 5 minutes.
+	TokenTTL time.Duration
+	// Context returns application-defined data that the IdP signs into the token of a user as oppid.Claims.Extra. If
+	// nil, there is none.
+	Context func(uid []byte) []byte
+	// EpochLength is the validity period of credentials. Defaults to 24 hours.
+	EpochLength time.Duration
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+type Server struct {
+	pp     *oppid.PublicParams
+	sk     *oppid.PrivateKey
+	pkWire []byte
+	ppWire []byte
+	cfg    Config
+	mux    *http.ServeMux
+
+	mu      sync.RWMutex
+	revoked [][]byte
+}
+
+func New(pp *oppid.PublicParams, sk *oppid.PrivateKey, pk *oppid.PublicKey, cfg Config) (*Server, error) {
+	if cfg.Users == nil {
+		return nil, errors.New("idp: user authenticator is required")
+	}
+	if sk.Scheme() != pp.Scheme() {
+		return nil, fmt.Errorf("idp: key of scheme %s does not match public parameters of scheme %s", sk.Scheme(), pp.Scheme())
+	}
+	pkWire, err := pk.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	ppWire, err := pp.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.EpochLength <= 0 {
+		cfg.EpochLength = defaultEpochLength
+	}
+	if cfg.TokenTTL <= 0 {
+		cfg.TokenTTL = defaultTokenTTL
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+
+	s := &Server{pp: pp, sk: sk, pkWire: pkWire, ppWire: ppWire, cfg: cfg, mux: http.NewServeMux()}
+	s.mux.HandleFunc("POST "+RegisterPath, s.handleRegister)
+	s.mux.HandleFunc("POST "+BlindRegisterPath, s.handleBlindRegister)
+	s.mux.HandleFunc("POST "+AuthorizePath, s.handleAuthorize)
+	s.mux.HandleFunc("GET "+KeysPath, s.handleKeys)
+	s.mux.HandleFunc("GET "+ParamsPath, s.handleParams)
+	s.mux.HandleFunc("GET "+KeyCommitmentPath, s.handleKeyCommitment)
+	s.mux.HandleFunc("GET "+RevocationListPath, s.handleRevocationList)
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Epoch returns the current epoch, the number of epochs of length Config.EpochLength since the Unix epoch.
+func (s *Server) Epoch() uint64 {
+	return uint64(s.cfg.Now().UnixNano() / int64(s.cfg.EpochLength))
+}
+
+// Revoke revokes the credentials of rid. The IdP no longer registers rid and refuses logins with its credentials,
+// which from now on cannot prove that their rid differs from rid.
+func (s *Server) Revoke(rid []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !slices.ContainsFunc(s.revoked, func(r []byte) bool { return bytes.Equal(r, rid) }) {
+		s.revoked = append(s.revoked, append([]byte(nil), rid...))
+	}
+}
+
+// Reinstate lifts the revocation of rid. The RP has to register again to obtain a credential.
+func (s *Server) Reinstate(rid []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.revoked = slices.DeleteFunc(s.revoked, func(r []byte) bool { return bytes.Equal(r, rid) })
+}
+
+// RevocationList returns the current epoch and the revoked rids.
+func (s *Server) RevocationList() oppid.RevocationList {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return oppid.RevocationList{Epoch: s.Epoch(), Revoked: slices.Clone(s.revoked)}
+}
+
+func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
+	if s.cfg.RPs != nil {
+		if _, err := s.cfg.RPs.Authenticate(r); err != nil {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	var req RegisterRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	if len(req.RID) == 0 {
+		http.Error(w, "missing rid", http.StatusBadRequest)
+		return
+	}
+
+	rl := s.RevocationList()
+	if rl.IsRevoked(req.RID) {
+		http.Error(w, "rid is revoked", http.StatusForbidden)
+		return
+	}
+
+	cred, err := s.pp.RegisterWithAttributes(s.sk, req.RID, oppid.Attributes{Expiry: rl.Epoch})
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	credWire, err := cred.MarshalBinary()
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, RegisterResponse{credWire})
+}
+
+func (s *Server) handleBlindRegister(w http.ResponseWriter, r *http.Request) {
+	if s.cfg.RPs != nil {
+		if _, err := s.cfg.RPs.Authenticate(r); err != nil {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	var req BlindRegisterRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	var credReq oppid.CredentialRequest
+	if err := credReq.UnmarshalBinary(req.Request); err != nil {
+		http.Error(w, "malformed request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bc, err := s.pp.IssueCredentialWithAttributes(s.sk, credReq, oppid.Attributes{Expiry: s.Epoch()})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	bcWire, err := bc.MarshalBinary()
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, BlindRegisterResponse{bcWire})
+}
+
+func (s *Server) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	uid, err := s.cfg.Users.Authenticate(r)
+	if err != nil || len(uid) == 0 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req AuthorizeRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	var auth oppid.Auth
+	var crid oppid.UsrCommitment
+	if err := auth.UnmarshalBinary(req.Auth); err != nil {
+		http.Error(w, "malformed auth: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := crid.UnmarshalBinary(req.Commitment); err != nil {
+		http.Error(w, "malformed commitment: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.SID) == 0 {
+		http.Error(w, "missing sid", http.StatusBadRequest)
+		return
+	}
+
+	now := s.cfg.Now()
+	claims := oppid.Claims{Issuer: s.cfg.Issuer, IssuedAt: now, ExpiresAt: now.Add(s.cfg.TokenTTL)}
+	if s.cfg.Context != nil {
+		claims.Extra = s.cfg.Context(uid)
+	}
+	ctx, err := claims.Context()
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	tk, err := s.pp.ResponseWithRevocation(s.sk, auth, crid, uid, ctx, req.SID, s.RevocationList())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	tkWire, err := tk.MarshalBinary()
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, AuthorizeResponse{tkWire, ctx})
+}
+
+func (s *Server) handleKeys(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, KeysResponse{s.pkWire})
+}
+
+func (s *Server) handleParams(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, ParamsResponse{s.ppWire})
+}
+
+func (s *Server) handleKeyCommitment(w http.ResponseWriter, r *http.Request) {
+	uid, err := s.cfg.Users.Authenticate(r)
+	if err != nil || len(uid) == 0 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	kcWire, err := s.pp.KeyCommitment(s.sk, uid).MarshalBinary()
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, KeyCommitmentResponse{kcWire})
+}
+
+func (s *Server) handleRevocationList(w http.ResponseWriter, _ *http.Request) {
+	rlWire, err := s.RevocationList().MarshalBinary()
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, RevocationListResponse{rlWire})
+}
+
+func decodeRequest(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		http.Error(w, "malformed request: "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
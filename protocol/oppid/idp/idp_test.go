@@ -0,0 +1,342 @@
+package idp
+
+import (
+	"OPPID-artifacts/pkg/oppid/sign/eddsa"
+	"OPPID-artifacts/protocol/oppid"
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testUser     = "alice"
+	testPassword = "secret"
+)
+
+func setupServer(t *testing.T, cfg Config) (*oppid.PublicParams, *httptest.Server) {
+	pp, srv := newServer(t, cfg)
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	return pp, ts
+}
+
+func newServer(t *testing.T, cfg Config) (*oppid.PublicParams, *Server) {
+	pp := oppid.Setup()
+	sk, pk, err := pp.KeyGen()
+	if err != nil {
+		t.Fatalf("KeyGen returned an error: %v", err)
+	}
+	if cfg.Users == nil {
+		cfg.Users = BasicAuth(map[string]string{testUser: testPassword})
+	}
+	srv, err := New(pp, sk, pk, cfg)
+	if err != nil {
+		t.Fatalf("New returned an error: %v", err)
+	}
+	return pp, srv
+}
+
+func revocationList(t *testing.T, client *Client) oppid.RevocationList {
+	t.Helper()
+	rl, err := client.RevocationList()
+	if err != nil {
+		t.Fatalf("RevocationList returned an error: %v", err)
+	}
+	return rl
+}
+
+func userClient(ts *httptest.Server, password string) *Client {
+	return &Client{
+		BaseURL:      ts.URL,
+		HTTPClient:   ts.Client(),
+		Authenticate: func(r *http.Request) { r.SetBasicAuth(testUser, password) },
+	}
+}
+
+func TestEndToEnd(t *testing.T) {
+	_, ts := setupServer(t, Config{Context: func(uid []byte) []byte { return append([]byte("ctx:"), uid...) }})
+	client := userClient(ts, testPassword)
+
+	pp, err := client.PublicParams()
+	if err != nil {
+		t.Fatalf("PublicParams returned an error: %v", err)
+	}
+
+	ipk, err := client.PublicKey()
+	if err != nil {
+		t.Fatalf("PublicKey returned an error: %v", err)
+	}
+
+	rid := []byte("rp.example.com")
+	cred, err := client.Register(rid)
+	if err != nil {
+		t.Fatalf("Register returned an error: %v", err)
+	}
+
+	sid := []byte("sessionID")
+	orid, crid, err := pp.Init(rid)
+	if err != nil {
+		t.Fatalf("Init returned an error: %v", err)
+	}
+	auth, err := pp.RequestWithRevocation(ipk, rid, cred, crid, orid, sid, revocationList(t, client))
+	if err != nil {
+		t.Fatalf("RequestWithRevocation returned an error: %v", err)
+	}
+
+	tk, ctx, err := client.Authorize(auth, crid, sid)
+	if err != nil {
+		t.Fatalf("Authorize returned an error: %v", err)
+	}
+	if !bytes.Equal(ctx, []byte("ctx:"+testUser)) {
+		t.Fatalf("unexpected context %q", ctx)
+	}
+
+	ftk, ppid, err := pp.Finalize(ipk, rid, ctx, sid, crid, orid, tk)
+	if err != nil {
+		t.Fatalf("Finalize returned an error: %v", err)
+	}
+	if !pp.Verify(ipk, rid, ppid, ctx, sid, ftk) {
+		t.Fatalf("Verify returned false for a token issued over HTTP")
+	}
+
+	kc, err := client.KeyCommitment()
+	if err != nil {
+		t.Fatalf("KeyCommitment returned an error: %v", err)
+	}
+	if !tk.KeyCommitment().Equal(kc) {
+		t.Fatalf("token was evaluated under another key than the published commitment")
+	}
+	if _, err := userClient(ts, "wrong").KeyCommitment(); err == nil {
+		t.Fatalf("KeyCommitment returned the commitment of an unauthenticated user")
+	}
+}
+
+func TestAuthorizeRequiresAuthentication(t *testing.T) {
+	pp, ts := setupServer(t, Config{})
+	client := userClient(ts, testPassword)
+
+	ipk, err := client.PublicKey()
+	if err != nil {
+		t.Fatalf("PublicKey returned an error: %v", err)
+	}
+	rid := []byte("rp.example.com")
+	cred, err := client.Register(rid)
+	if err != nil {
+		t.Fatalf("Register returned an error: %v", err)
+	}
+	sid := []byte("sessionID")
+	orid, crid, err := pp.Init(rid)
+	if err != nil {
+		t.Fatalf("Init returned an error: %v", err)
+	}
+	auth, err := pp.RequestWithRevocation(ipk, rid, cred, crid, orid, sid, revocationList(t, client))
+	if err != nil {
+		t.Fatalf("RequestWithRevocation returned an error: %v", err)
+	}
+
+	_, _, err = userClient(ts, "wrong").Authorize(auth, crid, sid)
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a wrong password, got %v", err)
+	}
+}
+
+func TestAuthorizeRejectsProofForOtherSession(t *testing.T) {
+	pp, ts := setupServer(t, Config{})
+	client := userClient(ts, testPassword)
+
+	ipk, _ := client.PublicKey()
+	rid := []byte("rp.example.com")
+	cred, _ := client.Register(rid)
+	orid, crid, err := pp.Init(rid)
+	if err != nil {
+		t.Fatalf("Init returned an error: %v", err)
+	}
+	auth, err := pp.RequestWithRevocation(ipk, rid, cred, crid, orid, []byte("sessionID"), revocationList(t, client))
+	if err != nil {
+		t.Fatalf("RequestWithRevocation returned an error: %v", err)
+	}
+
+	_, _, err = client.Authorize(auth, crid, []byte("otherSessionID"))
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a proof bound to another session, got %v", err)
+	}
+}
+
+func TestRegisterAuthorization(t *testing.T) {
+	rpAuth := AuthenticatorFunc(func(r *http.Request) ([]byte, error) {
+		if r.Header.Get("Authorization") != "Bearer rp-token" {
+			return nil, errors.New("invalid token")
+		}
+		return []byte("rp"), nil
+	})
+	_, ts := setupServer(t, Config{RPs: rpAuth})
+
+	anonymous := &Client{BaseURL: ts.URL, HTTPClient: ts.Client()}
+	if _, err := anonymous.Register([]byte("rp.example.com")); err == nil {
+		t.Fatalf("Register succeeded without RP authorization")
+	}
+
+	rp := &Client{BaseURL: ts.URL, HTTPClient: ts.Client(), Authenticate: func(r *http.Request) {
+		r.Header.Set("Authorization", "Bearer rp-token")
+	}}
+	if _, err := rp.Register([]byte("rp.example.com")); err != nil {
+		t.Fatalf("Register returned an error: %v", err)
+	}
+}
+
+func TestBlindRegister(t *testing.T) {
+	_, ts := setupServer(t, Config{})
+	broker := &Client{BaseURL: ts.URL, HTTPClient: ts.Client()}
+
+	pp, err := broker.PublicParams()
+	if err != nil {
+		t.Fatalf("PublicParams returned an error: %v", err)
+	}
+	ipk, err := broker.PublicKey()
+	if err != nil {
+		t.Fatalf("PublicKey returned an error: %v", err)
+	}
+
+	rid := []byte("rp.example.com")
+	opn, req, err := pp.RequestCredential(ipk, rid)
+	if err != nil {
+		t.Fatalf("RequestCredential returned an error: %v", err)
+	}
+	bc, err := broker.RegisterBlind(req)
+	if err != nil {
+		t.Fatalf("RegisterBlind returned an error: %v", err)
+	}
+	if _, err := pp.UnblindCredential(ipk, rid, opn, bc); err != nil {
+		t.Fatalf("UnblindCredential returned an error: %v", err)
+	}
+}
+
+func TestMalformedRequests(t *testing.T) {
+	_, ts := setupServer(t, Config{})
+
+	cases := map[string]string{
+		RegisterPath:      `{"rid":""}`,
+		BlindRegisterPath: `{"request":"AAAA"}`,
+		AuthorizePath:     `{"auth":"AAAA","commitment":"AAAA","sid":"AAAA"}`,
+	}
+	for path, body := range cases {
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+path, strings.NewReader(body))
+		req.SetBasicAuth(testUser, testPassword)
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatalf("request to %s failed: %v", path, err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", path, resp.StatusCode)
+		}
+	}
+}
+
+func TestNewRejectsSchemeMismatch(t *testing.T) {
+	sk, pk, err := oppid.Setup().KeyGen()
+	if err != nil {
+		t.Fatalf("KeyGen returned an error: %v", err)
+	}
+	pp := oppid.Setup(oppid.WithScheme(eddsa.Setup()))
+	if _, err := New(pp, sk, pk, Config{Users: BasicAuth(nil)}); err == nil {
+		t.Fatalf("New accepted a key of another scheme")
+	}
+}
+
+func TestRevokedRPIsRefused(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	pp, srv := newServer(t, Config{EpochLength: time.Hour, Now: func() time.Time { return now }})
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	client := userClient(ts, testPassword)
+
+	ipk, _ := client.PublicKey()
+	rid := []byte("rp.example.com")
+	cred, err := client.Register(rid)
+	if err != nil {
+		t.Fatalf("Register returned an error: %v", err)
+	}
+	if cred.Attributes().Expiry != srv.Epoch() {
+		t.Fatalf("credential is valid until epoch %d, want the current epoch %d", cred.Attributes().Expiry, srv.Epoch())
+	}
+	login := func(sid []byte, rl oppid.RevocationList) error {
+		orid, crid, err := pp.Init(rid)
+		if err != nil {
+			t.Fatalf("Init returned an error: %v", err)
+		}
+		auth, err := pp.RequestWithRevocation(ipk, rid, cred, crid, orid, sid, rl)
+		if err != nil {
+			t.Fatalf("RequestWithRevocation returned an error: %v", err)
+		}
+		_, _, err = client.Authorize(auth, crid, sid)
+		return err
+	}
+
+	before := revocationList(t, client)
+	if err := login([]byte("session1"), before); err != nil {
+		t.Fatalf("Authorize returned an error before the revocation: %v", err)
+	}
+
+	srv.Revoke(rid)
+	after := revocationList(t, client)
+	if !after.IsRevoked(rid) {
+		t.Fatalf("published revocation list does not contain the revoked rid")
+	}
+	var statusErr *StatusError
+	if err := login([]byte("session2"), before); !errors.As(err, &statusErr) || statusErr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a revoked RP, got %v", err)
+	}
+	orid, crid, _ := pp.Init(rid)
+	if _, err := pp.RequestWithRevocation(ipk, rid, cred, crid, orid, []byte("session3"), after); !errors.Is(err, oppid.ErrRevoked) {
+		t.Fatalf("RequestWithRevocation returned %v for a revoked rid, want ErrRevoked", err)
+	}
+	if _, err := client.Register(rid); !errors.As(err, &statusErr) || statusErr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 when a revoked RP registers, got %v", err)
+	}
+
+	srv.Reinstate(rid)
+	if err := login([]byte("session4"), revocationList(t, client)); err != nil {
+		t.Fatalf("Authorize returned an error after reinstating the RP: %v", err)
+	}
+}
+
+func TestStaleCredentialIsRefused(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	pp, srv := newServer(t, Config{EpochLength: time.Hour, Now: func() time.Time { return now }})
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	client := userClient(ts, testPassword)
+
+	ipk, _ := client.PublicKey()
+	rid := []byte("rp.example.com")
+	cred, _ := client.Register(rid)
+	orid, crid, _ := pp.Init(rid)
+	sid := []byte("sessionID")
+	auth, err := pp.RequestWithRevocation(ipk, rid, cred, crid, orid, sid, revocationList(t, client))
+	if err != nil {
+		t.Fatalf("RequestWithRevocation returned an error: %v", err)
+	}
+
+	now = now.Add(time.Hour)
+	var statusErr *StatusError
+	if _, _, err := client.Authorize(auth, crid, sid); !errors.As(err, &statusErr) || statusErr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a credential of the last epoch, got %v", err)
+	}
+
+	// The RP renews its credential by registering again.
+	renewed, err := client.Register(rid)
+	if err != nil {
+		t.Fatalf("Register returned an error: %v", err)
+	}
+	auth, _ = pp.RequestWithRevocation(ipk, rid, renewed, crid, orid, sid, revocationList(t, client))
+	if _, _, err := client.Authorize(auth, crid, sid); err != nil {
+		t.Fatalf("Authorize returned an error for a renewed credential: %v", err)
+	}
+}
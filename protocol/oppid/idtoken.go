@@ -0,0 +1,252 @@
+// Encodes finalized OPPID tokens in JWS compact serialization [2] with the claim set of OIDC ID Tokens [1].
+//
+// The token is integrity-protected by the IdP's signature inside the OPPID claims rather than by the JWS signature:
+// the IdP never sees the PPID and therefore cannot sign the JWS itself. The tokens are thus Unsecured JWS
+// ("alg":"none"), which OIDC forbids for ID Tokens [1, Sec. 2], so they are not accepted by OIDC libraries and must
+// be checked with VerifyIDToken, which verifies the embedded OPPID token. The `aud` claim carries the RP's rid and
+// `nonce` the session id, both of which are bound by the IdP's signature. The IdP asserts `iss`, `iat` and `exp` in
+// the context ctx that it signs, see Claims, and VerifyIDToken only accepts tokens whose claims match it.
+
+// References:
+// [1] https://openid.net/specs/openid-connect-core-1_0.html#IDToken
+// [2] https://www.rfc-editor.org/rfc/rfc7515#section-7.1
+
+package oppid
+
+import (
+	"OPPID-artifacts/pkg/oppid/utils"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const idTokenType = "JWT"
+
+type jwsHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+}
+
+type idTokenClaims struct {
+	Issuer    string `json:"iss,omitempty"`
+	Subject   string `json:"sub"`
+	Audience  string `json:"aud"`
+	Nonce     string `json:"nonce"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+
+	Context     string `json:"oppid_ctx"`
+	Commitment  string `json:"oppid_com"`
+	Opening     string `json:"oppid_opn"`
+	Blinding    string `json:"oppid_b"`
+	BlindedEval string `json:"oppid_by"`
+	Signature   string `json:"oppid_sig"`
+}
+
+// Claims are the claims of an ID Token that the IdP asserts. The IdP encodes them with Context and signs them into the
+// token as its context ctx in Response, such that the user agent cannot change them. Extra is application-defined.
+type Claims struct {
+	Issuer    string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	Extra     []byte
+}
+
+type contextClaims struct {
+	Issuer    string `json:"iss,omitempty"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	Extra     []byte `json:"ext,omitempty"`
+}
+
+// Context encodes the claims as the context ctx of a token.
+func (c Claims) Context() ([]byte, error) {
+	if c.ExpiresAt.IsZero() {
+		return nil, errors.New("oppid: claims without expiry")
+	}
+	return json.Marshal(contextClaims{c.Issuer, c.IssuedAt.Unix(), c.ExpiresAt.Unix(), c.Extra})
+}
+
+// ParseClaims decodes the claims that the IdP signed as the context ctx of a token.
+func ParseClaims(ctx []byte) (Claims, error) {
+	var c contextClaims
+	if err := json.Unmarshal(ctx, &c); err != nil {
+		return Claims{}, fmt.Errorf("oppid: invalid claims in context: %w", err)
+	}
+	if c.ExpiresAt == 0 {
+		return Claims{}, errors.New("oppid: claims without expiry")
+	}
+	return Claims{c.Issuer, time.Unix(c.IssuedAt, 0), time.Unix(c.ExpiresAt, 0), c.Extra}, nil
+}
+
+// NewIDToken returns the ID Token of a finalized token, whose iss, iat and exp are taken from the claims in ctx.
+func NewIDToken(ppid PPID, rid, sid, ctx []byte, ftk FinalizedToken) (*IDToken, error) {
+	c, err := ParseClaims(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &IDToken{c.Issuer, ppid, rid, sid, ctx, c.IssuedAt, c.ExpiresAt, ftk}, nil
+}
+
+// IDToken is the RP-facing view of a finalized OPPID token. Audience is the rid of the RP, Nonce the session id and
+// Context the signed context, from which Issuer, IssuedAt and ExpiresAt are taken, see NewIDToken.
+type IDToken struct {
+	Issuer    string
+	Subject   PPID
+	Audience  []byte
+	Nonce     []byte
+	Context   []byte
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	Token     FinalizedToken
+}
+
+var b64 = base64.RawURLEncoding
+
+// Encode serializes the ID Token as a compact Unsecured JWS.
+func (t *IDToken) Encode() (string, error) {
+	if !utf8.Valid(t.Audience) || !utf8.Valid(t.Nonce) {
+		return "", errors.New("oppid: aud and nonce must be valid UTF-8")
+	}
+
+	com, err := t.Token.com.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	opn, err := t.Token.opening.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	if t.Token.b == nil || t.Token.by == nil || t.Token.sig == nil {
+		return "", errors.New("oppid: empty finalized token")
+	}
+
+	claims := idTokenClaims{
+		Issuer:      t.Issuer,
+		Subject:     b64.EncodeToString(t.Subject),
+		Audience:    string(t.Audience),
+		Nonce:       string(t.Nonce),
+		IssuedAt:    t.IssuedAt.Unix(),
+		ExpiresAt:   t.ExpiresAt.Unix(),
+		Context:     b64.EncodeToString(t.Context),
+		Commitment:  b64.EncodeToString(com),
+		Opening:     b64.EncodeToString(opn),
+		Blinding:    b64.EncodeToString(utils.ScalarBytes(t.Token.b)),
+		BlindedEval: b64.EncodeToString(t.Token.by.BytesCompressed()),
+		Signature:   b64.EncodeToString(t.Token.sig),
+	}
+
+	header, err := json.Marshal(jwsHeader{Alg: "none", Typ: idTokenType})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	return b64.EncodeToString(header) + "." + b64.EncodeToString(payload) + ".", nil
+}
+
+// ParseIDToken decodes a compact JWS produced by Encode. It does not verify the token.
+func ParseIDToken(jws string) (*IDToken, error) {
+	parts := strings.Split(jws, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("oppid: malformed JWS")
+	}
+	if parts[2] != "" {
+		return nil, errors.New("oppid: unexpected JWS signature")
+	}
+
+	var header jwsHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("oppid: invalid JWS header: %w", err)
+	}
+	if header.Alg != "none" {
+		return nil, fmt.Errorf("oppid: unsupported JWS algorithm %q", header.Alg)
+	}
+
+	var claims idTokenClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("oppid: invalid JWS payload: %w", err)
+	}
+
+	fields := make([][]byte, 0, 7)
+	for _, s := range []string{claims.Subject, claims.Context, claims.Commitment, claims.Opening, claims.Blinding, claims.BlindedEval, claims.Signature} {
+		b, err := b64.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("oppid: invalid claim encoding: %w", err)
+		}
+		fields = append(fields, b)
+	}
+
+	var ftk FinalizedToken
+	if err := ftk.com.UnmarshalBinary(fields[2]); err != nil {
+		return nil, fmt.Errorf("oppid: invalid oppid_com claim: %w", err)
+	}
+	if err := ftk.opening.UnmarshalBinary(fields[3]); err != nil {
+		return nil, fmt.Errorf("oppid: invalid oppid_opn claim: %w", err)
+	}
+	b, err := utils.DecodeScalar(fields[4])
+	if err != nil {
+		return nil, fmt.Errorf("oppid: invalid oppid_b claim: %w", err)
+	}
+	by, err := utils.DecodeG1(fields[5])
+	if err != nil {
+		return nil, fmt.Errorf("oppid: invalid oppid_by claim: %w", err)
+	}
+	ftk.b, ftk.by, ftk.sig = b, by, fields[6]
+
+	return &IDToken{
+		Issuer:    claims.Issuer,
+		Subject:   fields[0],
+		Audience:  []byte(claims.Audience),
+		Nonce:     []byte(claims.Nonce),
+		Context:   fields[1],
+		IssuedAt:  time.Unix(claims.IssuedAt, 0),
+		ExpiresAt: time.Unix(claims.ExpiresAt, 0),
+		Token:     ftk,
+	}, nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := b64.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// VerifyIDToken parses an ID Token, checks that it was issued for rid and sid, verifies the embedded OPPID token and
+// checks that the token has not expired at time now according to the claims that the IdP signed, see Claims.
+func (pp *PublicParams) VerifyIDToken(ipk *PublicKey, rid, sid []byte, jws string, now time.Time) (*IDToken, error) {
+	t, err := ParseIDToken(jws)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(t.Audience, rid) {
+		return nil, errors.New("oppid: ID token audience mismatch")
+	}
+	if !bytes.Equal(t.Nonce, sid) {
+		return nil, errors.New("oppid: ID token nonce mismatch")
+	}
+	if !pp.Verify(ipk, rid, t.Subject, t.Context, sid, t.Token) {
+		return nil, errors.New("oppid: invalid ID token")
+	}
+	c, err := ParseClaims(t.Context)
+	if err != nil {
+		return nil, err
+	}
+	if t.Issuer != c.Issuer || !t.IssuedAt.Equal(c.IssuedAt) || !t.ExpiresAt.Equal(c.ExpiresAt) {
+		return nil, errors.New("oppid: ID token claims do not match the signed claims")
+	}
+	if !now.Before(c.ExpiresAt) {
+		return nil, errors.New("oppid: ID token expired")
+	}
+	return t, nil
+}
@@ -0,0 +1,154 @@
+package oppid
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func issueIDToken(t *testing.T) (*PublicParams, *PublicKey, []byte, []byte, string) {
+	pp, sk, pk := setupAndKeyGen(t)
+	rid := []byte("registrationID")
+	uid := []byte("userID")
+	sid := []byte("sessionID")
+	now := time.Now()
+	ctx, err := Claims{Issuer: "https://idp.example.com", IssuedAt: now, ExpiresAt: now.Add(time.Hour)}.Context()
+	if err != nil {
+		t.Fatalf("Context returned an error: %v", err)
+	}
+
+	cred := register(t, pp, sk, rid)
+	orid, crid := initUser(t, pp, rid)
+	auth, err := pp.Request(pk, rid, cred, crid, orid, sid)
+	if err != nil {
+		t.Fatalf("Request returned an error: %v", err)
+	}
+	token, err := pp.Response(sk, auth, crid, uid, ctx, sid)
+	if err != nil {
+		t.Fatalf("Response returned an error: %v", err)
+	}
+	ftk, ppid, err := pp.Finalize(pk, rid, ctx, sid, crid, orid, token)
+	if err != nil {
+		t.Fatalf("Finalize returned an error: %v", err)
+	}
+
+	idToken, err := NewIDToken(ppid, rid, sid, ctx, ftk)
+	if err != nil {
+		t.Fatalf("NewIDToken returned an error: %v", err)
+	}
+	jws, err := idToken.Encode()
+	if err != nil {
+		t.Fatalf("Encode returned an error: %v", err)
+	}
+	return pp, pk, rid, sid, jws
+}
+
+func TestIDTokenVerify(t *testing.T) {
+	pp, pk, rid, sid, jws := issueIDToken(t)
+
+	if strings.Count(jws, ".") != 2 || !strings.HasSuffix(jws, ".") {
+		t.Fatalf("ID token is not an unsecured compact JWS: %s", jws)
+	}
+
+	idToken, err := pp.VerifyIDToken(pk, rid, sid, jws, time.Now())
+	if err != nil {
+		t.Fatalf("VerifyIDToken rejected a valid ID token: %v", err)
+	}
+	if !bytes.Equal(idToken.Audience, rid) || len(idToken.Subject) == 0 {
+		t.Fatalf("VerifyIDToken returned unexpected claims")
+	}
+}
+
+func TestIDTokenRejectsWrongSession(t *testing.T) {
+	pp, pk, rid, _, jws := issueIDToken(t)
+	if _, err := pp.VerifyIDToken(pk, rid, []byte("otherSessionID"), jws, time.Now()); err == nil {
+		t.Fatalf("VerifyIDToken accepted a token for another session")
+	}
+	if _, err := pp.VerifyIDToken(pk, []byte("otherRID"), []byte("sessionID"), jws, time.Now()); err == nil {
+		t.Fatalf("VerifyIDToken accepted a token for another RP")
+	}
+}
+
+func TestIDTokenRejectsExpired(t *testing.T) {
+	pp, pk, rid, sid, jws := issueIDToken(t)
+	if _, err := pp.VerifyIDToken(pk, rid, sid, jws, time.Now().Add(2*time.Hour)); err == nil {
+		t.Fatalf("VerifyIDToken accepted an expired token")
+	}
+}
+
+// tamper sets the claim name in the payload of jws to value.
+func tamper(t *testing.T, jws, name string, value any) string {
+	t.Helper()
+	parts := strings.Split(jws, ".")
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims map[string]any
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+	claims[name] = value
+	payload, _ = json.Marshal(claims)
+	parts[1] = base64.RawURLEncoding.EncodeToString(payload)
+	return strings.Join(parts, ".")
+}
+
+func TestIDTokenRejectsTamperedSubject(t *testing.T) {
+	pp, pk, rid, sid, jws := issueIDToken(t)
+	jws = tamper(t, jws, "sub", base64.RawURLEncoding.EncodeToString([]byte("another-ppid")))
+	if _, err := pp.VerifyIDToken(pk, rid, sid, jws, time.Now()); err == nil {
+		t.Fatalf("VerifyIDToken accepted a token with a tampered subject")
+	}
+}
+
+func TestIDTokenRejectsTamperedClaims(t *testing.T) {
+	pp, pk, rid, sid, jws := issueIDToken(t)
+	later := time.Now().Add(2 * time.Hour)
+	if _, err := pp.VerifyIDToken(pk, rid, sid, tamper(t, jws, "exp", later.Add(time.Hour).Unix()), later); err == nil {
+		t.Fatalf("VerifyIDToken accepted a token with an extended expiry")
+	}
+	if _, err := pp.VerifyIDToken(pk, rid, sid, tamper(t, jws, "iss", "https://other.example.com"), time.Now()); err == nil {
+		t.Fatalf("VerifyIDToken accepted a token with another issuer")
+	}
+
+	// A context that the user agent extends is not signed by the IdP
+	ctx, _ := Claims{ExpiresAt: later.Add(time.Hour)}.Context()
+	jws = tamper(t, jws, "oppid_ctx", base64.RawURLEncoding.EncodeToString(ctx))
+	if _, err := pp.VerifyIDToken(pk, rid, sid, jws, later); err == nil {
+		t.Fatalf("VerifyIDToken accepted a token with a replaced context")
+	}
+}
+
+func TestClaims(t *testing.T) {
+	c := Claims{Issuer: "https://idp.example.com", IssuedAt: time.Unix(1000, 0), ExpiresAt: time.Unix(2000, 0), Extra: []byte("extra")}
+	ctx, err := c.Context()
+	if err != nil {
+		t.Fatalf("Context returned an error: %v", err)
+	}
+	got, err := ParseClaims(ctx)
+	if err != nil {
+		t.Fatalf("ParseClaims returned an error: %v", err)
+	}
+	if got.Issuer != c.Issuer || !got.IssuedAt.Equal(c.IssuedAt) || !got.ExpiresAt.Equal(c.ExpiresAt) || !bytes.Equal(got.Extra, c.Extra) {
+		t.Errorf("ParseClaims returned %+v, expected %+v", got, c)
+	}
+	if _, err := (Claims{Issuer: "https://idp.example.com"}).Context(); err == nil {
+		t.Errorf("Context accepted claims without expiry")
+	}
+	for _, ctx := range [][]byte{nil, []byte("context"), []byte(`{"iss":"https://idp.example.com"}`)} {
+		if _, err := ParseClaims(ctx); err == nil {
+			t.Errorf("ParseClaims accepted %q", ctx)
+		}
+	}
+}
+
+func TestIDTokenRejectsSignedAlgorithm(t *testing.T) {
+	_, _, _, _, jws := issueIDToken(t)
+	parts := strings.Split(jws, ".")
+	parts[0] = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+
+	if _, err := ParseIDToken(strings.Join(parts, ".")); err == nil {
+		t.Fatalf("ParseIDToken accepted an unexpected algorithm")
+	}
+}
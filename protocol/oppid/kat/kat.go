@@ -0,0 +1,209 @@
+// Package kat generates known-answer test (KAT) vectors for the OPPID protocol, so that implementations in other
+// languages can be validated against this reference implementation.
+//
+// All randomness is drawn from utils.NewDeterministicReader(seed), i.e., AES-256-CTR under SHA-256(seed) with an
+// all-zero IV, in the order of the protocol steps: Setup, KeyGen, Register, Init, Request, Response and Finalize.
+// Messages are given in their binary wire format, the private key as unencrypted PEM, and all bytes as hex strings.
+
+package kat
+
+import (
+	"OPPID-artifacts/pkg/oppid/utils"
+	"OPPID-artifacts/protocol/oppid"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// DefaultSeed is the seed of the published vectors in testdata/vectors.json.
+const DefaultSeed = "OPPID known-answer test vectors v1"
+
+// Hex is a byte string that is encoded as a hex string in JSON.
+type Hex []byte
+
+func (h Hex) MarshalJSON() ([]byte, error) {
+	return json.Marshal(hex.EncodeToString(h))
+}
+
+func (h *Hex) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	*h = b
+	return nil
+}
+
+type Inputs struct {
+	RID Hex `json:"rid"`
+	UID Hex `json:"uid"`
+	Ctx Hex `json:"ctx"`
+	SID Hex `json:"sid"`
+}
+
+// DefaultInputs returns the inputs of the published vectors.
+func DefaultInputs() Inputs {
+	return Inputs{
+		RID: Hex("rp.example.com"),
+		UID: Hex("alice@idp.example.com"),
+		Ctx: Hex("email_verified=true"),
+		SID: Hex("session-0001"),
+	}
+}
+
+type SetupStep struct {
+	PublicParams Hex `json:"public_params"`
+}
+
+type KeyGenStep struct {
+	PrivateKey string `json:"private_key_pem"`
+	PublicKey  Hex    `json:"public_key"`
+}
+
+type RegisterStep struct {
+	Credential Hex `json:"credential"`
+}
+
+type InitStep struct {
+	Opening    Hex `json:"opening"`
+	Commitment Hex `json:"commitment"`
+}
+
+type RequestStep struct {
+	Auth Hex `json:"auth"`
+}
+
+type ResponseStep struct {
+	Token Hex `json:"token"`
+}
+
+type FinalizeStep struct {
+	FinalizedToken Hex `json:"finalized_token"`
+	PPID           Hex `json:"ppid"`
+}
+
+type VerifyStep struct {
+	Valid bool `json:"valid"`
+}
+
+type Vectors struct {
+	Seed     Hex          `json:"seed"`
+	Inputs   Inputs       `json:"inputs"`
+	Setup    SetupStep    `json:"setup"`
+	KeyGen   KeyGenStep   `json:"keygen"`
+	Register RegisterStep `json:"register"`
+	Init     InitStep     `json:"init"`
+	Request  RequestStep  `json:"request"`
+	Response ResponseStep `json:"response"`
+	Finalize FinalizeStep `json:"finalize"`
+	Verify   VerifyStep   `json:"verify"`
+}
+
+// Generate runs one OPPID login with randomness derived from seed and records the output of every step.
+func Generate(seed []byte, in Inputs) (*Vectors, error) {
+	pp := oppid.Setup(oppid.WithRand(utils.NewDeterministicReader(seed)))
+	v := &Vectors{Seed: seed, Inputs: in}
+	var err error
+
+	if v.Setup.PublicParams, err = pp.MarshalBinary(); err != nil {
+		return nil, err
+	}
+
+	isk, ipk, err := pp.KeyGen()
+	if err != nil {
+		return nil, fmt.Errorf("keygen: %w", err)
+	}
+	skPEM, err := oppid.MarshalPrivateKeyPEM(isk, nil)
+	if err != nil {
+		return nil, err
+	}
+	v.KeyGen.PrivateKey = string(skPEM)
+	if v.KeyGen.PublicKey, err = ipk.MarshalBinary(); err != nil {
+		return nil, err
+	}
+
+	cred, err := pp.Register(isk, in.RID)
+	if err != nil {
+		return nil, fmt.Errorf("register: %w", err)
+	}
+	if v.Register.Credential, err = cred.MarshalBinary(); err != nil {
+		return nil, err
+	}
+
+	orid, crid, err := pp.Init(in.RID)
+	if err != nil {
+		return nil, fmt.Errorf("init: %w", err)
+	}
+	if v.Init.Opening, err = orid.MarshalBinary(); err != nil {
+		return nil, err
+	}
+	if v.Init.Commitment, err = crid.MarshalBinary(); err != nil {
+		return nil, err
+	}
+
+	auth, err := pp.Request(ipk, in.RID, cred, crid, orid, in.SID)
+	if err != nil {
+		return nil, fmt.Errorf("request: %w", err)
+	}
+	if v.Request.Auth, err = auth.MarshalBinary(); err != nil {
+		return nil, err
+	}
+
+	tk, err := pp.Response(isk, auth, crid, in.UID, in.Ctx, in.SID)
+	if err != nil {
+		return nil, fmt.Errorf("response: %w", err)
+	}
+	if v.Response.Token, err = tk.MarshalBinary(); err != nil {
+		return nil, err
+	}
+
+	ftk, ppid, err := pp.Finalize(ipk, in.RID, in.Ctx, in.SID, crid, orid, tk)
+	if err != nil {
+		return nil, fmt.Errorf("finalize: %w", err)
+	}
+	if v.Finalize.FinalizedToken, err = ftk.MarshalBinary(); err != nil {
+		return nil, err
+	}
+	v.Finalize.PPID = ppid
+
+	v.Verify.Valid = pp.Verify(ipk, in.RID, ppid, in.Ctx, in.SID, ftk)
+	if !v.Verify.Valid {
+		return nil, errors.New("verify: finalized token is invalid")
+	}
+	return v, nil
+}
+
+// Check regenerates the vectors from their seed and inputs and reports the first step whose output differs.
+func (v *Vectors) Check() error {
+	want, err := Generate(v.Seed, v.Inputs)
+	if err != nil {
+		return err
+	}
+	steps := []struct {
+		name      string
+		got, want any
+	}{
+		{"setup", v.Setup, want.Setup},
+		{"keygen", v.KeyGen, want.KeyGen},
+		{"register", v.Register, want.Register},
+		{"init", v.Init, want.Init},
+		{"request", v.Request, want.Request},
+		{"response", v.Response, want.Response},
+		{"finalize", v.Finalize, want.Finalize},
+		{"verify", v.Verify, want.Verify},
+	}
+	for _, step := range steps {
+		got, _ := json.Marshal(step.got)
+		exp, _ := json.Marshal(step.want)
+		if !bytes.Equal(got, exp) {
+			return fmt.Errorf("kat: %s step does not match", step.name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package kat
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata/vectors.json")
+
+var vectorsFile = filepath.Join("testdata", "vectors.json")
+
+func TestGenerateIsDeterministic(t *testing.T) {
+	v1, err := Generate([]byte("seed"), DefaultInputs())
+	if err != nil {
+		t.Fatalf("Generate returned an error: %v", err)
+	}
+	v2, err := Generate([]byte("seed"), DefaultInputs())
+	if err != nil {
+		t.Fatalf("Generate returned an error: %v", err)
+	}
+	j1, _ := json.Marshal(v1)
+	j2, _ := json.Marshal(v2)
+	if !bytes.Equal(j1, j2) {
+		t.Fatalf("Generate is not deterministic")
+	}
+
+	v3, err := Generate([]byte("other seed"), DefaultInputs())
+	if err != nil {
+		t.Fatalf("Generate returned an error: %v", err)
+	}
+	if bytes.Equal(v1.Request.Auth, v3.Request.Auth) {
+		t.Fatalf("different seeds yield the same proof")
+	}
+}
+
+func TestPublishedVectors(t *testing.T) {
+	if *update {
+		v, err := Generate([]byte(DefaultSeed), DefaultInputs())
+		if err != nil {
+			t.Fatalf("Generate returned an error: %v", err)
+		}
+		data, _ := json.MarshalIndent(v, "", "  ")
+		if err := os.WriteFile(vectorsFile, append(data, '\n'), 0644); err != nil {
+			t.Fatalf("failed to write vectors: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(vectorsFile)
+	if err != nil {
+		t.Fatalf("failed to read vectors: %v", err)
+	}
+	var v Vectors
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatalf("failed to parse vectors: %v", err)
+	}
+	if err := v.Check(); err != nil {
+		t.Fatalf("published vectors do not match, regenerate them with -update if the change is intended: %v", err)
+	}
+
+	v.Request.Auth[len(v.Request.Auth)-1] ^= 1
+	if err := v.Check(); err == nil {
+		t.Fatalf("Check accepted a modified proof")
+	}
+}
@@ -0,0 +1,302 @@
+// Key files for the IdP keys. A private key file is a sequence of PEM blocks: the token-signing key in the encoding of
+// its scheme, named by a Scheme header, the PS scalars x and y, and the HMAC key of the PRF. Every block carries a
+// Version header. With a passphrase, the whole sequence is sealed into a single block with AES-256-GCM under a key
+// derived by PBKDF2-HMAC-SHA256. Signing keys in RSA PRIVATE KEY and RSA PUBLIC KEY blocks, as written before
+// schemes became configurable, are read as RS256 keys.
+//
+// Keeping the PRF key is what keeps PPIDs stable: a restored key yields the same PPIDs as before.
+
+package oppid
+
+import (
+	FK "OPPID-artifacts/pkg/oppid/prf/fk"
+	"OPPID-artifacts/pkg/oppid/sign"
+	PS "OPPID-artifacts/pkg/oppid/sign/ps"
+	"OPPID-artifacts/pkg/oppid/sign/rsa256"
+	"OPPID-artifacts/pkg/oppid/sign/schemes"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+const (
+	keyFileVersion = "1"
+
+	pemSigningPrivateKey = "OPPID SIGNING PRIVATE KEY"
+	pemSigningPublicKey  = "OPPID SIGNING PUBLIC KEY"
+	pemRSAPrivateKey     = "RSA PRIVATE KEY" // legacy RS256 signing key
+	pemRSAPublicKey      = "RSA PUBLIC KEY"
+	pemPSPrivateKey      = "OPPID PS PRIVATE KEY"
+	pemPSPublicKey       = "OPPID PS PUBLIC KEY"
+	pemPRFKey            = "OPPID PRF KEY"
+	pemEncrypted         = "OPPID ENCRYPTED PRIVATE KEY"
+
+	kdfName       = "PBKDF2-HMAC-SHA256"
+	kdfIterations = 600_000
+	kdfSaltSize   = 16
+	prfKeySize    = 32
+)
+
+var ErrWrongPassphrase = errors.New("oppid: wrong passphrase or corrupted key file")
+
+// PublicKey returns the public key that belongs to the private key.
+func (sk *PrivateKey) PublicKey() *PublicKey {
+	return &PublicKey{sk.scheme, sk.sigSk.Public(), sk.psSk.Pk}
+}
+
+func pemBlock(typ string, data []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: typ, Headers: map[string]string{"Version": keyFileVersion}, Bytes: data})
+}
+
+func signingKeyBlock(typ, scheme string, data []byte) []byte {
+	headers := map[string]string{"Version": keyFileVersion, "Scheme": scheme}
+	return pem.EncodeToMemory(&pem.Block{Type: typ, Headers: headers, Bytes: data})
+}
+
+// signingKey returns the signing key block of the given type, or the legacy RSA block, and the scheme of the key.
+func signingKey(blocks map[string]*pem.Block, typ, legacyTyp string) (*pem.Block, sign.Scheme, error) {
+	block, legacy := blocks[typ], blocks[legacyTyp]
+	switch {
+	case block != nil && legacy == nil:
+		scheme, err := schemes.New(block.Headers["Scheme"])
+		return block, scheme, err
+	case block == nil && legacy != nil:
+		return legacy, rsa256.Setup(schemes.RSAKeySize), nil
+	default:
+		return nil, nil, errors.New("oppid: expected exactly one signing key")
+	}
+}
+
+// MarshalPrivateKeyPEM encodes the private key. If passphrase is not empty, the key is encrypted with it.
+func MarshalPrivateKeyPEM(sk *PrivateKey, passphrase []byte) ([]byte, error) {
+	sigSk, err := sk.sigSk.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	psSk, err := sk.psSk.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	if sk.prfKey == nil || len(*sk.prfKey) != prfKeySize {
+		return nil, errors.New("oppid: invalid PRF key")
+	}
+
+	var buf bytes.Buffer
+	buf.Write(signingKeyBlock(pemSigningPrivateKey, sk.scheme, sigSk))
+	buf.Write(pemBlock(pemPSPrivateKey, psSk))
+	buf.Write(pemBlock(pemPRFKey, *sk.prfKey))
+
+	if len(passphrase) == 0 {
+		return buf.Bytes(), nil
+	}
+	return seal(buf.Bytes(), passphrase)
+}
+
+// ParsePrivateKeyPEM decodes a private key written by MarshalPrivateKeyPEM and returns it with its public key.
+func ParsePrivateKeyPEM(data, passphrase []byte) (*PrivateKey, *PublicKey, error) {
+	blocks, err := decodeBlocks(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if enc, ok := blocks[pemEncrypted]; ok {
+		if len(blocks) != 1 {
+			return nil, nil, errors.New("oppid: unexpected blocks next to encrypted key")
+		}
+		plain, err := open(enc, passphrase)
+		if err != nil {
+			return nil, nil, err
+		}
+		if blocks, err = decodeBlocks(plain); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if len(blocks) != 3 || blocks[pemPSPrivateKey] == nil || blocks[pemPRFKey] == nil {
+		return nil, nil, errors.New("oppid: incomplete private key file")
+	}
+	block, scheme, err := signingKey(blocks, pemSigningPrivateKey, pemRSAPrivateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sk := &PrivateKey{scheme: scheme.Name(), psSk: new(PS.PrivateKey)}
+	if sk.sigSk, err = scheme.UnmarshalPrivateKey(block.Bytes); err != nil {
+		return nil, nil, fmt.Errorf("oppid: invalid %s key: %w", scheme.Name(), err)
+	}
+	if err := sk.psSk.UnmarshalBinary(blocks[pemPSPrivateKey].Bytes); err != nil {
+		return nil, nil, fmt.Errorf("oppid: invalid PS key: %w", err)
+	}
+	if err := checkPSKey(sk.psSk.Pk); err != nil {
+		return nil, nil, err
+	}
+	prfKey := FK.Key(append([]byte(nil), blocks[pemPRFKey].Bytes...))
+	if len(prfKey) != prfKeySize {
+		return nil, nil, errors.New("oppid: invalid PRF key")
+	}
+	sk.prfKey = &prfKey
+
+	return sk, sk.PublicKey(), nil
+}
+
+func MarshalPublicKeyPEM(pk *PublicKey) ([]byte, error) {
+	sigPk, err := pk.sigPk.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	psPk, err := pk.psPk.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return append(signingKeyBlock(pemSigningPublicKey, pk.scheme, sigPk), pemBlock(pemPSPublicKey, psPk)...), nil
+}
+
+func ParsePublicKeyPEM(data []byte) (*PublicKey, error) {
+	blocks, err := decodeBlocks(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(blocks) != 2 || blocks[pemPSPublicKey] == nil {
+		return nil, errors.New("oppid: incomplete public key file")
+	}
+	block, scheme, err := signingKey(blocks, pemSigningPublicKey, pemRSAPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	pk := &PublicKey{scheme: scheme.Name(), psPk: new(PS.PublicKey)}
+	if pk.sigPk, err = scheme.UnmarshalPublicKey(block.Bytes); err != nil {
+		return nil, fmt.Errorf("oppid: invalid %s key: %w", scheme.Name(), err)
+	}
+	if err := pk.psPk.UnmarshalBinary(blocks[pemPSPublicKey].Bytes); err != nil {
+		return nil, fmt.Errorf("oppid: invalid PS key: %w", err)
+	}
+	if err := checkPSKey(pk.psPk); err != nil {
+		return nil, err
+	}
+	return pk, nil
+}
+
+// checkPSKey rejects PS keys that do not sign rid and all attributes, e.g., from files written before credentials had
+// attributes.
+func checkPSKey(pk *PS.PublicKey) error {
+	if pk.Len() != numMessages {
+		return fmt.Errorf("oppid: PS key signs %d messages, want %d", pk.Len(), numMessages)
+	}
+	return nil
+}
+
+// decodeBlocks decodes all PEM blocks by type and rejects duplicates, unknown versions and trailing data.
+func decodeBlocks(data []byte) (map[string]*pem.Block, error) {
+	blocks := make(map[string]*pem.Block)
+	for {
+		block, rest := pem.Decode(data)
+		if block == nil {
+			if len(bytes.TrimSpace(rest)) != 0 {
+				return nil, errors.New("oppid: invalid PEM data")
+			}
+			return blocks, nil
+		}
+		if v := block.Headers["Version"]; v != keyFileVersion {
+			return nil, fmt.Errorf("oppid: unsupported key file version %q", v)
+		}
+		if _, dup := blocks[block.Type]; dup {
+			return nil, fmt.Errorf("oppid: duplicate %s block", block.Type)
+		}
+		blocks[block.Type] = block
+		data = rest
+	}
+}
+
+func seal(plain, passphrase []byte) ([]byte, error) {
+	salt := make([]byte, kdfSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(passphrase, salt, kdfIterations)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{
+		Type: pemEncrypted,
+		Headers: map[string]string{
+			"Version":    keyFileVersion,
+			"KDF":        kdfName,
+			"Iterations": strconv.Itoa(kdfIterations),
+			"Salt":       hex.EncodeToString(salt),
+			"Nonce":      hex.EncodeToString(nonce),
+		},
+		Bytes: aead.Seal(nil, nonce, plain, []byte(pemEncrypted)),
+	}), nil
+}
+
+func open(block *pem.Block, passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("oppid: key file is encrypted, passphrase required")
+	}
+	if block.Headers["KDF"] != kdfName {
+		return nil, fmt.Errorf("oppid: unsupported KDF %q", block.Headers["KDF"])
+	}
+	iterations, err := strconv.Atoi(block.Headers["Iterations"])
+	if err != nil || iterations < 1 {
+		return nil, errors.New("oppid: invalid KDF iterations")
+	}
+	salt, err1 := hex.DecodeString(block.Headers["Salt"])
+	nonce, err2 := hex.DecodeString(block.Headers["Nonce"])
+	if err1 != nil || err2 != nil || len(salt) == 0 {
+		return nil, errors.New("oppid: invalid encryption parameters")
+	}
+
+	aead, err := newAEAD(passphrase, salt, iterations)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, errors.New("oppid: invalid nonce length")
+	}
+	plain, err := aead.Open(nil, nonce, block.Bytes, []byte(pemEncrypted))
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return plain, nil
+}
+
+func newAEAD(passphrase, salt []byte, iterations int) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(pbkdf2(passphrase, salt, iterations))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// pbkdf2 derives a 32-byte key with PBKDF2-HMAC-SHA256 (RFC 8018), which needs a single output block.
+func pbkdf2(password, salt []byte, iterations int) []byte {
+	prf := hmac.New(sha256.New, password)
+	prf.Write(salt)
+	prf.Write(binary.BigEndian.AppendUint32(nil, 1))
+	u := prf.Sum(nil)
+
+	key := append([]byte(nil), u...)
+	for i := 1; i < iterations; i++ {
+		prf.Reset()
+		prf.Write(u)
+		u = prf.Sum(u[:0])
+		for j := range key {
+			key[j] ^= u[j]
+		}
+	}
+	return key
+}
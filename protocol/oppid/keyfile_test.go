@@ -0,0 +1,159 @@
+package oppid
+
+import (
+	"OPPID-artifacts/pkg/oppid/sign/schemes"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestPBKDF2Vector(t *testing.T) {
+	// RFC 7914, Sec. 11: PBKDF2-HMAC-SHA256 with P="passwd", S="salt", c=1 (first 32 bytes)
+	want, _ := hex.DecodeString("55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc")
+	if got := pbkdf2([]byte("passwd"), []byte("salt"), 1); !bytes.Equal(got, want) {
+		t.Fatalf("unexpected PBKDF2 output %x", got)
+	}
+}
+
+func TestPrivateKeyPEMKeepsPPIDs(t *testing.T) {
+	pp, sk, pk := setupAndKeyGen(t)
+	rid := []byte("registrationID")
+	uid := []byte("userID")
+	ppid := login(t, pp, sk, pk, rid, uid)
+
+	data, err := MarshalPrivateKeyPEM(sk, nil)
+	if err != nil {
+		t.Fatalf("MarshalPrivateKeyPEM returned an error: %v", err)
+	}
+	restoredSk, restoredPk, err := ParsePrivateKeyPEM(data, nil)
+	if err != nil {
+		t.Fatalf("ParsePrivateKeyPEM returned an error: %v", err)
+	}
+
+	if !bytes.Equal(ppid, login(t, pp, restoredSk, restoredPk, rid, uid)) {
+		t.Fatalf("restored key yields a different PPID")
+	}
+}
+
+func TestEncryptedPrivateKeyPEM(t *testing.T) {
+	pp, sk, pk := setupAndKeyGen(t)
+	passphrase := []byte("correct horse battery staple")
+
+	data, err := MarshalPrivateKeyPEM(sk, passphrase)
+	if err != nil {
+		t.Fatalf("MarshalPrivateKeyPEM returned an error: %v", err)
+	}
+	if bytes.Contains(data, []byte(pemPRFKey)) {
+		t.Fatalf("encrypted key file exposes the PRF key block")
+	}
+
+	if _, _, err := ParsePrivateKeyPEM(data, []byte("wrong passphrase")); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("expected ErrWrongPassphrase, got %v", err)
+	}
+	if _, _, err := ParsePrivateKeyPEM(data, nil); err == nil {
+		t.Fatalf("encrypted key file was parsed without a passphrase")
+	}
+
+	restoredSk, restoredPk, err := ParsePrivateKeyPEM(data, passphrase)
+	if err != nil {
+		t.Fatalf("ParsePrivateKeyPEM returned an error: %v", err)
+	}
+	rid, uid := []byte("registrationID"), []byte("userID")
+	if !bytes.Equal(login(t, pp, sk, pk, rid, uid), login(t, pp, restoredSk, restoredPk, rid, uid)) {
+		t.Fatalf("restored key yields a different PPID")
+	}
+}
+
+func TestPublicKeyPEM(t *testing.T) {
+	pp, sk, pk := setupAndKeyGen(t)
+
+	data, err := MarshalPublicKeyPEM(pk)
+	if err != nil {
+		t.Fatalf("MarshalPublicKeyPEM returned an error: %v", err)
+	}
+	restoredPk, err := ParsePublicKeyPEM(data)
+	if err != nil {
+		t.Fatalf("ParsePublicKeyPEM returned an error: %v", err)
+	}
+	login(t, pp, sk, restoredPk, []byte("registrationID"), []byte("userID"))
+
+	if _, err := ParsePublicKeyPEM(append(data, data...)); err == nil {
+		t.Fatalf("key file with duplicate blocks was accepted")
+	}
+	if _, err := ParsePublicKeyPEM(bytes.Replace(data, []byte("Version: 1"), []byte("Version: 2"), 1)); err == nil {
+		t.Fatalf("key file with unknown version was accepted")
+	}
+}
+
+func TestSigningKeySchemesPEM(t *testing.T) {
+	for _, s := range schemes.All() {
+		t.Run(s.Name(), func(t *testing.T) {
+			pp := Setup(WithScheme(s))
+			sk, _, err := pp.KeyGen()
+			if err != nil {
+				t.Fatalf("KeyGen returned an error: %v", err)
+			}
+			data, err := MarshalPrivateKeyPEM(sk, nil)
+			if err != nil {
+				t.Fatalf("MarshalPrivateKeyPEM returned an error: %v", err)
+			}
+			restoredSk, restoredPk, err := ParsePrivateKeyPEM(data, nil)
+			if err != nil {
+				t.Fatalf("ParsePrivateKeyPEM returned an error: %v", err)
+			}
+			if restoredSk.scheme != s.Name() {
+				t.Fatalf("restored key has scheme %q", restoredSk.scheme)
+			}
+			pkData, err := MarshalPublicKeyPEM(restoredPk)
+			if err != nil {
+				t.Fatalf("MarshalPublicKeyPEM returned an error: %v", err)
+			}
+			pk, err := ParsePublicKeyPEM(pkData)
+			if err != nil {
+				t.Fatalf("ParsePublicKeyPEM returned an error: %v", err)
+			}
+			login(t, pp, restoredSk, pk, []byte("registrationID"), []byte("userID"))
+		})
+	}
+}
+
+func TestLegacyRSAKeyFile(t *testing.T) {
+	pp, sk, _ := setupAndKeyGen(t)
+	data, err := MarshalPrivateKeyPEM(sk, nil)
+	if err != nil {
+		t.Fatalf("MarshalPrivateKeyPEM returned an error: %v", err)
+	}
+	// Key files written before signing schemes became configurable hold the RSA key in a plain PKCS #1 block
+	data = bytes.ReplaceAll(data, []byte(pemSigningPrivateKey), []byte(pemRSAPrivateKey))
+	data = bytes.Replace(data, []byte("Scheme: RS256\n"), nil, 1)
+
+	legacySk, legacyPk, err := ParsePrivateKeyPEM(data, nil)
+	if err != nil {
+		t.Fatalf("ParsePrivateKeyPEM returned an error for a legacy key file: %v", err)
+	}
+	login(t, pp, legacySk, legacyPk, []byte("registrationID"), []byte("userID"))
+
+	both := append(data, pemBlock(pemSigningPrivateKey, []byte("key"))...)
+	if _, _, err := ParsePrivateKeyPEM(both, nil); err == nil {
+		t.Fatalf("key file with two signing keys was accepted")
+	}
+}
+
+func TestKeyFileWithoutAttributes(t *testing.T) {
+	pp, _, pk := setupAndKeyGen(t)
+	// Key files written before credentials had attributes hold a PS key for rid only.
+	_, singlePk, err := pp.ps.KeyGen()
+	if err != nil {
+		t.Fatalf("KeyGen returned an error: %v", err)
+	}
+	old := *pk
+	old.psPk = singlePk
+	data, err := MarshalPublicKeyPEM(&old)
+	if err != nil {
+		t.Fatalf("MarshalPublicKeyPEM returned an error: %v", err)
+	}
+	if _, err := ParsePublicKeyPEM(data); err == nil {
+		t.Fatalf("key file with a PS key for rid only was accepted")
+	}
+}
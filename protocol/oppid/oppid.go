@@ -4,40 +4,151 @@ package oppid
 
 import (
 	PC "OPPID-artifacts/pkg/oppid/commit/pc"
+	NIZKCOM "OPPID-artifacts/pkg/oppid/nizk/com"
 	NIZK "OPPID-artifacts/pkg/oppid/nizk/comsig"
+	DLEQ "OPPID-artifacts/pkg/oppid/nizk/dleq"
+	NEQ "OPPID-artifacts/pkg/oppid/nizk/neq"
 	FK "OPPID-artifacts/pkg/oppid/prf/fk"
+	"OPPID-artifacts/pkg/oppid/sign"
 	PS "OPPID-artifacts/pkg/oppid/sign/ps"
-	RSA "OPPID-artifacts/pkg/oppid/sign/rsa256"
+	"OPPID-artifacts/pkg/oppid/sign/schemes"
+	"OPPID-artifacts/pkg/oppid/transcript"
 	"OPPID-artifacts/pkg/oppid/utils"
 	"bytes"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
+	"slices"
+	"sync"
 
 	GG "github.com/cloudflare/circl/ecc/bls12381"
 )
 
 const dstStr = "OPPID_BLS12384_XMD:SHA-256_OPPID_"
 
+// PublicParams, PrivateKey and PublicKey are not modified after Setup, KeyGen or decoding, and are safe for concurrent
+// use: an IdP may answer any number of logins at once with one key, and caches of derived values, e.g., fixed-base
+// tables, are filled under locks or sync.Once. With WithRand, the reader has to be safe for concurrent use as well.
 type PublicParams struct {
-	rsa *RSA.PublicParams
-	dst []byte
-	pc  *PC.PublicParams
-	ps  *PS.PublicParams
+	scheme sign.Scheme // signs tokens
+	dst    []byte
+	pc     *PC.PublicParams
+	ps     *PS.PublicParams
+	rand   io.Reader // nil means crypto/rand
+	rids   *ridTables
 }
 
+// Option configures Setup.
+type Option func(*PublicParams)
+
+// WithRand makes all operations draw their randomness from r instead of crypto/rand. With a deterministic r, e.g.,
+// utils.NewDeterministicReader, runs become reproducible; this is meant for test vectors only. Operations that run
+// concurrently need a reader that is safe for concurrent use, which such a reader is not.
+func WithRand(r io.Reader) Option {
+	return func(pp *PublicParams) {
+		pp.rand = r
+		pp.pc.Rand = r
+		pp.ps.Rand = r
+	}
+}
+
+// WithScheme makes the IdP sign tokens with s instead of the default RS256, see package schemes.
+func WithScheme(s sign.Scheme) Option {
+	return func(pp *PublicParams) {
+		pp.scheme = s
+	}
+}
+
+// Keys carry the name of their signature scheme, so that they can be encoded without the public parameters.
 type PublicKey struct {
-	rsaPk *RSA.PublicKey
-	psPk  *PS.PublicKey
+	scheme string
+	sigPk  sign.PublicKey
+	psPk   *PS.PublicKey
 }
 
 type PrivateKey struct {
-	rsaSk  *RSA.PrivateKey
+	scheme string
+	sigSk  sign.PrivateKey
 	psSk   *PS.PrivateKey
 	prfKey *FK.Key
 }
 
+// Attribute names an attribute of a Credential besides its rid, see Request.
+type Attribute int
+
+const (
+	AttrExpiry Attribute = iota + 1 // Attributes.Expiry
+	AttrTier                        // Attributes.Tier
+	AttrScopes                      // Attributes.Scopes
+)
+
+// numMessages is the number of messages signed in a credential: rid and every Attribute.
+const numMessages = int(AttrScopes) + 1
+
+// Attributes are signed into a Credential along with rid. The RP can disclose any of them to the IdP in Request
+// without revealing rid, e.g., its expiry to show that the credential is still valid.
+type Attributes struct {
+	Expiry uint64 // last epoch in which the credential is valid, 0 if it does not expire
+	Tier   string
+	Scopes []string
+}
+
+// Expired reports whether the credential is no longer valid in epoch.
+func (a Attributes) Expired(epoch uint64) bool {
+	return a.Expiry != 0 && a.Expiry < epoch
+}
+
+// message encodes attr as the message that is signed for it.
+func (a Attributes) message(attr Attribute) []byte {
+	switch attr {
+	case AttrExpiry:
+		return binary.BigEndian.AppendUint64(nil, a.Expiry)
+	case AttrTier:
+		return []byte(a.Tier)
+	case AttrScopes:
+		t := transcript.New(dstStr + "SCOPES")
+		for _, scope := range a.Scopes {
+			t.Append("scope", []byte(scope))
+		}
+		return t.Bytes()
+	}
+	return nil
+}
+
+// messages returns the signed messages of a credential on rid with the attributes a.
+func (a Attributes) messages(rid []byte) [][]byte {
+	msgs := [][]byte{rid}
+	for attr := AttrExpiry; attr <= AttrScopes; attr++ {
+		msgs = append(msgs, a.message(attr))
+	}
+	return msgs
+}
+
 type Credential struct {
-	sig PS.Signature
+	sig   PS.Signature
+	attrs Attributes
+}
+
+// Attributes returns the attributes that the IdP signed into the credential.
+func (c Credential) Attributes() Attributes { return c.attrs }
+
+// CredentialRequest asks the IdP for a credential on a rid that it does not learn: a commitment to rid and a proof
+// of knowledge of its opening.
+type CredentialRequest struct {
+	com   PC.Commitment
+	proof *NIZKCOM.Proof
+}
+
+// CredentialOpening is the RP's secret opening of its CredentialRequest, needed to unblind the credential.
+type CredentialOpening struct {
+	opn PC.Opening
+}
+
+// BlindCredential is the IdP's signature on the rid committed to in a CredentialRequest and the attributes it chose.
+type BlindCredential struct {
+	sig   PS.Signature
+	attrs Attributes
 }
 
 type UsrCommitment struct {
@@ -51,12 +162,39 @@ type UsrOpening struct {
 }
 
 type Auth struct {
-	proof NIZK.Proof
+	proof      NIZK.Proof
+	attrs      Attributes  // only the disclosed attributes are set
+	disclosed  []Attribute // in ascending order
+	nonRevoked []NEQ.Proof // one per revoked rid, see RequestWithRevocation
+}
+
+// Disclosed returns the attributes that the RP disclosed in Request and which ones they are; the others are zero.
+// Response checks them against the credential, so the IdP can rely on them, e.g., with Attributes.Expired.
+func (a Auth) Disclosed() (Attributes, []Attribute) {
+	return a.attrs, append([]Attribute(nil), a.disclosed...)
+}
+
+// disclosedMessages returns the messages of the disclosed attributes by their index in the credential.
+func (a Auth) disclosedMessages() map[int][]byte {
+	msgs := make(map[int][]byte, len(a.disclosed))
+	for _, attr := range a.disclosed {
+		msgs[int(attr)] = a.attrs.message(attr)
+	}
+	return msgs
 }
 
 type Token struct {
-	sig RSA.Signature
-	by  *GG.G1 // blinded evaluation
+	sig   sign.Signature
+	by    *GG.G1     // blinded evaluation
+	kc    *GG.G1     // commitment to the PRF key of the user
+	proof DLEQ.Proof // by was evaluated under kc
+}
+
+// KeyCommitment is the IdP's public commitment to the PRF key that it derives for one user. Every token carries it
+// with a proof that its PPID was evaluated under the committed key; a user who sees the same commitment across logins
+// knows that the IdP did not use other keys to hand out inconsistent or tracking PPIDs.
+type KeyCommitment struct {
+	kc *GG.G1
 }
 
 type FinalizedToken struct {
@@ -64,20 +202,19 @@ type FinalizedToken struct {
 	opening PC.Opening
 	b       *GG.Scalar
 	by      *GG.G1
-	sig     RSA.Signature
+	sig     sign.Signature
 }
 
 type PPID = []byte
 
 func tokenBytes(com *PC.Commitment, bx, by *GG.G1, ctx, sid []byte) []byte {
-	tkBuf := bytes.NewBuffer(nil)
-	tkBuf.Write([]byte(dstStr + "TOKEN"))
-	tkBuf.Write(com.Element.Bytes())
-	tkBuf.Write(bx.Bytes()) // blinded rid
-	tkBuf.Write(by.Bytes()) // blinded ppid
-	tkBuf.Write(ctx)
-	tkBuf.Write(sid)
-	return tkBuf.Bytes()
+	return transcript.New(dstStr+"TOKEN").
+		Append("com", com.Element.Bytes()).
+		Append("bx", bx.Bytes()). // blinded rid
+		Append("by", by.Bytes()). // blinded ppid
+		Append("ctx", ctx).
+		Append("sid", sid).
+		Bytes()
 }
 
 // hashToPoint hashes input to a point on G1 curve
@@ -87,85 +224,382 @@ func hashToPoint(input []byte, dst []byte) *GG.G1 {
 	return g
 }
 
+// ridTables caches the hashed rids, which the user multiplies in Init, Request and Finalize and the RP in Verify, for
+// at most maxRids rids. From the ridTableUses-th use of a rid on, it is multiplied with a fixed-base table, whose cost
+// of about five multiplications has then paid off.
+type ridTables struct {
+	mu   sync.Mutex
+	rids map[string]*ridTable
+}
+
+type ridTable struct {
+	point *GG.G1
+	uses  int
+	table *utils.G1Table
+}
+
+const (
+	maxRids      = 64
+	ridTableUses = 4
+)
+
+func newRidTables() *ridTables { return &ridTables{rids: make(map[string]*ridTable)} }
+
+// mulRid returns k*H(rid).
+func (pp *PublicParams) mulRid(k *GG.Scalar, rid []byte) *GG.G1 {
+	if pp.rids == nil {
+		return utils.GenerateG1Point(k, hashToPoint(rid, []byte(dstStr)))
+	}
+	c := pp.rids
+	c.mu.Lock()
+	r, ok := c.rids[string(rid)]
+	if !ok {
+		if len(c.rids) >= maxRids {
+			clear(c.rids)
+		}
+		r = &ridTable{point: hashToPoint(rid, []byte(dstStr))}
+		c.rids[string(rid)] = r
+	}
+	r.uses++
+	if r.uses == ridTableUses {
+		r.table = utils.NewG1Table(r.point)
+	}
+	point, table := r.point, r.table
+	c.mu.Unlock()
+
+	if table != nil {
+		return table.Mul(k)
+	}
+	return utils.GenerateG1Point(k, point)
+}
+
 // createAuxBuffer creates an auxiliary buffer for proof inputs
 func createAuxBuffer(bx *GG.G1, sid []byte) []byte {
-	var aux bytes.Buffer
-	aux.Write(bx.Bytes())
-	aux.Write(sid)
-	return aux.Bytes()
+	return transcript.New(dstStr+"AUX").Append("bx", bx.Bytes()).Append("sid", sid).Bytes()
+}
+
+// createEvalAux creates the auxiliary input that binds the PRF evaluation proof to the login
+func createEvalAux(com *PC.Commitment, sid []byte) []byte {
+	return transcript.New(dstStr+"EVAL").Append("com", com.Element.Bytes()).Append("sid", sid).Bytes()
 }
 
 // createPublicInputs creates the public inputs for NIZK proof verification
-func createPublicInputs(pc *PC.PublicParams, ps *PS.PublicKey, com *PC.Commitment) NIZK.PublicInputs {
-	return NIZK.PublicInputs{ps, pc, com}
+func createPublicInputs(pc *PC.PublicParams, ps *PS.PublicKey, com *PC.Commitment, disclosed map[int][]byte) NIZK.PublicInputs {
+	return NIZK.PublicInputs{PS: ps, PC: pc, Com: com, Disclosed: disclosed}
 }
 
-func Setup() *PublicParams {
-	rsa := RSA.Setup(2048)
+// Setup creates the public parameters. The commitment generators are derived transparently from PC.DefaultSeed, so
+// Setup is deterministic and its output can be checked with VerifyParams.
+func Setup(opts ...Option) *PublicParams {
 	dst := []byte(dstStr + "COM_SIG") // Commitments & signatures must hash to the same domain (dst) for the (NIZK) proof
 	pc := PC.Setup(dst)
 	ps := PS.Setup(dst)
-	return &PublicParams{rsa, dst, pc, ps}
+	pp := &PublicParams{scheme: schemes.Default(), dst: dst, pc: pc, ps: ps, rids: newRidTables()}
+	for _, opt := range opts {
+		opt(pp)
+	}
+	return pp
 }
 
-func (pp *PublicParams) KeyGen() (*PrivateKey, *PublicKey) {
-	rsaSk, rsaPk := pp.rsa.KeyGen()
-	psSk, psPk := pp.ps.KeyGen()
-	prfKey := FK.KeyGen()
-	return &PrivateKey{rsaSk, psSk, prfKey}, &PublicKey{rsaPk, psPk}
+// VerifyParams checks public parameters that were handed over by another party: the commitment generators must be
+// derived from their public seed and all building blocks must share the same domain separation tag.
+func (pp *PublicParams) VerifyParams() error {
+	if pp.scheme == nil || pp.pc == nil || pp.ps == nil {
+		return errors.New("incomplete public parameters")
+	}
+	if err := pp.pc.VerifyParams(); err != nil {
+		return err
+	}
+	if !bytes.Equal(pp.pc.Dst, pp.dst) || !bytes.Equal(pp.ps.Dst, pp.dst) {
+		return errors.New("commitment and signature domains differ")
+	}
+	if rsa, ok := pp.scheme.(interface{ KeySize() int }); ok && rsa.KeySize() < 2048 {
+		return fmt.Errorf("RSA key size %d is too small", rsa.KeySize())
+	}
+	return nil
 }
 
-func (pp *PublicParams) Register(k *PrivateKey, rid []byte) Credential {
-	return Credential{pp.ps.Sign(k.psSk, rid)}
+func (pp *PublicParams) KeyGen() (*PrivateKey, *PublicKey, error) {
+	sigSk, sigPk, err := pp.scheme.KeyGen(pp.rand)
+	if err != nil {
+		return nil, nil, err
+	}
+	psSk, psPk, err := pp.ps.KeyGenMulti(numMessages)
+	if err != nil {
+		return nil, nil, err
+	}
+	prfKey, err := FK.KeyGen(pp.rand)
+	if err != nil {
+		return nil, nil, err
+	}
+	name := pp.scheme.Name()
+	return &PrivateKey{name, sigSk, psSk, prfKey}, &PublicKey{name, sigPk, psPk}, nil
 }
 
-func (pp *PublicParams) Init(rid []byte) (UsrOpening, UsrCommitment) {
-	com, opn := pp.pc.Commit(rid)
-	b := utils.GenerateRandomScalarNotOne()
-	bx := utils.GenerateG1Point(b, hashToPoint(rid, []byte(dstStr)))
-	return UsrOpening{opn, b}, UsrCommitment{com, bx}
+// Register issues a credential on rid without attributes.
+func (pp *PublicParams) Register(k *PrivateKey, rid []byte) (Credential, error) {
+	return pp.RegisterWithAttributes(k, rid, Attributes{})
 }
 
-func (pp *PublicParams) Request(ipk *PublicKey, rid []byte, cred Credential, crid UsrCommitment, orid UsrOpening, sid []byte) (Auth, error) {
-	bx := utils.GenerateG1Point(orid.b, hashToPoint(rid, []byte(dstStr)))
+// RegisterWithAttributes issues a credential on rid and attrs.
+func (pp *PublicParams) RegisterWithAttributes(k *PrivateKey, rid []byte, attrs Attributes) (Credential, error) {
+	sig, err := pp.ps.SignMulti(k.psSk, attrs.messages(rid))
+	if err != nil {
+		return Credential{}, err
+	}
+	return Credential{sig, attrs}, nil
+}
+
+// RequestCredential starts the blind registration of an RP: RequestCredential (RP) -> IssueCredential (IdP) ->
+// UnblindCredential (RP) yields the same Credential as Register, without revealing rid to the IdP.
+func (pp *PublicParams) RequestCredential(ipk *PublicKey, rid []byte) (CredentialOpening, CredentialRequest, error) {
+	if ipk == nil || ipk.psPk == nil || !ipk.psPk.VerifyCommitmentBase() {
+		return CredentialOpening{}, CredentialRequest{}, errors.New("malformed IdP key")
+	}
+	params := pp.ps.CommitmentParams(ipk.psPk)
+	com, opn, err := params.Commit(rid)
+	if err != nil {
+		return CredentialOpening{}, CredentialRequest{}, err
+	}
+	proof, err := NIZKCOM.Prove(pp.rand, &NIZKCOM.PublicInput{Params: params, Com: &com},
+		&NIZKCOM.Witness{Msg: rid, Opening: &opn}, []byte(dstStr+"ISSUE"))
+	if err != nil {
+		return CredentialOpening{}, CredentialRequest{}, err
+	}
+	return CredentialOpening{opn}, CredentialRequest{com, proof}, nil
+}
+
+// IssueCredential signs the rid committed to in req, without attributes, after checking the proof of its opening.
+func (pp *PublicParams) IssueCredential(k *PrivateKey, req CredentialRequest) (BlindCredential, error) {
+	return pp.IssueCredentialWithAttributes(k, req, Attributes{})
+}
+
+// IssueCredentialWithAttributes signs the rid committed to in req and attrs after checking the proof of its opening.
+func (pp *PublicParams) IssueCredentialWithAttributes(k *PrivateKey, req CredentialRequest, attrs Attributes) (BlindCredential, error) {
+	params := pp.ps.CommitmentParams(k.psSk.Pk)
+	if !NIZKCOM.Verify(&NIZKCOM.PublicInput{Params: params, Com: &req.com}, req.proof, []byte(dstStr+"ISSUE")) {
+		return BlindCredential{}, errors.New("invalid credential request proof")
+	}
+	sig, err := pp.ps.BlindSign(k.psSk, req.com, attrs.messages(nil)[1:]...)
+	if err != nil {
+		return BlindCredential{}, err
+	}
+	return BlindCredential{sig, attrs}, nil
+}
+
+// UnblindCredential turns the IdP's blind credential into a Credential on rid and checks it.
+func (pp *PublicParams) UnblindCredential(ipk *PublicKey, rid []byte, opn CredentialOpening, bc BlindCredential) (Credential, error) {
+	sig, err := pp.ps.Unblind(bc.sig, opn.opn)
+	if err != nil {
+		return Credential{}, err
+	}
+	if ipk == nil || !pp.ps.VerifyMulti(ipk.psPk, bc.attrs.messages(rid), sig) {
+		return Credential{}, errors.New("blind credential did not verify")
+	}
+	return Credential{sig, bc.attrs}, nil
+}
+
+func (pp *PublicParams) Init(rid []byte) (UsrOpening, UsrCommitment, error) {
+	com, opn, err := pp.pc.Commit(rid)
+	if err != nil {
+		return UsrOpening{}, UsrCommitment{}, err
+	}
+	b, err := utils.GenerateRandomScalarNotOneFrom(pp.rand)
+	if err != nil {
+		return UsrOpening{}, UsrCommitment{}, err
+	}
+	bx := pp.mulRid(b, rid)
+	return UsrOpening{opn, b}, UsrCommitment{com, bx}, nil
+}
+
+// validCommitment reports whether both parts of the user commitment are valid group elements.
+func validCommitment(crid UsrCommitment) bool {
+	return utils.IsValidG1(crid.com.Element) && utils.IsValidG1(crid.bx)
+}
+
+// Request proves that the RP holds a credential on the rid in crid. It discloses the attributes in disclose, each at
+// most once, and keeps the others hidden.
+func (pp *PublicParams) Request(ipk *PublicKey, rid []byte, cred Credential, crid UsrCommitment, orid UsrOpening, sid []byte, disclose ...Attribute) (Auth, error) {
+	if !utils.IsValidScalar(orid.b) || !validCommitment(crid) {
+		return Auth{}, errors.New("malformed user commitment or opening")
+	}
+	auth, err := newAuth(cred.attrs, disclose)
+	if err != nil {
+		return Auth{}, err
+	}
+	bx := pp.mulRid(orid.b, rid)
 
 	if !bx.IsEqual(crid.bx) || !pp.pc.Open(rid, crid.com, orid.opn) {
 		return Auth{}, fmt.Errorf("rid blinding or commitment is not correct")
 	}
 
 	w := NIZK.Witnesses{
-		Msg:     rid,
+		Msgs:    cred.attrs.messages(rid),
 		Sig:     &cred.sig,
 		Opening: &orid.opn,
 	}
 
-	p := createPublicInputs(pp.pc, ipk.psPk, &crid.com)
+	p := createPublicInputs(pp.pc, ipk.psPk, &crid.com, auth.disclosedMessages())
 	aux := createAuxBuffer(bx, sid)
 
-	pi := NIZK.Prove(w, p, aux, pp.dst)
-	return Auth{pi}, nil
+	auth.proof, err = NIZK.Prove(pp.rand, w, p, aux, pp.dst)
+	if err != nil {
+		return Auth{}, err
+	}
+	return auth, nil
+}
+
+// newAuth keeps the attributes in disclose from attrs, sorted and checked for duplicates.
+func newAuth(attrs Attributes, disclose []Attribute) (Auth, error) {
+	var auth Auth
+	auth.disclosed = append([]Attribute(nil), disclose...)
+	slices.Sort(auth.disclosed)
+	for i, attr := range auth.disclosed {
+		if attr < AttrExpiry || attr > AttrScopes {
+			return Auth{}, fmt.Errorf("unknown attribute %d", attr)
+		}
+		if i > 0 && auth.disclosed[i-1] == attr {
+			return Auth{}, fmt.Errorf("attribute %d disclosed twice", attr)
+		}
+		switch attr {
+		case AttrExpiry:
+			auth.attrs.Expiry = attrs.Expiry
+		case AttrTier:
+			auth.attrs.Tier = attrs.Tier
+		case AttrScopes:
+			auth.attrs.Scopes = append([]string(nil), attrs.Scopes...)
+		}
+	}
+	return auth, nil
 }
 
 func (pp *PublicParams) Response(isk *PrivateKey, auth Auth, crid UsrCommitment, uid, ctx, sid []byte) (Token, error) {
-	p := createPublicInputs(pp.pc, isk.psSk.Pk, &crid.com)
+	if !validCommitment(crid) {
+		return Token{}, errors.New("malformed user commitment")
+	}
+	p := createPublicInputs(pp.pc, isk.psSk.Pk, &crid.com, auth.disclosedMessages())
 	aux := createAuxBuffer(crid.bx, sid)
 
 	if !NIZK.Verify(auth.proof, p, aux) {
 		return Token{}, errors.New("invalid authentication proof")
 	}
+	return pp.issueToken(isk, crid, uid, ctx, sid)
+}
 
-	by := FK.Eval(isk.prfKey, crid.bx.Bytes(), uid)
+// issueToken evaluates the PRF on the blinded rid of crid and signs the token, once the authentication proof has been
+// verified.
+func (pp *PublicParams) issueToken(isk *PrivateKey, crid UsrCommitment, uid, ctx, sid []byte) (Token, error) {
+	if isk.scheme != pp.scheme.Name() {
+		return Token{}, fmt.Errorf("key of scheme %s cannot sign %s tokens", isk.scheme, pp.scheme.Name())
+	}
+	by, proof, err := FK.VerifiableBlindEval(pp.rand, isk.prfKey, crid.bx, uid, createEvalAux(&crid.com, sid))
+	if err != nil {
+		return Token{}, err
+	}
 	tkBytes := tokenBytes(&crid.com, crid.bx, by, ctx, sid)
-	sig := pp.rsa.Sign(isk.rsaSk, tkBytes)
+	sig, err := pp.scheme.Sign(pp.rand, isk.sigSk, tkBytes)
+	if err != nil {
+		return Token{}, err
+	}
+
+	return Token{sig, by, FK.Commit(isk.prfKey, uid), proof}, nil
+}
 
-	return Token{sig, by}, nil
+// ResponseInput is a login that ResponseBatch answers, with the values that Response takes.
+type ResponseInput struct {
+	Auth Auth
+	Crid UsrCommitment
+	Uid  []byte
+	Ctx  []byte
+	Sid  []byte
+}
+
+// ResponseBatch answers queued logins like Response for each of them, but verifies their authentication proofs at once
+// with NIZK.BatchVerify. If a batch fails, it is split in halves until the invalid proofs are found, so that a few
+// invalid logins cost a few more batches rather than falling back to Verify for all. It returns a token or an error
+// for each input, in the same order.
+func (pp *PublicParams) ResponseBatch(isk *PrivateKey, in []ResponseInput) ([]Token, []error) {
+	tks, errs := make([]Token, len(in)), make([]error, len(in))
+	var (
+		proofs []NIZK.Proof
+		inputs []NIZK.PublicInputs
+		aux    [][]byte
+		idx    []int // index into in of each proof
+	)
+	for i, v := range in {
+		if !validCommitment(v.Crid) {
+			errs[i] = errors.New("malformed user commitment")
+			continue
+		}
+		proofs = append(proofs, v.Auth.proof)
+		inputs = append(inputs, createPublicInputs(pp.pc, isk.psSk.Pk, &v.Crid.com, v.Auth.disclosedMessages()))
+		aux = append(aux, createAuxBuffer(v.Crid.bx, v.Sid))
+		idx = append(idx, i)
+	}
+
+	var verify func(lo, hi int)
+	verify = func(lo, hi int) {
+		if lo == hi || NIZK.BatchVerify(pp.rand, proofs[lo:hi], inputs[lo:hi], aux[lo:hi]) {
+			return
+		}
+		if hi-lo == 1 {
+			errs[idx[lo]] = errors.New("invalid authentication proof")
+			return
+		}
+		mid := lo + (hi-lo)/2
+		verify(lo, mid)
+		verify(mid, hi)
+	}
+	verify(0, len(proofs))
+
+	for _, i := range idx {
+		if errs[i] == nil {
+			tks[i], errs[i] = pp.issueToken(isk, in[i].Crid, in[i].Uid, in[i].Ctx, in[i].Sid)
+		}
+	}
+	return tks, errs
+}
+
+// KeyCommitment returns the commitment to the PRF key of user uid, which the IdP publishes to the user.
+func (pp *PublicParams) KeyCommitment(isk *PrivateKey, uid []byte) KeyCommitment {
+	return KeyCommitment{FK.Commit(isk.prfKey, uid)}
+}
+
+// KeyCommitment returns the commitment to the PRF key under which the token was evaluated. Finalize checks the
+// evaluation against it; the user checks that it does not change between logins.
+func (tk Token) KeyCommitment() KeyCommitment { return KeyCommitment{tk.kc} }
+
+// Equal reports whether both commitments are set and commit to the same key.
+func (c KeyCommitment) Equal(other KeyCommitment) bool {
+	return c.kc != nil && other.kc != nil && c.kc.IsEqual(other.kc)
+}
+
+// Scheme returns the name of the scheme that signs tokens.
+func (pp *PublicParams) Scheme() string { return pp.scheme.Name() }
+
+// Scheme returns the name of the signature scheme of the key.
+func (sk *PrivateKey) Scheme() string { return sk.scheme }
+
+// Scheme returns the name of the signature scheme of the key.
+func (pk *PublicKey) Scheme() string { return pk.scheme }
+
+// verifySig verifies a token signature and rejects keys of another scheme.
+func (pp *PublicParams) verifySig(ipk *PublicKey, tkBytes []byte, sig sign.Signature) bool {
+	return ipk != nil && ipk.scheme == pp.scheme.Name() && pp.scheme.Verify(ipk.sigPk, tkBytes, sig)
 }
 
 func (pp *PublicParams) Finalize(ipk *PublicKey, rid, ctx, sid []byte, crid UsrCommitment, orid UsrOpening, tk Token) (FinalizedToken, PPID, error) {
-	bx := utils.GenerateG1Point(orid.b, hashToPoint(rid, []byte(dstStr)))
+	if !utils.IsValidScalar(orid.b) || !utils.IsValidG1(crid.com.Element) || !utils.IsValidG1(tk.by) ||
+		!utils.IsValidG1(tk.kc) {
+		return FinalizedToken{}, nil, errors.New("malformed commitment, opening or token")
+	}
+	bx := pp.mulRid(orid.b, rid)
+	if !FK.VerifyBlindEval(tk.kc, bx, tk.by, tk.proof, createEvalAux(&crid.com, sid)) {
+		return FinalizedToken{}, nil, errors.New("PRF evaluation proof did not verify")
+	}
 	tkBytes := tokenBytes(&crid.com, bx, tk.by, ctx, sid)
 
-	if !pp.pc.Open(rid, crid.com, orid.opn) || !pp.rsa.Verify(ipk.rsaPk, tkBytes, tk.sig) {
+	if !pp.pc.Open(rid, crid.com, orid.opn) || !pp.verifySig(ipk, tkBytes, tk.sig) {
 		return FinalizedToken{}, nil, errors.New("commitment or signature did not verify")
 	}
 
@@ -176,13 +610,62 @@ func (pp *PublicParams) Finalize(ipk *PublicKey, rid, ctx, sid []byte, crid UsrC
 	return FinalizedToken{crid.com, orid.opn, orid.b, tk.by, tk.sig}, y.Bytes(), nil
 }
 
+// Verify returns false for malformed tokens, e.g., with missing, identity or zero components.
 func (pp *PublicParams) Verify(ipk *PublicKey, rid, ppid, ctx, sid []byte, ftk FinalizedToken) bool {
-	bx := utils.GenerateG1Point(ftk.b, hashToPoint(rid, []byte(dstStr)))
+	tkBytes, ok := pp.checkToken(rid, ppid, ctx, sid, ftk)
+	return ok && pp.verifySig(ipk, tkBytes, ftk.sig)
+}
+
+// checkToken checks everything of a finalized token but its signature and returns the signed bytes.
+func (pp *PublicParams) checkToken(rid, ppid, ctx, sid []byte, ftk FinalizedToken) ([]byte, bool) {
+	if !utils.IsValidScalar(ftk.b) || !utils.IsValidG1(ftk.com.Element) || !utils.IsValidG1(ftk.by) {
+		return nil, false
+	}
+	bx := pp.mulRid(ftk.b, rid)
 	tkBytes := tokenBytes(&ftk.com, bx, ftk.by, ctx, sid)
 
 	bldInv := new(GG.Scalar)
 	bldInv.Inv(ftk.b)
 	y := utils.GenerateG1Point(bldInv, ftk.by)
 
-	return pp.pc.Open(rid, ftk.com, ftk.opening) && pp.rsa.Verify(ipk.rsaPk, tkBytes, ftk.sig) && bytes.Equal(ppid, y.Bytes())
+	return tkBytes, pp.pc.Open(rid, ftk.com, ftk.opening) && bytes.Equal(ppid, y.Bytes())
+}
+
+// VerifyInput is a finalized token with the values that Verify checks it against.
+type VerifyInput struct {
+	PPID  PPID
+	Ctx   []byte
+	Sid   []byte
+	Token FinalizedToken
+}
+
+// VerifyBatch checks tokens that one IdP issued for the same RP, like Verify for each of them. If the token scheme
+// implements sign.BatchVerifier, e.g., BLS, the signatures are verified at once, e.g., with a single multi-pairing.
+// It returns false if any token is invalid, without telling which; an empty batch is invalid.
+func (pp *PublicParams) VerifyBatch(ipk *PublicKey, rid []byte, in []VerifyInput) bool {
+	if len(in) == 0 || ipk == nil || ipk.scheme != pp.scheme.Name() {
+		return false
+	}
+	msgs, sigs := make([][]byte, len(in)), make([]sign.Signature, len(in))
+	for i, v := range in {
+		tkBytes, ok := pp.checkToken(rid, v.PPID, v.Ctx, v.Sid, v.Token)
+		if !ok {
+			return false
+		}
+		msgs[i], sigs[i] = tkBytes, v.Token.sig
+	}
+
+	if bv, ok := pp.scheme.(sign.BatchVerifier); ok {
+		pks := make([]sign.PublicKey, len(in))
+		for i := range pks {
+			pks[i] = ipk.sigPk
+		}
+		return bv.BatchVerify(pp.rand, pks, msgs, sigs)
+	}
+	for i := range msgs {
+		if !pp.scheme.Verify(ipk.sigPk, msgs[i], sigs[i]) {
+			return false
+		}
+	}
+	return true
 }
@@ -2,9 +2,17 @@ package oppid
 
 import (
 	PC "OPPID-artifacts/pkg/oppid/commit/pc"
-	NIZK "OPPID-artifacts/pkg/oppid/nizk/comsig"
+	FK "OPPID-artifacts/pkg/oppid/prf/fk"
+	"OPPID-artifacts/pkg/oppid/sign"
+	"OPPID-artifacts/pkg/oppid/sign/bls"
+	"OPPID-artifacts/pkg/oppid/sign/ecdsa256"
 	PS "OPPID-artifacts/pkg/oppid/sign/ps"
+	"OPPID-artifacts/pkg/oppid/sign/rsa256"
+	"OPPID-artifacts/pkg/oppid/sign/schemes"
 	"OPPID-artifacts/pkg/oppid/utils"
+	"bytes"
+	"fmt"
+	"sync"
 	"testing"
 
 	GG "github.com/cloudflare/circl/ecc/bls12381"
@@ -15,22 +23,43 @@ func setupAndKeyGen(t *testing.T) (*PublicParams, *PrivateKey, *PublicKey) {
 	if oppid == nil {
 		t.Fatalf("Setup returned nil")
 	}
-	if oppid.rsa == nil || oppid.pc == nil || oppid.ps == nil {
+	if oppid.scheme == nil || oppid.pc == nil || oppid.ps == nil {
 		t.Fatalf("Setup did not initialize all public parameters")
 	}
-	sk, pk := oppid.KeyGen()
+	sk, pk, err := oppid.KeyGen()
+	if err != nil {
+		t.Fatalf("KeyGen returned an error: %v", err)
+	}
 	if sk == nil || pk == nil {
 		t.Fatalf("KeyGen returned nil")
 	}
-	if sk.rsaSk == nil || sk.psSk == nil || sk.prfKey == nil {
+	if sk.sigSk == nil || sk.psSk == nil || sk.prfKey == nil {
 		t.Fatalf("KeyGen did not initialize all private keys")
 	}
-	if pk.rsaPk == nil || pk.psPk == nil {
+	if pk.sigPk == nil || pk.psPk == nil {
 		t.Fatalf("KeyGen did not initialize all public keys")
 	}
 	return oppid, sk, pk
 }
 
+func register(t *testing.T, pp *PublicParams, sk *PrivateKey, rid []byte) Credential {
+	t.Helper()
+	cred, err := pp.Register(sk, rid)
+	if err != nil {
+		t.Fatalf("Register returned an error: %v", err)
+	}
+	return cred
+}
+
+func initUser(t *testing.T, pp *PublicParams, rid []byte) (UsrOpening, UsrCommitment) {
+	t.Helper()
+	orid, crid, err := pp.Init(rid)
+	if err != nil {
+		t.Fatalf("Init returned an error: %v", err)
+	}
+	return orid, crid
+}
+
 func TestKeyGen(t *testing.T) {
 	setupAndKeyGen(t)
 }
@@ -38,7 +67,10 @@ func TestKeyGen(t *testing.T) {
 func TestRegister(t *testing.T) {
 	oppid, sk, _ := setupAndKeyGen(t)
 	rid := []byte("registrationID")
-	cred := oppid.Register(sk, rid)
+	cred, err := oppid.Register(sk, rid)
+	if err != nil {
+		t.Fatalf("Register returned an error: %v", err)
+	}
 	if cred.sig == (PS.Signature{}) {
 		t.Fatalf("Register did not return a valid signature")
 	}
@@ -47,7 +79,10 @@ func TestRegister(t *testing.T) {
 func TestInit(t *testing.T) {
 	oppid, _, _ := setupAndKeyGen(t)
 	rid := []byte("registrationID")
-	orid, crid := oppid.Init(rid)
+	orid, crid, err := oppid.Init(rid)
+	if err != nil {
+		t.Fatalf("Init returned an error: %v", err)
+	}
 	if orid.opn == (PC.Opening{}) || orid.b == nil {
 		t.Fatalf("Init did not return a valid UsrOpening")
 	}
@@ -59,14 +94,14 @@ func TestInit(t *testing.T) {
 func TestRequest(t *testing.T) {
 	oppid, sk, pk := setupAndKeyGen(t)
 	rid := []byte("registrationID")
-	cred := oppid.Register(sk, rid)
-	orid, crid := oppid.Init(rid)
+	cred := register(t, oppid, sk, rid)
+	orid, crid := initUser(t, oppid, rid)
 	sid := []byte("sessionID")
 	auth, err := oppid.Request(pk, rid, cred, crid, orid, sid)
 	if err != nil {
 		t.Fatalf("Request returned an error: %v", err)
 	}
-	if auth.proof == (NIZK.Proof{}) {
+	if _, err := auth.proof.MarshalBinary(); err != nil {
 		t.Fatalf("Request did not return a valid proof")
 	}
 }
@@ -74,8 +109,8 @@ func TestRequest(t *testing.T) {
 func TestResponse(t *testing.T) {
 	oppid, sk, pk := setupAndKeyGen(t)
 	rid := []byte("registrationID")
-	cred := oppid.Register(sk, rid)
-	orid, crid := oppid.Init(rid)
+	cred := register(t, oppid, sk, rid)
+	orid, crid := initUser(t, oppid, rid)
 	sid := []byte("sessionID")
 	auth, err := oppid.Request(pk, rid, cred, crid, orid, sid)
 	if err != nil {
@@ -95,8 +130,8 @@ func TestResponse(t *testing.T) {
 func TestFinalize(t *testing.T) {
 	oppid, sk, pk := setupAndKeyGen(t)
 	rid := []byte("registrationID")
-	cred := oppid.Register(sk, rid)
-	orid, crid := oppid.Init(rid)
+	cred := register(t, oppid, sk, rid)
+	orid, crid := initUser(t, oppid, rid)
 	sid := []byte("sessionID")
 	auth, err := oppid.Request(pk, rid, cred, crid, orid, sid)
 	if err != nil {
@@ -120,8 +155,8 @@ func TestFinalize(t *testing.T) {
 func TestVerify(t *testing.T) {
 	oppid, sk, pk := setupAndKeyGen(t)
 	rid := []byte("registrationID")
-	cred := oppid.Register(sk, rid)
-	orid, crid := oppid.Init(rid)
+	cred := register(t, oppid, sk, rid)
+	orid, crid := initUser(t, oppid, rid)
 	sid := []byte("sessionID")
 	auth, err := oppid.Request(pk, rid, cred, crid, orid, sid)
 	if err != nil {
@@ -146,9 +181,10 @@ func TestVerify(t *testing.T) {
 func TestInvalidUserCommitment(t *testing.T) {
 	oppid, sk, pk := setupAndKeyGen(t)
 	rid := []byte("registrationID")
-	cred := oppid.Register(sk, rid)
-	orid, crid := oppid.Init(rid)
-	alteredCrid := UsrCommitment{com: crid.com, bx: utils.GenerateG1Point(utils.GenerateRandomScalar(), GG.G1Generator())}
+	cred := register(t, oppid, sk, rid)
+	orid, crid := initUser(t, oppid, rid)
+	s, _ := utils.GenerateRandomScalar()
+	alteredCrid := UsrCommitment{com: crid.com, bx: utils.GenerateG1Point(s, GG.G1Generator())}
 	sid := []byte("sessionID")
 	_, err := oppid.Request(pk, rid, cred, alteredCrid, orid, sid)
 	if err == nil {
@@ -159,8 +195,8 @@ func TestInvalidUserCommitment(t *testing.T) {
 func TestInvalidTokenSignature(t *testing.T) {
 	pp, sk, pk := setupAndKeyGen(t)
 	rid := []byte("registrationID")
-	cred := pp.Register(sk, rid)
-	orid, crid := pp.Init(rid)
+	cred := register(t, pp, sk, rid)
+	orid, crid := initUser(t, pp, rid)
 	sid := []byte("sessionID")
 	auth, err := pp.Request(pk, rid, cred, crid, orid, sid)
 	if err != nil {
@@ -179,11 +215,57 @@ func TestInvalidTokenSignature(t *testing.T) {
 	}
 }
 
+// TestInconsistentEvaluation simulates an IdP that evaluates the PPID under another PRF key and signs the result.
+func TestInconsistentEvaluation(t *testing.T) {
+	pp, sk, pk := setupAndKeyGen(t)
+	rid := []byte("registrationID")
+	cred := register(t, pp, sk, rid)
+	orid, crid := initUser(t, pp, rid)
+	sid := []byte("sessionID")
+	auth, err := pp.Request(pk, rid, cred, crid, orid, sid)
+	if err != nil {
+		t.Fatalf("Request returned an error: %v", err)
+	}
+	uid := []byte("userID")
+	ctx := []byte("context")
+	token, err := pp.Response(sk, auth, crid, uid, ctx, sid)
+	if err != nil {
+		t.Fatalf("Response returned an error: %v", err)
+	}
+	if !token.KeyCommitment().Equal(pp.KeyCommitment(sk, uid)) {
+		t.Fatalf("token carries another key commitment than the published one")
+	}
+
+	tracking, _ := FK.KeyGen(nil)
+	forged := token
+	forged.by = FK.BlindEval(tracking, crid.bx, uid)
+	forged.sig, _ = pp.scheme.Sign(nil, sk.sigSk, tokenBytes(&crid.com, crid.bx, forged.by, ctx, sid))
+	if _, _, err := pp.Finalize(pk, rid, ctx, sid, crid, orid, forged); err == nil {
+		t.Fatalf("Finalize accepted an evaluation under another key")
+	}
+
+	// With a matching commitment and proof the token finalizes, but its commitment differs from the published one.
+	forged.kc = FK.Commit(tracking, uid)
+	forged.by, forged.proof, _ = FK.VerifiableBlindEval(nil, tracking, crid.bx, uid, createEvalAux(&crid.com, sid))
+	forged.sig, _ = pp.scheme.Sign(nil, sk.sigSk, tokenBytes(&crid.com, crid.bx, forged.by, ctx, sid))
+	if _, _, err := pp.Finalize(pk, rid, ctx, sid, crid, orid, forged); err != nil {
+		t.Fatalf("Finalize returned an error: %v", err)
+	}
+	if forged.KeyCommitment().Equal(pp.KeyCommitment(sk, uid)) {
+		t.Fatalf("commitment to another key equals the published one")
+	}
+
+	forged.proof = token.proof
+	if _, _, err := pp.Finalize(pk, rid, ctx, sid, crid, orid, forged); err == nil {
+		t.Fatalf("Finalize accepted a proof for another evaluation")
+	}
+}
+
 func TestInvalidFinalizeCommitment(t *testing.T) {
 	pp, sk, pk := setupAndKeyGen(t)
 	rid := []byte("registrationID")
-	cred := pp.Register(sk, rid)
-	orid, crid := pp.Init(rid)
+	cred := register(t, pp, sk, rid)
+	orid, crid := initUser(t, pp, rid)
 	sid := []byte("sessionID")
 	auth, err := pp.Request(pk, rid, cred, crid, orid, sid)
 	if err != nil {
@@ -205,8 +287,8 @@ func TestInvalidFinalizeCommitment(t *testing.T) {
 func TestInvalidVerifyCommitment(t *testing.T) {
 	pp, sk, pk := setupAndKeyGen(t)
 	rid := []byte("registrationID")
-	cred := pp.Register(sk, rid)
-	orid, crid := pp.Init(rid)
+	cred := register(t, pp, sk, rid)
+	orid, crid := initUser(t, pp, rid)
 	sid := []byte("sessionID")
 	auth, err := pp.Request(pk, rid, cred, crid, orid, sid)
 	if err != nil {
@@ -233,8 +315,8 @@ func TestInvalidVerifyCommitment(t *testing.T) {
 func TestInvalidVerifySignature(t *testing.T) {
 	pp, sk, pk := setupAndKeyGen(t)
 	rid := []byte("registrationID")
-	cred := pp.Register(sk, rid)
-	orid, crid := pp.Init(rid)
+	cred := register(t, pp, sk, rid)
+	orid, crid := initUser(t, pp, rid)
 	sid := []byte("sessionID")
 	auth, err := pp.Request(pk, rid, cred, crid, orid, sid)
 	if err != nil {
@@ -257,3 +339,574 @@ func TestInvalidVerifySignature(t *testing.T) {
 		t.Fatalf("Verify accepted an altered signature")
 	}
 }
+
+func login(t *testing.T, pp *PublicParams, sk *PrivateKey, pk *PublicKey, rid, uid []byte) PPID {
+	t.Helper()
+	ctx := []byte("context")
+	sid := []byte("sessionID")
+	cred := register(t, pp, sk, rid)
+	orid, crid := initUser(t, pp, rid)
+	auth, err := pp.Request(pk, rid, cred, crid, orid, sid)
+	if err != nil {
+		t.Fatalf("Request returned an error: %v", err)
+	}
+	token, err := pp.Response(sk, auth, crid, uid, ctx, sid)
+	if err != nil {
+		t.Fatalf("Response returned an error: %v", err)
+	}
+	_, ppid, err := pp.Finalize(pk, rid, ctx, sid, crid, orid, token)
+	if err != nil {
+		t.Fatalf("Finalize returned an error: %v", err)
+	}
+	return ppid
+}
+
+func TestPPIDIsPairwiseAndStable(t *testing.T) {
+	pp, sk, pk := setupAndKeyGen(t)
+	rid := []byte("registrationID")
+	uid := []byte("userID")
+
+	ppid := login(t, pp, sk, pk, rid, uid)
+	if !bytes.Equal(ppid, login(t, pp, sk, pk, rid, uid)) {
+		t.Fatalf("repeated logins of the same user at the same RP yield different PPIDs")
+	}
+	if bytes.Equal(ppid, login(t, pp, sk, pk, rid, []byte("otherUserID"))) {
+		t.Fatalf("different users obtained the same PPID")
+	}
+	if bytes.Equal(ppid, login(t, pp, sk, pk, []byte("otherRegistrationID"), uid)) {
+		t.Fatalf("the same user obtained the same PPID at different RPs")
+	}
+}
+
+func TestVerifyParams(t *testing.T) {
+	pp, _, _ := setupAndKeyGen(t)
+	if err := pp.VerifyParams(); err != nil {
+		t.Fatalf("VerifyParams rejected the output of Setup: %v", err)
+	}
+
+	data, err := pp.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary returned an error: %v", err)
+	}
+	var decoded PublicParams
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary returned an error: %v", err)
+	}
+	if err := decoded.VerifyParams(); err != nil {
+		t.Fatalf("VerifyParams rejected decoded parameters: %v", err)
+	}
+
+	s, _ := utils.GenerateRandomScalar()
+	decoded.pc.H = utils.GenerateG1Point(s, GG.G1Generator())
+	if err := decoded.VerifyParams(); err == nil {
+		t.Fatalf("VerifyParams accepted a commitment generator with known discrete log")
+	}
+}
+
+func TestMalformedInputs(t *testing.T) {
+	pp, sk, pk := setupAndKeyGen(t)
+	rid := []byte("registrationID")
+	uid := []byte("userID")
+	ctx := []byte("context")
+	sid := []byte("sessionID")
+
+	cred := register(t, pp, sk, rid)
+	orid, crid := initUser(t, pp, rid)
+	auth, err := pp.Request(pk, rid, cred, crid, orid, sid)
+	if err != nil {
+		t.Fatalf("Request returned an error: %v", err)
+	}
+	tk, err := pp.Response(sk, auth, crid, uid, ctx, sid)
+	if err != nil {
+		t.Fatalf("Response returned an error: %v", err)
+	}
+	ftk, ppid, err := pp.Finalize(pk, rid, ctx, sid, crid, orid, tk)
+	if err != nil {
+		t.Fatalf("Finalize returned an error: %v", err)
+	}
+
+	identity := new(GG.G1)
+	identity.SetIdentity()
+	zero := new(GG.Scalar)
+
+	for name, c := range map[string]UsrCommitment{
+		"identity bx":         {com: crid.com, bx: identity},
+		"identity commitment": {com: PC.Commitment{Element: identity}, bx: crid.bx},
+		"empty":               {},
+	} {
+		if _, err := pp.Response(sk, auth, c, uid, ctx, sid); err == nil {
+			t.Fatalf("Response accepted a commitment with %s", name)
+		}
+		if _, err := pp.Request(pk, rid, cred, c, orid, sid); err == nil {
+			t.Fatalf("Request accepted a commitment with %s", name)
+		}
+	}
+	if _, err := pp.Response(sk, Auth{}, crid, uid, ctx, sid); err == nil {
+		t.Fatalf("Response accepted an empty proof")
+	}
+	if _, err := pp.Request(pk, rid, Credential{}, crid, orid, sid); err == nil {
+		t.Fatalf("Request accepted an empty credential")
+	}
+
+	if _, _, err := pp.Finalize(pk, rid, ctx, sid, crid, UsrOpening{opn: orid.opn, b: zero}, tk); err == nil {
+		t.Fatalf("Finalize accepted a zero blinding")
+	}
+	if _, _, err := pp.Finalize(pk, rid, ctx, sid, crid, orid, Token{sig: tk.sig, by: identity}); err == nil {
+		t.Fatalf("Finalize accepted an identity evaluation")
+	}
+
+	for name, f := range map[string]FinalizedToken{
+		"zero blinding":       {ftk.com, ftk.opening, zero, ftk.by, ftk.sig},
+		"identity evaluation": {ftk.com, ftk.opening, ftk.b, identity, ftk.sig},
+		"zero opening":        {ftk.com, PC.Opening{Scalar: zero}, ftk.b, ftk.by, ftk.sig},
+		"empty":               {},
+	} {
+		if pp.Verify(pk, rid, ppid, ctx, sid, f) {
+			t.Fatalf("Verify accepted a token with %s", name)
+		}
+	}
+}
+
+func TestFieldBoundaryCollision(t *testing.T) {
+	oppid, sk, pk := setupAndKeyGen(t)
+	rid := []byte("registrationID")
+	cred := register(t, oppid, sk, rid)
+	orid, crid := initUser(t, oppid, rid)
+	auth, err := oppid.Request(pk, rid, cred, crid, orid, []byte("c"))
+	if err != nil {
+		t.Fatalf("Request returned an error: %v", err)
+	}
+	token, err := oppid.Response(sk, auth, crid, []byte("userID"), []byte("ab"), []byte("c"))
+	if err != nil {
+		t.Fatalf("Response returned an error: %v", err)
+	}
+	if _, _, err := oppid.Finalize(pk, rid, []byte("a"), []byte("bc"), crid, orid, token); err == nil {
+		t.Fatalf("Finalize accepted a token with shifted ctx/sid boundary")
+	}
+	finalToken, ppid, err := oppid.Finalize(pk, rid, []byte("ab"), []byte("c"), crid, orid, token)
+	if err != nil {
+		t.Fatalf("Finalize returned an error: %v", err)
+	}
+	if oppid.Verify(pk, rid, ppid, []byte("a"), []byte("bc"), finalToken) {
+		t.Fatalf("Verify accepted a token with shifted ctx/sid boundary")
+	}
+}
+
+func TestSignatureSchemes(t *testing.T) {
+	rid := []byte("registrationID")
+	uid := []byte("userID")
+	for _, s := range schemes.All() {
+		t.Run(s.Name(), func(t *testing.T) {
+			pp := Setup(WithScheme(s))
+			sk, pk, err := pp.KeyGen()
+			if err != nil {
+				t.Fatalf("KeyGen returned an error: %v", err)
+			}
+			if err := pp.VerifyParams(); err != nil {
+				t.Fatalf("VerifyParams returned an error: %v", err)
+			}
+			ppid := login(t, pp, sk, pk, rid, uid)
+
+			var decodedPp PublicParams
+			var decodedPk PublicKey
+			roundTrip(t, pp, &decodedPp)
+			roundTrip(t, pk, &decodedPk)
+			if decodedPp.scheme.Name() != s.Name() || decodedPk.scheme != s.Name() {
+				t.Fatalf("decoding lost the signature scheme")
+			}
+			if !bytes.Equal(ppid, login(t, &decodedPp, sk, &decodedPk, rid, uid)) {
+				t.Fatalf("decoded parameters yield a different PPID")
+			}
+		})
+	}
+}
+
+func TestSignatureSchemeMismatch(t *testing.T) {
+	rs256, sk, pk := setupAndKeyGen(t)
+	es256 := Setup(WithScheme(ecdsa256.Setup()))
+	rid := []byte("registrationID")
+	sid := []byte("sessionID")
+	cred := register(t, rs256, sk, rid)
+	orid, crid := initUser(t, rs256, rid)
+	auth, err := rs256.Request(pk, rid, cred, crid, orid, sid)
+	if err != nil {
+		t.Fatalf("Request returned an error: %v", err)
+	}
+	if _, err := es256.Response(sk, auth, crid, []byte("userID"), nil, sid); err == nil {
+		t.Fatalf("Response signed with a key of another scheme")
+	}
+	token, err := rs256.Response(sk, auth, crid, []byte("userID"), nil, sid)
+	if err != nil {
+		t.Fatalf("Response returned an error: %v", err)
+	}
+	if _, _, err := Setup(WithScheme(rsa256.SetupPSS(2048))).Finalize(pk, rid, nil, sid, crid, orid, token); err == nil {
+		t.Fatalf("Finalize accepted a key of another scheme")
+	}
+}
+
+// issue runs a login for uid and returns the finalized token with the values it is verified against.
+func issue(t *testing.T, pp *PublicParams, sk *PrivateKey, pk *PublicKey, rid, uid, sid []byte) VerifyInput {
+	t.Helper()
+	ctx := []byte("context")
+	cred := register(t, pp, sk, rid)
+	orid, crid := initUser(t, pp, rid)
+	auth, err := pp.Request(pk, rid, cred, crid, orid, sid)
+	if err != nil {
+		t.Fatalf("Request returned an error: %v", err)
+	}
+	token, err := pp.Response(sk, auth, crid, uid, ctx, sid)
+	if err != nil {
+		t.Fatalf("Response returned an error: %v", err)
+	}
+	ftk, ppid, err := pp.Finalize(pk, rid, ctx, sid, crid, orid, token)
+	if err != nil {
+		t.Fatalf("Finalize returned an error: %v", err)
+	}
+	return VerifyInput{PPID: ppid, Ctx: ctx, Sid: sid, Token: ftk}
+}
+
+func TestVerifyBatch(t *testing.T) {
+	rid := []byte("registrationID")
+	for _, s := range []sign.Scheme{bls.SetupMinSig(), bls.SetupMinPK(), rsa256.Setup(schemes.RSAKeySize)} {
+		t.Run(s.Name(), func(t *testing.T) {
+			pp := Setup(WithScheme(s))
+			sk, pk, _ := pp.KeyGen()
+			batch := []VerifyInput{
+				issue(t, pp, sk, pk, rid, []byte("alice"), []byte("sessionID-1")),
+				issue(t, pp, sk, pk, rid, []byte("bob"), []byte("sessionID-2")),
+				issue(t, pp, sk, pk, rid, []byte("carol"), []byte("sessionID-3")),
+			}
+			if !pp.VerifyBatch(pk, rid, batch) {
+				t.Fatalf("Expected batch to be valid")
+			}
+			if pp.VerifyBatch(pk, []byte("otherRID"), batch) {
+				t.Errorf("Expected batch for another RP to be invalid")
+			}
+			if pp.VerifyBatch(pk, rid, nil) {
+				t.Errorf("Expected empty batch to be invalid")
+			}
+
+			swapped := append([]VerifyInput(nil), batch...)
+			swapped[0].Token.sig, swapped[1].Token.sig = batch[1].Token.sig, batch[0].Token.sig
+			if pp.VerifyBatch(pk, rid, swapped) {
+				t.Errorf("Expected batch with swapped signatures to be invalid")
+			}
+			wrongPPID := append([]VerifyInput(nil), batch...)
+			wrongPPID[2].PPID = batch[0].PPID
+			if pp.VerifyBatch(pk, rid, wrongPPID) {
+				t.Errorf("Expected batch with a wrong PPID to be invalid")
+			}
+
+			_, otherPk, _ := pp.KeyGen()
+			if pp.VerifyBatch(otherPk, rid, batch) {
+				t.Errorf("Expected batch under another key to be invalid")
+			}
+		})
+	}
+}
+
+func TestBlindRegister(t *testing.T) {
+	pp, sk, pk := setupAndKeyGen(t)
+	rid := []byte("registrationID")
+
+	opn, req, err := pp.RequestCredential(pk, rid)
+	if err != nil {
+		t.Fatalf("RequestCredential returned an error: %v", err)
+	}
+	var decodedReq CredentialRequest
+	roundTrip(t, req, &decodedReq)
+	bc, err := pp.IssueCredential(sk, decodedReq)
+	if err != nil {
+		t.Fatalf("IssueCredential returned an error: %v", err)
+	}
+	var decodedBc BlindCredential
+	roundTrip(t, bc, &decodedBc)
+	cred, err := pp.UnblindCredential(pk, rid, opn, decodedBc)
+	if err != nil {
+		t.Fatalf("UnblindCredential returned an error: %v", err)
+	}
+
+	// The blind credential works like one from Register.
+	sid := []byte("sessionID")
+	orid, crid := initUser(t, pp, rid)
+	auth, err := pp.Request(pk, rid, cred, crid, orid, sid)
+	if err != nil {
+		t.Fatalf("Request returned an error: %v", err)
+	}
+	if _, err := pp.Response(sk, auth, crid, []byte("userID"), nil, sid); err != nil {
+		t.Fatalf("Response rejected a blindly issued credential: %v", err)
+	}
+
+	if _, err := pp.UnblindCredential(pk, []byte("otherRID"), opn, bc); err == nil {
+		t.Errorf("UnblindCredential accepted a credential for another rid")
+	}
+}
+
+func TestBlindRegisterRejectsInvalidRequest(t *testing.T) {
+	pp, sk, pk := setupAndKeyGen(t)
+	_, otherPk, _ := pp.KeyGen()
+	rid := []byte("registrationID")
+
+	// A request for another IdP commits under other generators.
+	_, req, err := pp.RequestCredential(otherPk, rid)
+	if err != nil {
+		t.Fatalf("RequestCredential returned an error: %v", err)
+	}
+	if _, err := pp.IssueCredential(sk, req); err == nil {
+		t.Errorf("IssueCredential accepted a request for another IdP")
+	}
+
+	_, req, _ = pp.RequestCredential(pk, rid)
+	_, other, _ := pp.RequestCredential(pk, []byte("otherRID"))
+	req.com = other.com
+	if _, err := pp.IssueCredential(sk, req); err == nil {
+		t.Errorf("IssueCredential accepted a proof for another commitment")
+	}
+
+	pk.psPk.Y1 = otherPk.psPk.Y1
+	if _, _, err := pp.RequestCredential(pk, rid); err == nil {
+		t.Errorf("RequestCredential accepted a key with an inconsistent commitment base")
+	}
+}
+
+func TestSelectiveDisclosure(t *testing.T) {
+	pp, sk, pk := setupAndKeyGen(t)
+	rid := []byte("registrationID")
+	sid := []byte("sessionID")
+	attrs := Attributes{Expiry: 10, Tier: "premium", Scopes: []string{"openid", "email"}}
+
+	cred, err := pp.RegisterWithAttributes(sk, rid, attrs)
+	if err != nil {
+		t.Fatalf("RegisterWithAttributes returned an error: %v", err)
+	}
+	var decodedCred Credential
+	roundTrip(t, cred, &decodedCred)
+	if got := decodedCred.Attributes(); got.Expiry != 10 || got.Tier != "premium" || len(got.Scopes) != 2 {
+		t.Fatalf("decoded credential has attributes %+v, want %+v", got, attrs)
+	}
+
+	// The RP shows that its credential has not expired, and nothing else.
+	orid, crid := initUser(t, pp, rid)
+	a, err := pp.Request(pk, rid, decodedCred, crid, orid, sid, AttrExpiry)
+	if err != nil {
+		t.Fatalf("Request returned an error: %v", err)
+	}
+	var auth Auth
+	roundTrip(t, a, &auth)
+	disclosed, which := auth.Disclosed()
+	if len(which) != 1 || which[0] != AttrExpiry || disclosed.Expiry != 10 || disclosed.Tier != "" || disclosed.Scopes != nil {
+		t.Fatalf("Disclosed returned %+v for %v, want only the expiry", disclosed, which)
+	}
+	if disclosed.Expired(10) || !disclosed.Expired(11) {
+		t.Errorf("Expected credential to be valid in epoch 10 and expired in epoch 11")
+	}
+	if _, err := pp.Response(sk, auth, crid, []byte("userID"), nil, sid); err != nil {
+		t.Fatalf("Response rejected a valid disclosure: %v", err)
+	}
+
+	forged := auth
+	forged.attrs.Expiry = 20
+	if _, err := pp.Response(sk, forged, crid, []byte("userID"), nil, sid); err == nil {
+		t.Errorf("Response accepted a forged expiry")
+	}
+	hidden := auth
+	hidden.attrs, hidden.disclosed = Attributes{}, nil
+	if _, err := pp.Response(sk, hidden, crid, []byte("userID"), nil, sid); err == nil {
+		t.Errorf("Response accepted a proof without the disclosed expiry")
+	}
+
+	all, err := pp.Request(pk, rid, cred, crid, orid, sid, AttrScopes, AttrTier, AttrExpiry)
+	if err != nil {
+		t.Fatalf("Request returned an error: %v", err)
+	}
+	if _, err := pp.Response(sk, all, crid, []byte("userID"), nil, sid); err != nil {
+		t.Fatalf("Response rejected a disclosure of all attributes: %v", err)
+	}
+	if disclosed, _ := all.Disclosed(); disclosed.Tier != "premium" || len(disclosed.Scopes) != 2 {
+		t.Errorf("Disclosed returned %+v, want all attributes", disclosed)
+	}
+}
+
+func TestRequestRejectsInvalidDisclosure(t *testing.T) {
+	pp, sk, pk := setupAndKeyGen(t)
+	rid := []byte("registrationID")
+	cred := register(t, pp, sk, rid)
+	orid, crid := initUser(t, pp, rid)
+
+	for _, disclose := range [][]Attribute{{AttrTier, AttrTier}, {0}, {AttrScopes + 1}} {
+		if _, err := pp.Request(pk, rid, cred, crid, orid, []byte("sessionID"), disclose...); err == nil {
+			t.Errorf("Request accepted disclosing %v", disclose)
+		}
+	}
+}
+
+func TestBlindRegisterWithAttributes(t *testing.T) {
+	pp, sk, pk := setupAndKeyGen(t)
+	rid := []byte("registrationID")
+	attrs := Attributes{Expiry: 7, Tier: "basic"}
+
+	opn, req, _ := pp.RequestCredential(pk, rid)
+	bc, err := pp.IssueCredentialWithAttributes(sk, req, attrs)
+	if err != nil {
+		t.Fatalf("IssueCredentialWithAttributes returned an error: %v", err)
+	}
+	cred, err := pp.UnblindCredential(pk, rid, opn, bc)
+	if err != nil {
+		t.Fatalf("UnblindCredential returned an error: %v", err)
+	}
+	if cred.Attributes().Expiry != 7 || cred.Attributes().Tier != "basic" {
+		t.Fatalf("credential has attributes %+v, want %+v", cred.Attributes(), attrs)
+	}
+
+	bc.attrs.Expiry = 8
+	if _, err := pp.UnblindCredential(pk, rid, opn, bc); err == nil {
+		t.Errorf("UnblindCredential accepted changed attributes")
+	}
+}
+
+func TestResponseBatch(t *testing.T) {
+	pp, sk, pk := setupAndKeyGen(t)
+	rid := []byte("registrationID")
+	ctx := []byte("context")
+	cred := register(t, pp, sk, rid)
+
+	in := make([]ResponseInput, 5)
+	orids := make([]UsrOpening, len(in))
+	for i := range in {
+		sid := []byte(fmt.Sprintf("sessionID-%d", i))
+		orid, crid := initUser(t, pp, rid)
+		auth, err := pp.Request(pk, rid, cred, crid, orid, sid)
+		if err != nil {
+			t.Fatalf("Request returned an error: %v", err)
+		}
+		in[i] = ResponseInput{Auth: auth, Crid: crid, Uid: []byte(fmt.Sprintf("user-%d", i)), Ctx: ctx, Sid: sid}
+		orids[i] = orid
+	}
+	in[1].Sid = []byte("otherSessionID") // proof bound to another session
+	in[3].Auth = in[4].Auth              // proof for another commitment
+
+	tks, errs := pp.ResponseBatch(sk, in)
+	if len(tks) != len(in) || len(errs) != len(in) {
+		t.Fatalf("ResponseBatch returned %d tokens and %d errors for %d inputs", len(tks), len(errs), len(in))
+	}
+	for i, err := range errs {
+		if failed := i == 1 || i == 3; failed != (err != nil) {
+			t.Fatalf("Unexpected error for login %d: %v", i, err)
+		}
+		if err != nil {
+			continue
+		}
+		if _, _, err := pp.Finalize(pk, rid, ctx, in[i].Sid, in[i].Crid, orids[i], tks[i]); err != nil {
+			t.Errorf("Finalize returned an error for login %d: %v", i, err)
+		}
+	}
+
+	if _, errs := pp.ResponseBatch(sk, []ResponseInput{{Auth: in[0].Auth, Uid: in[0].Uid, Ctx: ctx, Sid: in[0].Sid}}); errs[0] == nil {
+		t.Errorf("Expected malformed user commitment to be rejected")
+	}
+	if tks, errs := pp.ResponseBatch(sk, nil); len(tks) != 0 || len(errs) != 0 {
+		t.Errorf("Expected empty batch to return no tokens")
+	}
+}
+
+func TestRidTables(t *testing.T) {
+	pp := Setup()
+	rid := []byte("registrationID")
+	k, _ := utils.GenerateRandomScalar()
+	want := utils.GenerateG1Point(k, hashToPoint(rid, []byte(dstStr)))
+	for i := 0; i < ridTableUses+1; i++ {
+		if !pp.mulRid(k, rid).IsEqual(want) {
+			t.Fatalf("mulRid differs from ScalarMult in use %d", i)
+		}
+	}
+	if pp.rids.rids[string(rid)].table == nil {
+		t.Errorf("Expected a table after %d uses", ridTableUses)
+	}
+	for i := 0; i < maxRids; i++ {
+		pp.mulRid(k, []byte(fmt.Sprintf("rid-%d", i)))
+	}
+	if len(pp.rids.rids) > maxRids {
+		t.Errorf("Expected at most %d cached rids, got %d", maxRids, len(pp.rids.rids))
+	}
+}
+
+// TestConcurrentResponse answers many logins at once with one key while RPs register, to be run with -race.
+func TestConcurrentResponse(t *testing.T) {
+	const users, goroutines = 4, 16
+	rid := []byte("registrationID")
+	ctx := []byte("context")
+	for _, s := range schemes.All() {
+		t.Run(s.Name(), func(t *testing.T) {
+			pp := Setup(WithScheme(s))
+			sk, pk, err := pp.KeyGen()
+			if err != nil {
+				t.Fatalf("KeyGen returned an error: %v", err)
+			}
+			cred := register(t, pp, sk, rid)
+			_, credReq, err := pp.RequestCredential(pk, rid)
+			if err != nil {
+				t.Fatalf("RequestCredential returned an error: %v", err)
+			}
+
+			in := make([]ResponseInput, users)
+			orids := make([]UsrOpening, users)
+			for i := range in {
+				sid := []byte(fmt.Sprintf("sessionID-%d", i))
+				orid, crid := initUser(t, pp, rid)
+				auth, err := pp.Request(pk, rid, cred, crid, orid, sid)
+				if err != nil {
+					t.Fatalf("Request returned an error: %v", err)
+				}
+				in[i] = ResponseInput{Auth: auth, Crid: crid, Uid: []byte(fmt.Sprintf("user-%d", i)), Ctx: ctx, Sid: sid}
+				orids[i] = orid
+			}
+
+			tks := make([]Token, goroutines)
+			errs := make([]error, goroutines+2)
+			var wg sync.WaitGroup
+			for g := 0; g < goroutines; g++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					v := in[g%users]
+					tks[g], errs[g] = pp.Response(sk, v.Auth, v.Crid, v.Uid, v.Ctx, v.Sid)
+				}()
+			}
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				_, errs[goroutines] = pp.Register(sk, []byte("otherRegistrationID"))
+			}()
+			go func() {
+				defer wg.Done()
+				_, errs[goroutines+1] = pp.IssueCredential(sk, credReq)
+			}()
+			wg.Wait()
+
+			ppids := make([]PPID, users)
+			for g, tk := range tks {
+				if errs[g] != nil {
+					t.Fatalf("Response %d returned an error: %v", g, errs[g])
+				}
+				v := in[g%users]
+				ftk, ppid, err := pp.Finalize(pk, rid, ctx, v.Sid, v.Crid, orids[g%users], tk)
+				if err != nil {
+					t.Fatalf("Finalize of response %d returned an error: %v", g, err)
+				}
+				if !pp.Verify(pk, rid, ppid, ctx, v.Sid, ftk) {
+					t.Fatalf("Verify of response %d failed", g)
+				}
+				if ppids[g%users] == nil {
+					ppids[g%users] = ppid
+				} else if !bytes.Equal(ppids[g%users], ppid) {
+					t.Errorf("Response %d yields another PPID than an earlier response for the same user", g)
+				}
+			}
+			for _, err := range errs[goroutines:] {
+				if err != nil {
+					t.Errorf("Concurrent registration returned an error: %v", err)
+				}
+			}
+		})
+	}
+}
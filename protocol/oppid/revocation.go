@@ -0,0 +1,104 @@
+package oppid
+
+import (
+	PC "OPPID-artifacts/pkg/oppid/commit/pc"
+	NEQ "OPPID-artifacts/pkg/oppid/nizk/neq"
+	"OPPID-artifacts/pkg/oppid/transcript"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var (
+	ErrRevoked = errors.New("oppid: credential is revoked")
+	ErrExpired = errors.New("oppid: credential is expired")
+)
+
+// RevocationList is what the IdP publishes about the validity of credentials: the current epoch, in which it issues
+// credentials with Attributes.Expiry set to Epoch, and the rids whose credentials it revoked. Credentials of earlier
+// epochs are stale, so an RP whose rid is revoked can no longer log users in, even with a blindly issued credential
+// of the current epoch.
+type RevocationList struct {
+	Epoch   uint64
+	Revoked [][]byte
+}
+
+// IsRevoked reports whether rid is on the list.
+func (rl RevocationList) IsRevoked(rid []byte) bool {
+	return slices.ContainsFunc(rl.Revoked, func(r []byte) bool { return bytes.Equal(r, rid) })
+}
+
+// check reports whether a credential with attributes attrs is valid in the epoch of the list.
+func (rl RevocationList) check(attrs Attributes) error {
+	if attrs.Expiry == 0 || attrs.Expired(rl.Epoch) {
+		return fmt.Errorf("%w: not valid in epoch %d", ErrExpired, rl.Epoch)
+	}
+	return nil
+}
+
+// createRevocationAux creates the auxiliary input that binds the non-revocation proofs to the login and the epoch
+func createRevocationAux(crid UsrCommitment, sid []byte, epoch uint64) []byte {
+	return transcript.New(dstStr+"REVOCATION").
+		Append("bx", crid.bx.Bytes()).
+		Append("sid", sid).
+		Append("epoch", binary.BigEndian.AppendUint64(nil, epoch)).
+		Bytes()
+}
+
+func revocationStatement(pc *PC.PublicParams, crid UsrCommitment, rid []byte) NEQ.Statement {
+	return NEQ.Statement{Params: pc, Com: &crid.com, Value: rid}
+}
+
+// RequestWithRevocation is Request for an IdP that publishes rl. It discloses the expiry of the credential, which
+// must be valid in rl.Epoch, and proves for every revoked rid that crid commits to another one.
+func (pp *PublicParams) RequestWithRevocation(ipk *PublicKey, rid []byte, cred Credential, crid UsrCommitment, orid UsrOpening, sid []byte, rl RevocationList, disclose ...Attribute) (Auth, error) {
+	if err := rl.check(cred.attrs); err != nil {
+		return Auth{}, err
+	}
+	if rl.IsRevoked(rid) {
+		return Auth{}, ErrRevoked
+	}
+	if !slices.Contains(disclose, AttrExpiry) {
+		disclose = append(slices.Clip(disclose), AttrExpiry)
+	}
+	auth, err := pp.Request(ipk, rid, cred, crid, orid, sid, disclose...)
+	if err != nil {
+		return Auth{}, err
+	}
+
+	aux := createRevocationAux(crid, sid, rl.Epoch)
+	auth.nonRevoked = make([]NEQ.Proof, len(rl.Revoked))
+	for i, revoked := range rl.Revoked {
+		st := revocationStatement(pp.pc, crid, revoked)
+		if auth.nonRevoked[i], err = NEQ.Prove(pp.rand, st, rid, orid.opn, aux); err != nil {
+			return Auth{}, err
+		}
+	}
+	return auth, nil
+}
+
+// ResponseWithRevocation is Response for an IdP that publishes rl. It rejects credentials that are not valid in
+// rl.Epoch or whose rid is revoked.
+func (pp *PublicParams) ResponseWithRevocation(isk *PrivateKey, auth Auth, crid UsrCommitment, uid, ctx, sid []byte, rl RevocationList) (Token, error) {
+	if !slices.Contains(auth.disclosed, AttrExpiry) {
+		return Token{}, fmt.Errorf("%w: expiry not disclosed", ErrExpired)
+	}
+	if err := rl.check(auth.attrs); err != nil {
+		return Token{}, err
+	}
+	if !validCommitment(crid) {
+		return Token{}, errors.New("malformed user commitment")
+	}
+	if len(auth.nonRevoked) != len(rl.Revoked) {
+		return Token{}, fmt.Errorf("%w: %d non-revocation proofs for %d revoked rids", ErrRevoked, len(auth.nonRevoked), len(rl.Revoked))
+	}
+	aux := createRevocationAux(crid, sid, rl.Epoch)
+	for i, revoked := range rl.Revoked {
+		if !NEQ.Verify(revocationStatement(pp.pc, crid, revoked), auth.nonRevoked[i], aux) {
+			return Token{}, ErrRevoked
+		}
+	}
+	return pp.Response(isk, auth, crid, uid, ctx, sid)
+}
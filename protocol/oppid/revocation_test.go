@@ -0,0 +1,117 @@
+package oppid
+
+import (
+	"errors"
+	"testing"
+)
+
+func registerInEpoch(t *testing.T, pp *PublicParams, sk *PrivateKey, rid []byte, epoch uint64) Credential {
+	t.Helper()
+	cred, err := pp.RegisterWithAttributes(sk, rid, Attributes{Expiry: epoch})
+	if err != nil {
+		t.Fatalf("RegisterWithAttributes returned an error: %v", err)
+	}
+	return cred
+}
+
+func TestRevocationListLogin(t *testing.T) {
+	pp, sk, pk := setupAndKeyGen(t)
+	rid := []byte("rp.example.com")
+	sid := []byte("sessionID")
+	rl := RevocationList{Epoch: 5, Revoked: [][]byte{[]byte("revoked.example.com"), []byte("other.example.com")}}
+
+	cred := registerInEpoch(t, pp, sk, rid, rl.Epoch)
+	orid, crid := initUser(t, pp, rid)
+	a, err := pp.RequestWithRevocation(pk, rid, cred, crid, orid, sid, rl)
+	if err != nil {
+		t.Fatalf("RequestWithRevocation returned an error: %v", err)
+	}
+	var auth Auth
+	roundTrip(t, a, &auth)
+	var decodedRl RevocationList
+	roundTrip(t, rl, &decodedRl)
+
+	tk, err := pp.ResponseWithRevocation(sk, auth, crid, []byte("userID"), nil, sid, decodedRl)
+	if err != nil {
+		t.Fatalf("ResponseWithRevocation returned an error: %v", err)
+	}
+	if _, _, err := pp.Finalize(pk, rid, nil, sid, crid, orid, tk); err != nil {
+		t.Fatalf("Finalize returned an error: %v", err)
+	}
+
+	// The proofs are bound to the session.
+	if _, err := pp.ResponseWithRevocation(sk, auth, crid, []byte("userID"), nil, []byte("otherSID"), rl); err == nil {
+		t.Errorf("ResponseWithRevocation accepted non-revocation proofs of another session")
+	}
+}
+
+func TestRevokedRPIsRefused(t *testing.T) {
+	pp, sk, pk := setupAndKeyGen(t)
+	rid := []byte("rp.example.com")
+	sid := []byte("sessionID")
+	before := RevocationList{Epoch: 5}
+	after := RevocationList{Epoch: 5, Revoked: [][]byte{rid}}
+
+	// Issued blindly, so the IdP cannot refuse it at issuance.
+	opn, req, _ := pp.RequestCredential(pk, rid)
+	bc, err := pp.IssueCredentialWithAttributes(sk, req, Attributes{Expiry: before.Epoch})
+	if err != nil {
+		t.Fatalf("IssueCredentialWithAttributes returned an error: %v", err)
+	}
+	cred, err := pp.UnblindCredential(pk, rid, opn, bc)
+	if err != nil {
+		t.Fatalf("UnblindCredential returned an error: %v", err)
+	}
+
+	orid, crid := initUser(t, pp, rid)
+	if _, err := pp.RequestWithRevocation(pk, rid, cred, crid, orid, sid, after); !errors.Is(err, ErrRevoked) {
+		t.Fatalf("RequestWithRevocation returned %v for a revoked rid, want ErrRevoked", err)
+	}
+
+	// A request made against the list before the revocation is refused under the current list.
+	auth, err := pp.RequestWithRevocation(pk, rid, cred, crid, orid, sid, before)
+	if err != nil {
+		t.Fatalf("RequestWithRevocation returned an error: %v", err)
+	}
+	if _, err := pp.ResponseWithRevocation(sk, auth, crid, []byte("userID"), nil, sid, after); !errors.Is(err, ErrRevoked) {
+		t.Fatalf("ResponseWithRevocation returned %v for a revoked rid, want ErrRevoked", err)
+	}
+
+	// Proofs for another rid do not pass for the revoked one.
+	other := RevocationList{Epoch: 5, Revoked: [][]byte{[]byte("other.example.com")}}
+	auth, _ = pp.RequestWithRevocation(pk, rid, cred, crid, orid, sid, other)
+	if _, err := pp.ResponseWithRevocation(sk, auth, crid, []byte("userID"), nil, sid, after); !errors.Is(err, ErrRevoked) {
+		t.Fatalf("ResponseWithRevocation returned %v for proofs on another list, want ErrRevoked", err)
+	}
+}
+
+func TestStaleEpochIsRefused(t *testing.T) {
+	pp, sk, pk := setupAndKeyGen(t)
+	rid := []byte("rp.example.com")
+	sid := []byte("sessionID")
+	current := RevocationList{Epoch: 5}
+
+	orid, crid := initUser(t, pp, rid)
+	stale := registerInEpoch(t, pp, sk, rid, current.Epoch-1)
+	if _, err := pp.RequestWithRevocation(pk, rid, stale, crid, orid, sid, current); !errors.Is(err, ErrExpired) {
+		t.Fatalf("RequestWithRevocation returned %v for a stale credential, want ErrExpired", err)
+	}
+	auth, err := pp.RequestWithRevocation(pk, rid, stale, crid, orid, sid, RevocationList{Epoch: current.Epoch - 1})
+	if err != nil {
+		t.Fatalf("RequestWithRevocation returned an error: %v", err)
+	}
+	if _, err := pp.ResponseWithRevocation(sk, auth, crid, []byte("userID"), nil, sid, current); !errors.Is(err, ErrExpired) {
+		t.Fatalf("ResponseWithRevocation returned %v for a stale credential, want ErrExpired", err)
+	}
+
+	// Credentials without an epoch and requests that hide the expiry are refused, too.
+	unbound := register(t, pp, sk, rid)
+	if _, err := pp.RequestWithRevocation(pk, rid, unbound, crid, orid, sid, current); !errors.Is(err, ErrExpired) {
+		t.Fatalf("RequestWithRevocation returned %v for a credential without epoch, want ErrExpired", err)
+	}
+	fresh := registerInEpoch(t, pp, sk, rid, current.Epoch)
+	hidden, _ := pp.Request(pk, rid, fresh, crid, orid, sid)
+	if _, err := pp.ResponseWithRevocation(sk, hidden, crid, []byte("userID"), nil, sid, current); !errors.Is(err, ErrExpired) {
+		t.Fatalf("ResponseWithRevocation returned %v for a hidden expiry, want ErrExpired", err)
+	}
+}
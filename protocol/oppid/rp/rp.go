@@ -0,0 +1,210 @@
+// Package rp implements the relying party (RP) side of OPPID logins. An RP holds the credential it obtained at
+// registration, issues a fresh session id (sid) per login, verifies the ID Token that the user agent returns against
+// the IdP's public key and maps the contained PPID to a stable account.
+
+package rp
+
+import (
+	"OPPID-artifacts/protocol/oppid"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSessionTTL = 5 * time.Minute
+	defaultKeyTTL     = time.Hour
+	sidLength         = 32
+)
+
+var (
+	ErrUnknownSession = errors.New("rp: unknown or already used session")
+	ErrSessionExpired = errors.New("rp: session expired")
+	ErrInvalidToken   = errors.New("rp: invalid ID token")
+)
+
+// KeySource provides the IdP's current public key, e.g., an idp.Client.
+type KeySource interface {
+	PublicKey() (*oppid.PublicKey, error)
+}
+
+type Config struct {
+	RID        []byte
+	Credential oppid.Credential
+	Keys       KeySource
+	// SessionTTL bounds the time between Begin and Complete. Defaults to 5 minutes.
+	SessionTTL time.Duration
+	// KeyTTL bounds how long a fetched IdP public key is cached. Defaults to 1 hour.
+	KeyTTL time.Duration
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Challenge is handed to the user agent to start a login.
+type Challenge struct {
+	RID        []byte
+	SID        []byte
+	Credential oppid.Credential
+}
+
+// Account is the RP-local account of a user, identified by the user's PPID for this RP.
+type Account struct {
+	ID        string
+	PPID      oppid.PPID
+	Created   time.Time
+	LastLogin time.Time
+}
+
+type RP struct {
+	pp  *oppid.PublicParams
+	cfg Config
+
+	mu       sync.Mutex
+	sessions map[string]time.Time // sid -> expiry
+	accounts map[string]*Account  // account id -> account
+	key      *oppid.PublicKey
+	keyExp   time.Time
+}
+
+func New(pp *oppid.PublicParams, cfg Config) (*RP, error) {
+	if len(cfg.RID) == 0 || cfg.Keys == nil {
+		return nil, errors.New("rp: rid and key source are required")
+	}
+	if cfg.SessionTTL == 0 {
+		cfg.SessionTTL = defaultSessionTTL
+	}
+	if cfg.KeyTTL == 0 {
+		cfg.KeyTTL = defaultKeyTTL
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return &RP{
+		pp:       pp,
+		cfg:      cfg,
+		sessions: make(map[string]time.Time),
+		accounts: make(map[string]*Account),
+	}, nil
+}
+
+// SetCredential replaces the credential handed out in challenges, e.g., with the one for a new epoch.
+func (rp *RP) SetCredential(cred oppid.Credential) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	rp.cfg.Credential = cred
+}
+
+// Begin starts a login and returns the challenge for the user agent. Each sid can be completed at most once.
+func (rp *RP) Begin() (Challenge, error) {
+	var buf [sidLength]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return Challenge{}, err
+	}
+	sid := base64.RawURLEncoding.EncodeToString(buf[:])
+
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	now := rp.cfg.Now()
+	for s, exp := range rp.sessions {
+		if !now.Before(exp) {
+			delete(rp.sessions, s)
+		}
+	}
+	rp.sessions[sid] = now.Add(rp.cfg.SessionTTL)
+
+	return Challenge{rp.cfg.RID, []byte(sid), rp.cfg.Credential}, nil
+}
+
+// Complete finishes the login of session sid with the ID Token returned by the user agent and returns the account of
+// the user. The session is consumed whether or not the token verifies.
+func (rp *RP) Complete(sid []byte, idToken string) (*Account, error) {
+	rp.mu.Lock()
+	exp, ok := rp.sessions[string(sid)]
+	delete(rp.sessions, string(sid))
+	rp.mu.Unlock()
+
+	now := rp.cfg.Now()
+	if !ok {
+		return nil, ErrUnknownSession
+	}
+	if !now.Before(exp) {
+		return nil, ErrSessionExpired
+	}
+
+	ipk, fresh, err := rp.publicKey(now, false)
+	if err != nil {
+		return nil, err
+	}
+	t, err := rp.pp.VerifyIDToken(ipk, rp.cfg.RID, sid, idToken, now)
+	if err != nil && !fresh {
+		// The IdP may have rotated its key since it was cached
+		if ipk, _, err = rp.publicKey(now, true); err != nil {
+			return nil, err
+		}
+		t, err = rp.pp.VerifyIDToken(ipk, rp.cfg.RID, sid, idToken, now)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	return rp.account(t.Subject, now), nil
+}
+
+// Account returns the account with the given id, if any.
+func (rp *RP) Account(id string) (*Account, bool) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	acc, ok := rp.accounts[id]
+	if !ok {
+		return nil, false
+	}
+	cp := *acc
+	return &cp, true
+}
+
+// AccountID derives the stable account id for a PPID.
+func AccountID(ppid oppid.PPID) string {
+	h := sha256.Sum256(ppid)
+	return base64.RawURLEncoding.EncodeToString(h[:])
+}
+
+func (rp *RP) account(ppid oppid.PPID, now time.Time) *Account {
+	id := AccountID(ppid)
+
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	acc, ok := rp.accounts[id]
+	if !ok {
+		acc = &Account{ID: id, PPID: append(oppid.PPID(nil), ppid...), Created: now}
+		rp.accounts[id] = acc
+	}
+	acc.LastLogin = now
+	cp := *acc
+	return &cp
+}
+
+// publicKey returns the cached IdP key, fetching it if the cache is empty, expired or refresh is set. The boolean
+// result reports whether the key was fetched by this call.
+func (rp *RP) publicKey(now time.Time, refresh bool) (*oppid.PublicKey, bool, error) {
+	rp.mu.Lock()
+	if rp.key != nil && !refresh && now.Before(rp.keyExp) {
+		key := rp.key
+		rp.mu.Unlock()
+		return key, false, nil
+	}
+	rp.mu.Unlock()
+
+	key, err := rp.cfg.Keys.PublicKey()
+	if err != nil {
+		return nil, false, fmt.Errorf("rp: fetching IdP public key: %w", err)
+	}
+
+	rp.mu.Lock()
+	rp.key, rp.keyExp = key, now.Add(rp.cfg.KeyTTL)
+	rp.mu.Unlock()
+	return key, true, nil
+}
@@ -0,0 +1,160 @@
+package rp
+
+import (
+	"OPPID-artifacts/protocol/oppid"
+	"OPPID-artifacts/protocol/oppid/idp"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type testEnv struct {
+	pp     *oppid.PublicParams
+	rp     *RP
+	client *idp.Client
+	now    time.Time
+}
+
+func setupEnv(t *testing.T) *testEnv {
+	pp := oppid.Setup()
+	sk, pk, err := pp.KeyGen()
+	if err != nil {
+		t.Fatalf("KeyGen returned an error: %v", err)
+	}
+	srv, err := idp.New(pp, sk, pk, idp.Config{Users: idp.AuthenticatorFunc(func(r *http.Request) ([]byte, error) {
+		return []byte(r.Header.Get("X-User")), nil
+	})})
+	if err != nil {
+		t.Fatalf("idp.New returned an error: %v", err)
+	}
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+
+	env := &testEnv{pp: pp, now: time.Now()}
+	env.client = &idp.Client{BaseURL: ts.URL, HTTPClient: ts.Client()}
+
+	rid := []byte("rp.example.com")
+	cred, err := env.client.Register(rid)
+	if err != nil {
+		t.Fatalf("Register returned an error: %v", err)
+	}
+	env.rp, err = New(pp, Config{RID: rid, Credential: cred, Keys: env.client, Now: func() time.Time { return env.now }})
+	if err != nil {
+		t.Fatalf("New returned an error: %v", err)
+	}
+	return env
+}
+
+// login plays the user agent for user uid and returns the ID Token for the challenge.
+func (env *testEnv) login(t *testing.T, ch Challenge, uid string) string {
+	t.Helper()
+	ipk, err := env.client.PublicKey()
+	if err != nil {
+		t.Fatalf("PublicKey returned an error: %v", err)
+	}
+
+	orid, crid, err := env.pp.Init(ch.RID)
+	if err != nil {
+		t.Fatalf("Init returned an error: %v", err)
+	}
+	rl, err := env.client.RevocationList()
+	if err != nil {
+		t.Fatalf("RevocationList returned an error: %v", err)
+	}
+	auth, err := env.pp.RequestWithRevocation(ipk, ch.RID, ch.Credential, crid, orid, ch.SID, rl)
+	if err != nil {
+		t.Fatalf("RequestWithRevocation returned an error: %v", err)
+	}
+
+	user := *env.client
+	user.Authenticate = func(r *http.Request) { r.Header.Set("X-User", uid) }
+	tk, ctx, err := user.Authorize(auth, crid, ch.SID)
+	if err != nil {
+		t.Fatalf("Authorize returned an error: %v", err)
+	}
+
+	ftk, ppid, err := env.pp.Finalize(ipk, ch.RID, ctx, ch.SID, crid, orid, tk)
+	if err != nil {
+		t.Fatalf("Finalize returned an error: %v", err)
+	}
+	idToken, err := oppid.NewIDToken(ppid, ch.RID, ch.SID, ctx, ftk)
+	if err != nil {
+		t.Fatalf("NewIDToken returned an error: %v", err)
+	}
+	jws, err := idToken.Encode()
+	if err != nil {
+		t.Fatalf("Encode returned an error: %v", err)
+	}
+	return jws
+}
+
+func TestLoginReturnsStableAccount(t *testing.T) {
+	env := setupEnv(t)
+
+	ch, err := env.rp.Begin()
+	if err != nil {
+		t.Fatalf("Begin returned an error: %v", err)
+	}
+	first, err := env.rp.Complete(ch.SID, env.login(t, ch, "alice"))
+	if err != nil {
+		t.Fatalf("Complete returned an error: %v", err)
+	}
+
+	ch, _ = env.rp.Begin()
+	second, err := env.rp.Complete(ch.SID, env.login(t, ch, "alice"))
+	if err != nil {
+		t.Fatalf("Complete returned an error: %v", err)
+	}
+	if first.ID != second.ID {
+		t.Fatalf("the same user obtained different accounts")
+	}
+	if _, ok := env.rp.Account(first.ID); !ok {
+		t.Fatalf("account was not stored")
+	}
+
+	ch, _ = env.rp.Begin()
+	other, err := env.rp.Complete(ch.SID, env.login(t, ch, "bob"))
+	if err != nil {
+		t.Fatalf("Complete returned an error: %v", err)
+	}
+	if other.ID == first.ID {
+		t.Fatalf("different users obtained the same account")
+	}
+}
+
+func TestReplayIsRejected(t *testing.T) {
+	env := setupEnv(t)
+	ch, _ := env.rp.Begin()
+	jws := env.login(t, ch, "alice")
+
+	if _, err := env.rp.Complete(ch.SID, jws); err != nil {
+		t.Fatalf("Complete returned an error: %v", err)
+	}
+	if _, err := env.rp.Complete(ch.SID, jws); !errors.Is(err, ErrUnknownSession) {
+		t.Fatalf("expected ErrUnknownSession on replay, got %v", err)
+	}
+}
+
+func TestExpiredSessionIsRejected(t *testing.T) {
+	env := setupEnv(t)
+	ch, _ := env.rp.Begin()
+	jws := env.login(t, ch, "alice")
+
+	env.now = env.now.Add(defaultSessionTTL + time.Second)
+	if _, err := env.rp.Complete(ch.SID, jws); !errors.Is(err, ErrSessionExpired) {
+		t.Fatalf("expected ErrSessionExpired, got %v", err)
+	}
+}
+
+func TestTokenForOtherSessionIsRejected(t *testing.T) {
+	env := setupEnv(t)
+	ch1, _ := env.rp.Begin()
+	ch2, _ := env.rp.Begin()
+	jws := env.login(t, ch1, "alice")
+
+	if _, err := env.rp.Complete(ch2.SID, jws); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken, got %v", err)
+	}
+}
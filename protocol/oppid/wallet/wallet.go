@@ -0,0 +1,385 @@
+// Package wallet implements the user agent of OPPID. It stores the credentials that RPs hand out per rid and runs a
+// login as a state machine: RP challenge -> Init -> Request -> IdP -> Finalize -> hand-off to the RP. The state of
+// every in-flight login is persisted after each step, so an interrupted login can be resumed where it stopped.
+//
+// The wallet also pins the IdP's commitment to the user's PRF key and rejects tokens evaluated under another one.
+
+package wallet
+
+import (
+	"OPPID-artifacts/protocol/oppid"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	credentialPrefix = "cred/"
+	loginPrefix      = "login/"
+	keyCommitmentKey = "key-commitment"
+)
+
+var (
+	ErrNoCredential  = errors.New("wallet: no credential for rid")
+	ErrKeyCommitment = errors.New("wallet: IdP evaluated the PPID under another key commitment")
+)
+
+// Step is the last completed step of a login.
+type Step int
+
+const (
+	StepChallenged Step = iota
+	StepInitialized
+	StepRequested
+	StepAuthorized
+	StepFinalized
+	StepDelivered
+)
+
+func (s Step) String() string {
+	switch s {
+	case StepChallenged:
+		return "challenged"
+	case StepInitialized:
+		return "initialized"
+	case StepRequested:
+		return "requested"
+	case StepAuthorized:
+		return "authorized"
+	case StepFinalized:
+		return "finalized"
+	case StepDelivered:
+		return "delivered"
+	default:
+		return fmt.Sprintf("step(%d)", int(s))
+	}
+}
+
+// StepError reports the step of a login that failed. The login stays at its previous step and can be resumed.
+type StepError struct {
+	Step Step // the step that was attempted
+	Err  error
+}
+
+func (e *StepError) Error() string { return fmt.Sprintf("wallet: %s step failed: %v", e.Step, e.Err) }
+func (e *StepError) Unwrap() error { return e.Err }
+
+// IdP is the interface of the identity provider as seen by the user agent, e.g., an idp.Client.
+type IdP interface {
+	PublicKey() (*oppid.PublicKey, error)
+	RevocationList() (oppid.RevocationList, error)
+	Authorize(auth oppid.Auth, crid oppid.UsrCommitment, sid []byte) (oppid.Token, []byte, error)
+}
+
+// Deliver hands the ID Token of session sid to the RP.
+type Deliver func(sid []byte, idToken string) error
+
+// Challenge is the RP's request to log in. Credential may be nil if the wallet already stores one for RID.
+type Challenge struct {
+	RID        []byte
+	SID        []byte
+	Credential *oppid.Credential
+}
+
+// Config configures a Wallet. The claims of ID Tokens, e.g., their expiry, are signed by the IdP, see idp.Config.
+type Config struct{}
+
+type Wallet struct {
+	pp    *oppid.PublicParams
+	idp   IdP
+	store Store
+	cfg   Config
+}
+
+func New(pp *oppid.PublicParams, idp IdP, store Store, cfg Config) *Wallet {
+	return &Wallet{pp, idp, store, cfg}
+}
+
+// Login is the persisted state of one login.
+type Login struct {
+	Step    Step
+	RID     []byte
+	SID     []byte
+	Context []byte
+	PPID    oppid.PPID
+	IDToken string
+
+	orid oppid.UsrOpening
+	crid oppid.UsrCommitment
+	auth oppid.Auth
+	tk   oppid.Token
+}
+
+func credentialKey(rid []byte) string {
+	return credentialPrefix + base64.RawURLEncoding.EncodeToString(rid)
+}
+
+func loginKey(sid []byte) string {
+	return loginPrefix + base64.RawURLEncoding.EncodeToString(sid)
+}
+
+func (w *Wallet) StoreCredential(rid []byte, cred oppid.Credential) error {
+	data, err := cred.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return w.store.Put(credentialKey(rid), data)
+}
+
+func (w *Wallet) Credential(rid []byte) (oppid.Credential, error) {
+	data, err := w.store.Get(credentialKey(rid))
+	if errors.Is(err, ErrNotFound) {
+		return oppid.Credential{}, ErrNoCredential
+	}
+	if err != nil {
+		return oppid.Credential{}, err
+	}
+	var cred oppid.Credential
+	err = cred.UnmarshalBinary(data)
+	return cred, err
+}
+
+// PinKeyCommitment makes the wallet accept only tokens evaluated under kc, e.g., the commitment that the IdP publishes
+// for the user. Without it, the wallet pins the commitment of the first finalized token.
+func (w *Wallet) PinKeyCommitment(kc oppid.KeyCommitment) error {
+	data, err := kc.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return w.store.Put(keyCommitmentKey, data)
+}
+
+// checkKeyCommitment compares kc with the pinned commitment, or pins it if there is none yet.
+func (w *Wallet) checkKeyCommitment(kc oppid.KeyCommitment) error {
+	data, err := w.store.Get(keyCommitmentKey)
+	if errors.Is(err, ErrNotFound) {
+		return w.PinKeyCommitment(kc)
+	}
+	if err != nil {
+		return err
+	}
+	var pinned oppid.KeyCommitment
+	if err := pinned.UnmarshalBinary(data); err != nil {
+		return err
+	}
+	if !pinned.Equal(kc) {
+		return ErrKeyCommitment
+	}
+	return nil
+}
+
+// Start records a new login for the RP challenge. A credential in the challenge replaces the stored one for its rid.
+func (w *Wallet) Start(ch Challenge) (*Login, error) {
+	if len(ch.RID) == 0 || len(ch.SID) == 0 {
+		return nil, errors.New("wallet: challenge without rid or sid")
+	}
+	if ch.Credential != nil {
+		if err := w.StoreCredential(ch.RID, *ch.Credential); err != nil {
+			return nil, err
+		}
+	} else if _, err := w.Credential(ch.RID); err != nil {
+		return nil, err
+	}
+
+	l := &Login{Step: StepChallenged, RID: ch.RID, SID: ch.SID}
+	if err := w.save(l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Resume loads the persisted login of session sid.
+func (w *Wallet) Resume(sid []byte) (*Login, error) {
+	data, err := w.store.Get(loginKey(sid))
+	if err != nil {
+		return nil, err
+	}
+	l := new(Login)
+	if err := l.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Pending returns the session ids of all logins that have not been delivered yet.
+func (w *Wallet) Pending() ([][]byte, error) {
+	keys, err := w.store.Keys(loginPrefix)
+	if err != nil {
+		return nil, err
+	}
+	sids := make([][]byte, 0, len(keys))
+	for _, k := range keys {
+		sid, err := base64.RawURLEncoding.DecodeString(k[len(loginPrefix):])
+		if err != nil {
+			return nil, err
+		}
+		sids = append(sids, sid)
+	}
+	return sids, nil
+}
+
+// Run advances the login through all remaining steps and hands the ID Token to the RP via deliver. On failure it
+// returns a *StepError and the login can be resumed later.
+func (w *Wallet) Run(l *Login, deliver Deliver) error {
+	for l.Step != StepDelivered {
+		if err := w.Next(l, deliver); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Next performs the next step of the login and persists the result.
+func (w *Wallet) Next(l *Login, deliver Deliver) error {
+	next := l.Step + 1
+	if err := w.step(l, next, deliver); err != nil {
+		return &StepError{next, err}
+	}
+	l.Step = next
+
+	if next == StepDelivered {
+		return w.store.Delete(loginKey(l.SID))
+	}
+	if err := w.save(l); err != nil {
+		return &StepError{next, err}
+	}
+	return nil
+}
+
+func (w *Wallet) step(l *Login, next Step, deliver Deliver) error {
+	switch next {
+	case StepInitialized:
+		var err error
+		l.orid, l.crid, err = w.pp.Init(l.RID)
+		return err
+
+	case StepRequested:
+		cred, err := w.Credential(l.RID)
+		if err != nil {
+			return err
+		}
+		ipk, err := w.idp.PublicKey()
+		if err != nil {
+			return err
+		}
+		rl, err := w.idp.RevocationList()
+		if err != nil {
+			return err
+		}
+		l.auth, err = w.pp.RequestWithRevocation(ipk, l.RID, cred, l.crid, l.orid, l.SID, rl)
+		return err
+
+	case StepAuthorized:
+		tk, ctx, err := w.idp.Authorize(l.auth, l.crid, l.SID)
+		if err != nil {
+			return err
+		}
+		l.tk, l.Context = tk, ctx
+		return nil
+
+	case StepFinalized:
+		ipk, err := w.idp.PublicKey()
+		if err != nil {
+			return err
+		}
+		ftk, ppid, err := w.pp.Finalize(ipk, l.RID, l.Context, l.SID, l.crid, l.orid, l.tk)
+		if err != nil {
+			return err
+		}
+		if err := w.checkKeyCommitment(l.tk.KeyCommitment()); err != nil {
+			return err
+		}
+		idToken, err := oppid.NewIDToken(ppid, l.RID, l.SID, l.Context, ftk)
+		if err != nil {
+			return err
+		}
+		jws, err := idToken.Encode()
+		if err != nil {
+			return err
+		}
+		l.PPID, l.IDToken = ppid, jws
+		return nil
+
+	case StepDelivered:
+		return deliver(l.SID, l.IDToken)
+
+	default:
+		return fmt.Errorf("login already %s", l.Step)
+	}
+}
+
+func (w *Wallet) save(l *Login) error {
+	data, err := l.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return w.store.Put(loginKey(l.SID), data)
+}
+
+type persistedLogin struct {
+	Step    Step   `json:"step"`
+	RID     []byte `json:"rid"`
+	SID     []byte `json:"sid"`
+	Context []byte `json:"ctx,omitempty"`
+	PPID    []byte `json:"ppid,omitempty"`
+	IDToken string `json:"id_token,omitempty"`
+	Opening []byte `json:"opening,omitempty"`
+	Commit  []byte `json:"commitment,omitempty"`
+	Auth    []byte `json:"auth,omitempty"`
+	Token   []byte `json:"token,omitempty"`
+}
+
+func (l *Login) MarshalBinary() ([]byte, error) {
+	p := persistedLogin{Step: l.Step, RID: l.RID, SID: l.SID, Context: l.Context, PPID: l.PPID, IDToken: l.IDToken}
+
+	var err error
+	if l.Step >= StepInitialized {
+		if p.Opening, err = l.orid.MarshalBinary(); err != nil {
+			return nil, err
+		}
+		if p.Commit, err = l.crid.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
+	if l.Step >= StepRequested {
+		if p.Auth, err = l.auth.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
+	if l.Step >= StepAuthorized {
+		if p.Token, err = l.tk.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(p)
+}
+
+func (l *Login) UnmarshalBinary(data []byte) error {
+	var p persistedLogin
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	login := Login{Step: p.Step, RID: p.RID, SID: p.SID, Context: p.Context, PPID: p.PPID, IDToken: p.IDToken}
+
+	if p.Step >= StepInitialized {
+		if err := login.orid.UnmarshalBinary(p.Opening); err != nil {
+			return err
+		}
+		if err := login.crid.UnmarshalBinary(p.Commit); err != nil {
+			return err
+		}
+	}
+	if p.Step >= StepRequested {
+		if err := login.auth.UnmarshalBinary(p.Auth); err != nil {
+			return err
+		}
+	}
+	if p.Step >= StepAuthorized {
+		if err := login.tk.UnmarshalBinary(p.Token); err != nil {
+			return err
+		}
+	}
+	*l = login
+	return nil
+}
@@ -0,0 +1,213 @@
+package wallet
+
+import (
+	"OPPID-artifacts/protocol/oppid"
+	"OPPID-artifacts/protocol/oppid/idp"
+	"OPPID-artifacts/protocol/oppid/rp"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type testEnv struct {
+	pp     *oppid.PublicParams
+	client *idp.Client
+	rp     *rp.RP
+}
+
+func setupEnv(t *testing.T) *testEnv {
+	pp := oppid.Setup()
+	sk, pk, err := pp.KeyGen()
+	if err != nil {
+		t.Fatalf("KeyGen returned an error: %v", err)
+	}
+	srv, err := idp.New(pp, sk, pk, idp.Config{Users: idp.BasicAuth(map[string]string{"alice": "secret"})})
+	if err != nil {
+		t.Fatalf("idp.New returned an error: %v", err)
+	}
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+
+	client := &idp.Client{BaseURL: ts.URL, HTTPClient: ts.Client(), Authenticate: func(r *http.Request) {
+		r.SetBasicAuth("alice", "secret")
+	}}
+	rid := []byte("rp.example.com")
+	cred, err := client.Register(rid)
+	if err != nil {
+		t.Fatalf("Register returned an error: %v", err)
+	}
+	relyingParty, err := rp.New(pp, rp.Config{RID: rid, Credential: cred, Keys: client})
+	if err != nil {
+		t.Fatalf("rp.New returned an error: %v", err)
+	}
+	return &testEnv{pp, client, relyingParty}
+}
+
+func (env *testEnv) challenge(t *testing.T) (Challenge, *[]string) {
+	ch, err := env.rp.Begin()
+	if err != nil {
+		t.Fatalf("Begin returned an error: %v", err)
+	}
+	return Challenge{ch.RID, ch.SID, &ch.Credential}, new([]string)
+}
+
+func (env *testEnv) deliver(accounts *[]string) Deliver {
+	return func(sid []byte, idToken string) error {
+		acc, err := env.rp.Complete(sid, idToken)
+		if err != nil {
+			return err
+		}
+		*accounts = append(*accounts, acc.ID)
+		return nil
+	}
+}
+
+// flakyIdP fails the first authorization.
+type flakyIdP struct {
+	IdP
+	failed bool
+}
+
+func (f *flakyIdP) Authorize(auth oppid.Auth, crid oppid.UsrCommitment, sid []byte) (oppid.Token, []byte, error) {
+	if !f.failed {
+		f.failed = true
+		return oppid.Token{}, nil, errors.New("connection reset")
+	}
+	return f.IdP.Authorize(auth, crid, sid)
+}
+
+func TestLogin(t *testing.T) {
+	env := setupEnv(t)
+	w := New(env.pp, env.client, NewMemoryStore(), Config{})
+
+	ch, accounts := env.challenge(t)
+	l, err := w.Start(ch)
+	if err != nil {
+		t.Fatalf("Start returned an error: %v", err)
+	}
+	if err := w.Run(l, env.deliver(accounts)); err != nil {
+		t.Fatalf("Run returned an error: %v", err)
+	}
+	if l.Step != StepDelivered || len(*accounts) != 1 {
+		t.Fatalf("login was not delivered")
+	}
+	if pending, _ := w.Pending(); len(pending) != 0 {
+		t.Fatalf("delivered login is still pending")
+	}
+
+	// The second login reuses the stored credential
+	ch, _ = env.challenge(t)
+	ch.Credential = nil
+	l, err = w.Start(ch)
+	if err != nil {
+		t.Fatalf("Start returned an error: %v", err)
+	}
+	if err := w.Run(l, env.deliver(accounts)); err != nil {
+		t.Fatalf("Run returned an error: %v", err)
+	}
+	if (*accounts)[0] != (*accounts)[1] {
+		t.Fatalf("repeated logins ended in different accounts")
+	}
+}
+
+func TestResumeInterruptedLogin(t *testing.T) {
+	env := setupEnv(t)
+	store, err := NewDirStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDirStore returned an error: %v", err)
+	}
+
+	w := New(env.pp, &flakyIdP{IdP: env.client}, store, Config{})
+	ch, accounts := env.challenge(t)
+	l, err := w.Start(ch)
+	if err != nil {
+		t.Fatalf("Start returned an error: %v", err)
+	}
+
+	err = w.Run(l, env.deliver(accounts))
+	var stepErr *StepError
+	if !errors.As(err, &stepErr) || stepErr.Step != StepAuthorized {
+		t.Fatalf("expected the authorize step to fail, got %v", err)
+	}
+
+	// A new wallet instance picks the login up from the store
+	w = New(env.pp, env.client, store, Config{})
+	pending, err := w.Pending()
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("expected one pending login, got %d (%v)", len(pending), err)
+	}
+	l, err = w.Resume(pending[0])
+	if err != nil {
+		t.Fatalf("Resume returned an error: %v", err)
+	}
+	if l.Step != StepRequested {
+		t.Fatalf("resumed login is at step %s, want %s", l.Step, StepRequested)
+	}
+	if err := w.Run(l, env.deliver(accounts)); err != nil {
+		t.Fatalf("Run returned an error: %v", err)
+	}
+	if len(*accounts) != 1 {
+		t.Fatalf("resumed login was not delivered")
+	}
+}
+
+func TestFailedDeliveryCanBeRetried(t *testing.T) {
+	env := setupEnv(t)
+	w := New(env.pp, env.client, NewMemoryStore(), Config{})
+	ch, accounts := env.challenge(t)
+	l, _ := w.Start(ch)
+
+	err := w.Run(l, func([]byte, string) error { return errors.New("RP unavailable") })
+	var stepErr *StepError
+	if !errors.As(err, &stepErr) || stepErr.Step != StepDelivered {
+		t.Fatalf("expected the delivery step to fail, got %v", err)
+	}
+
+	l, err = w.Resume(ch.SID)
+	if err != nil {
+		t.Fatalf("Resume returned an error: %v", err)
+	}
+	if err := w.Run(l, env.deliver(accounts)); err != nil {
+		t.Fatalf("Run returned an error: %v", err)
+	}
+}
+
+func TestStartWithoutCredential(t *testing.T) {
+	env := setupEnv(t)
+	w := New(env.pp, env.client, NewMemoryStore(), Config{})
+	ch, _ := env.challenge(t)
+	ch.Credential = nil
+	if _, err := w.Start(ch); !errors.Is(err, ErrNoCredential) {
+		t.Fatalf("expected ErrNoCredential, got %v", err)
+	}
+}
+
+func TestRejectsChangedKeyCommitment(t *testing.T) {
+	env := setupEnv(t)
+	w := New(env.pp, env.client, NewMemoryStore(), Config{})
+
+	other, _, _ := env.pp.KeyGen()
+	if err := w.PinKeyCommitment(env.pp.KeyCommitment(other, []byte("alice"))); err != nil {
+		t.Fatalf("PinKeyCommitment returned an error: %v", err)
+	}
+	ch, accounts := env.challenge(t)
+	l, err := w.Start(ch)
+	if err != nil {
+		t.Fatalf("Start returned an error: %v", err)
+	}
+	if err := w.Run(l, env.deliver(accounts)); !errors.Is(err, ErrKeyCommitment) {
+		t.Fatalf("Run returned %v, want %v", err, ErrKeyCommitment)
+	}
+
+	kc, err := env.client.KeyCommitment()
+	if err != nil {
+		t.Fatalf("KeyCommitment returned an error: %v", err)
+	}
+	if err := w.PinKeyCommitment(kc); err != nil {
+		t.Fatalf("PinKeyCommitment returned an error: %v", err)
+	}
+	if err := w.Run(l, env.deliver(accounts)); err != nil {
+		t.Fatalf("Run returned an error after pinning the published commitment: %v", err)
+	}
+}
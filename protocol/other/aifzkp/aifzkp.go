@@ -9,29 +9,30 @@ package aifzkp
 import (
 	PC "OPPID-artifacts/pkg/oppid/commit/pc"
 	NIZK "OPPID-artifacts/pkg/oppid/nizk/comsig"
+	"OPPID-artifacts/pkg/oppid/sign"
 	PS "OPPID-artifacts/pkg/oppid/sign/ps"
-	RSA "OPPID-artifacts/pkg/oppid/sign/rsa256"
+	"OPPID-artifacts/pkg/oppid/sign/schemes"
+	"OPPID-artifacts/pkg/oppid/transcript"
 	"bytes"
 	"errors"
-	"log"
 )
 
 const dstStr = "OPPID_BLS12384_XMD:SHA-256_AIF-ZKP_"
 
 type PublicParams struct {
-	rsa *RSA.PublicParams
-	dst []byte
-	pc  *PC.PublicParams
-	ps  *PS.PublicParams
+	scheme sign.Scheme
+	dst    []byte
+	pc     *PC.PublicParams
+	ps     *PS.PublicParams
 }
 
 type PublicKey struct {
-	rsaPk *RSA.PublicKey
+	sigPk sign.PublicKey
 	psPk  *PS.PublicKey
 }
 
 type PrivateKey struct {
-	rsaSk *RSA.PrivateKey
+	sigSk sign.PrivateKey
 	psSk  *PS.PrivateKey
 }
 
@@ -52,57 +53,93 @@ type Auth struct {
 }
 
 type Token struct {
-	sig RSA.Signature
+	sig sign.Signature
 }
 
 type FinalizedToken struct {
 	com     PC.Commitment
 	opening PC.Opening
-	sig     RSA.Signature
+	sig     sign.Signature
 }
 
 func tokenBytes(com *PC.Commitment, uid, ctx, sid []byte) []byte {
-	var buf bytes.Buffer
-	buf.Write([]byte(dstStr + "TOKEN"))
-	buf.Write(com.Element.Bytes())
-	buf.Write(uid)
-	buf.Write(ctx)
-	buf.Write(sid)
-	return buf.Bytes()
+	return transcript.New(dstStr+"TOKEN").
+		Append("com", com.Element.Bytes()).
+		Append("uid", uid).
+		Append("ctx", ctx).
+		Append("sid", sid).
+		Bytes()
 }
 
-func Setup() *PublicParams {
-	rsa := RSA.Setup(2048)
+// Option configures Setup.
+type Option func(*PublicParams)
 
+// WithScheme makes the IdP sign with s instead of the default RS256, see package schemes.
+func WithScheme(s sign.Scheme) Option {
+	return func(pp *PublicParams) {
+		pp.scheme = s
+	}
+}
+
+func Setup(opts ...Option) *PublicParams {
 	dst := []byte(dstStr + "COM_SIG") // Commitments & signatures hash to the same domain (dst) for the (NIZK) proof
 	pc := PC.Setup(dst)
 	ps := PS.Setup(dst)
 
-	return &PublicParams{rsa, dst, pc, ps}
+	pp := &PublicParams{schemes.Default(), dst, pc, ps}
+	for _, opt := range opts {
+		opt(pp)
+	}
+	return pp
+}
+
+// VerifyParams checks that the commitment generators were derived transparently and that commitments and signatures
+// share the same domain separation tag.
+func (pp *PublicParams) VerifyParams() error {
+	if err := pp.pc.VerifyParams(); err != nil {
+		return err
+	}
+	if !bytes.Equal(pp.pc.Dst, pp.dst) || !bytes.Equal(pp.ps.Dst, pp.dst) {
+		return errors.New("commitment and signature domains differ")
+	}
+	return nil
 }
 
-func (pp *PublicParams) KeyGen() (*PrivateKey, *PublicKey) {
-	rsaSk, rsaPk := pp.rsa.KeyGen()
-	psSk, psPk := pp.ps.KeyGen()
-	return &PrivateKey{rsaSk, psSk}, &PublicKey{rsaPk, psPk}
+func (pp *PublicParams) KeyGen() (*PrivateKey, *PublicKey, error) {
+	sigSk, sigPk, err := pp.scheme.KeyGen(nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	psSk, psPk, err := pp.ps.KeyGen()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &PrivateKey{sigSk, psSk}, &PublicKey{sigPk, psPk}, nil
 }
 
-func (pp *PublicParams) Register(k *PrivateKey, rid []byte) Credential {
-	return Credential{pp.ps.Sign(k.psSk, rid)}
+func (pp *PublicParams) Register(k *PrivateKey, rid []byte) (Credential, error) {
+	sig, err := pp.ps.Sign(k.psSk, rid)
+	if err != nil {
+		return Credential{}, err
+	}
+	return Credential{sig}, nil
 }
 
-func (pp *PublicParams) Init(rid []byte) (UsrOpening, UsrCommitment) {
-	com, opn := pp.pc.Commit(rid)
-	return UsrOpening{opn}, UsrCommitment{com}
+func (pp *PublicParams) Init(rid []byte) (UsrOpening, UsrCommitment, error) {
+	com, opn, err := pp.pc.Commit(rid)
+	if err != nil {
+		return UsrOpening{}, UsrCommitment{}, err
+	}
+	return UsrOpening{opn}, UsrCommitment{com}, nil
 }
 
-func (pp *PublicParams) Request(ipk *PublicKey, rid []byte, c Credential, crid UsrCommitment, orid UsrOpening, sid []byte) Auth {
+func (pp *PublicParams) Request(ipk *PublicKey, rid []byte, c Credential, crid UsrCommitment, orid UsrOpening, sid []byte) (Auth, error) {
 	if !pp.pc.Open(rid, crid.com, orid.opening) {
-		log.Fatalf("Commitment is not correct")
+		return Auth{}, errors.New("commitment is not correct")
 	}
 
 	var w NIZK.Witnesses
-	w.Msg = rid
+	w.Msgs = [][]byte{rid}
 	w.Sig = &c.sig
 	w.Opening = &orid.opening
 
@@ -111,9 +148,12 @@ func (pp *PublicParams) Request(ipk *PublicKey, rid []byte, c Credential, crid U
 	p.PS = ipk.psPk
 	p.Com = &crid.com
 
-	pi := NIZK.Prove(w, p, sid, pp.dst)
+	pi, err := NIZK.Prove(nil, w, p, sid, pp.dst)
+	if err != nil {
+		return Auth{}, err
+	}
 
-	return Auth{pi}
+	return Auth{pi}, nil
 }
 
 func (pp *PublicParams) Response(isk *PrivateKey, auth Auth, crid UsrCommitment, uid, ctx, sid []byte) (Token, error) {
@@ -129,28 +169,31 @@ func (pp *PublicParams) Response(isk *PrivateKey, auth Auth, crid UsrCommitment,
 
 	tkBytes := tokenBytes(&crid.com, uid, ctx, sid)
 
-	var tk Token
-	tk.sig = pp.rsa.Sign(isk.rsaSk, tkBytes)
+	sig, err := pp.scheme.Sign(nil, isk.sigSk, tkBytes)
+	if err != nil {
+		return Token{}, err
+	}
 
-	return tk, nil
+	return Token{sig}, nil
 }
 
 func (pp *PublicParams) Finalize(ipk *PublicKey, rid, uid, ctx, sid []byte, crid UsrCommitment, orid UsrOpening, t Token) (FinalizedToken, error) {
+	if !pp.pc.Open(rid, crid.com, orid.opening) {
+		return FinalizedToken{}, errors.New("commitment or signature did not verify")
+	}
 	tkBytes := tokenBytes(&crid.com, uid, ctx, sid)
-	isValidCom := pp.pc.Open(rid, crid.com, orid.opening)
-	isValidSig := pp.rsa.Verify(ipk.rsaPk, tkBytes, t.sig)
-	if !isValidCom || !isValidSig {
+	if !pp.scheme.Verify(ipk.sigPk, tkBytes, t.sig) {
 		return FinalizedToken{}, errors.New("commitment or signature did not verify")
 	}
 
 	return FinalizedToken{crid.com, orid.opening, t.sig}, nil
 }
 
+// Verify returns false for malformed tokens, e.g., with a missing or identity commitment.
 func (pp *PublicParams) Verify(ipk *PublicKey, rid, uid, ctx, sid []byte, ft FinalizedToken) bool {
+	if !pp.pc.Open(rid, ft.com, ft.opening) {
+		return false
+	}
 	tkBytes := tokenBytes(&ft.com, uid, ctx, sid)
-
-	isValidCom := pp.pc.Open(rid, ft.com, ft.opening)
-	isValidSig := pp.rsa.Verify(ipk.rsaPk, tkBytes, ft.sig)
-
-	return isValidCom && isValidSig
+	return pp.scheme.Verify(ipk.sigPk, tkBytes, ft.sig)
 }
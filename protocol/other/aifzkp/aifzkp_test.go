@@ -1,6 +1,7 @@
 package aifzkp
 
 import (
+	"OPPID-artifacts/pkg/oppid/sign/schemes"
 	"crypto/rand"
 	"testing"
 )
@@ -13,11 +14,18 @@ func generateContextAndSessionID() ([16]byte, [8]byte) {
 	return ctx, sid
 }
 
+func TestAIFZKPVerifyParams(t *testing.T) {
+	aifZkp := Setup()
+	if err := aifZkp.VerifyParams(); err != nil {
+		t.Errorf("Expected transparent public parameters, but got error: %v", err)
+	}
+}
+
 func TestAIFZKPRegister(t *testing.T) {
 	aifZkp := Setup()
-	isk, _ := aifZkp.KeyGen()
+	isk, _, _ := aifZkp.KeyGen()
 
-	cred := aifZkp.Register(isk, []byte("Test-RID"))
+	cred, _ := aifZkp.Register(isk, []byte("Test-RID"))
 	if cred.sig.One == nil || cred.sig.Two == nil {
 		t.Errorf("Failed to register AIFZKP: credential signature parts are nil")
 	}
@@ -27,7 +35,7 @@ func TestAIFZKPInit(t *testing.T) {
 	aifZkp := Setup()
 	aifZkp.KeyGen()
 
-	orid, crid := aifZkp.Init([]byte("Test-RID"))
+	orid, crid, _ := aifZkp.Init([]byte("Test-RID"))
 	if orid.opening.Scalar == nil || crid.com.Element == nil {
 		t.Errorf("Failed to initialize request: opening scalar or commitment element is nil")
 	}
@@ -35,16 +43,16 @@ func TestAIFZKPInit(t *testing.T) {
 
 func TestAIFZKPRequestResponse(t *testing.T) {
 	aifZkp := Setup()
-	isk, ipk := aifZkp.KeyGen()
+	isk, ipk, _ := aifZkp.KeyGen()
 
 	rid := []byte("Test-RID")
 	uid := []byte("alice.doe@idp.com")
 	ctx, sid := generateContextAndSessionID()
 
-	cred := aifZkp.Register(isk, rid)
+	cred, _ := aifZkp.Register(isk, rid)
 
-	orid, crid := aifZkp.Init(rid)
-	auth := aifZkp.Request(ipk, rid, cred, crid, orid, sid[:])
+	orid, crid, _ := aifZkp.Init(rid)
+	auth, _ := aifZkp.Request(ipk, rid, cred, crid, orid, sid[:])
 	_, err := aifZkp.Response(isk, auth, crid, uid, ctx[:], sid[:])
 	if err != nil {
 		t.Errorf("Expected the authentication request to succeed, but got error: %v", err)
@@ -53,16 +61,16 @@ func TestAIFZKPRequestResponse(t *testing.T) {
 
 func TestAIFZKPFinVf(t *testing.T) {
 	aifZkp := Setup()
-	isk, ipk := aifZkp.KeyGen()
+	isk, ipk, _ := aifZkp.KeyGen()
 
 	rid := []byte("Test-RID")
 	uid := []byte("alice.doe@idp.com")
 	ctx, sid := generateContextAndSessionID()
 
-	cred := aifZkp.Register(isk, rid)
+	cred, _ := aifZkp.Register(isk, rid)
 
-	orid, crid := aifZkp.Init(rid)
-	auth := aifZkp.Request(ipk, rid, cred, crid, orid, sid[:])
+	orid, crid, _ := aifZkp.Init(rid)
+	auth, _ := aifZkp.Request(ipk, rid, cred, crid, orid, sid[:])
 
 	tk, err := aifZkp.Response(isk, auth, crid, uid, ctx[:], sid[:])
 	if err != nil {
@@ -82,7 +90,7 @@ func TestAIFZKPFinVf(t *testing.T) {
 
 func TestAIFZKPEdgeCases(t *testing.T) {
 	aifZkp := Setup()
-	isk, ipk := aifZkp.KeyGen()
+	isk, ipk, _ := aifZkp.KeyGen()
 
 	rid1 := []byte("Test-RID")
 	rid2 := []byte("Test-RID")
@@ -93,14 +101,14 @@ func TestAIFZKPEdgeCases(t *testing.T) {
 	ctx1, sid1 := generateContextAndSessionID()
 	ctx2, sid2 := generateContextAndSessionID()
 
-	cred1 := aifZkp.Register(isk, rid1)
-	cred2 := aifZkp.Register(isk, rid2)
+	cred1, _ := aifZkp.Register(isk, rid1)
+	cred2, _ := aifZkp.Register(isk, rid2)
 
-	orid1, crid1 := aifZkp.Init(rid1)
-	orid2, crid2 := aifZkp.Init(rid2)
+	orid1, crid1, _ := aifZkp.Init(rid1)
+	orid2, crid2, _ := aifZkp.Init(rid2)
 
-	auth1 := aifZkp.Request(ipk, rid1, cred1, crid1, orid1, sid1[:])
-	auth2 := aifZkp.Request(ipk, rid2, cred2, crid2, orid2, sid2[:])
+	auth1, _ := aifZkp.Request(ipk, rid1, cred1, crid1, orid1, sid1[:])
+	auth2, _ := aifZkp.Request(ipk, rid2, cred2, crid2, orid2, sid2[:])
 
 	tk1, _ := aifZkp.Response(isk, auth1, crid1, uid1, ctx1[:], sid1[:])
 	tk2, _ := aifZkp.Response(isk, auth2, crid2, uid2, ctx2[:], sid2[:])
@@ -162,3 +170,82 @@ func TestAIFZKPEdgeCases(t *testing.T) {
 		t.Errorf("Expected verification to fail with mismatched session, but it succeeded")
 	}
 }
+
+func TestAIFZKPRequestWithWrongOpening(t *testing.T) {
+	aifZkp := Setup()
+	isk, ipk, _ := aifZkp.KeyGen()
+
+	rid := []byte("Test-RID")
+	_, sid := generateContextAndSessionID()
+
+	cred, _ := aifZkp.Register(isk, rid)
+	_, crid, _ := aifZkp.Init(rid)
+	otherOrid, _, _ := aifZkp.Init(rid)
+
+	if _, err := aifZkp.Request(ipk, rid, cred, crid, otherOrid, sid[:]); err == nil {
+		t.Errorf("Expected the request to fail with a wrong opening, but it succeeded")
+	}
+	if _, err := aifZkp.Request(ipk, rid, cred, UsrCommitment{}, otherOrid, sid[:]); err == nil {
+		t.Errorf("Expected the request to fail with an empty commitment, but it succeeded")
+	}
+	if aifZkp.Verify(ipk, rid, []byte("uid"), nil, sid[:], FinalizedToken{}) {
+		t.Errorf("Expected verification of an empty token to fail, but it succeeded")
+	}
+}
+
+func TestAIFZKPFieldBoundaryCollision(t *testing.T) {
+	aifZkp := Setup()
+	isk, ipk, _ := aifZkp.KeyGen()
+
+	rid := []byte("Test-RID")
+	uid := []byte("alice")
+	ctx := []byte("ab")
+	sid := []byte("c")
+
+	cred, _ := aifZkp.Register(isk, rid)
+	orid, crid, _ := aifZkp.Init(rid)
+	auth, _ := aifZkp.Request(ipk, rid, cred, crid, orid, sid)
+	tk, err := aifZkp.Response(isk, auth, crid, uid, ctx, sid)
+	if err != nil {
+		t.Fatalf("Expected the authentication request to succeed, but got error: %v", err)
+	}
+	ftk, err := aifZkp.Finalize(ipk, rid, uid, ctx, sid, crid, orid, tk)
+	if err != nil {
+		t.Fatalf("Expected token finalization to succeed, but got error: %v", err)
+	}
+
+	shifted := []struct{ uid, ctx, sid string }{
+		{"alic", "eab", "c"},
+		{"alice", "a", "bc"},
+		{"aliceab", "", "c"},
+	}
+	for _, s := range shifted {
+		if aifZkp.Verify(ipk, rid, []byte(s.uid), []byte(s.ctx), []byte(s.sid), ftk) {
+			t.Errorf("Expected verification to fail for shifted fields %q, %q, %q", s.uid, s.ctx, s.sid)
+		}
+	}
+}
+
+func TestAIFZKPSignatureSchemes(t *testing.T) {
+	rid := []byte("Test-RID")
+	uid := []byte("alice.doe@idp.com")
+	ctx, sid := generateContextAndSessionID()
+	for _, s := range schemes.All() {
+		aifZkp := Setup(WithScheme(s))
+		isk, ipk, _ := aifZkp.KeyGen()
+		cred, _ := aifZkp.Register(isk, rid)
+		orid, crid, _ := aifZkp.Init(rid)
+		auth, _ := aifZkp.Request(ipk, rid, cred, crid, orid, sid[:])
+		tk, err := aifZkp.Response(isk, auth, crid, uid, ctx[:], sid[:])
+		if err != nil {
+			t.Fatalf("%s: Response returned an error: %v", s.Name(), err)
+		}
+		ftk, err := aifZkp.Finalize(ipk, rid, uid, ctx[:], sid[:], crid, orid, tk)
+		if err != nil {
+			t.Fatalf("%s: Finalize returned an error: %v", s.Name(), err)
+		}
+		if !aifZkp.Verify(ipk, rid, uid, ctx[:], sid[:], ftk) {
+			t.Errorf("%s: Expected the final verification to succeed", s.Name())
+		}
+	}
+}
@@ -0,0 +1,38 @@
+// Encodings of the AIF-ZKP messages, to account for their sizes. Group elements are compressed and scalars fixed-size;
+// signatures carry a 2-byte big-endian length prefix. There are no decoders, as the messages never leave the process.
+
+package aifzkp
+
+import "OPPID-artifacts/protocol"
+
+func (c Credential) MarshalBinary() ([]byte, error) {
+	return c.sig.MarshalBinary()
+}
+
+func (c UsrCommitment) MarshalBinary() ([]byte, error) {
+	return c.com.MarshalBinary()
+}
+
+func (o UsrOpening) MarshalBinary() ([]byte, error) {
+	return o.opening.MarshalBinary()
+}
+
+func (a Auth) MarshalBinary() ([]byte, error) {
+	return a.proof.MarshalBinary()
+}
+
+func (t Token) MarshalBinary() ([]byte, error) {
+	return protocol.AppendField(nil, t.sig)
+}
+
+func (f FinalizedToken) MarshalBinary() ([]byte, error) {
+	com, err := f.com.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	opn, err := f.opening.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return protocol.AppendField(append(com, opn...), f.sig)
+}
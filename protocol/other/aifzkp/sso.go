@@ -0,0 +1,143 @@
+package aifzkp
+
+import (
+	"OPPID-artifacts/protocol"
+	"bytes"
+	"errors"
+)
+
+// Adapter of AIF-ZKP to protocol.SSO. It follows the one of OPPID, but the token binds the uid itself, which is
+// therefore the account.
+
+type sso struct{ opts []Option }
+
+// NewSSO returns AIF-ZKP as a protocol.SSO with public parameters set up with opts.
+func NewSSO(opts ...Option) protocol.SSO { return &sso{opts} }
+
+func (s *sso) Name() string { return "AIF-ZKP" }
+
+func (s *sso) Setup() (protocol.IdP, error) {
+	pp := Setup(s.opts...)
+	isk, ipk, err := pp.KeyGen()
+	if err != nil {
+		return nil, err
+	}
+	return &ssoIdP{pp, isk, ipk}, nil
+}
+
+type ssoIdP struct {
+	pp  *PublicParams
+	isk *PrivateKey
+	ipk *PublicKey
+}
+
+type ssoChallenge struct {
+	rid  []byte
+	cred Credential
+}
+
+func (m ssoChallenge) MarshalBinary() ([]byte, error) {
+	return protocol.MarshalFields(protocol.Bytes(m.rid), m.cred)
+}
+
+type ssoRequest struct {
+	auth Auth
+	crid UsrCommitment
+}
+
+func (m ssoRequest) MarshalBinary() ([]byte, error) {
+	return protocol.MarshalFields(m.auth, m.crid)
+}
+
+type ssoState struct {
+	rid, uid, ctx, sid []byte
+	crid               UsrCommitment
+	orid               UsrOpening
+}
+
+type ssoResult struct {
+	ft  FinalizedToken
+	uid []byte
+}
+
+func (m ssoResult) MarshalBinary() ([]byte, error) {
+	return protocol.MarshalFields(m.ft, protocol.Bytes(m.uid))
+}
+
+func (idp *ssoIdP) Register(rid []byte) (protocol.RP, error) {
+	cred, err := idp.pp.Register(idp.isk, rid)
+	if err != nil {
+		return nil, err
+	}
+	return &ssoRP{idp, bytes.Clone(rid), cred}, nil
+}
+
+func (idp *ssoIdP) User(uid []byte) (protocol.User, error) {
+	return &ssoUser{idp, bytes.Clone(uid)}, nil
+}
+
+func (idp *ssoIdP) Response(uid []byte, req protocol.Message, ctx, sid []byte) (protocol.Message, error) {
+	r, ok := req.(ssoRequest)
+	if !ok {
+		return nil, protocol.ErrMessage
+	}
+	return idp.pp.Response(idp.isk, r.auth, r.crid, uid, ctx, sid)
+}
+
+type ssoUser struct {
+	idp *ssoIdP
+	uid []byte
+}
+
+func (u *ssoUser) ID() []byte { return u.uid }
+
+func (u *ssoUser) Request(ch protocol.Challenge) (protocol.Message, protocol.State, error) {
+	m, ok := ch.Msg.(ssoChallenge)
+	if !ok {
+		return nil, nil, protocol.ErrMessage
+	}
+	pp := u.idp.pp
+	orid, crid, err := pp.Init(m.rid)
+	if err != nil {
+		return nil, nil, err
+	}
+	auth, err := pp.Request(u.idp.ipk, m.rid, m.cred, crid, orid, ch.Sid)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ssoRequest{auth, crid}, ssoState{m.rid, u.uid, ch.Ctx, ch.Sid, crid, orid}, nil
+}
+
+func (u *ssoUser) Finalize(st protocol.State, tk protocol.Message) (protocol.Message, error) {
+	s, ok1 := st.(ssoState)
+	t, ok2 := tk.(Token)
+	if !ok1 || !ok2 {
+		return nil, protocol.ErrMessage
+	}
+	ft, err := u.idp.pp.Finalize(u.idp.ipk, s.rid, s.uid, s.ctx, s.sid, s.crid, s.orid, t)
+	if err != nil {
+		return nil, err
+	}
+	return ssoResult{ft, s.uid}, nil
+}
+
+type ssoRP struct {
+	idp  *ssoIdP
+	rid  []byte
+	cred Credential
+}
+
+func (rp *ssoRP) Begin() (protocol.Challenge, error) {
+	return protocol.NewChallenge(nil, ssoChallenge{rp.rid, rp.cred})
+}
+
+func (rp *ssoRP) Complete(ch protocol.Challenge, res protocol.Message) (protocol.Account, error) {
+	r, ok := res.(ssoResult)
+	if !ok {
+		return nil, protocol.ErrMessage
+	}
+	if !rp.idp.pp.Verify(rp.idp.ipk, rp.rid, r.uid, ch.Ctx, ch.Sid, r.ft) {
+		return nil, errors.New("invalid token")
+	}
+	return protocol.Account(r.uid), nil
+}
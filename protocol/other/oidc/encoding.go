@@ -0,0 +1,10 @@
+// Encoding of the OIDC token, to account for its size: the PPID followed by the signature with a 2-byte big-endian
+// length prefix. There is no decoder, as tokens never leave the process.
+
+package oidc
+
+import "OPPID-artifacts/protocol"
+
+func (t Token) MarshalBinary() ([]byte, error) {
+	return protocol.AppendField(t.ppid[:], t.sig)
+}
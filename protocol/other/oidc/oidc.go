@@ -7,74 +7,93 @@
 package oidc
 
 import (
-	RSA "OPPID-artifacts/pkg/oppid/sign/rsa256"
-	"bytes"
+	"OPPID-artifacts/pkg/oppid/sign"
+	"OPPID-artifacts/pkg/oppid/sign/schemes"
+	"OPPID-artifacts/pkg/oppid/transcript"
 	"crypto/rand"
 	"crypto/sha256"
-	"log"
+	"fmt"
 )
 
+const dstStr = "OPPID_BLS12384_XMD:SHA-256_OIDC_"
+
 type PublicParams struct {
-	rsa *RSA.PublicParams
+	scheme sign.Scheme
 }
 
 type PublicKey struct {
-	key *RSA.PublicKey
+	key sign.PublicKey
 }
 
 type PrivateKey struct {
-	key  *RSA.PrivateKey
+	key  sign.PrivateKey
 	salt [32]byte
 }
 
 type PPID = [32]byte
 
 type Token struct {
-	sig  RSA.Signature
+	sig  sign.Signature
 	ppid PPID
 }
 
 func tokenBytes(rid []byte, ppid PPID, ctx, sid []byte) []byte {
-	var buf bytes.Buffer
-	buf.Write(rid)
-	buf.Write(ppid[:])
-	buf.Write(ctx)
-	buf.Write(sid)
-	return buf.Bytes()
+	return transcript.New(dstStr+"TOKEN").
+		Append("rid", rid).
+		Append("ppid", ppid[:]).
+		Append("ctx", ctx).
+		Append("sid", sid).
+		Bytes()
 }
 
-func Setup() *PublicParams {
-	return &PublicParams{RSA.Setup(2048)}
+// Option configures Setup.
+type Option func(*PublicParams)
+
+// WithScheme makes the IdP sign with s instead of the default RS256, see package schemes.
+func WithScheme(s sign.Scheme) Option {
+	return func(pp *PublicParams) {
+		pp.scheme = s
+	}
 }
 
-func (pp *PublicParams) KeyGen() (*PrivateKey, *PublicKey) {
-	sk, pk := pp.rsa.KeyGen()
+func Setup(opts ...Option) *PublicParams {
+	pp := &PublicParams{schemes.Default()}
+	for _, opt := range opts {
+		opt(pp)
+	}
+	return pp
+}
 
-	var salt [32]byte
-	_, err := rand.Read(salt[:])
+func (pp *PublicParams) KeyGen() (*PrivateKey, *PublicKey, error) {
+	sk, pk, err := pp.scheme.KeyGen(nil)
 	if err != nil {
-		log.Fatalf("failed to generate random salt: %v", err)
+		return nil, nil, err
 	}
 
-	return &PrivateKey{sk, salt}, &PublicKey{pk}
+	var salt [32]byte
+	if _, err := rand.Read(salt[:]); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate random salt: %w", err)
+	}
+
+	return &PrivateKey{sk, salt}, &PublicKey{pk}, nil
 }
 
-func (pp *PublicParams) Response(k *PrivateKey, rid, uid, ctx, sid []byte) Token {
+func (pp *PublicParams) Response(k *PrivateKey, rid, uid, ctx, sid []byte) (Token, error) {
 	var tk Token
 
-	var buf bytes.Buffer
-	buf.Write(rid)
-	buf.Write(uid)
-	buf.Write(k.salt[:])
-
-	tk.ppid = sha256.Sum256(buf.Bytes())
+	ppidBytes := transcript.New(dstStr+"PPID").Append("rid", rid).Append("uid", uid).Append("salt", k.salt[:])
+	tk.ppid = sha256.Sum256(ppidBytes.Bytes())
 	tkBytes := tokenBytes(rid, tk.ppid, ctx, sid)
-	tk.sig = pp.rsa.Sign(k.key, tkBytes)
+	sig, err := pp.scheme.Sign(nil, k.key, tkBytes)
+	if err != nil {
+		return Token{}, err
+	}
+	tk.sig = sig
 
-	return tk
+	return tk, nil
 }
 
 func (pp *PublicParams) Verify(p *PublicKey, rid, ctx, sid []byte, tk Token) bool {
 	tkBytes := tokenBytes(rid, tk.ppid, ctx, sid)
-	return pp.rsa.Verify(p.key, tkBytes, tk.sig)
+	return pp.scheme.Verify(p.key, tkBytes, tk.sig)
 }
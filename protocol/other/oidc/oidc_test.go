@@ -1,19 +1,20 @@
 package oidc
 
 import (
+	"OPPID-artifacts/pkg/oppid/sign/schemes"
 	"testing"
 )
 
 func TestOIDCResponseAndVerify(t *testing.T) {
 	oidc := Setup()
-	isk, ipk := oidc.KeyGen()
+	isk, ipk, _ := oidc.KeyGen()
 
 	rid := []byte("Test-RID")
 	uid := []byte("alice.doe@idp.com")
 	ctx := []byte("Test-CTX")
 	sid := []byte("Test-SID")
 
-	tk := oidc.Response(isk, rid, uid, ctx, sid)
+	tk, _ := oidc.Response(isk, rid, uid, ctx, sid)
 
 	isValid := oidc.Verify(ipk, rid, ctx, sid, tk)
 	if !isValid {
@@ -23,14 +24,14 @@ func TestOIDCResponseAndVerify(t *testing.T) {
 
 func TestOIDCResponseAndVerifyInvalidInputs(t *testing.T) {
 	oidc := Setup()
-	isk, ipk := oidc.KeyGen()
+	isk, ipk, _ := oidc.KeyGen()
 
 	rid := []byte("Test-RID")
 	uid := []byte("alice.doe@idp.com")
 	ctx := []byte("Test-CTX")
 	sid := []byte("Test-SID")
 
-	tk := oidc.Response(isk, rid, uid, ctx, sid)
+	tk, _ := oidc.Response(isk, rid, uid, ctx, sid)
 
 	// Modify one byte of the sign to simulate an invalid sign
 	tk.sig[0] ^= 0xFF
@@ -40,3 +41,42 @@ func TestOIDCResponseAndVerifyInvalidInputs(t *testing.T) {
 		t.Fatalf("Expected verification to fail for tampered sign")
 	}
 }
+
+func TestOIDCFieldBoundaryCollision(t *testing.T) {
+	oidc := Setup()
+	isk, ipk, _ := oidc.KeyGen()
+
+	rid := []byte("Test-RID")
+	tk, _ := oidc.Response(isk, rid, []byte("alice"), []byte("ab"), []byte("c"))
+
+	if oidc.Verify(ipk, rid, []byte("a"), []byte("bc"), tk) {
+		t.Fatalf("Expected verification to fail for shifted ctx/sid boundary")
+	}
+	if oidc.Verify(ipk, rid, []byte("abc"), nil, tk) {
+		t.Fatalf("Expected verification to fail for an empty sid")
+	}
+
+	// The PPID must not collide either when rid and uid are split differently
+	tk1, _ := oidc.Response(isk, []byte("rp"), []byte("alice"), nil, nil)
+	tk2, _ := oidc.Response(isk, []byte("rpa"), []byte("lice"), nil, nil)
+	if tk1.ppid == tk2.ppid {
+		t.Fatalf("Expected distinct PPIDs for shifted rid/uid boundary")
+	}
+}
+
+func TestOIDCSignatureSchemes(t *testing.T) {
+	for _, s := range schemes.All() {
+		oidc := Setup(WithScheme(s))
+		isk, ipk, err := oidc.KeyGen()
+		if err != nil {
+			t.Fatalf("%s: KeyGen returned an error: %v", s.Name(), err)
+		}
+		tk, err := oidc.Response(isk, []byte("Test-RID"), []byte("alice"), []byte("Test-CTX"), []byte("Test-SID"))
+		if err != nil {
+			t.Fatalf("%s: Response returned an error: %v", s.Name(), err)
+		}
+		if !oidc.Verify(ipk, []byte("Test-RID"), []byte("Test-CTX"), []byte("Test-SID"), tk) {
+			t.Fatalf("%s: Token is not valid", s.Name())
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package oidc
+
+import (
+	"OPPID-artifacts/protocol"
+	"bytes"
+	"errors"
+)
+
+// Adapter of OIDC to protocol.SSO. The user forwards the rid to the IdP and the token back to the RP, and the account
+// is the PPID.
+
+type sso struct{ opts []Option }
+
+// NewSSO returns OIDC as a protocol.SSO with public parameters set up with opts.
+func NewSSO(opts ...Option) protocol.SSO { return &sso{opts} }
+
+func (s *sso) Name() string { return "OIDC" }
+
+func (s *sso) Setup() (protocol.IdP, error) {
+	pp := Setup(s.opts...)
+	isk, ipk, err := pp.KeyGen()
+	if err != nil {
+		return nil, err
+	}
+	return &ssoIdP{pp, isk, ipk}, nil
+}
+
+type ssoIdP struct {
+	pp  *PublicParams
+	isk *PrivateKey
+	ipk *PublicKey
+}
+
+// ssoRid is both the challenge and the request.
+type ssoRid []byte
+
+func (m ssoRid) MarshalBinary() ([]byte, error) { return m, nil }
+
+func (idp *ssoIdP) Register(rid []byte) (protocol.RP, error) {
+	return &ssoRP{idp, bytes.Clone(rid)}, nil
+}
+
+func (idp *ssoIdP) User(uid []byte) (protocol.User, error) {
+	return &ssoUser{bytes.Clone(uid)}, nil
+}
+
+func (idp *ssoIdP) Response(uid []byte, req protocol.Message, ctx, sid []byte) (protocol.Message, error) {
+	rid, ok := req.(ssoRid)
+	if !ok {
+		return nil, protocol.ErrMessage
+	}
+	return idp.pp.Response(idp.isk, rid, uid, ctx, sid)
+}
+
+type ssoUser struct{ uid []byte }
+
+func (u *ssoUser) ID() []byte { return u.uid }
+
+func (u *ssoUser) Request(ch protocol.Challenge) (protocol.Message, protocol.State, error) {
+	rid, ok := ch.Msg.(ssoRid)
+	if !ok {
+		return nil, nil, protocol.ErrMessage
+	}
+	return rid, nil, nil
+}
+
+func (u *ssoUser) Finalize(_ protocol.State, tk protocol.Message) (protocol.Message, error) {
+	if _, ok := tk.(Token); !ok {
+		return nil, protocol.ErrMessage
+	}
+	return tk, nil
+}
+
+type ssoRP struct {
+	idp *ssoIdP
+	rid []byte
+}
+
+func (rp *ssoRP) Begin() (protocol.Challenge, error) {
+	return protocol.NewChallenge(nil, ssoRid(rp.rid))
+}
+
+func (rp *ssoRP) Complete(ch protocol.Challenge, res protocol.Message) (protocol.Account, error) {
+	tk, ok := res.(Token)
+	if !ok {
+		return nil, protocol.ErrMessage
+	}
+	if !rp.idp.pp.Verify(rp.idp.ipk, rp.rid, ch.Ctx, ch.Sid, tk) {
+		return nil, errors.New("invalid token")
+	}
+	return protocol.Account(bytes.Clone(tk.ppid[:])), nil
+}
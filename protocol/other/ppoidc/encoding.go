@@ -0,0 +1,44 @@
+// Encodings of the PPOIDC messages, to account for their sizes. Nonces and masked subjects are fixed-size and the
+// proof is a compressed Groth16 proof; all other fields carry a 2-byte big-endian length prefix. There are no decoders,
+// as the messages never leave the process.
+
+package ppoidc
+
+import "OPPID-artifacts/protocol"
+
+func (b ClientIDBinding) MarshalBinary() ([]byte, error) {
+	return protocol.MarshalFields(protocol.Bytes(b.Id), protocol.Bytes(b.name), protocol.Bytes(b.ruri),
+		protocol.Bytes(b.sig))
+}
+
+func (r Request) MarshalBinary() ([]byte, error) {
+	proof, err := r.proof.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	buf, err := protocol.AppendField(nil, r.maskedAud)
+	if err != nil {
+		return nil, err
+	}
+	buf = append(buf, r.maskedSub[:]...)
+	return append(buf, proof...), nil
+}
+
+// MarshalBinary encodes the nonces and the pairwise subject that the user hands to the RP.
+func (st UserRPState) MarshalBinary() ([]byte, error) {
+	buf := append(append(st.rpNonce[:], st.uNonce1[:]...), st.uNonce2[:]...)
+	return protocol.AppendField(buf, st.PairwiseSub)
+}
+
+func (t PrivateIdToken) MarshalBinary() ([]byte, error) {
+	buf, err := protocol.AppendField(nil, t.aud)
+	if err != nil {
+		return nil, err
+	}
+	buf = append(buf, t.sub[:]...)
+	fields, err := protocol.MarshalFields(protocol.Bytes(t.ctx), protocol.Bytes(t.sid), protocol.Bytes(t.sig))
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, fields...), nil
+}
@@ -6,9 +6,10 @@
 package ppoidc
 
 import (
-	RSA "OPPID-artifacts/pkg/oppid/sign/rsa256"
+	"OPPID-artifacts/pkg/oppid/sign"
+	"OPPID-artifacts/pkg/oppid/sign/schemes"
+	"OPPID-artifacts/pkg/oppid/transcript"
 	hash2 "OPPID-artifacts/pkg/other/nizk/hash"
-	"bytes"
 	"crypto/rand"
 	"crypto/sha256"
 	"errors"
@@ -17,18 +18,19 @@ import (
 const dstStr = "OPPID_BLS12384_XMD:SHA-256_PPOIDC_"
 
 type PublicParams struct {
-	rsa       *RSA.PublicParams
+	scheme    sign.Scheme
+	dir       string
 	hashProof *hash2.PublicParams
 	pk        *hash2.ProvingKey
 	vk        *hash2.VerifyingKey
 }
 
 type PublicKey struct {
-	rsaPk *RSA.PublicKey
+	sigPk sign.PublicKey
 }
 
 type PrivateKey struct {
-	rsaSk *RSA.PrivateKey
+	sigSk sign.PrivateKey
 }
 
 type UserId = []byte
@@ -46,7 +48,7 @@ type ClientIDBinding struct {
 	Id   ClientId
 	name ClientName
 	ruri RedirectUri
-	sig  RSA.Signature
+	sig  sign.Signature
 }
 
 type Request struct {
@@ -67,64 +69,87 @@ type PrivateIdToken struct {
 	sub MaskedSub
 	ctx []byte
 	sid []byte
-	sig RSA.Signature
+	sig sign.Signature
 }
 
 func tokenBytes(maskedAud MaskedAud, maskedSub MaskedSub, ctx, sid []byte) []byte {
-	tkBuf := bytes.NewBuffer(nil)
-	tkBuf.Write([]byte(dstStr + "TOKEN"))
-	tkBuf.Write(maskedAud)
-	tkBuf.Write(maskedSub[:])
-	tkBuf.Write(ctx)
-	tkBuf.Write(sid)
-	return tkBuf.Bytes()
+	return transcript.New(dstStr+"TOKEN").
+		Append("aud", maskedAud).
+		Append("sub", maskedSub[:]).
+		Append("ctx", ctx).
+		Append("sid", sid).
+		Bytes()
 }
 
-func Setup() (*PublicParams, error) {
-	hashProof, err := hash2.Setup()
+func certBytes(id ClientId, name ClientName, ruri RedirectUri) []byte {
+	return transcript.New(dstStr+"CERT").Append("id", id).Append("name", name).Append("ruri", ruri).Bytes()
+}
+
+// Option configures Setup.
+type Option func(*PublicParams)
+
+// WithScheme makes the IdP sign with s instead of the default RS256, see package schemes.
+func WithScheme(s sign.Scheme) Option {
+	return func(pp *PublicParams) {
+		pp.scheme = s
+	}
+}
+
+// WithArtifactDir keeps the compiled hash circuit and its Groth16 keys in dir instead of the working directory, see
+// hash.Setup.
+func WithArtifactDir(dir string) Option {
+	return func(pp *PublicParams) {
+		pp.dir = dir
+	}
+}
+
+func Setup(opts ...Option) (*PublicParams, error) {
+	pp := &PublicParams{scheme: schemes.Default(), dir: "."}
+	for _, opt := range opts {
+		opt(pp)
+	}
+	hashProof, err := hash2.Setup(pp.dir)
 	if err != nil {
 		return nil, err
 	}
-	pk, vk, errKGen := hashProof.KeyGen()
-	if errKGen != nil {
-		panic(errKGen)
+	pk, vk, err := hashProof.KeyGen()
+	if err != nil {
+		return nil, err
 	}
-	return &PublicParams{RSA.Setup(2048), hashProof, pk, vk}, nil
+	pp.hashProof, pp.pk, pp.vk = hashProof, pk, vk
+	return pp, nil
 }
 
-func (pp *PublicParams) KeyGen() (*PrivateKey, *PublicKey) {
-	rsaSk, rsaPk := pp.rsa.KeyGen()
-	return &PrivateKey{rsaSk}, &PublicKey{rsaPk}
+func (pp *PublicParams) KeyGen() (*PrivateKey, *PublicKey, error) {
+	sigSk, sigPk, err := pp.scheme.KeyGen(nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &PrivateKey{sigSk}, &PublicKey{sigPk}, nil
 }
 
-func (pp *PublicParams) Register(k *PrivateKey, name ClientName, ruri RedirectUri) ClientIDBinding {
+func (pp *PublicParams) Register(k *PrivateKey, name ClientName, ruri RedirectUri) (ClientIDBinding, error) {
 	var id [16]byte
-	_, _ = rand.Read(id[:])
-
-	var buf bytes.Buffer
-	buf.Write([]byte(dstStr + "CERT"))
-	buf.Write(id[:])
-	buf.Write(name)
-	buf.Write(ruri)
+	if _, err := rand.Read(id[:]); err != nil {
+		return ClientIDBinding{}, err
+	}
 
 	var bin ClientIDBinding
 	bin.Id = id[:]
 	bin.name = name
 	bin.ruri = ruri
-	bin.sig = pp.rsa.Sign(k.rsaSk, buf.Bytes())
+	sig, err := pp.scheme.Sign(nil, k.sigSk, certBytes(id[:], name, ruri))
+	if err != nil {
+		return ClientIDBinding{}, err
+	}
+	bin.sig = sig
 
-	return bin
+	return bin, nil
 }
 
 // Init maps step (5) of the protocol [1, p.7]
 func (pp *PublicParams) Init(ipk *PublicKey, uid UserId, cert ClientIDBinding, rpNonce Nonce) (Request, UserRPState, error) {
-	var buf bytes.Buffer
-	buf.Write([]byte(dstStr + "CERT"))
-	buf.Write(cert.Id)
-	buf.Write(cert.name)
-	buf.Write(cert.ruri)
-
-	isValid := pp.rsa.Verify(ipk.rsaPk, buf.Bytes(), cert.sig)
+	isValid := pp.scheme.Verify(ipk.sigPk, certBytes(cert.Id, cert.name, cert.ruri), cert.sig)
 	if !isValid {
 		return Request{}, UserRPState{}, errors.New("invalid certificate")
 	}
@@ -176,7 +201,10 @@ func (pp *PublicParams) Response(isk *PrivateKey, uid UserId, req Request, ctx,
 		return PrivateIdToken{}, errors.New("invalid proof")
 	}
 	tkBytes := tokenBytes(req.maskedAud, req.maskedSub, ctx, sid)
-	sig := pp.rsa.Sign(isk.rsaSk, tkBytes)
+	sig, err := pp.scheme.Sign(nil, isk.sigSk, tkBytes)
+	if err != nil {
+		return PrivateIdToken{}, err
+	}
 
 	return PrivateIdToken{req.maskedAud, req.maskedSub, ctx, sid, sig}, nil
 }
@@ -200,5 +228,5 @@ func (pp *PublicParams) Verify(ipk *PublicKey, id ClientId, st UserRPState, tk P
 	copy(maskedSub[:], sum)
 
 	tkBytes := tokenBytes(maskedAud, maskedSub, tk.ctx, tk.sid)
-	return pp.rsa.Verify(ipk.rsaPk, tkBytes, tk.sig)
+	return pp.scheme.Verify(ipk.sigPk, tkBytes, tk.sig)
 }
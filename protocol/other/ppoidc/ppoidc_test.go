@@ -1,6 +1,7 @@
 package ppoidc
 
 import (
+	"bytes"
 	"crypto/rand"
 	"testing"
 )
@@ -10,7 +11,10 @@ func setupAndKeyGen(t *testing.T) (*PublicParams, *PrivateKey, *PublicKey) {
 	if err != nil {
 		t.Fatalf("Setup failed: %v", err)
 	}
-	isk, ipk := ppoidc.KeyGen()
+	isk, ipk, err := ppoidc.KeyGen()
+	if err != nil {
+		t.Fatalf("KeyGen failed: %v", err)
+	}
 
 	return ppoidc, isk, ipk
 }
@@ -23,7 +27,9 @@ func TestRegister(t *testing.T) {
 	ppoidc, isk, _ := setupAndKeyGen(t)
 	name := ClientName("Test ID")
 	ruid := RedirectUri("Test redirect URI")
-	ppoidc.Register(isk, name, ruid)
+	if _, err := ppoidc.Register(isk, name, ruid); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
 }
 
 func TestInit(t *testing.T) {
@@ -32,7 +38,7 @@ func TestInit(t *testing.T) {
 	uid := UserId("Test ID")
 	name := ClientName("Test ID")
 	ruid := RedirectUri("Test redirect URI")
-	cert := ppoidc.Register(isk, name, ruid)
+	cert, _ := ppoidc.Register(isk, name, ruid)
 
 	var nonceRP Nonce
 	_, _ = rand.Read(nonceRP[:])
@@ -49,7 +55,7 @@ func TestResponse(t *testing.T) {
 	uid := UserId("Test ID")
 	name := ClientName("Test ID")
 	ruid := RedirectUri("Test redirect URI")
-	cert := ppoidc.Register(isk, name, ruid)
+	cert, _ := ppoidc.Register(isk, name, ruid)
 
 	var nonceRP Nonce
 	_, _ = rand.Read(nonceRP[:])
@@ -70,7 +76,7 @@ func TestVerify(t *testing.T) {
 	uid := UserId("Test ID")
 	name := ClientName("Test ID")
 	ruid := RedirectUri("Test redirect URI")
-	cert := ppoidc.Register(isk, name, ruid)
+	cert, _ := ppoidc.Register(isk, name, ruid)
 
 	var nonceRP Nonce
 	_, _ = rand.Read(nonceRP[:])
@@ -86,3 +92,13 @@ func TestVerify(t *testing.T) {
 		t.Fatalf("Verify returned false for a valid token")
 	}
 }
+
+func TestFieldBoundaryCollision(t *testing.T) {
+	var sub MaskedSub
+	if bytes.Equal(tokenBytes([]byte("aud"), sub, []byte("ab"), []byte("c")), tokenBytes([]byte("aud"), sub, []byte("a"), []byte("bc"))) {
+		t.Fatalf("token bytes collide for shifted ctx/sid boundary")
+	}
+	if bytes.Equal(certBytes([]byte("id"), []byte("ab"), []byte("c")), certBytes([]byte("id"), []byte("a"), []byte("bc"))) {
+		t.Fatalf("certificate bytes collide for shifted name/ruri boundary")
+	}
+}
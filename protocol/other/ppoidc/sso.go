@@ -0,0 +1,138 @@
+package ppoidc
+
+import (
+	"OPPID-artifacts/protocol"
+	"bytes"
+	"crypto/rand"
+	"errors"
+)
+
+// Adapter of PPOIDC to protocol.SSO. The RP hands its client id binding and a fresh nonce out in the challenge, the
+// user proves the masked subject to the IdP and passes its nonces on to the RP, and the account is the pairwise
+// subject.
+
+type sso struct{ opts []Option }
+
+// NewSSO returns PPOIDC as a protocol.SSO with public parameters set up with opts. Setup compiles the hash circuit
+// and generates its keys unless they are found in the artifact directory, see WithArtifactDir.
+func NewSSO(opts ...Option) protocol.SSO { return &sso{opts} }
+
+func (s *sso) Name() string { return "PPOIDC" }
+
+func (s *sso) Setup() (protocol.IdP, error) {
+	pp, err := Setup(s.opts...)
+	if err != nil {
+		return nil, err
+	}
+	isk, ipk, err := pp.KeyGen()
+	if err != nil {
+		return nil, err
+	}
+	return &ssoIdP{pp, isk, ipk}, nil
+}
+
+type ssoIdP struct {
+	pp  *PublicParams
+	isk *PrivateKey
+	ipk *PublicKey
+}
+
+type ssoChallenge struct {
+	cert    ClientIDBinding
+	rpNonce Nonce
+}
+
+func (m ssoChallenge) MarshalBinary() ([]byte, error) {
+	cert, err := m.cert.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return append(cert, m.rpNonce[:]...), nil
+}
+
+type ssoResult struct {
+	st UserRPState
+	tk PrivateIdToken
+}
+
+func (m ssoResult) MarshalBinary() ([]byte, error) {
+	return protocol.MarshalFields(m.st, m.tk)
+}
+
+func (idp *ssoIdP) Register(rid []byte) (protocol.RP, error) {
+	cert, err := idp.pp.Register(idp.isk, rid, rid)
+	if err != nil {
+		return nil, err
+	}
+	return &ssoRP{idp, cert}, nil
+}
+
+func (idp *ssoIdP) User(uid []byte) (protocol.User, error) {
+	return &ssoUser{idp, bytes.Clone(uid)}, nil
+}
+
+func (idp *ssoIdP) Response(uid []byte, req protocol.Message, ctx, sid []byte) (protocol.Message, error) {
+	r, ok := req.(Request)
+	if !ok {
+		return nil, protocol.ErrMessage
+	}
+	return idp.pp.Response(idp.isk, uid, r, ctx, sid)
+}
+
+type ssoUser struct {
+	idp *ssoIdP
+	uid UserId
+}
+
+func (u *ssoUser) ID() []byte { return u.uid }
+
+func (u *ssoUser) Request(ch protocol.Challenge) (protocol.Message, protocol.State, error) {
+	m, ok := ch.Msg.(ssoChallenge)
+	if !ok {
+		return nil, nil, protocol.ErrMessage
+	}
+	req, st, err := u.idp.pp.Init(u.idp.ipk, u.uid, m.cert, m.rpNonce)
+	if err != nil {
+		return nil, nil, err
+	}
+	return req, st, nil
+}
+
+func (u *ssoUser) Finalize(st protocol.State, tk protocol.Message) (protocol.Message, error) {
+	s, ok1 := st.(UserRPState)
+	t, ok2 := tk.(PrivateIdToken)
+	if !ok1 || !ok2 {
+		return nil, protocol.ErrMessage
+	}
+	return ssoResult{s, t}, nil
+}
+
+type ssoRP struct {
+	idp  *ssoIdP
+	cert ClientIDBinding
+}
+
+func (rp *ssoRP) Begin() (protocol.Challenge, error) {
+	var rpNonce Nonce
+	if _, err := rand.Read(rpNonce[:]); err != nil {
+		return protocol.Challenge{}, err
+	}
+	return protocol.NewChallenge(nil, ssoChallenge{rp.cert, rpNonce})
+}
+
+// Complete also checks that the token and the nonce belong to the session, as Verify takes them from the token and
+// the user.
+func (rp *ssoRP) Complete(ch protocol.Challenge, res protocol.Message) (protocol.Account, error) {
+	r, ok1 := res.(ssoResult)
+	m, ok2 := ch.Msg.(ssoChallenge)
+	if !ok1 || !ok2 {
+		return nil, protocol.ErrMessage
+	}
+	if r.st.rpNonce != m.rpNonce || !bytes.Equal(r.tk.sid, ch.Sid) || !bytes.Equal(r.tk.ctx, ch.Ctx) {
+		return nil, errors.New("token of another session")
+	}
+	if !rp.idp.pp.Verify(rp.idp.ipk, rp.cert.Id, r.st, r.tk) {
+		return nil, errors.New("invalid token")
+	}
+	return protocol.Account(bytes.Clone(r.st.PairwiseSub)), nil
+}
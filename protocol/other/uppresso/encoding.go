@@ -0,0 +1,27 @@
+// Encodings of the UPPRESSO messages, to account for their sizes. Group elements are compressed; endpoints and
+// signatures carry a 2-byte big-endian length prefix. There are no decoders, as the messages never leave the process.
+
+package uppresso
+
+import (
+	"OPPID-artifacts/protocol"
+	"errors"
+)
+
+func (c CertRP) MarshalBinary() ([]byte, error) {
+	if c.Id == nil {
+		return nil, errors.New("uppresso: empty certificate")
+	}
+	buf, err := protocol.AppendField(c.Id.BytesCompressed(), c.enPtRP)
+	if err != nil {
+		return nil, err
+	}
+	return protocol.AppendField(buf, c.sig)
+}
+
+func (t Token) MarshalBinary() ([]byte, error) {
+	if t.pidU == nil {
+		return nil, errors.New("uppresso: empty token")
+	}
+	return protocol.AppendField(t.pidU.BytesCompressed(), t.sig)
+}
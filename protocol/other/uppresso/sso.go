@@ -0,0 +1,143 @@
+package uppresso
+
+import (
+	"OPPID-artifacts/pkg/oppid/utils"
+	"OPPID-artifacts/protocol"
+	"bytes"
+	"errors"
+
+	GG "github.com/cloudflare/circl/ecc/bls12381"
+)
+
+// Adapter of UPPRESSO to protocol.SSO. The RP hands its certificate out in the challenge, the user derives the RP's
+// pseudonym with a fresh t that it passes on to the RP, and the account is Acct. User ids are hashed to scalars.
+
+type sso struct{ opts []Option }
+
+// NewSSO returns UPPRESSO as a protocol.SSO with public parameters set up with opts.
+func NewSSO(opts ...Option) protocol.SSO { return &sso{opts} }
+
+func (s *sso) Name() string { return "UPPRESSO" }
+
+func (s *sso) Setup() (protocol.IdP, error) {
+	pp := Setup(s.opts...)
+	isk, ipk, err := pp.KeyGen()
+	if err != nil {
+		return nil, err
+	}
+	return &ssoIdP{pp, isk, ipk}, nil
+}
+
+type ssoIdP struct {
+	pp  *PublicParams
+	isk *PrivateKey
+	ipk *PublicKey
+}
+
+type ssoRequest struct{ pidRP *PidRP }
+
+func (m ssoRequest) MarshalBinary() ([]byte, error) {
+	if m.pidRP == nil {
+		return nil, errors.New("uppresso: empty pseudonym")
+	}
+	return m.pidRP.BytesCompressed(), nil
+}
+
+type ssoState struct {
+	pidRP *PidRP
+	t     *GG.Scalar
+}
+
+type ssoResult struct {
+	t  *GG.Scalar
+	tk Token
+}
+
+func (m ssoResult) MarshalBinary() ([]byte, error) {
+	if m.t == nil {
+		return nil, errors.New("uppresso: empty pseudonym randomness")
+	}
+	tk, err := m.tk.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return append(utils.ScalarBytes(m.t), tk...), nil
+}
+
+func idU(uid []byte) *IdU {
+	u := utils.HashToScalar(uid, []byte(dstStr+"UID"))
+	return &u
+}
+
+func (idp *ssoIdP) Register(rid []byte) (protocol.RP, error) {
+	cert, err := idp.pp.Register(idp.isk, rid, rid)
+	if err != nil {
+		return nil, err
+	}
+	return &ssoRP{idp, cert}, nil
+}
+
+func (idp *ssoIdP) User(uid []byte) (protocol.User, error) {
+	return &ssoUser{idp, bytes.Clone(uid)}, nil
+}
+
+func (idp *ssoIdP) Response(uid []byte, req protocol.Message, ctx, sid []byte) (protocol.Message, error) {
+	r, ok := req.(ssoRequest)
+	if !ok {
+		return nil, protocol.ErrMessage
+	}
+	return idp.pp.Response(idp.isk, r.pidRP, idU(uid), ctx, sid)
+}
+
+type ssoUser struct {
+	idp *ssoIdP
+	uid []byte
+}
+
+func (u *ssoUser) ID() []byte { return u.uid }
+
+func (u *ssoUser) Request(ch protocol.Challenge) (protocol.Message, protocol.State, error) {
+	cert, ok := ch.Msg.(CertRP)
+	if !ok {
+		return nil, nil, protocol.ErrMessage
+	}
+	pidRP, t, err := u.idp.pp.Init(u.idp.ipk, &cert)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ssoRequest{pidRP}, ssoState{pidRP, t}, nil
+}
+
+func (u *ssoUser) Finalize(st protocol.State, tk protocol.Message) (protocol.Message, error) {
+	s, ok1 := st.(ssoState)
+	t, ok2 := tk.(Token)
+	if !ok1 || !ok2 {
+		return nil, protocol.ErrMessage
+	}
+	return ssoResult{s.t, t}, nil
+}
+
+type ssoRP struct {
+	idp  *ssoIdP
+	cert CertRP
+}
+
+func (rp *ssoRP) Begin() (protocol.Challenge, error) {
+	return protocol.NewChallenge(nil, rp.cert)
+}
+
+func (rp *ssoRP) Complete(ch protocol.Challenge, res protocol.Message) (protocol.Account, error) {
+	r, ok := res.(ssoResult)
+	if !ok {
+		return nil, protocol.ErrMessage
+	}
+	if !utils.IsValidScalar(r.t) {
+		return nil, errors.New("malformed pseudonym randomness")
+	}
+	pp := rp.idp.pp
+	acct := pp.Verify(rp.idp.ipk, pp.Request(rp.cert.Id, r.t), r.t, ch.Ctx, ch.Sid, r.tk)
+	if acct == nil {
+		return nil, errors.New("invalid token")
+	}
+	return protocol.Account(acct.Bytes()), nil
+}
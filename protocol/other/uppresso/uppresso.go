@@ -7,9 +7,10 @@
 package uppresso
 
 import (
-	RSA "OPPID-artifacts/pkg/oppid/sign/rsa256"
+	"OPPID-artifacts/pkg/oppid/sign"
+	"OPPID-artifacts/pkg/oppid/sign/schemes"
+	"OPPID-artifacts/pkg/oppid/transcript"
 	"OPPID-artifacts/pkg/oppid/utils"
-	"bytes"
 	"errors"
 
 	GG "github.com/cloudflare/circl/ecc/bls12381"
@@ -18,15 +19,15 @@ import (
 const dstStr = "OPPID_BLS12384_XMD:SHA-256_UPPRESSO_"
 
 type PublicParams struct {
-	rsa *RSA.PublicParams
+	scheme sign.Scheme
 }
 
 type PublicKey struct {
-	rsaPk *RSA.PublicKey
+	sigPk sign.PublicKey
 }
 
 type PrivateKey struct {
-	rsaSk *RSA.PrivateKey
+	sigSk sign.PrivateKey
 }
 
 type IdRP = GG.G1    // Identity of an RP
@@ -43,58 +44,79 @@ type Acct = GG.G1 // corresponds to a ppid
 type CertRP struct {
 	Id     *IdRP
 	enPtRP EnPtRP
-	sig    RSA.Signature
+	sig    sign.Signature
 }
 
 type Token struct {
 	pidU *PidU
-	sig  RSA.Signature
+	sig  sign.Signature
 }
 
 // tokenBytes generates a byte representation of the token
 func tokenBytes(pidRP *PidRP, pidU *PidU, ctx, sid []byte) []byte {
-	tkBuf := bytes.NewBuffer(nil)
-	tkBuf.Write([]byte(dstStr + "TOKEN"))
-	tkBuf.Write(pidRP.Bytes())
-	tkBuf.Write(pidU.Bytes())
-	tkBuf.Write(ctx)
-	tkBuf.Write(sid)
-	return tkBuf.Bytes()
+	return transcript.New(dstStr+"TOKEN").
+		Append("pid_rp", pidRP.Bytes()).
+		Append("pid_u", pidU.Bytes()).
+		Append("ctx", ctx).
+		Append("sid", sid).
+		Bytes()
 }
 
-func Setup() *PublicParams {
-	return &PublicParams{RSA.Setup(2048)}
+// certBytes generates a byte representation of the RP certificate
+func certBytes(idRP *IdRP, enPt EnPtRP) []byte {
+	return transcript.New(dstStr+"CERT").Append("id_rp", idRP.Bytes()).Append("endpoint", enPt).Bytes()
 }
 
-func (pp *PublicParams) KeyGen() (*PrivateKey, *PublicKey) {
-	rsaSk, rsaPk := pp.rsa.KeyGen()
-	return &PrivateKey{rsaSk}, &PublicKey{rsaPk}
+// Option configures Setup.
+type Option func(*PublicParams)
+
+// WithScheme makes the IdP sign with s instead of the default RS256, see package schemes.
+func WithScheme(s sign.Scheme) Option {
+	return func(pp *PublicParams) {
+		pp.scheme = s
+	}
+}
+
+func Setup(opts ...Option) *PublicParams {
+	pp := &PublicParams{schemes.Default()}
+	for _, opt := range opts {
+		opt(pp)
+	}
+	return pp
 }
 
-func (pp *PublicParams) Register(k *PrivateKey, id []byte, enPt EnPtRP) CertRP {
+func (pp *PublicParams) KeyGen() (*PrivateKey, *PublicKey, error) {
+	sigSk, sigPk, err := pp.scheme.KeyGen(nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &PrivateKey{sigSk}, &PublicKey{sigPk}, nil
+}
+
+func (pp *PublicParams) Register(k *PrivateKey, id []byte, enPt EnPtRP) (CertRP, error) {
 	r := utils.HashToScalar(id, []byte(dstStr+"REG"))
 	idRP := utils.GenerateG1Point(&r, GG.G1Generator())
 
-	var buf bytes.Buffer
-	buf.Write([]byte(dstStr + "CERT"))
-	buf.Write(idRP.Bytes())
-	buf.Write(enPt)
-
-	return CertRP{idRP, enPt, pp.rsa.Sign(k.rsaSk, buf.Bytes())}
+	sig, err := pp.scheme.Sign(nil, k.sigSk, certBytes(idRP, enPt))
+	if err != nil {
+		return CertRP{}, err
+	}
+	return CertRP{idRP, enPt, sig}, nil
 }
 
 func (pp *PublicParams) Init(ipk *PublicKey, cert *CertRP) (*PidRP, *GG.Scalar, error) {
-	var buf bytes.Buffer
-	buf.Write([]byte(dstStr + "CERT"))
-	buf.Write(cert.Id.Bytes())
-	buf.Write(cert.enPtRP)
-
-	isValid := pp.rsa.Verify(ipk.rsaPk, buf.Bytes(), cert.sig)
+	if cert == nil || !utils.IsValidG1(cert.Id) {
+		return nil, nil, errors.New("malformed certificate")
+	}
+	isValid := pp.scheme.Verify(ipk.sigPk, certBytes(cert.Id, cert.enPtRP), cert.sig)
 	if !isValid {
 		return nil, nil, errors.New("invalid certificate")
 	}
 
-	t := utils.GenerateRandomScalar()
+	t, err := utils.GenerateRandomScalarNotOne()
+	if err != nil {
+		return nil, nil, err
+	}
 	pidRP := utils.GenerateG1Point(t, cert.Id)
 
 	return pidRP, t, nil
@@ -104,22 +126,32 @@ func (pp *PublicParams) Request(idRP *IdRP, t *GG.Scalar) *PidRP {
 	return utils.GenerateG1Point(t, idRP)
 }
 
-func (pp *PublicParams) Response(isk *PrivateKey, pidRP *PidRP, uid *IdU, ctx, sid []byte) Token {
+func (pp *PublicParams) Response(isk *PrivateKey, pidRP *PidRP, uid *IdU, ctx, sid []byte) (Token, error) {
+	if !utils.IsValidG1(pidRP) {
+		return Token{}, errors.New("malformed RP pseudonym")
+	}
 	pidU := utils.GenerateG1Point(uid, pidRP)
 	tkBytes := tokenBytes(pidRP, pidU, ctx, sid)
-	sig := pp.rsa.Sign(isk.rsaSk, tkBytes)
+	sig, err := pp.scheme.Sign(nil, isk.sigSk, tkBytes)
+	if err != nil {
+		return Token{}, err
+	}
 
-	return Token{pidU, sig}
+	return Token{pidU, sig}, nil
 }
 
+// Verify returns nil for invalid or malformed tokens.
 func (pp *PublicParams) Verify(ipk *PublicKey, pidRP *PidRP, t *GG.Scalar, ctx, sid []byte, tk Token) *Acct {
+	if !utils.IsValidG1(pidRP) || !utils.IsValidG1(tk.pidU) || !utils.IsValidScalar(t) {
+		return nil
+	}
 	tkBytes := tokenBytes(pidRP, tk.pidU, ctx, sid)
-	if !pp.rsa.Verify(ipk.rsaPk, tkBytes, tk.sig) {
+	if !pp.scheme.Verify(ipk.sigPk, tkBytes, tk.sig) {
 		return nil
 	}
 
 	tInv := new(GG.Scalar)
 	tInv.Inv(t)
 
-	return utils.GenerateG1Point(t, tk.pidU)
+	return utils.GenerateG1Point(tInv, tk.pidU)
 }
@@ -1,8 +1,11 @@
 package uppresso
 
 import (
+	"OPPID-artifacts/pkg/oppid/sign/schemes"
 	"OPPID-artifacts/pkg/oppid/utils"
 	"testing"
+
+	GG "github.com/cloudflare/circl/ecc/bls12381"
 )
 
 func TestSetup(t *testing.T) {
@@ -10,28 +13,31 @@ func TestSetup(t *testing.T) {
 	if uppresso == nil {
 		t.Fatal("Setup returned nil")
 	}
-	if uppresso.rsa == nil {
+	if uppresso.scheme == nil {
 		t.Fatal("Setup did not initialize RSA parameters")
 	}
 }
 
 func TestKeyGen(t *testing.T) {
 	uppresso := Setup()
-	isk, ipk := uppresso.KeyGen()
+	isk, ipk, err := uppresso.KeyGen()
+	if err != nil {
+		t.Fatalf("KeyGen returned an error: %v", err)
+	}
 	if isk == nil || ipk == nil {
 		t.Fatal("KeyGen returned nil")
 	}
-	if isk.rsaSk == nil || ipk.rsaPk == nil {
+	if isk.sigSk == nil || ipk.sigPk == nil {
 		t.Fatal("KeyGen did not generate valid RSA keys")
 	}
 }
 
 func TestRegister(t *testing.T) {
 	uppresso := Setup()
-	isk, _ := uppresso.KeyGen()
+	isk, _, _ := uppresso.KeyGen()
 	id := []byte("test-id")
 	enPt := []byte("endpoint")
-	cert := uppresso.Register(isk, id, enPt)
+	cert, _ := uppresso.Register(isk, id, enPt)
 	if cert.Id == nil {
 		t.Fatal("Register did not generate a valid idRP")
 	}
@@ -42,10 +48,10 @@ func TestRegister(t *testing.T) {
 
 func TestInit(t *testing.T) {
 	uppresso := Setup()
-	isk, ipk := uppresso.KeyGen()
+	isk, ipk, _ := uppresso.KeyGen()
 	id := []byte("test-id")
 	enPt := []byte("endpoint")
-	cert := uppresso.Register(isk, id, enPt)
+	cert, _ := uppresso.Register(isk, id, enPt)
 
 	pidRP, _, err := uppresso.Init(ipk, &cert)
 	if err != nil {
@@ -58,10 +64,10 @@ func TestInit(t *testing.T) {
 
 func TestRequest(t *testing.T) {
 	uppresso := Setup()
-	sk, pk := uppresso.KeyGen()
+	sk, pk, _ := uppresso.KeyGen()
 	id := []byte("test-id")
 	enPt := []byte("endpoint")
-	cert := uppresso.Register(sk, id, enPt)
+	cert, _ := uppresso.Register(sk, id, enPt)
 	_, r, _ := uppresso.Init(pk, &cert)
 	pidRP := uppresso.Request(cert.Id, r)
 	if pidRP == nil {
@@ -71,15 +77,15 @@ func TestRequest(t *testing.T) {
 
 func TestResponse(t *testing.T) {
 	uppresso := Setup()
-	sk, pk := uppresso.KeyGen()
+	sk, pk, _ := uppresso.KeyGen()
 	id := []byte("test-id")
 	enPt := []byte("endpoint")
-	cert := uppresso.Register(sk, id, enPt)
+	cert, _ := uppresso.Register(sk, id, enPt)
 	pidRP, _, _ := uppresso.Init(pk, &cert)
-	idU := utils.GenerateRandomScalar()
+	idU, _ := utils.GenerateRandomScalar()
 	ctx := []byte("context")
 	sid := []byte("session-id")
-	token := uppresso.Response(sk, pidRP, idU, ctx, sid)
+	token, _ := uppresso.Response(sk, pidRP, idU, ctx, sid)
 	if token.pidU == nil {
 		t.Fatal("Response did not generate a valid pidU")
 	}
@@ -90,18 +96,73 @@ func TestResponse(t *testing.T) {
 
 func TestVerify(t *testing.T) {
 	uppresso := Setup()
-	sk, pk := uppresso.KeyGen()
+	sk, pk, _ := uppresso.KeyGen()
 	id := []byte("test-id")
 	enPt := []byte("endpoint")
-	cert := uppresso.Register(sk, id, enPt)
+	cert, _ := uppresso.Register(sk, id, enPt)
 	pidRP, r, _ := uppresso.Init(pk, &cert)
 	rpPidRP := uppresso.Request(cert.Id, r)
-	idU := utils.GenerateRandomScalar()
+	idU, _ := utils.GenerateRandomScalar()
 	ctx := []byte("context")
 	sid := []byte("session-id")
-	token := uppresso.Response(sk, pidRP, idU, ctx, sid)
+	token, _ := uppresso.Response(sk, pidRP, idU, ctx, sid)
 	acct := uppresso.Verify(pk, rpPidRP, r, ctx, sid, token)
 	if acct == nil {
 		t.Fatal("Verify failed")
 	}
+	if !acct.IsEqual(utils.GenerateG1Point(idU, cert.Id)) {
+		t.Fatal("Expected the account ID_U*ID_RP, independent of the pseudonym")
+	}
+}
+
+func TestMalformedInputs(t *testing.T) {
+	uppresso := Setup()
+	sk, pk, _ := uppresso.KeyGen()
+
+	identity := new(GG.G1)
+	identity.SetIdentity()
+
+	if _, _, err := uppresso.Init(pk, &CertRP{Id: identity}); err == nil {
+		t.Fatal("Init accepted a certificate with an identity RP identifier")
+	}
+	idU, _ := utils.GenerateRandomScalar()
+	if _, err := uppresso.Response(sk, identity, idU, []byte("ctx"), []byte("sid")); err == nil {
+		t.Fatal("Response accepted an identity RP pseudonym")
+	}
+	if acct := uppresso.Verify(pk, GG.G1Generator(), idU, []byte("ctx"), []byte("sid"), Token{}); acct != nil {
+		t.Fatal("Verify accepted an empty token")
+	}
+}
+
+func TestFieldBoundaryCollision(t *testing.T) {
+	uppresso := Setup()
+	sk, pk, _ := uppresso.KeyGen()
+	cert, _ := uppresso.Register(sk, []byte("test-id"), []byte("endpoint"))
+	pidRP, r, _ := uppresso.Init(pk, &cert)
+	rpPidRP := uppresso.Request(cert.Id, r)
+	idU, _ := utils.GenerateRandomScalar()
+	token, _ := uppresso.Response(sk, pidRP, idU, []byte("ab"), []byte("c"))
+	if uppresso.Verify(pk, rpPidRP, r, []byte("a"), []byte("bc"), token) != nil {
+		t.Fatal("Verify accepted a token with shifted ctx/sid boundary")
+	}
+}
+
+func TestSignatureSchemes(t *testing.T) {
+	for _, s := range schemes.All() {
+		uppresso := Setup(WithScheme(s))
+		sk, pk, _ := uppresso.KeyGen()
+		cert, err := uppresso.Register(sk, []byte("test-id"), []byte("endpoint"))
+		if err != nil {
+			t.Fatalf("%s: Register returned an error: %v", s.Name(), err)
+		}
+		pidRP, r, err := uppresso.Init(pk, &cert)
+		if err != nil {
+			t.Fatalf("%s: Init returned an error: %v", s.Name(), err)
+		}
+		idU, _ := utils.GenerateRandomScalar()
+		token, _ := uppresso.Response(sk, pidRP, idU, []byte("context"), []byte("session-id"))
+		if uppresso.Verify(pk, uppresso.Request(cert.Id, r), r, []byte("context"), []byte("session-id"), token) == nil {
+			t.Fatalf("%s: Verify failed", s.Name())
+		}
+	}
 }
@@ -0,0 +1,41 @@
+// Package protocols lists the adapters of all SSO protocols, e.g., for the generic tests and benchmarks.
+
+package protocols
+
+import (
+	"OPPID-artifacts/pkg/oppid/sign"
+	"OPPID-artifacts/protocol"
+	OPPID "OPPID-artifacts/protocol/oppid"
+	"OPPID-artifacts/protocol/other/aifzkp"
+	"OPPID-artifacts/protocol/other/oidc"
+	"OPPID-artifacts/protocol/other/ppoidc"
+	"OPPID-artifacts/protocol/other/uppresso"
+	"fmt"
+)
+
+// All returns every protocol with the default token-signature scheme, with OPPID first.
+func All() []protocol.SSO {
+	return []protocol.SSO{OPPID.NewSSO(), aifzkp.NewSSO(), ppoidc.NewSSO(), uppresso.NewSSO(), oidc.NewSSO()}
+}
+
+// AllWithScheme returns every protocol with the IdP signing tokens with s.
+func AllWithScheme(s sign.Scheme) []protocol.SSO {
+	return []protocol.SSO{OPPID.NewSSO(OPPID.WithScheme(s)), aifzkp.NewSSO(aifzkp.WithScheme(s)),
+		ppoidc.NewSSO(ppoidc.WithScheme(s)), uppresso.NewSSO(uppresso.WithScheme(s)), oidc.NewSSO(oidc.WithScheme(s))}
+}
+
+// Slow reports whether a login with p takes minutes rather than milliseconds, e.g., for the SNARK of PPOIDC, so that
+// short tests and benchmarks can skip it.
+func Slow(p protocol.SSO) bool {
+	return p.Name() == "PPOIDC"
+}
+
+// New returns the protocol with the given name.
+func New(name string) (protocol.SSO, error) {
+	for _, p := range All() {
+		if p.Name() == name {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("protocol: unknown protocol %q", name)
+}
@@ -0,0 +1,169 @@
+package protocols
+
+import (
+	"OPPID-artifacts/protocol"
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// forEach runs test against every protocol with a fresh IdP.
+func forEach(t *testing.T, test func(t *testing.T, idp protocol.IdP)) {
+	for _, p := range All() {
+		t.Run(p.Name(), func(t *testing.T) {
+			if testing.Short() && Slow(p) {
+				t.Skip("skipping slow protocol in short mode")
+			}
+			idp, err := p.Setup()
+			if err != nil {
+				t.Fatalf("Setup returned an error: %v", err)
+			}
+			test(t, idp)
+		})
+	}
+}
+
+func setup(t *testing.T, idp protocol.IdP, rid, uid string) (protocol.RP, protocol.User) {
+	t.Helper()
+	rp, err := idp.Register([]byte(rid))
+	if err != nil {
+		t.Fatalf("Register returned an error: %v", err)
+	}
+	u, err := idp.User([]byte(uid))
+	if err != nil {
+		t.Fatalf("User returned an error: %v", err)
+	}
+	return rp, u
+}
+
+func login(t *testing.T, idp protocol.IdP, rp protocol.RP, u protocol.User) protocol.Account {
+	t.Helper()
+	acct, err := protocol.Login(idp, rp, u)
+	if err != nil {
+		t.Fatalf("Login returned an error: %v", err)
+	}
+	if len(acct) == 0 {
+		t.Fatalf("Login returned an empty account")
+	}
+	return acct
+}
+
+func TestLogin(t *testing.T) {
+	forEach(t, func(t *testing.T, idp protocol.IdP) {
+		rp, alice := setup(t, idp, "Test-RID", "alice.doe@idp.com")
+		bob, err := idp.User([]byte("bob.doe@idp.com"))
+		if err != nil {
+			t.Fatalf("User returned an error: %v", err)
+		}
+
+		acct := login(t, idp, rp, alice)
+		if !bytes.Equal(login(t, idp, rp, alice), acct) {
+			t.Errorf("Expected the same account for another login of the same user")
+		}
+		if bytes.Equal(login(t, idp, rp, bob), acct) {
+			t.Errorf("Expected different accounts for different users")
+		}
+	})
+}
+
+func TestCompleteOtherSession(t *testing.T) {
+	forEach(t, func(t *testing.T, idp protocol.IdP) {
+		rp, u := setup(t, idp, "Test-RID", "alice.doe@idp.com")
+		ch, _ := rp.Begin()
+		other, _ := rp.Begin()
+
+		req, st, err := u.Request(ch)
+		if err != nil {
+			t.Fatalf("Request returned an error: %v", err)
+		}
+		tk, err := idp.Response(u.ID(), req, ch.Ctx, ch.Sid)
+		if err != nil {
+			t.Fatalf("Response returned an error: %v", err)
+		}
+		res, err := u.Finalize(st, tk)
+		if err != nil {
+			t.Fatalf("Finalize returned an error: %v", err)
+		}
+		if _, err := rp.Complete(other, res); err == nil {
+			t.Errorf("Expected token of another session to be rejected")
+		}
+		if _, err := rp.Complete(ch, res); err != nil {
+			t.Errorf("Complete returned an error: %v", err)
+		}
+	})
+}
+
+func TestForeignMessages(t *testing.T) {
+	forEach(t, func(t *testing.T, idp protocol.IdP) {
+		rp, u := setup(t, idp, "Test-RID", "alice.doe@idp.com")
+		ch, _ := rp.Begin()
+		if _, err := idp.Response(u.ID(), "request", ch.Ctx, ch.Sid); !errors.Is(err, protocol.ErrMessage) {
+			t.Errorf("Expected ErrMessage from Response, got %v", err)
+		}
+		if _, err := rp.Complete(ch, nil); !errors.Is(err, protocol.ErrMessage) {
+			t.Errorf("Expected ErrMessage from Complete, got %v", err)
+		}
+		ch.Msg = struct{}{}
+		if _, _, err := u.Request(ch); !errors.Is(err, protocol.ErrMessage) {
+			t.Errorf("Expected ErrMessage from Request, got %v", err)
+		}
+	})
+}
+
+// TestConcurrentResponse answers the same request from many goroutines at once, to be run with -race.
+func TestConcurrentResponse(t *testing.T) {
+	const goroutines = 8
+	forEach(t, func(t *testing.T, idp protocol.IdP) {
+		rp, u := setup(t, idp, "Test-RID", "alice.doe@idp.com")
+		ch, _ := rp.Begin()
+		req, st, err := u.Request(ch)
+		if err != nil {
+			t.Fatalf("Request returned an error: %v", err)
+		}
+
+		tks := make([]protocol.Message, goroutines)
+		errs := make([]error, goroutines)
+		var wg sync.WaitGroup
+		for g := range tks {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				tks[g], errs[g] = idp.Response(u.ID(), req, ch.Ctx, ch.Sid)
+			}()
+		}
+		wg.Wait()
+
+		var acct protocol.Account
+		for g, tk := range tks {
+			if errs[g] != nil {
+				t.Fatalf("Response %d returned an error: %v", g, errs[g])
+			}
+			res, err := u.Finalize(st, tk)
+			if err != nil {
+				t.Fatalf("Finalize of response %d returned an error: %v", g, err)
+			}
+			a, err := rp.Complete(ch, res)
+			if err != nil {
+				t.Fatalf("Complete of response %d returned an error: %v", g, err)
+			}
+			if acct == nil {
+				acct = a
+			} else if !bytes.Equal(a, acct) {
+				t.Errorf("Response %d yields another account", g)
+			}
+		}
+	})
+}
+
+func TestNew(t *testing.T) {
+	for _, p := range All() {
+		q, err := New(p.Name())
+		if err != nil || q.Name() != p.Name() {
+			t.Errorf("New(%q) returned %v, %v", p.Name(), q, err)
+		}
+	}
+	if _, err := New("unknown"); err == nil {
+		t.Errorf("Expected unknown protocol to be rejected")
+	}
+}
@@ -0,0 +1,178 @@
+// Package protocol abstracts the single sign-on (SSO) protocols of this repository behind per-role adapters, so that
+// one driver, test suite and benchmark harness can run a login with any of them. A login runs as follows:
+//
+//	RP.Begin -> User.Request -> IdP.Response -> User.Finalize -> RP.Complete
+//
+// Messages between the roles are opaque to the driver; every adapter only accepts the messages of its own protocol.
+
+package protocol
+
+import (
+	"crypto/rand"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Message is a protocol message handed from one role to the next. Messages implement encoding.BinaryMarshaler, so
+// that their sizes can be accounted for, see package metrics.
+type Message any
+
+// State is the state that a user keeps between Request and Finalize of one login.
+type State any
+
+// Account identifies a user at an RP. Logins of the same user at the same RP yield the same account.
+type Account []byte
+
+// Challenge is handed from the RP to the user to start a login.
+type Challenge struct {
+	Sid []byte  // session id
+	Ctx []byte  // context the IdP includes in the token
+	Msg Message // protocol-specific part, e.g., the RP's credential or certificate
+}
+
+// SidLength is the length of the session ids drawn by NewChallenge.
+const SidLength = 16
+
+// NewChallenge returns a challenge for msg with a fresh random session id, for use in RP.Begin.
+func NewChallenge(ctx []byte, msg Message) (Challenge, error) {
+	sid := make([]byte, SidLength)
+	if _, err := rand.Read(sid); err != nil {
+		return Challenge{}, err
+	}
+	return Challenge{sid, ctx, msg}, nil
+}
+
+// SSO is a protocol, e.g., OPPID or OIDC.
+type SSO interface {
+	Name() string
+	// Setup generates the public parameters and the keys of an IdP.
+	Setup() (IdP, error)
+}
+
+// IdP is the identity provider. It registers RPs and users and answers their login requests.
+type IdP interface {
+	// Register registers the RP with identifier rid.
+	Register(rid []byte) (RP, error)
+	// User returns the user with identifier uid, e.g., an email address.
+	User(uid []byte) (User, error)
+	// Response answers the request of the authenticated user uid with a token.
+	Response(uid []byte, req Message, ctx, sid []byte) (Message, error)
+}
+
+// User is the user agent of one user.
+type User interface {
+	ID() []byte
+	// Request builds the login request to the IdP for the challenge of an RP.
+	Request(ch Challenge) (Message, State, error)
+	// Finalize turns the IdP's token into the message for the RP.
+	Finalize(st State, tk Message) (Message, error)
+}
+
+// RP is a registered relying party.
+type RP interface {
+	// Begin starts a login.
+	Begin() (Challenge, error)
+	// Complete verifies the user's message for the login started with ch and returns the account of the user.
+	Complete(ch Challenge, res Message) (Account, error)
+}
+
+// ErrMessage is returned by adapters for messages of another protocol.
+var ErrMessage = errors.New("protocol: unexpected message type")
+
+// Step identifies a message of a login.
+type Step int
+
+const (
+	StepChallenge Step = iota // from the RP to the user
+	StepRequest               // from the user to the IdP
+	StepToken                 // from the IdP to the user
+	StepResult                // from the user to the RP
+)
+
+func (s Step) String() string {
+	switch s {
+	case StepChallenge:
+		return "challenge"
+	case StepRequest:
+		return "request"
+	case StepToken:
+		return "token"
+	case StepResult:
+		return "result"
+	}
+	return fmt.Sprintf("Step(%d)", int(s))
+}
+
+// Observer is called with every message of a login, in order.
+type Observer func(s Step, m Message)
+
+// Login runs one login of u at rp with idp and returns the account of u at rp.
+func Login(idp IdP, rp RP, u User) (Account, error) {
+	return LoginObserved(idp, rp, u, nil)
+}
+
+// LoginObserved runs Login and hands every message to observe, if not nil. For the challenge, that is its
+// protocol-specific part.
+func LoginObserved(idp IdP, rp RP, u User, observe Observer) (Account, error) {
+	if observe == nil {
+		observe = func(Step, Message) {}
+	}
+	ch, err := rp.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("protocol: begin: %w", err)
+	}
+	observe(StepChallenge, ch.Msg)
+	req, st, err := u.Request(ch)
+	if err != nil {
+		return nil, fmt.Errorf("protocol: request: %w", err)
+	}
+	observe(StepRequest, req)
+	tk, err := idp.Response(u.ID(), req, ch.Ctx, ch.Sid)
+	if err != nil {
+		return nil, fmt.Errorf("protocol: response: %w", err)
+	}
+	observe(StepToken, tk)
+	res, err := u.Finalize(st, tk)
+	if err != nil {
+		return nil, fmt.Errorf("protocol: finalize: %w", err)
+	}
+	observe(StepResult, res)
+	acct, err := rp.Complete(ch, res)
+	if err != nil {
+		return nil, fmt.Errorf("protocol: complete: %w", err)
+	}
+	return acct, nil
+}
+
+// AppendField appends b to buf with a 2-byte big-endian length prefix, for variable-length fields of encoded messages.
+func AppendField(buf, b []byte) ([]byte, error) {
+	if len(b) > math.MaxUint16 {
+		return nil, fmt.Errorf("protocol: field of %d bytes exceeds maximum length", len(b))
+	}
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(b)))
+	return append(buf, b...), nil
+}
+
+// Bytes is a message field that encodes as itself.
+type Bytes []byte
+
+func (b Bytes) MarshalBinary() ([]byte, error) { return b, nil }
+
+// MarshalFields concatenates the encodings of fields, each with a length prefix, e.g., for adapter messages that bundle
+// several protocol messages.
+func MarshalFields(fields ...encoding.BinaryMarshaler) ([]byte, error) {
+	var buf []byte
+	for _, f := range fields {
+		b, err := f.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		if buf, err = AppendField(buf, b); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}